@@ -1,12 +1,17 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/lunarway/release-manager/internal/artifact"
 	intslack "github.com/lunarway/release-manager/internal/slack"
 	"github.com/nlopes/slack"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +35,9 @@ func addCommand(options *Options) *cobra.Command {
 		appendPushSubCommand(options),
 		appendSnykCodeSubCommand(options),
 		appendSnykDockerSubCommand(options),
+		appendSBOMSubCommand(options),
+		appendProvenanceSubCommand(options),
+		appendCustomSubCommand(options),
 	)
 	return command
 }
@@ -210,6 +218,7 @@ func appendPushSubCommand(options *Options) *cobra.Command {
 	command.Flags().StringVar(&pushData.Image, "image", "", "")
 	command.Flags().StringVar(&pushData.Tag, "tag", "", "")
 	command.Flags().StringVar(&pushData.DockerVersion, "docker-version", "", "")
+	command.Flags().StringVar(&pushData.Digest, "digest", "", "digest of the pushed image, e.g. sha256:<hex>. Used to pin the image on release")
 	// errors are skipped here as the only case they can occour are if thee flag
 	// does not exist on the command.
 	//nolint:errcheck
@@ -221,6 +230,237 @@ func appendPushSubCommand(options *Options) *cobra.Command {
 	return command
 }
 
+func appendSBOMSubCommand(options *Options) *cobra.Command {
+	var sbomData artifact.SBOMData
+	var file string
+	command := &cobra.Command{
+		Use:   "sbom",
+		Short: "add a reference to a software bill of materials",
+		Long: `Add a reference to a CycloneDX or SPDX software bill of materials document.
+
+If --file is set the format, spec version, generator, number of components and
+digest are read from the JSON document. Flags take precedence over values read
+from the document.`,
+		Example: `  artifact add sbom --file bom.json --url https://sbom.example.com/product/bom.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data := sbomData
+			if file != "" {
+				document, err := os.ReadFile(file)
+				if err != nil {
+					return errors.Wrapf(err, "read sbom file '%s'", file)
+				}
+				data, err = artifact.SummarizeSBOM(document)
+				if err != nil {
+					return errors.WithMessagef(err, "sbom file '%s'", file)
+				}
+				flags := cmd.Flags()
+				data.URL = sbomData.URL
+				if flags.Changed("format") {
+					data.Format = sbomData.Format
+				}
+				if flags.Changed("spec-version") {
+					data.SpecVersion = sbomData.SpecVersion
+				}
+				if flags.Changed("digest") {
+					data.Digest = sbomData.Digest
+				}
+				if flags.Changed("generator") {
+					data.Generator = sbomData.Generator
+				}
+				if flags.Changed("components") {
+					data.Components = sbomData.Components
+				}
+			}
+			if data.Format != artifact.SBOMFormatCycloneDX && data.Format != artifact.SBOMFormatSPDX {
+				return errors.Errorf("format must be %s or %s: got '%s'", artifact.SBOMFormatCycloneDX, artifact.SBOMFormatSPDX, data.Format)
+			}
+			err := artifact.Update(path.Join(options.RootPath, options.FileName), func(s artifact.Spec) artifact.Spec {
+				return setStage(s, artifact.Stage{
+					ID:   artifact.StageIDSBOM,
+					Name: "SBOM",
+					Data: data,
+				})
+			})
+			if err != nil {
+				return err
+			}
+			err = notifySlack(options, fmt.Sprintf(":white_check_mark: <%s|*SBOM*> (%s, %d components)", data.URL, data.Format, data.Components), intslack.MsgColorYellow)
+			if err != nil {
+				fmt.Printf("Error notifying slack")
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&file, "file", "", "path to a CycloneDX or SPDX JSON document to summarize")
+	command.Flags().StringVar(&sbomData.URL, "url", "", "URL where the document is stored")
+	command.Flags().StringVar(&sbomData.Format, "format", "", "document format: cyclonedx or spdx")
+	command.Flags().StringVar(&sbomData.SpecVersion, "spec-version", "", "version of the document format specification")
+	command.Flags().StringVar(&sbomData.Digest, "digest", "", "digest of the document, e.g. sha256:<hex>")
+	command.Flags().StringVar(&sbomData.Generator, "generator", "", "tool that generated the document")
+	command.Flags().IntVar(&sbomData.Components, "components", 0, "number of components in the document")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("url")
+	return command
+}
+
+func appendProvenanceSubCommand(options *Options) *cobra.Command {
+	var provenanceData artifact.ProvenanceData
+	var materials []string
+	command := &cobra.Command{
+		Use:   "provenance",
+		Short: "add build provenance",
+		Long: `Add SLSA style build provenance describing how the artifact was built.
+
+The invocation URL, source and start time default to the CI job URL,
+application commit and CI start time of the artifact.`,
+		Example: `  artifact add provenance --builder-id https://jenkins.example.com --material pkg:docker/golang@sha256:1234`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, m := range materials {
+				material, err := parseMaterial(m)
+				if err != nil {
+					return err
+				}
+				provenanceData.Materials = append(provenanceData.Materials, material)
+			}
+			err := artifact.Update(path.Join(options.RootPath, options.FileName), func(s artifact.Spec) artifact.Spec {
+				data := provenanceData
+				if data.InvocationURL == "" {
+					data.InvocationURL = s.CI.JobURL
+				}
+				if data.Source.URI == "" {
+					data.Source.URI = s.Application.URL
+				}
+				if data.Source.Digest == "" && s.Application.SHA != "" {
+					data.Source.Digest = "sha1:" + s.Application.SHA
+				}
+				data.StartedOn = s.CI.Start
+				data.FinishedOn = time.Now()
+				return setStage(s, artifact.Stage{
+					ID:   artifact.StageIDProvenance,
+					Name: "Provenance",
+					Data: data,
+				})
+			})
+			if err != nil {
+				return err
+			}
+			err = notifySlack(options, fmt.Sprintf(":white_check_mark: *Provenance* (%s)", provenanceData.BuilderID), intslack.MsgColorYellow)
+			if err != nil {
+				fmt.Printf("Error notifying slack")
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&provenanceData.BuilderID, "builder-id", "", "identifier of the build platform, e.g. the CI system URL")
+	command.Flags().StringVar(&provenanceData.BuildType, "build-type", "", "URI describing how the build was run")
+	command.Flags().StringVar(&provenanceData.InvocationURL, "invocation-url", "", "URL of the build run. Defaults to the CI job URL of the artifact")
+	command.Flags().StringVar(&provenanceData.Source.URI, "source-uri", "", "URI of the source repository. Defaults to the application URL of the artifact")
+	command.Flags().StringVar(&provenanceData.Source.Digest, "source-digest", "", "digest of the source revision. Defaults to the application SHA of the artifact")
+	command.Flags().StringArrayVar(&materials, "material", nil, "build input in the format <uri>@<algorithm>:<digest>. Can be repeated")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("builder-id")
+	return command
+}
+
+// parseMaterial parses a provenance material in the format
+// <uri>@<algorithm>:<digest>. The URI may itself contain '@'.
+func parseMaterial(s string) (artifact.ProvenanceMaterial, error) {
+	i := strings.LastIndex(s, "@")
+	if i <= 0 || !strings.Contains(s[i+1:], ":") {
+		return artifact.ProvenanceMaterial{}, errors.Errorf("material '%s' must be in the format <uri>@<algorithm>:<digest>", s)
+	}
+	return artifact.ProvenanceMaterial{
+		URI:    s[:i],
+		Digest: s[i+1:],
+	}, nil
+}
+
+func appendCustomSubCommand(options *Options) *cobra.Command {
+	var id, name, dataFile string
+	command := &cobra.Command{
+		Use:   "custom",
+		Short: "add a custom stage with free-form data",
+		Long: `Add a custom stage with free-form data read from a JSON file.
+
+The data must be a JSON object. The keys "status", "url" and "summary" are
+optional but must be strings if set. "status" must be one of passed, failed,
+warning or skipped and "url" an absolute http or https URL.`,
+		Example: `  artifact add custom --id lint --name Lint --data-file lint.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := os.ReadFile(dataFile)
+			if err != nil {
+				return errors.Wrapf(err, "read data file '%s'", dataFile)
+			}
+			var data artifact.CustomData
+			err = json.Unmarshal(content, &data)
+			if err == nil && data == nil {
+				err = errors.New("got null")
+			}
+			if err != nil {
+				return errors.Wrapf(err, "data file '%s' must contain a JSON object", dataFile)
+			}
+			stageID := artifact.StageID(id)
+			err = artifact.ValidateCustomStage(stageID, data)
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				name = id
+			}
+			err = artifact.Update(path.Join(options.RootPath, options.FileName), func(s artifact.Spec) artifact.Spec {
+				return setStage(s, artifact.Stage{
+					ID:   stageID,
+					Name: name,
+					Data: data,
+				})
+			})
+			if err != nil {
+				return err
+			}
+			err = notifySlack(options, customStageMessage(name, data), intslack.MsgColorYellow)
+			if err != nil {
+				fmt.Printf("Error notifying slack")
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVar(&id, "id", "", "id of the stage. Must be lowercase alphanumeric characters or '-'")
+	command.Flags().StringVar(&name, "name", "", "display name of the stage. Defaults to the id")
+	command.Flags().StringVar(&dataFile, "data-file", "", "path to a JSON file with the stage data")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("id")
+	//nolint:errcheck
+	command.MarkFlagRequired("data-file")
+	return command
+}
+
+// customStageMessage returns the Slack message line of a custom stage.
+func customStageMessage(name string, data artifact.CustomData) string {
+	icon := ":white_check_mark:"
+	switch data.Status() {
+	case artifact.CustomStatusFailed:
+		icon = ":x:"
+	case artifact.CustomStatusWarning:
+		icon = ":warning:"
+	case artifact.CustomStatusSkipped:
+		icon = ":heavy_minus_sign:"
+	}
+	title := fmt.Sprintf("*%s*", name)
+	if data.URL() != "" {
+		title = fmt.Sprintf("<%s|*%s*>", data.URL(), name)
+	}
+	if data.Summary() == "" {
+		return fmt.Sprintf("%s %s", icon, title)
+	}
+	return fmt.Sprintf("%s %s (%s)", icon, title, data.Summary())
+}
+
 func setStage(s artifact.Spec, stage artifact.Stage) artifact.Spec {
 	var updatedStages []artifact.Stage
 	var replaced bool
@@ -115,3 +115,36 @@ func Test(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomStageMessage(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		data   artifact.CustomData
+		output string
+	}{
+		{
+			desc:   "no typed keys",
+			data:   artifact.CustomData{"warnings": 2},
+			output: ":white_check_mark: *Lint*",
+		},
+		{
+			desc: "failed with url and summary",
+			data: artifact.CustomData{
+				"status":  "failed",
+				"url":     "https://ci.example.com/lint",
+				"summary": "2 errors",
+			},
+			output: ":x: <https://ci.example.com/lint|*Lint*> (2 errors)",
+		},
+		{
+			desc:   "warning",
+			data:   artifact.CustomData{"status": "warning"},
+			output: ":warning: *Lint*",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.output, customStageMessage("Lint", tc.data))
+		})
+	}
+}
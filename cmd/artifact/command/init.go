@@ -18,6 +18,7 @@ import (
 func initCommand(options *Options) *cobra.Command {
 	var s artifact.Spec
 	var users []string
+	var labels []string
 
 	command := &cobra.Command{
 		Use:   "init",
@@ -56,6 +57,11 @@ func initCommand(options *Options) *cobra.Command {
 				}
 			}
 
+			s.Labels, err = artifact.ParseLabels(labels)
+			if err != nil {
+				return err
+			}
+
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -114,6 +120,7 @@ func initCommand(options *Options) *cobra.Command {
 	command.Flags().StringVar(&s.Service, "service", "", "the service name")
 	command.Flags().StringVar(&s.Namespace, "namespace", "", "the namespace to deploy the service to")
 	command.Flags().StringVar(&s.Squad, "squad", "", "the squad who owns the service")
+	command.Flags().StringArrayVar(&labels, "label", nil, "label to search for the artifact by, key-value pair: <key>=<value>. Can be repeated")
 
 	// Init git data
 	command.Flags().StringVar(&s.Application.AuthorName, "git-author-name", "", "the commit author name")
@@ -8,14 +8,16 @@ import (
 
 	"github.com/lunarway/release-manager/internal/flow"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/signing"
 	intslack "github.com/lunarway/release-manager/internal/slack"
 	"github.com/nlopes/slack"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 func pushCommand(options *Options) *cobra.Command {
 	releaseManagerClient := httpinternal.Client{}
-	var idpURL, clientID, clientSecret, artifactScope string
+	var idpURL, clientID, clientSecret, artifactScope, signingKeyPath string
 	command := &cobra.Command{
 		Use:   "push",
 		Short: "push artifact to artifact repository",
@@ -26,6 +28,18 @@ func pushCommand(options *Options) *cobra.Command {
 			authenticator := httpinternal.NewClientAuthenticator(clientID, clientSecret, idpURL, artifactScope)
 			releaseManagerClient.Auth = &authenticator
 
+			if signingKeyPath != "" {
+				key, err := signing.LoadPrivateKey(signingKeyPath)
+				if err != nil {
+					return errors.WithMessagef(err, "load signing key '%s'", signingKeyPath)
+				}
+				signature, err := signing.Sign(options.RootPath, key)
+				if err != nil {
+					return errors.WithMessage(err, "sign artifact")
+				}
+				fmt.Printf("Artifact signed with key %s: %s\n", signature.KeyID, signature.Digest)
+			}
+
 			artifactID, err = flow.PushArtifactToReleaseManager(ctx, &releaseManagerClient, options.FileName, options.RootPath)
 			if err != nil {
 				return err
@@ -52,6 +66,7 @@ func pushCommand(options *Options) *cobra.Command {
 	command.Flags().StringVar(&clientID, "client-id", "", "client id of this application issued by the identity provider")
 	command.Flags().StringVar(&clientSecret, "client-secret", "", "the client secret")
 	command.Flags().StringVar(&artifactScope, "artifact-scope", "release_artifact", "scope to request from iDP")
+	command.Flags().StringVar(&signingKeyPath, "signing-key", os.Getenv("ARTIFACT_SIGNING_KEY"), "path to a PEM encoded ed25519 private key to sign the artifact with. If empty the artifact is not signed")
 
 	// errors are skipped here as the only case they can occour are if thee flag
 	// does not exist on the command.
@@ -23,6 +23,7 @@ func StartDaemon() *cobra.Command {
 	var environment, kubeConfigPath string
 	var idpURL, clientID, clientSecret, daemonScope string
 	var moduloCrashReportNotif float64
+	var stateReportInterval time.Duration
 	var logConfiguration *log.Configuration
 
 	client := httpinternal.Client{}
@@ -61,6 +62,10 @@ func StartDaemon() *cobra.Command {
 			kubernetes.RegisterJobInformer(kubectl.InformerFactory, exporter, handlerFactory, kubectl.Clientset)
 			kubernetes.RegisterPodInformer(kubectl.InformerFactory, exporter, handlerFactory, kubectl.Clientset, moduloCrashReportNotif)
 			kubernetes.RegisterStatefulSetInformer(kubectl.InformerFactory, exporter, handlerFactory, kubectl.Clientset)
+			var stateReporter *kubernetes.StateReporter
+			if stateReportInterval > 0 {
+				stateReporter = kubernetes.NewStateReporter(kubectl.InformerFactory, exporter)
+			}
 			server := flux_notification_controller.NewHttpServer()
 			go func() {
 				err := server.ListenAndServe()
@@ -76,6 +81,9 @@ func StartDaemon() *cobra.Command {
 			if err != nil {
 				return errors.WithMessage(err, "could not start client")
 			}
+			if stateReporter != nil {
+				go stateReporter.Run(stateReportInterval, stopCh)
+			}
 
 			sigs := make(chan os.Signal, 1)
 			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -106,6 +114,7 @@ func StartDaemon() *cobra.Command {
 	command.Flags().StringVar(&environment, "environment", "", "environment where release-daemon is running")
 	command.Flags().StringVar(&kubeConfigPath, "kubeconfig", "", "path to kubeconfig file. If not specified, then daemon is expected to run inside kubernetes")
 	command.Flags().Float64Var(&moduloCrashReportNotif, "modulo-crash-report-notif", 5, "modulo for how often to report CrashLoopBackOff events")
+	command.Flags().DurationVar(&stateReportInterval, "state-report-interval", 5*time.Minute, "interval between reporting controlled resources to the release-manager for drift detection. Zero disables reporting")
 	command.Flags().StringVar(&idpURL, "idp-url", "", "the url of the identity provider")
 	command.Flags().StringVar(&clientID, "client-id", "", "client id of this application issued by the identity provider")
 	command.Flags().StringVar(&clientSecret, "client-secret", "", "the client secret")
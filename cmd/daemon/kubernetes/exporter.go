@@ -14,6 +14,7 @@ type Exporter interface {
 	SendSuccessfulReleaseEvent(c context.Context, event httpinternal.ReleaseEvent) error
 	SendPodErrorEvent(c context.Context, event httpinternal.PodErrorEvent) error
 	SendJobErrorEvent(c context.Context, event httpinternal.JobErrorEvent) error
+	SendClusterStateReport(c context.Context, report httpinternal.ClusterStateReport) error
 }
 
 type ReleaseManagerExporter struct {
@@ -66,3 +67,18 @@ func (e *ReleaseManagerExporter) SendJobErrorEvent(ctx context.Context, event ht
 	}
 	return nil
 }
+
+func (e *ReleaseManagerExporter) SendClusterStateReport(ctx context.Context, report httpinternal.ClusterStateReport) error {
+	e.Log.With("resources", len(report.Resources)).Infof("ClusterState Report")
+	var resp httpinternal.KubernetesNotifyResponse
+	url, err := e.Client.URL("webhook/daemon/k8s/state")
+	if err != nil {
+		return err
+	}
+	report.Environment = e.Environment
+	err = e.Client.Do(http.MethodPost, url, report, &resp)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+)
+
+// StateReporter periodically reports the release manager controlled resources
+// running in the cluster allowing the release manager to detect drift from
+// the config repository.
+type StateReporter struct {
+	exporter     Exporter
+	deployments  appslisters.DeploymentLister
+	daemonSets   appslisters.DaemonSetLister
+	statefulSets appslisters.StatefulSetLister
+}
+
+// NewStateReporter allocates a StateReporter listing resources from the
+// informers of informerFactory. It must be called before the informer factory
+// is started.
+func NewStateReporter(informerFactory informers.SharedInformerFactory, exporter Exporter) *StateReporter {
+	apps := informerFactory.Apps().V1()
+	return &StateReporter{
+		exporter:     exporter,
+		deployments:  apps.Deployments().Lister(),
+		daemonSets:   apps.DaemonSets().Lister(),
+		statefulSets: apps.StatefulSets().Lister(),
+	}
+}
+
+// Run reports the cluster state every interval until stopCh is closed. The
+// informer caches must be synced before calling Run.
+func (s *StateReporter) Run(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := s.Report(context.Background())
+		if err != nil {
+			log.Errorf("Failed to report cluster state: %v", err)
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Report sends the current cluster state to the exporter.
+func (s *StateReporter) Report(ctx context.Context) error {
+	deployments, err := s.deployments.List(labels.Everything())
+	if err != nil {
+		return errors.WithMessage(err, "list deployments")
+	}
+	daemonSets, err := s.daemonSets.List(labels.Everything())
+	if err != nil {
+		return errors.WithMessage(err, "list daemonsets")
+	}
+	statefulSets, err := s.statefulSets.List(labels.Everything())
+	if err != nil {
+		return errors.WithMessage(err, "list statefulsets")
+	}
+	return s.exporter.SendClusterStateReport(ctx, http.ClusterStateReport{
+		Resources: clusterResources(deployments, daemonSets, statefulSets),
+	})
+}
+
+// clusterResources returns the resources controlled by the release manager.
+func clusterResources(deployments []*appsv1.Deployment, daemonSets []*appsv1.DaemonSet, statefulSets []*appsv1.StatefulSet) []http.ClusterResource {
+	resources := []http.ClusterResource{}
+	add := func(kind string, meta metav1.ObjectMeta) {
+		if !isControlled(meta) {
+			return
+		}
+		resources = append(resources, http.ClusterResource{
+			Kind:       kind,
+			Namespace:  meta.Namespace,
+			Name:       meta.Name,
+			ArtifactID: meta.Annotations[artifactIDAnnotationKey],
+		})
+	}
+	for _, d := range deployments {
+		add("Deployment", d.ObjectMeta)
+	}
+	for _, d := range daemonSets {
+		add("DaemonSet", d.ObjectMeta)
+	}
+	for _, s := range statefulSets {
+		add("StatefulSet", s.ObjectMeta)
+	}
+	return resources
+}
+
+// isControlled reports whether a resource is controlled by the release manager
+// and not marked for termination.
+func isControlled(meta metav1.ObjectMeta) bool {
+	return meta.DeletionTimestamp == nil &&
+		meta.Annotations[controlledAnnotationKey] == "true" &&
+		meta.Annotations[artifactIDAnnotationKey] != ""
+}
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterResources(t *testing.T) {
+	controlled := func(name, artifactID string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "dev",
+			Annotations: map[string]string{
+				controlledAnnotationKey: "true",
+				artifactIDAnnotationKey: artifactID,
+			},
+		}
+	}
+	now := metav1.Now()
+	terminating := controlled("terminating", "master-3")
+	terminating.DeletionTimestamp = &now
+
+	resources := clusterResources(
+		[]*appsv1.Deployment{
+			{ObjectMeta: controlled("api", "master-1")},
+			{ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "dev"}},
+			{ObjectMeta: controlled("no-artifact", "")},
+			{ObjectMeta: terminating},
+		},
+		[]*appsv1.DaemonSet{
+			{ObjectMeta: controlled("agent", "master-2")},
+		},
+		[]*appsv1.StatefulSet{
+			{ObjectMeta: controlled("db", "master-4")},
+		},
+	)
+
+	assert.Equal(t, []http.ClusterResource{
+		{Kind: "Deployment", Namespace: "dev", Name: "api", ArtifactID: "master-1"},
+		{Kind: "DaemonSet", Namespace: "dev", Name: "agent", ArtifactID: "master-2"},
+		{Kind: "StatefulSet", Namespace: "dev", Name: "db", ArtifactID: "master-4"},
+	}, resources)
+}
@@ -0,0 +1,29 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+)
+
+// Changelog returns the changes between the artifact of service released to
+// environment and artifactID.
+func Changelog(client *httpinternal.Client, service, environment, namespace, artifactID string) (httpinternal.ChangelogResponse, error) {
+	var resp httpinternal.ChangelogResponse
+	params := url.Values{}
+	params.Add("artifactId", artifactID)
+	if namespace != "" {
+		params.Add("namespace", namespace)
+	}
+	path, err := client.URLWithQuery(fmt.Sprintf("changelog/%s/%s", service, environment), params)
+	if err != nil {
+		return resp, err
+	}
+	err = client.Do(http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
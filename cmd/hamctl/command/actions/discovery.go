@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+)
+
+// Environments returns the environments known by the release manager.
+func Environments(client *httpinternal.Client) ([]string, error) {
+	var resp httpinternal.ListEnvironmentsResponse
+	path, err := client.URL("environments")
+	if err != nil {
+		return nil, err
+	}
+	err = client.Do(http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Environments, nil
+}
+
+// Namespaces returns the namespaces of environment.
+func Namespaces(client *httpinternal.Client, environment string) ([]string, error) {
+	var resp httpinternal.ListNamespacesResponse
+	path, err := client.URL(fmt.Sprintf("environments/%s/namespaces", url.PathEscape(environment)))
+	if err != nil {
+		return nil, err
+	}
+	err = client.Do(http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Namespaces, nil
+}
+
+// Services returns the services of namespace in environment.
+func Services(client *httpinternal.Client, environment, namespace string) ([]string, error) {
+	var resp httpinternal.ListServicesResponse
+	path, err := client.URL(fmt.Sprintf("environments/%s/namespaces/%s/services", url.PathEscape(environment), url.PathEscape(namespace)))
+	if err != nil {
+		return nil, err
+	}
+	err = client.Do(http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Services, nil
+}
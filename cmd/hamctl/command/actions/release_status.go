@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/pkg/errors"
+)
+
+// ReleaseStatus returns the lifecycle of the release with id.
+func ReleaseStatus(client *httpinternal.Client, id string) (httpinternal.ReleaseStatusResponse, error) {
+	var resp httpinternal.ReleaseStatusResponse
+	path, err := client.URL(fmt.Sprintf("releases/%s", id))
+	if err != nil {
+		return httpinternal.ReleaseStatusResponse{}, err
+	}
+	err = client.Do(http.MethodGet, path, nil, &resp)
+	if err != nil {
+		return httpinternal.ReleaseStatusResponse{}, err
+	}
+	return resp, nil
+}
+
+type WaitOptions struct {
+	// Timeout is the maximum duration to wait for a release to complete.
+	Timeout time.Duration
+	// Interval is the duration between polls of the release status.
+	Interval time.Duration
+}
+
+// WaitForRelease polls the status of the release with id until it reaches a
+// terminal state or opts.Timeout is exceeded. Each new transition is reported
+// to progress.
+//
+// An error is returned if the release failed or did not complete in time.
+func WaitForRelease(client *httpinternal.Client, id string, opts WaitOptions, progress func(httpinternal.ReleaseStatusTransition)) (httpinternal.ReleaseStatusResponse, error) {
+	deadline := time.Now().Add(opts.Timeout)
+	var reported int
+	for {
+		status, err := ReleaseStatus(client, id)
+		if err != nil {
+			return httpinternal.ReleaseStatusResponse{}, errors.WithMessagef(err, "get status of release '%s'", id)
+		}
+		for ; reported < len(status.Transitions); reported++ {
+			progress(status.Transitions[reported])
+		}
+		switch status.State {
+		case "rolledOut", "skipped":
+			return status, nil
+		case "failed":
+			return status, errors.Errorf("release '%s' failed: %s", id, status.Error)
+		}
+		if !time.Now().Add(opts.Interval).Before(deadline) {
+			return status, errors.Errorf("timed out after %s waiting for release '%s' in state '%s'", opts.Timeout, id, status.State)
+		}
+		time.Sleep(opts.Interval)
+	}
+}
@@ -0,0 +1,25 @@
+package actions
+
+import (
+	"net/http"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+)
+
+// Undeploy removes service from environment.
+func Undeploy(client *httpinternal.Client, service, environment, namespace string) (httpinternal.UndeployResponse, error) {
+	var resp httpinternal.UndeployResponse
+	path, err := client.URL("undeploy")
+	if err != nil {
+		return httpinternal.UndeployResponse{}, err
+	}
+	err = client.Do(http.MethodPost, path, httpinternal.UndeployRequest{
+		Service:     service,
+		Environment: environment,
+		Namespace:   namespace,
+	}, &resp)
+	if err != nil {
+		return httpinternal.UndeployResponse{}, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/spf13/cobra"
+)
+
+func NewAdmin(client *httpinternal.Client) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "admin",
+		Short: "Administrate the release manager",
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	command.AddCommand(
+		newAdminDeadLetters(client),
+		newAdminReplay(client),
+	)
+	return command
+}
+
+func newAdminDeadLetters(client *httpinternal.Client) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "dead-letters",
+		Short: "List events dropped by the release manager after failed handlings",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			var resp httpinternal.ListDeadLettersResponse
+			path, err := client.URL("admin/dead-letters")
+			if err != nil {
+				return err
+			}
+			err = client.Do(http.MethodGet, path, nil, &resp)
+			if err != nil {
+				return err
+			}
+			return writeDeadLettersTable(os.Stdout, resp.DeadLetters, time.Now())
+		},
+	}
+	return command
+}
+
+func newAdminReplay(client *httpinternal.Client) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "replay <id>",
+		Short: "Publish a dropped event again",
+		Long: `Publish a dropped event again once the problem it failed on is fixed. The
+event is removed from the dead letters and stored again if it fails anew.`,
+		Example: `List dropped events:
+
+  hamctl admin dead-letters
+
+Replay a dropped event:
+
+  hamctl admin replay 3f1c6a6e-5d1b-4e1f-8c1a-0b6f3b5f0a9e`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			var resp httpinternal.ReplayDeadLetterResponse
+			path, err := client.URL(fmt.Sprintf("admin/dead-letters/%s/replay", url.PathEscape(args[0])))
+			if err != nil {
+				return err
+			}
+			err = client.Do(http.MethodPost, path, nil, &resp)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Replayed %s event %s\n", resp.DeadLetter.Type, resp.DeadLetter.ID)
+			return nil
+		},
+	}
+	return command
+}
+
+// writeDeadLettersTable writes letters as a table with a line per dead letter.
+func writeDeadLettersTable(w io.Writer, letters []httpinternal.DeadLetter, now time.Time) error {
+	if len(letters) == 0 {
+		_, err := fmt.Fprintln(w, "No dead letters")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "ID\tTYPE\tSERVICE\tENVIRONMENT\tDROPPED\tERROR\n")
+	for _, letter := range letters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", letter.ID, letter.Type, valueOrDash(letter.Service), valueOrDash(letter.Environment), humanize.RelTime(letter.DroppedAt, now, "ago", "from now"), letter.Error)
+	}
+	return tw.Flush()
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDeadLettersTable(t *testing.T) {
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name    string
+		letters []httpinternal.DeadLetter
+		output  string
+	}{
+		{
+			name:   "no dead letters",
+			output: "No dead letters\n",
+		},
+		{
+			name: "dead letters",
+			letters: []httpinternal.DeadLetter{
+				{ID: "1", Type: "release.artifactId", Service: "product", Environment: "dev", Error: "clone failed", DroppedAt: now.Add(-time.Hour)},
+				{ID: "2", Type: "released", Error: "unmarshal event", DroppedAt: now.Add(-2 * time.Minute)},
+			},
+			output: `ID   TYPE                 SERVICE   ENVIRONMENT   DROPPED         ERROR
+1    release.artifactId   product   dev           1 hour ago      clone failed
+2    released             -         -             2 minutes ago   unmarshal event
+`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := writeDeadLettersTable(&buf, tc.letters, now)
+
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.output, buf.String())
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/template"
+	"github.com/lunarway/release-manager/internal/artifact"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func NewArtifacts(client *httpinternal.Client, service *string) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "artifacts",
+		Short: "Find artifacts across services.",
+		Example: `Find the artifacts built from a commit and where they are released:
+
+	hamctl artifacts search --sha abc123`,
+	}
+	command.AddCommand(newArtifactsSearch(client, service))
+	return command
+}
+
+var artifactsSearchDefaultTemplate = `{{ rightPad "Date" 21 }}{{ rightPad "Service" 25 }}{{ rightPad "Artifact" 30 }}{{ rightPad "Environments" 25 }}Message
+{{ range $k, $v := .Artifacts -}}
+{{ rightPad (.ArtifactFrom.Format dateFormat) 21 }}{{ rightPad .Service 25 }}{{ rightPad .ArtifactID 30 }}{{ rightPad .Environments 25 }}{{ .CommitMessage }}
+{{ end -}}
+`
+
+type artifactsSearchData struct {
+	Artifacts []artifactsSearchDataArtifact
+}
+
+type artifactsSearchDataArtifact struct {
+	Service       string
+	ArtifactID    string
+	ArtifactFrom  time.Time
+	SHA           string
+	Author        string
+	CommitMessage string
+	Environments  string
+	Labels        map[string]string
+	Stages        []artifact.Stage
+}
+
+func templateArtifactsSearch(dest io.Writer, templateText string, data artifactsSearchData) error {
+	return template.Output(dest, "artifactsSearch", templateText, data)
+}
+
+func newArtifactsSearch(client *httpinternal.Client, service *string) *cobra.Command {
+	var sha, author, template string
+	var labels []string
+	var count int
+	var command = &cobra.Command{
+		Use:   "search",
+		Short: "Search artifacts by commit SHA, author and labels.",
+		Long: `Search artifacts by commit SHA, author and labels along with the environments they are currently released to.
+
+Only artifacts built after the release manager started indexing are found. The
+search includes all services unless --service is set explicitly.`,
+		Example: `Find the artifacts containing a commit and where they are running:
+
+	hamctl artifacts search --sha abc123
+
+Find the latest artifacts of an author with a label:
+
+	hamctl artifacts search --author jane@example.com --label ticket=ABC-123`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			params := url.Values{}
+			// the service flag defaults to the service of the current directory
+			// so it is only used for filtering if set explicitly
+			if c.Flags().Changed("service") {
+				params.Add("service", *service)
+			}
+			if sha != "" {
+				params.Add("sha", sha)
+			}
+			if author != "" {
+				params.Add("author", author)
+			}
+			_, err := artifact.ParseLabels(labels)
+			if err != nil {
+				return err
+			}
+			for _, label := range labels {
+				params.Add("label", label)
+			}
+			if len(params) == 0 {
+				return errors.New("at least one of --sha, --author, --label or --service must be specified")
+			}
+			params.Add("count", strconv.Itoa(count))
+			path, err := client.URLWithQuery("artifacts/search", params)
+			if err != nil {
+				return err
+			}
+			var resp httpinternal.SearchArtifactsResponse
+			err = client.Do(http.MethodGet, path, nil, &resp)
+			if err != nil {
+				return err
+			}
+			if len(template) == 0 {
+				template = artifactsSearchDefaultTemplate
+			}
+			return templateArtifactsSearch(os.Stdout, template, mapSearchArtifactsResponseToTemplate(resp))
+		},
+	}
+	command.Flags().StringVar(&sha, "sha", "", "commit SHA or prefix of a commit SHA the artifact is built from")
+	command.Flags().StringVar(&author, "author", "", "name or email of the commit author")
+	command.Flags().StringArrayVar(&labels, "label", nil, "label of the artifact, key-value pair: <key>=<value>. Can be repeated")
+	command.Flags().IntVar(&count, "count", 20, "maximum number of artifacts to return sorted by latest")
+	command.Flags().StringVarP(&template, "template", "", "", "template string to format the output. The format is Go templates (http://golang.org/pkg/text/template/#pkg-overview).")
+	return command
+}
+
+func mapSearchArtifactsResponseToTemplate(resp httpinternal.SearchArtifactsResponse) artifactsSearchData {
+	var artifacts []artifactsSearchDataArtifact
+	for _, result := range resp.Artifacts {
+		environments := strings.Join(result.Environments, ",")
+		if environments == "" {
+			environments = "-"
+		}
+		artifacts = append(artifacts, artifactsSearchDataArtifact{
+			Service:       result.Artifact.Service,
+			ArtifactID:    result.Artifact.ID,
+			ArtifactFrom:  result.Artifact.CI.End,
+			SHA:           result.Artifact.Application.SHA,
+			Author:        result.Artifact.Application.AuthorEmail,
+			CommitMessage: result.Artifact.Application.Message,
+			Environments:  environments,
+			Labels:        result.Artifact.Labels,
+			Stages:        result.Artifact.Stages,
+		})
+	}
+	return artifactsSearchData{
+		Artifacts: artifacts,
+	}
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateArtifactsSearch(t *testing.T) {
+	end := time.Date(2020, time.March, 4, 10, 20, 30, 0, time.UTC)
+	resp := httpinternal.SearchArtifactsResponse{
+		Artifacts: []httpinternal.SearchArtifactsResult{
+			{
+				Artifact: artifact.Spec{
+					ID:      "master-abc1234-5678",
+					Service: "product",
+					Application: artifact.Repository{
+						SHA:     "abc1234def",
+						Message: "Fix the thing",
+					},
+					CI: artifact.CI{
+						End: end,
+					},
+				},
+				Environments: []string{"dev", "staging"},
+			},
+			{
+				Artifact: artifact.Spec{
+					ID:      "master-abc1234-9999",
+					Service: "api",
+					Application: artifact.Repository{
+						SHA:     "abc1234def",
+						Message: "Fix the thing",
+					},
+					CI: artifact.CI{
+						End: end,
+					},
+				},
+			},
+		},
+	}
+	var output bytes.Buffer
+
+	err := templateArtifactsSearch(&output, artifactsSearchDefaultTemplate, mapSearchArtifactsResponseToTemplate(resp))
+
+	require.NoError(t, err, "unexpected error")
+	require.Equal(t, `Date                 Service                  Artifact                      Environments             Message
+2020-03-04 10:20:30  product                  master-abc1234-5678           dev,staging              Fix the thing
+2020-03-04 10:20:30  api                      master-abc1234-9999           -                        Fix the thing
+`, output.String())
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
+	"github.com/lunarway/release-manager/cmd/hamctl/command/completion"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/spf13/cobra"
+)
+
+func NewChangelog(client *httpinternal.Client, service *string, logger LoggerFunc) *cobra.Command {
+	var environment, namespace, artifactID string
+	command := &cobra.Command{
+		Use:   "changelog",
+		Short: `Show the changes between the released artifact and another artifact.`,
+		Long: `Show the changes between the artifact currently released to an environment
+and another artifact.
+
+The changes are the commits of the source repository between the two artifacts
+grouped by their conventional commit type. Only artifacts built from GitHub
+repositories are supported.`,
+		Example: `Show the changes of artifact 'master-1234ds13g3-12s46g356g' compared to
+the artifact released to 'prod':
+
+  hamctl changelog --service product --env prod --to master-1234ds13g3-12s46g356g`,
+		Args: cobra.ExactArgs(0),
+		PreRun: func(c *cobra.Command, args []string) {
+			defaultShuttleString(shuttleSpecFromFile, &namespace, func(s *shuttleSpec) string {
+				return s.Vars.K8S.Namespace
+			})
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			resp, err := actions.Changelog(client, *service, environment, namespace, artifactID)
+			if err != nil {
+				return err
+			}
+			logger("Changes of %s from %s (%s) to %s (%s) in %s\n", resp.Service, resp.FromArtifactID, shortSHA(resp.FromSHA), resp.ToArtifactID, shortSHA(resp.ToSHA), resp.Environment)
+			if len(resp.Groups) == 0 {
+				logger("\nNo changes\n")
+				return nil
+			}
+			for _, group := range resp.Groups {
+				logger("\n%s\n", group.Title)
+				for _, commit := range group.Commits {
+					logger("  - %s %s\n", shortSHA(commit.SHA), changelogEntry(commit))
+				}
+			}
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&environment, "env", "e", "", "environment of the released artifact to compare with (required)")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("env")
+	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
+	command.Flags().StringVar(&artifactID, "to", "", "artifact to list changes up to (required)")
+	//nolint:errcheck
+	command.MarkFlagRequired("to")
+	command.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace the service is deployed to (defaults to env)")
+	completion.FlagAnnotation(command, "namespace", "__hamctl_get_namespaces")
+	return command
+}
+
+// changelogEntry formats commit as a single changelog line.
+func changelogEntry(commit httpinternal.ChangelogCommit) string {
+	entry := commit.Subject
+	if commit.Scope != "" {
+		entry = "(" + commit.Scope + ") " + entry
+	}
+	if commit.Breaking {
+		entry = "BREAKING " + entry
+	}
+	if commit.AuthorName != "" {
+		entry += " (" + commit.AuthorName + ")"
+	}
+	return entry
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
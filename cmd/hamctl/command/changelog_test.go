@@ -0,0 +1,98 @@
+package command_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/command"
+	internalhttp "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/changelog/product/prod" || r.Method != http.MethodGet {
+			internalhttp.Error(rw, "not found", http.StatusNotFound)
+			return
+		}
+		resp := internalhttp.ChangelogResponse{
+			Service:        "product",
+			Environment:    "prod",
+			FromArtifactID: "master-1",
+			FromSHA:        "1111111111",
+			ToArtifactID:   r.URL.Query().Get("artifactId"),
+			ToSHA:          "2222222222",
+		}
+		if resp.ToArtifactID == "master-2" {
+			resp.Groups = []internalhttp.ChangelogGroup{
+				{
+					Type:  "feat",
+					Title: "Features",
+					Commits: []internalhttp.ChangelogCommit{
+						{SHA: "2222222222", Scope: "api", Subject: "add endpoint", Breaking: true, AuthorName: "Foo"},
+					},
+				},
+				{
+					Type:  "",
+					Title: "Other Changes",
+					Commits: []internalhttp.ChangelogCommit{
+						{SHA: "3333333333", Subject: "Merge pull request #1"},
+					},
+				},
+			}
+		}
+		err := json.NewEncoder(rw).Encode(resp)
+		require.NoError(t, err, "failed to encode test response payload")
+	}))
+	defer server.Close()
+
+	c := internalhttp.Client{
+		BaseURL: server.URL,
+		Auth:    NoopAuthClient{},
+	}
+
+	tt := []struct {
+		name   string
+		args   []string
+		output []string
+	}{
+		{
+			name: "changes",
+			args: []string{"--env", "prod", "--to", "master-2"},
+			output: []string{
+				"Changes of product from master-1 (1111111) to master-2 (2222222) in prod\n",
+				"\nFeatures\n",
+				"  - 2222222 BREAKING (api) add endpoint (Foo)\n",
+				"\nOther Changes\n",
+				"  - 3333333 Merge pull request #1\n",
+			},
+		},
+		{
+			name: "no changes",
+			args: []string{"--env", "prod", "--to", "master-1"},
+			output: []string{
+				"Changes of product from master-1 (1111111) to master-1 (2222222) in prod\n",
+				"\nNo changes\n",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var output []string
+			service := "product"
+			cmd := command.NewChangelog(&c, &service, func(f string, args ...interface{}) {
+				output = append(output, fmt.Sprintf(f, args...))
+			})
+			cmd.SetArgs(tc.args)
+
+			err := cmd.Execute()
+
+			assert.NoError(t, err, "unexpected execution error")
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
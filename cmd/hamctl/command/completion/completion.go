@@ -19,8 +19,49 @@ func FlagAnnotation(c *cobra.Command, f string, completionFunc string) {
 
 // Hamctl contains bash completions for the hamctl command.
 const Hamctl = `
+# __hamctl_flag_value prints the value of flag --$1 (or -$2) from the words
+# currently being completed.
+__hamctl_flag_value()
+{
+	local long=$1 short=$2 i
+	for (( i=0; i<${#words[@]}; i++ )); do
+		case "${words[i]}" in
+			--${long}=*)
+				echo "${words[i]#*=}"
+				return
+				;;
+			--${long})
+				echo "${words[i+1]}"
+				return
+				;;
+			-${short:-$long})
+				echo "${words[i+1]}"
+				return
+				;;
+		esac
+	done
+}
+
+# __hamctl_list runs 'hamctl list' with the base URL used in the completed
+# command.
+__hamctl_list()
+{
+	local base_url
+	base_url=$(__hamctl_flag_value http-base-url)
+	if [[ -n "${base_url}" ]]; then
+		hamctl --http-base-url "${base_url}" list "$@" 2>/dev/null
+	else
+		hamctl list "$@" 2>/dev/null
+	fi
+}
+
 __hamctl_get_environments()
 {
+	local hamctl_out
+	if hamctl_out=$(__hamctl_list environments) && [[ -n "${hamctl_out}" ]]; then
+		COMPREPLY=( $( compgen -W "${hamctl_out[*]}" -- "$cur" ) )
+		return
+	fi
 	local template
 	template=$'{{ range $k, $v := . }}{{ $k }} {{ end }}'
 	local shuttle_out
@@ -33,6 +74,13 @@ __hamctl_get_environments()
 
 __hamctl_get_namespaces()
 {
+	local env
+	env=$(__hamctl_flag_value env e)
+	local hamctl_out
+	if [[ -n "${env}" ]] && hamctl_out=$(__hamctl_list namespaces --env "${env}") && [[ -n "${hamctl_out}" ]]; then
+		COMPREPLY=( $( compgen -W "${hamctl_out[*]}" -- "$cur" ) )
+		return
+	fi
 	local template
 	template="{{ range .items  }}{{ .metadata.name }} {{ end }}"
 	local kubectl_out
@@ -41,6 +89,20 @@ __hamctl_get_namespaces()
 	fi
 }
 
+__hamctl_get_services()
+{
+	local env namespace
+	env=$(__hamctl_flag_value env e)
+	namespace=$(__hamctl_flag_value namespace n)
+	if [[ -z "${env}" ]]; then
+		return
+	fi
+	local hamctl_out
+	if hamctl_out=$(__hamctl_list services --env "${env}" --namespace "${namespace:-${env}}"); then
+		COMPREPLY=( $( compgen -W "${hamctl_out[*]}" -- "$cur" ) )
+	fi
+}
+
 __hamctl_get_branches()
 {
 	local git_out
@@ -11,6 +11,7 @@ import (
 	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
 	"github.com/lunarway/release-manager/cmd/hamctl/command/completion"
 	"github.com/lunarway/release-manager/cmd/hamctl/template"
+	"github.com/lunarway/release-manager/internal/artifact"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
 	"github.com/spf13/cobra"
 )
@@ -67,6 +68,7 @@ type describeReleaseDataRelease struct {
 	CommitURL       string
 	CommitMessage   string
 	Intent          string
+	Stages          []artifact.Stage
 }
 
 func templateDescribeRelease(dest io.Writer, templateText string, data describeReleaseData) error {
@@ -136,6 +138,7 @@ func mapReleaseResponseToTemplate(resp httpinternal.DescribeReleaseResponse) des
 			CommitURL:       release.Artifact.Application.URL,
 			CommitMessage:   release.Artifact.Application.Message,
 			Intent:          template.IntentString(release.Intent),
+			Stages:          release.Artifact.Stages,
 		})
 	}
 	d := describeReleaseData{
@@ -152,6 +155,12 @@ var describeArtifactDefaultTemplate = `Latest artifacts for service: {{ .Service
 {{ rightPad "Date" 21 }}{{ rightPad "Artifact" 30 }}Message
 {{ range $k, $v := .Artifacts -}}
 {{ rightPad (.ArtifactFrom.Format dateFormat) 21 }}{{ rightPad .ArtifactID 30 }}{{ .CommitMessage }}
+{{ with stage .Stages "sbom" -}}
+{{ rightPad "" 21 }}SBOM: {{ .Data.Format }} {{ .Data.SpecVersion }} with {{ .Data.Components }} components {{ .Data.URL }}
+{{ end -}}
+{{ with stage .Stages "provenance" -}}
+{{ rightPad "" 21 }}Provenance: built by {{ .Data.BuilderID }}{{ with .Data.InvocationURL }} {{ . }}{{ end }}
+{{ end -}}
 {{ end -}}
 `
 
@@ -164,6 +173,7 @@ type describeArtifactDataArtifact struct {
 	ArtifactID    string
 	ArtifactFrom  time.Time
 	CommitMessage string
+	Stages        []artifact.Stage
 }
 
 func templateDescribeArtifact(dest io.Writer, templateText string, data describeArtifactData) error {
@@ -187,7 +197,11 @@ Get details about the latest 5 artifacts for a service:
 
 Format the output with a custom template:
 
-	hamctl describe artifact --service product --template '{{ .Service }}'`,
+	hamctl describe artifact --service product --template '{{ .Service }}'
+
+Show the status of a custom stage of the artifacts:
+
+	hamctl describe artifact --service product --template '{{ range .Artifacts }}{{ .ArtifactID }} {{ with stage .Stages "lint" }}{{ .Data.status }}{{ end }}{{ "\n" }}{{ end }}'`,
 		Args: cobra.ExactArgs(0),
 		RunE: func(c *cobra.Command, args []string) error {
 			var resp httpinternal.DescribeArtifactResponse
@@ -223,6 +237,7 @@ func mapArtifactResponseToTemplate(resp httpinternal.DescribeArtifactResponse) d
 			ArtifactID:    a.ID,
 			ArtifactFrom:  a.CI.End,
 			CommitMessage: a.Application.Message,
+			Stages:        a.Stages,
 		})
 	}
 	return describeArtifactData{
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
+	"github.com/lunarway/release-manager/cmd/hamctl/command/completion"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/spf13/cobra"
+)
+
+func NewList(client *httpinternal.Client, logger LoggerFunc) *cobra.Command {
+	var command = &cobra.Command{
+		Use:   "list",
+		Short: "List environments, namespaces and services known by the release manager",
+		Example: `List all environments:
+
+  hamctl list environments
+
+List namespaces in environment 'dev':
+
+  hamctl list namespaces --env dev
+
+List services in namespace 'dev' of environment 'dev':
+
+  hamctl list services --env dev --namespace dev`,
+		Run: func(c *cobra.Command, args []string) {
+			c.HelpFunc()(c, args)
+		},
+	}
+	command.AddCommand(
+		newListEnvironments(client, logger),
+		newListNamespaces(client, logger),
+		newListServices(client, logger),
+	)
+	return command
+}
+
+func newListEnvironments(client *httpinternal.Client, logger LoggerFunc) *cobra.Command {
+	return &cobra.Command{
+		Use:   "environments",
+		Short: "List environments",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			environments, err := actions.Environments(client)
+			if err != nil {
+				return err
+			}
+			printLines(logger, environments)
+			return nil
+		},
+	}
+}
+
+func newListNamespaces(client *httpinternal.Client, logger LoggerFunc) *cobra.Command {
+	var environment string
+	var command = &cobra.Command{
+		Use:   "namespaces",
+		Short: "List namespaces in an environment",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			namespaces, err := actions.Namespaces(client, environment)
+			if err != nil {
+				return err
+			}
+			printLines(logger, namespaces)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&environment, "env", "e", "", "environment to list namespaces of (required)")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("env")
+	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
+	return command
+}
+
+func newListServices(client *httpinternal.Client, logger LoggerFunc) *cobra.Command {
+	var environment, namespace string
+	var command = &cobra.Command{
+		Use:   "services",
+		Short: "List services in a namespace of an environment",
+		Args:  cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			if namespace == "" {
+				namespace = environment
+			}
+			services, err := actions.Services(client, environment, namespace)
+			if err != nil {
+				return err
+			}
+			printLines(logger, services)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&environment, "env", "e", "", "environment to list services of (required)")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("env")
+	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
+	command.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace to list services of (defaults to env)")
+	completion.FlagAnnotation(command, "namespace", "__hamctl_get_namespaces")
+	return command
+}
+
+func printLines(logger LoggerFunc, lines []string) {
+	for _, l := range lines {
+		logger("%s\n", l)
+	}
+}
@@ -0,0 +1,95 @@
+package command_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/command"
+	internalhttp "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+		switch r.URL.Path {
+		case "/environments":
+			resp = internalhttp.ListEnvironmentsResponse{
+				Environments: []string{"dev", "prod"},
+			}
+		case "/environments/dev/namespaces":
+			resp = internalhttp.ListNamespacesResponse{
+				Environment: "dev",
+				Namespaces:  []string{"dev", "other"},
+			}
+		case "/environments/dev/namespaces/dev/services":
+			resp = internalhttp.ListServicesResponse{
+				Environment: "dev",
+				Namespace:   "dev",
+				Services:    []string{"a", "b"},
+			}
+		case "/environments/dev/namespaces/other/services":
+			resp = internalhttp.ListServicesResponse{
+				Environment: "dev",
+				Namespace:   "other",
+				Services:    []string{"c"},
+			}
+		default:
+			internalhttp.Error(rw, "not found", http.StatusNotFound)
+			return
+		}
+		err := json.NewEncoder(rw).Encode(resp)
+		require.NoError(t, err, "failed to encode test response payload")
+	}))
+	defer server.Close()
+
+	c := internalhttp.Client{
+		BaseURL: server.URL,
+		Auth:    NoopAuthClient{},
+	}
+
+	tt := []struct {
+		name   string
+		args   []string
+		output []string
+	}{
+		{
+			name:   "environments",
+			args:   []string{"environments"},
+			output: []string{"dev\n", "prod\n"},
+		},
+		{
+			name:   "namespaces",
+			args:   []string{"namespaces", "--env", "dev"},
+			output: []string{"dev\n", "other\n"},
+		},
+		{
+			name:   "services in default namespace",
+			args:   []string{"services", "--env", "dev"},
+			output: []string{"a\n", "b\n"},
+		},
+		{
+			name:   "services in namespace",
+			args:   []string{"services", "--env", "dev", "--namespace", "other"},
+			output: []string{"c\n"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var output []string
+			cmd := command.NewList(&c, func(f string, args ...interface{}) {
+				output = append(output, fmt.Sprintf(f, args...))
+			})
+			cmd.SetArgs(tc.args)
+
+			err := cmd.Execute()
+
+			assert.NoError(t, err, "unexpected execution error")
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package command
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func NewMatrix(client *httpinternal.Client) *cobra.Command {
+	var squad, output string
+	var command = &cobra.Command{
+		Use:   "matrix",
+		Short: "List the current release of all services in all environments",
+		Example: `List current releases of all services:
+
+  hamctl matrix
+
+List current releases of services owned by squad 'aura' as CSV:
+
+  hamctl matrix --squad aura --output csv`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			var write func(io.Writer, httpinternal.StatusMatrixResponse) error
+			switch output {
+			case "table":
+				write = func(w io.Writer, m httpinternal.StatusMatrixResponse) error {
+					return writeMatrixTable(w, m, time.Now())
+				}
+			case "json":
+				write = writeMatrixJSON
+			case "csv":
+				write = writeMatrixCSV
+			default:
+				return errors.Errorf("unknown output format '%s': must be one of table, json or csv", output)
+			}
+
+			var resp httpinternal.StatusMatrixResponse
+			params := url.Values{}
+			if squad != "" {
+				params.Add("squad", squad)
+			}
+			path, err := client.URLWithQuery("status/matrix", params)
+			if err != nil {
+				return err
+			}
+			err = client.Do(http.MethodGet, path, nil, &resp)
+			if err != nil {
+				return err
+			}
+			return write(os.Stdout, resp)
+		},
+	}
+	command.Flags().StringVar(&squad, "squad", "", "only list services owned by this squad")
+	command.Flags().StringVarP(&output, "output", "o", "table", "output format. One of table, json or csv")
+	return command
+}
+
+// writeMatrixTable writes m as a table with a column per environment. Each
+// service spans three lines with the artifact ID, when and by whom it was
+// released and its vulnerabilities.
+func writeMatrixTable(w io.Writer, m httpinternal.StatusMatrixResponse, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "SERVICE\t%s\n", strings.ToUpper(strings.Join(m.Environments, "\t")))
+	for _, service := range m.Services {
+		releases := make(map[string][]httpinternal.StatusMatrixRelease)
+		for _, release := range service.Releases {
+			releases[release.Environment] = append(releases[release.Environment], release)
+		}
+		artifacts := []string{service.Service}
+		released := []string{""}
+		vulnerabilities := []string{""}
+		for _, environment := range m.Environments {
+			var a, r, v []string
+			for _, release := range releases[environment] {
+				a = append(a, release.ArtifactID)
+				r = append(r, releasedString(release, now))
+				v = append(v, fmt.Sprintf("%d/%d/%d", release.HighVulnerabilities, release.MediumVulnerabilities, release.LowVulnerabilities))
+			}
+			if len(a) == 0 {
+				a = []string{"-"}
+			}
+			artifacts = append(artifacts, strings.Join(a, ", "))
+			released = append(released, strings.Join(r, ", "))
+			vulnerabilities = append(vulnerabilities, strings.Join(v, ", "))
+		}
+		fmt.Fprintf(tw, "%s\n", strings.Join(artifacts, "\t"))
+		fmt.Fprintf(tw, "%s\n", strings.Join(released, "\t"))
+		fmt.Fprintf(tw, "%s\n", strings.Join(vulnerabilities, "\t"))
+	}
+	return tw.Flush()
+}
+
+// releasedString returns when and by whom release was released. The release
+// commit may not be found, eg. if the config repository history is rewritten,
+// in which case it is reported as unknown.
+func releasedString(release httpinternal.StatusMatrixRelease, now time.Time) string {
+	if release.ReleasedAt.IsZero() {
+		return "unknown"
+	}
+	by := release.ReleasedByName
+	if by == "" {
+		by = release.ReleasedByEmail
+	}
+	return fmt.Sprintf("%s by %s", humanize.RelTime(release.ReleasedAt, now, "ago", "from now"), by)
+}
+
+func writeMatrixJSON(w io.Writer, m httpinternal.StatusMatrixResponse) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// writeMatrixCSV writes m as CSV with a row per release.
+func writeMatrixCSV(w io.Writer, m httpinternal.StatusMatrixResponse) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"service", "squad", "environment", "namespace", "artifact_id", "released_at", "released_by_name", "released_by_email", "high_vulnerabilities", "medium_vulnerabilities", "low_vulnerabilities"})
+	if err != nil {
+		return err
+	}
+	for _, service := range m.Services {
+		for _, release := range service.Releases {
+			var releasedAt string
+			if !release.ReleasedAt.IsZero() {
+				releasedAt = release.ReleasedAt.UTC().Format(time.RFC3339)
+			}
+			err := cw.Write([]string{
+				service.Service,
+				release.Squad,
+				release.Environment,
+				release.Namespace,
+				release.ArtifactID,
+				releasedAt,
+				release.ReleasedByName,
+				release.ReleasedByEmail,
+				strconv.FormatInt(release.HighVulnerabilities, 10),
+				strconv.FormatInt(release.MediumVulnerabilities, 10),
+				strconv.FormatInt(release.LowVulnerabilities, 10),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMatrix(now time.Time) httpinternal.StatusMatrixResponse {
+	return httpinternal.StatusMatrixResponse{
+		Environments: []string{"dev", "prod"},
+		Services: []httpinternal.StatusMatrixService{
+			{
+				Service: "a",
+				Squad:   "x",
+				Releases: []httpinternal.StatusMatrixRelease{
+					{Environment: "dev", Namespace: "dev", ArtifactID: "master-2-a", Squad: "x", ReleasedAt: now.Add(-time.Hour), ReleasedByName: "Jane", ReleasedByEmail: "jane@example.com", HighVulnerabilities: 1},
+					{Environment: "prod", Namespace: "prod", ArtifactID: "master-1-a", Squad: "x", ReleasedAt: now.Add(-48 * time.Hour), ReleasedByEmail: "john@example.com"},
+				},
+			},
+			{
+				Service: "b",
+				Squad:   "y",
+				Releases: []httpinternal.StatusMatrixRelease{
+					{Environment: "dev", Namespace: "dev", ArtifactID: "master-1-b", Squad: "y"},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteMatrixTable(t *testing.T) {
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+
+	err := writeMatrixTable(&buf, testMatrix(now), now)
+
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, `SERVICE   DEV                  PROD
+a         master-2-a           master-1-a
+          1 hour ago by Jane   2 days ago by john@example.com
+          1/0/0                0/0/0
+b         master-1-b           -
+          unknown              
+          0/0/0                
+`, buf.String())
+}
+
+func TestWriteMatrixCSV(t *testing.T) {
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+
+	err := writeMatrixCSV(&buf, testMatrix(now))
+
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, `service,squad,environment,namespace,artifact_id,released_at,released_by_name,released_by_email,high_vulnerabilities,medium_vulnerabilities,low_vulnerabilities
+a,x,dev,dev,master-2-a,2020-01-02T11:00:00Z,Jane,jane@example.com,1,0,0
+a,x,prod,prod,master-1-a,2019-12-31T12:00:00Z,,john@example.com,0,0,0
+b,y,dev,dev,master-1-b,,,,0,0,0
+`, buf.String())
+}
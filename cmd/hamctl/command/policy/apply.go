@@ -26,13 +26,14 @@ func NewApply(client *httpinternal.Client, service *string) *cobra.Command {
 			}
 			return nil
 		},
-		ValidArgs: []string{"auto-release", "branch-restriction"},
+		ValidArgs: []string{"auto-release", "branch-restriction", "required-stages"},
 		Run: func(c *cobra.Command, args []string) {
 			c.HelpFunc()(c, args)
 		},
 	}
 	command.AddCommand(autoRelease(client, service))
 	command.AddCommand(branchRestriction(client, service))
+	command.AddCommand(requiredStages(client, service))
 	return command
 }
 
@@ -112,3 +113,46 @@ func branchRestriction(client *httpinternal.Client, service *string) *cobra.Comm
 	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
 	return command
 }
+
+func requiredStages(client *httpinternal.Client, service *string) *cobra.Command {
+	var stages []string
+	var env string
+	var command = &cobra.Command{
+		Use:   "required-stages",
+		Short: "Required stages policy for limiting releases to artifacts with specific stages",
+		Long: `Required stages policy for limiting releases to artifacts containing specific stages, e.g. provenance, to an environment.
+
+Example:
+
+  hamctl policy --service product apply required-stages --env prod --stage provenance --stage sbom`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(c *cobra.Command, args []string) error {
+			var resp httpinternal.ApplyRequiredStagesPolicyResponse
+			path, err := client.URL(pathRequiredStages)
+			if err != nil {
+				return err
+			}
+			err = client.Do(http.MethodPatch, path, httpinternal.ApplyRequiredStagesPolicyRequest{
+				Service:     *service,
+				Environment: env,
+				Stages:      stages,
+			}, &resp)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("[✓] Applied required stages policy '%s' to service '%s'\n", resp.ID, resp.Service)
+			return nil
+		},
+	}
+	command.Flags().StringSliceVar(&stages, "stage", nil, "Stage ID required in artifacts. Can be repeated")
+	// errors are skipped here as the only case they can occur are if the flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("stage")
+	command.Flags().StringVarP(&env, "env", "e", "", "Environment to apply requirement to")
+	//nolint:errcheck
+	command.MarkFlagRequired("env")
+	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
+	return command
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/lunarway/release-manager/cmd/hamctl/template"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
@@ -31,6 +32,14 @@ Branch restrictions:
 {{ printf $columnFormat .Environment .BranchRegex .ID }}
 {{ end -}}
 {{ end -}}
+{{ if ne (len .RequiredStages) 0 -}}
+Required stages:
+{{ $columnFormat := printf "%%-%ds     %%-%ds     %%-%ds" .RequiredStagesEnvMaxLen .RequiredStagesStagesMaxLen .RequiredStagesIDMaxLen }}
+{{ printf $columnFormat "ENV" "STAGES" "ID" }}
+{{ range $k, $v := .RequiredStages -}}
+{{ printf $columnFormat .Environment .Stages .ID }}
+{{ end -}}
+{{ end -}}
 `
 
 type listPoliciesData struct {
@@ -43,6 +52,10 @@ type listPoliciesData struct {
 	BranchRestrictionsBranchRegexMaxLen int
 	BranchRestrictionsEnvMaxLen         int
 	BranchRestrictionsIDMaxLen          int
+	RequiredStages                      []listPoliciesDataRequiredStages
+	RequiredStagesEnvMaxLen             int
+	RequiredStagesStagesMaxLen          int
+	RequiredStagesIDMaxLen              int
 }
 
 type listPoliciesDataAutoRelease struct {
@@ -57,6 +70,12 @@ type listPoliciesDataBranchRestriction struct {
 	ID          string
 }
 
+type listPoliciesDataRequiredStages struct {
+	Environment string
+	Stages      string
+	ID          string
+}
+
 func templateListPolicies(dest io.Writer, data listPoliciesData) error {
 	return template.Output(dest, "describeArtifact", listPoliciesTemplate, data)
 }
@@ -112,6 +131,15 @@ func mapListResponseToTemplate(resp httpinternal.ListPoliciesResponse) listPolic
 		})
 	}
 
+	var requiredStages []listPoliciesDataRequiredStages
+	for _, r := range resp.RequiredStages {
+		requiredStages = append(requiredStages, listPoliciesDataRequiredStages{
+			Environment: r.Environment,
+			Stages:      strings.Join(r.Stages, ","),
+			ID:          r.ID,
+		})
+	}
+
 	return listPoliciesData{
 		Service: resp.Service,
 
@@ -136,6 +164,17 @@ func mapListResponseToTemplate(resp httpinternal.ListPoliciesResponse) listPolic
 		BranchRestrictionsIDMaxLen: maxLen(branchRestriction, func(i int) string {
 			return branchRestriction[i].ID
 		}),
+
+		RequiredStages: requiredStages,
+		RequiredStagesEnvMaxLen: maxLen(requiredStages, func(i int) string {
+			return requiredStages[i].Environment
+		}),
+		RequiredStagesStagesMaxLen: maxLen(requiredStages, func(i int) string {
+			return requiredStages[i].Stages
+		}),
+		RequiredStagesIDMaxLen: maxLen(requiredStages, func(i int) string {
+			return requiredStages[i].ID
+		}),
 	}
 }
 
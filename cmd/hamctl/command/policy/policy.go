@@ -4,4 +4,5 @@ const (
 	path                 = "policies"
 	pathAutoRelease      = "policies/auto-release"
 	pathBranchRestrction = "policies/branch-restriction"
+	pathRequiredStages   = "policies/required-stages"
 )
@@ -12,6 +12,7 @@ import (
 
 func NewPromote(client *httpinternal.Client, service *string, releaseClient ReleaseArtifact) *cobra.Command {
 	var toEnvironment, fromEnvironment, namespace string
+	var wait waitOptions
 	var command = &cobra.Command{
 		Use:   "promote",
 		Short: "Promote a service to a specific environment following promoting conventions.",
@@ -46,10 +47,11 @@ func NewPromote(client *httpinternal.Client, service *string, releaseClient Rele
 			if err != nil {
 				return err
 			}
-			printReleaseResponse(func(s string, i ...interface{}) {
+			logger := func(s string, i ...interface{}) {
 				fmt.Printf(s, i...)
-			}, resp)
-			return nil
+			}
+			printReleaseResponse(logger, resp)
+			return waitForReleases(client, logger, wait, resp)
 		},
 	}
 	command.Flags().StringVarP(&toEnvironment, "env", "e", "", "Environment to promote to (required)")
@@ -61,6 +63,7 @@ func NewPromote(client *httpinternal.Client, service *string, releaseClient Rele
 	command.MarkFlagRequired("env")
 	command.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace the service is deployed to (defaults to env)")
 	completion.FlagAnnotation(command, "namespace", "__hamctl_get_namespaces")
+	registerWaitFlags(command, &wait)
 
 	return command
 }
@@ -23,6 +23,7 @@ func NewRelease(client *httpinternal.Client, service *string, logger LoggerFunc,
 	var branch, artifact string
 	var currentBranch bool
 	var environments []string
+	var wait waitOptions
 	var command = &cobra.Command{
 		Use:   "release",
 		Short: `Release a specific artifact or latest artifact from a branch into a specific environment.`,
@@ -36,7 +37,11 @@ Release latest artifact from branch 'master' of service 'product' into environme
 
 Release latest artifact from current branch of service 'product' into environment 'dev':
 
-  hamctl release --service product --env dev --current-branch`,
+  hamctl release --service product --env dev --current-branch
+
+Release latest artifact from branch 'master' of service 'product' into environment 'dev' and wait for it to be rolled out:
+
+  hamctl release --service product --env dev --branch master --wait`,
 		Args: cobra.ExactArgs(0),
 		RunE: func(*cobra.Command, []string) error {
 			environments = trimEmptyValues(environments)
@@ -76,6 +81,7 @@ Release latest artifact from current branch of service 'product' into environmen
 				for _, resp := range resps {
 					printReleaseResponse(logger, resp)
 				}
+				return waitForReleases(client, logger, wait, resps...)
 
 			case artifact != "":
 				logger("Release of service: %s\n", *service)
@@ -86,6 +92,7 @@ Release latest artifact from current branch of service 'product' into environmen
 				for _, resp := range resps {
 					printReleaseResponse(logger, resp)
 				}
+				return waitForReleases(client, logger, wait, resps...)
 			}
 
 			return nil
@@ -102,6 +109,7 @@ Release latest artifact from current branch of service 'product' into environmen
 	command.Flags().StringVar(&artifact, "artifact", "", "release this artifact id (mutually exclusive with --branch and --current-branch)")
 	command.Flags().BoolVarP(&currentBranch, "current-branch", "c", false, "release latest artifact from the current branch (mutually exclusive with --artifact and --branch)")
 	completion.FlagAnnotation(command, "branch", "__hamctl_get_branches")
+	registerWaitFlags(command, &wait)
 	return command
 }
 
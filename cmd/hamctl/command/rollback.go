@@ -30,6 +30,7 @@ func NewRollback(
 ) *cobra.Command {
 	var environment, namespace, artifactID string
 	var artifactLength int
+	var wait waitOptions
 	command := &cobra.Command{
 		Use:   "rollback",
 		Short: `Rollback to the previous artifact in an environment.`,
@@ -109,7 +110,7 @@ has no effect.`,
 			printReleaseResponse(func(s string, i ...interface{}) {
 				logger(s, i...)
 			}, resp)
-			return nil
+			return waitForReleases(client, logger, wait, resp)
 		},
 	}
 	command.Flags().StringVarP(&environment, "env", "e", "", "environment to release to (required)")
@@ -123,6 +124,7 @@ has no effect.`,
 	command.Flags().
 		StringVarP(&artifactID, "artifact", "", "", "artifact to roll back to. Defaults to previously released artifact for the environment")
 	command.Flags().IntVar(&artifactLength, "length", 3, "number of releases to fetch")
+	registerWaitFlags(command, &wait)
 	return command
 }
 
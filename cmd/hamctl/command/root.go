@@ -49,7 +49,7 @@ func NewRoot(version *string) (*cobra.Command, error) {
 			}
 
 			var missingFlags []string
-			if service == "" {
+			if service == "" && requiresService(c) {
 				missingFlags = append(missingFlags, "service")
 			}
 			if client.BaseURL == "" {
@@ -69,23 +69,42 @@ func NewRoot(version *string) (*cobra.Command, error) {
 		fmt.Printf(f, args...)
 	}
 	command.AddCommand(
+		NewAdmin(&client),
+		NewArtifacts(&client, &service),
+		NewChangelog(&client, &service, loggerFunc),
 		NewCompletion(command),
 		NewDescribe(&client, &service),
+		NewList(&client, loggerFunc),
+		NewMatrix(&client),
 		NewPolicy(&client, &service),
 		NewPromote(&client, &service, releaseClient),
 		NewRelease(&client, &service, loggerFunc, releaseClient, git.GetCurrentBranch),
 		NewRollback(&client, &service, loggerFunc, SelectRollbackReleaseFunc, releaseClient),
 		NewStatus(&client, &service),
+		NewUndeploy(&client, &service, loggerFunc),
 		NewVersion(*version),
 		Login(authenticator),
 	)
 	command.PersistentFlags().DurationVar(&client.Timeout, "http-timeout", 120*time.Second, "HTTP request timeout")
 	command.PersistentFlags().StringVar(&client.BaseURL, "http-base-url", "", "address of the http release manager server")
 	command.PersistentFlags().StringVar(&service, "service", "", "service name to execute commands for")
+	completion.FlagAnnotation(command, "service", "__hamctl_get_services")
 
 	return command, nil
 }
 
+// requiresService reports whether command c operates on a specific service
+// and thus requires the service flag.
+func requiresService(c *cobra.Command) bool {
+	for ; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "list", "matrix", "artifacts", "admin":
+			return false
+		}
+	}
+	return true
+}
+
 type shuttleSpec struct {
 	Vars shuttleSpecVars
 }
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
+	"github.com/lunarway/release-manager/cmd/hamctl/command/completion"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/spf13/cobra"
+)
+
+func NewUndeploy(client *httpinternal.Client, service *string, logger LoggerFunc) *cobra.Command {
+	var environment, namespace string
+	command := &cobra.Command{
+		Use:   "undeploy",
+		Short: `Remove a service from an environment.`,
+		Long: `Remove a service from an environment.
+
+The command removes the released resources and the cluster configuration of the
+service from the config repository in a single commit. The release policies of
+the service are respected like for any other release.
+
+The undeploy is refused while auto-release policies of the service target the
+environment as they would release the service again on the next artifact.
+Delete them with 'hamctl policy --service <service> delete <policy-id>' before
+undeploying.`,
+		Example: `Remove service 'product' from environment 'dev':
+
+  hamctl undeploy --service product --env dev`,
+		Args: cobra.ExactArgs(0),
+		PreRun: func(c *cobra.Command, args []string) {
+			defaultShuttleString(shuttleSpecFromFile, &namespace, func(s *shuttleSpec) string {
+				return s.Vars.K8S.Namespace
+			})
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			resp, err := actions.Undeploy(client, *service, environment, namespace)
+			if err != nil {
+				logger("[X] Undeploy of service '%s' from %s failed\n", *service, environment)
+				logger("    Error:\n")
+				logger("    %s\n", err)
+				return err
+			}
+			if resp.Status != "" {
+				logger("%s\n", resp.Status)
+				return nil
+			}
+			logger("[✓] Undeploy of %s (%s) from %s completed\n", resp.Service, resp.ArtifactID, resp.Environment)
+			return nil
+		},
+	}
+	command.Flags().StringVarP(&environment, "env", "e", "", "environment to remove the service from (required)")
+	// errors are skipped here as the only case they can occour are if thee flag
+	// does not exist on the command.
+	//nolint:errcheck
+	command.MarkFlagRequired("env")
+	completion.FlagAnnotation(command, "env", "__hamctl_get_environments")
+	command.Flags().StringVarP(&namespace, "namespace", "n", "", "namespace the service is deployed to (defaults to env)")
+	completion.FlagAnnotation(command, "namespace", "__hamctl_get_namespaces")
+	return command
+}
@@ -0,0 +1,97 @@
+package command_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/command"
+	internalhttp "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndeploy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var req internalhttp.UndeployRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		require.NoError(t, err, "failed to decode test request payload")
+		if r.URL.Path != "/undeploy" || r.Method != http.MethodPost {
+			internalhttp.Error(rw, "not found", http.StatusNotFound)
+			return
+		}
+		resp := internalhttp.UndeployResponse{
+			Service:     req.Service,
+			Environment: req.Environment,
+			Namespace:   req.Namespace,
+		}
+		switch req.Environment {
+		case "dev":
+			resp.ArtifactID = "master-1234"
+		case "prod":
+			internalhttp.Error(rw, "cannot undeploy service 'product' from environment 'prod' due to branch restriction policy", http.StatusBadRequest)
+			return
+		default:
+			resp.Status = fmt.Sprintf("Service '%s' is not released to environment '%s'", req.Service, req.Environment)
+		}
+		err = json.NewEncoder(rw).Encode(resp)
+		require.NoError(t, err, "failed to encode test response payload")
+	}))
+	defer server.Close()
+
+	c := internalhttp.Client{
+		BaseURL: server.URL,
+		Auth:    NoopAuthClient{},
+	}
+
+	tt := []struct {
+		name   string
+		args   []string
+		output []string
+		err    bool
+	}{
+		{
+			name:   "released service",
+			args:   []string{"--env", "dev"},
+			output: []string{"[✓] Undeploy of product (master-1234) from dev completed\n"},
+		},
+		{
+			name:   "service not released",
+			args:   []string{"--env", "staging"},
+			output: []string{"Service 'product' is not released to environment 'staging'\n"},
+		},
+		{
+			name: "prohibited",
+			args: []string{"--env", "prod"},
+			output: []string{
+				"[X] Undeploy of service 'product' from prod failed\n",
+				"    Error:\n",
+			},
+			err: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var output []string
+			service := "product"
+			cmd := command.NewUndeploy(&c, &service, func(f string, args ...interface{}) {
+				output = append(output, fmt.Sprintf(f, args...))
+			})
+			cmd.SetArgs(tc.args)
+
+			err := cmd.Execute()
+
+			if tc.err {
+				assert.Error(t, err, "expected execution error")
+				// the last line contains the error with a random reference
+				require.Len(t, output, len(tc.output)+1)
+				output = output[:len(tc.output)]
+			} else {
+				assert.NoError(t, err, "unexpected execution error")
+			}
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"time"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type waitOptions struct {
+	Enabled bool
+	actions.WaitOptions
+}
+
+func registerWaitFlags(cmd *cobra.Command, opts *waitOptions) {
+	cmd.Flags().BoolVar(&opts.Enabled, "wait", false, "wait for the release to be rolled out and exit non-zero if it fails")
+	cmd.Flags().DurationVar(&opts.Timeout, "wait-timeout", 10*time.Minute, "maximum duration to wait for the release to be rolled out")
+	cmd.Flags().DurationVar(&opts.Interval, "wait-interval", 2*time.Second, "duration between checks of the release status when waiting")
+}
+
+// waitForReleases blocks until all releases in resps are completed if waiting
+// is enabled. Progress is reported to logger.
+func waitForReleases(client *httpinternal.Client, logger LoggerFunc, opts waitOptions, resps ...actions.ReleaseResult) error {
+	if !opts.Enabled {
+		return nil
+	}
+	var failed bool
+	for _, resp := range resps {
+		if resp.Error != nil {
+			// the error is already reported by printReleaseResponse
+			failed = true
+			continue
+		}
+		err := waitForRelease(client, logger, opts, resp)
+		if err != nil {
+			logger("[X] %s\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("one or more releases did not complete")
+	}
+	return nil
+}
+
+func waitForRelease(client *httpinternal.Client, logger LoggerFunc, opts waitOptions, resp actions.ReleaseResult) error {
+	switch {
+	case resp.Response.Status != "":
+		// nothing was released so there is nothing to wait for
+		return nil
+	case resp.Response.ReleaseID == "":
+		return errors.Errorf("release to %s: server did not return a release id to wait for", resp.Environment)
+	}
+	logger("Waiting for release %s to %s\n", resp.Response.ReleaseID, resp.Environment)
+	_, err := actions.WaitForRelease(client, resp.Response.ReleaseID, opts.WaitOptions, func(t httpinternal.ReleaseStatusTransition) {
+		logger("    %s: %s\n", t.State, t.Message)
+	})
+	if err != nil {
+		return err
+	}
+	logger("[✓] Release of %s to %s completed\n", resp.Response.Tag, resp.Environment)
+	return nil
+}
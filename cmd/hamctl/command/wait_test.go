@@ -0,0 +1,142 @@
+package command_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lunarway/release-manager/cmd/hamctl/command"
+	"github.com/lunarway/release-manager/cmd/hamctl/command/actions"
+	internalhttp "github.com/lunarway/release-manager/internal/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelease_wait(t *testing.T) {
+	var (
+		serviceName = "service-name"
+		artifactID  = "master-1-2"
+	)
+	t.Setenv("HAMCTL_USER_NAME", "test")
+	t.Setenv("HAMCTL_USER_EMAIL", "test@example.com")
+
+	tt := []struct {
+		name     string
+		args     []string
+		statuses []internalhttp.ReleaseStatusResponse
+		output   []string
+		err      error
+	}{
+		{
+			name: "rolled out",
+			statuses: []internalhttp.ReleaseStatusResponse{
+				{State: "pushed", Transitions: []internalhttp.ReleaseStatusTransition{
+					{State: "queued", Message: "release accepted"},
+					{State: "pushed", Message: "pushed to config repository"},
+				}},
+				{State: "rolledOut", Transitions: []internalhttp.ReleaseStatusTransition{
+					{State: "queued", Message: "release accepted"},
+					{State: "pushed", Message: "pushed to config repository"},
+					{State: "rolledOut", Message: "deployment available"},
+				}},
+			},
+			output: []string{
+				"Release of service: service-name\n",
+				"[✓] Release of master-1-2 to dev initialized\n",
+				"Waiting for release release-id to dev\n",
+				"    queued: release accepted\n",
+				"    pushed: pushed to config repository\n",
+				"    rolledOut: deployment available\n",
+				"[✓] Release of master-1-2 to dev completed\n",
+			},
+		},
+		{
+			name: "failed",
+			statuses: []internalhttp.ReleaseStatusResponse{
+				{State: "failed", Error: "pod crashed", Transitions: []internalhttp.ReleaseStatusTransition{
+					{State: "queued", Message: "release accepted"},
+					{State: "failed", Message: "pod crashed"},
+				}},
+			},
+			output: []string{
+				"Release of service: service-name\n",
+				"[✓] Release of master-1-2 to dev initialized\n",
+				"Waiting for release release-id to dev\n",
+				"    queued: release accepted\n",
+				"    failed: pod crashed\n",
+				"[X] release 'release-id' failed: pod crashed\n",
+			},
+			err: fmt.Errorf("one or more releases did not complete"),
+		},
+		{
+			name: "timeout",
+			args: []string{"--wait-timeout", "0s"},
+			statuses: []internalhttp.ReleaseStatusResponse{
+				{State: "queued", Transitions: []internalhttp.ReleaseStatusTransition{
+					{State: "queued", Message: "release accepted"},
+				}},
+			},
+			output: []string{
+				"Release of service: service-name\n",
+				"[✓] Release of master-1-2 to dev initialized\n",
+				"Waiting for release release-id to dev\n",
+				"    queued: release accepted\n",
+				"[X] timed out after 0s waiting for release 'release-id' in state 'queued'\n",
+			},
+			err: fmt.Errorf("one or more releases did not complete"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var polls int
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "releases/release-id"):
+					status := tc.statuses[polls]
+					if polls < len(tc.statuses)-1 {
+						polls++
+					}
+					err := json.NewEncoder(rw).Encode(status)
+					require.NoError(t, err, "failed to encode test response payload")
+				case strings.HasSuffix(r.URL.Path, "release"):
+					var req internalhttp.ReleaseRequest
+					err := json.NewDecoder(r.Body).Decode(&req)
+					require.NoError(t, err, "failed to decode test request payload")
+					err = json.NewEncoder(rw).Encode(internalhttp.ReleaseResponse{
+						Service:       serviceName,
+						ReleaseID:     "release-id",
+						ToEnvironment: req.Environment,
+						Tag:           req.ArtifactID,
+					})
+					require.NoError(t, err, "failed to encode test response payload")
+				default:
+					rw.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			c := internalhttp.Client{
+				BaseURL: server.URL,
+				Auth:    NoopAuthClient{},
+			}
+			var output []string
+			cmd := command.NewRelease(&c, &serviceName, func(f string, args ...interface{}) {
+				output = append(output, fmt.Sprintf(f, args...))
+			}, actions.NewReleaseHttpClient(&c), func() string { return "master" })
+			cmd.SetArgs(append([]string{"--artifact", artifactID, "--env", "dev", "--wait", "--wait-interval", "1ms"}, tc.args...))
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+
+			err := cmd.Execute()
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/lunarway/release-manager/internal/artifact"
 	"github.com/lunarway/release-manager/internal/intent"
 )
 
@@ -57,6 +58,16 @@ func tmplMaxLength(list interface{}, key string) (int, error) {
 	return max, nil
 }
 
+// tmplStage returns the stage with id or nil if no such stage exists.
+func tmplStage(stages []artifact.Stage, id string) *artifact.Stage {
+	for i := range stages {
+		if string(stages[i].ID) == id {
+			return &stages[i]
+		}
+	}
+	return nil
+}
+
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
 		"rightPad":  tmplRightPad,
@@ -70,6 +81,7 @@ func FuncMap() template.FuncMap {
 		"humanizeTime": func(input time.Time) string {
 			return humanize.Time(input)
 		},
+		"stage": tmplStage,
 	}
 }
 
@@ -85,6 +97,8 @@ func IntentString(i intent.Intent) string {
 		return fmt.Sprintf("%s branch release", i.ReleaseBranch.Branch)
 	case intent.TypeRollback:
 		return fmt.Sprintf("rollback of %s", i.Rollback.PreviousArtifactID)
+	case intent.TypeUndeploy:
+		return "undeploy"
 	default:
 		return fmt.Sprintf("unknown intent type '%s'", i.Type)
 	}
@@ -1,8 +1,10 @@
 package template
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/lunarway/release-manager/internal/artifact"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -61,3 +63,25 @@ func TestTmplMaxLength(t *testing.T) {
 		})
 	}
 }
+
+func TestOutput_stage(t *testing.T) {
+	stages := []artifact.Stage{
+		{
+			ID:   artifact.StageIDBuild,
+			Name: "Build",
+		},
+		{
+			ID:   "lint",
+			Name: "Lint",
+			Data: artifact.CustomData{
+				"status": "passed",
+			},
+		},
+	}
+	var output strings.Builder
+
+	err := Output(&output, "test", `{{ with stage . "lint" }}{{ .Name }}: {{ .Data.status }}{{ end }}{{ with stage . "e2e" }}e2e{{ end }}`, stages)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Lint: passed", output.String(), "output not as expected")
+}
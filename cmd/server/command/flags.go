@@ -94,3 +94,91 @@ func parseGrafanaConfig(value string) (string, grafanaConfig, error) {
 		URL:    splits[2],
 	}, nil
 }
+
+// configRepoRoute is a config repository environments are routed to.
+type configRepoRoute struct {
+	URL               string
+	SSHPrivateKeyPath string
+	SigningKey        string
+}
+
+// configRepoRoutes maps environments to config repositories. It implements
+// pflag.SliceValue.
+type configRepoRoutes map[string]configRepoRoute
+
+func (opts *configRepoRoutes) String() string {
+	values := opts.GetSlice()
+	if len(values) == 0 {
+		return "[]"
+	}
+	return strings.Join(values, ",")
+}
+
+func (opts *configRepoRoutes) Type() string {
+	return "<env>=<ssh-url>=<ssh-private-key>[=<signing-key>]"
+}
+
+func (opts *configRepoRoutes) Set(csv string) error {
+	for _, split := range strings.Split(csv, ",") {
+		err := opts.Append(split)
+		if err != nil {
+			return fmt.Errorf("flag value '%s': %w", split, err)
+		}
+	}
+	return nil
+}
+
+// GetSlice returns the flag value list as an array of strings.
+func (opts *configRepoRoutes) GetSlice() []string {
+	var values []string
+	for env, route := range *opts {
+		value := fmt.Sprintf("%s=%s=%s", env, route.URL, route.SSHPrivateKeyPath)
+		if route.SigningKey != "" {
+			value += "=" + route.SigningKey
+		}
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Append adds the specified value to the end of the flag value list.
+func (opts *configRepoRoutes) Append(value string) error {
+	env, route, err := parseConfigRepoRoute(value)
+	if err != nil {
+		return err
+	}
+	if *opts == nil {
+		*opts = configRepoRoutes{}
+	}
+	(*opts)[env] = route
+	return nil
+}
+
+// Replace will fully overwrite any data currently in the flag value list.
+func (opts *configRepoRoutes) Replace(values []string) error {
+	newOpts := configRepoRoutes{}
+	for _, value := range values {
+		err := newOpts.Append(value)
+		if err != nil {
+			return err
+		}
+	}
+	*opts = newOpts
+	return nil
+}
+
+func parseConfigRepoRoute(value string) (string, configRepoRoute, error) {
+	splits := strings.SplitN(value, "=", 4)
+	if len(splits) < 3 || splits[0] == "" || splits[1] == "" || splits[2] == "" {
+		return "", configRepoRoute{}, errors.New("value must be formatted as <env>=<ssh-url>=<ssh-private-key>[=<signing-key>]")
+	}
+	route := configRepoRoute{
+		URL:               splits[1],
+		SSHPrivateKeyPath: splits[2],
+	}
+	if len(splits) == 4 {
+		route.SigningKey = splits[3]
+	}
+	return splits[0], route, nil
+}
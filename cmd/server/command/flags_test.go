@@ -322,3 +322,69 @@ func TestGrafanaOptions_Replace(t *testing.T) {
 		})
 	}
 }
+
+var _ pflag.SliceValue = &configRepoRoutes{}
+var _ pflag.Value = &configRepoRoutes{}
+
+func TestConfigRepoRoutes_Set(t *testing.T) {
+	tt := []struct {
+		name   string
+		input  string
+		output configRepoRoutes
+		err    error
+	}{
+		{
+			name:   "empty",
+			input:  "",
+			output: configRepoRoutes{},
+			err:    errors.New("flag value '': value must be formatted as <env>=<ssh-url>=<ssh-private-key>[=<signing-key>]"),
+		},
+		{
+			name:  "without signing key",
+			input: "prod=git@github.com:lunarway/k8s-prod.git=/etc/prod/id_rsa",
+			output: configRepoRoutes{
+				"prod": configRepoRoute{
+					URL:               "git@github.com:lunarway/k8s-prod.git",
+					SSHPrivateKeyPath: "/etc/prod/id_rsa",
+				},
+			},
+		},
+		{
+			name:  "multiple with signing key",
+			input: "dev=git@github.com:lunarway/k8s-dev.git=/etc/dev/id_rsa=ABCD,prod=git@github.com:lunarway/k8s-prod.git=/etc/prod/id_rsa",
+			output: configRepoRoutes{
+				"dev": configRepoRoute{
+					URL:               "git@github.com:lunarway/k8s-dev.git",
+					SSHPrivateKeyPath: "/etc/dev/id_rsa",
+					SigningKey:        "ABCD",
+				},
+				"prod": configRepoRoute{
+					URL:               "git@github.com:lunarway/k8s-prod.git",
+					SSHPrivateKeyPath: "/etc/prod/id_rsa",
+				},
+			},
+		},
+		{
+			name:   "missing ssh private key",
+			input:  "prod=git@github.com:lunarway/k8s-prod.git",
+			output: configRepoRoutes{},
+			err:    errors.New("flag value 'prod=git@github.com:lunarway/k8s-prod.git': value must be formatted as <env>=<ssh-url>=<ssh-private-key>[=<signing-key>]"),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := configRepoRoutes{}
+
+			err := opts.Set(tc.input)
+
+			if tc.err != nil {
+				require.EqualError(t, err, tc.err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.output, opts)
+			assert.Equal(t, tc.input, opts.String(), "string not as expected")
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lunarway/release-manager/cmd/server/http"
 	"github.com/lunarway/release-manager/internal/git"
+	"github.com/lunarway/release-manager/internal/layout"
 	"github.com/lunarway/release-manager/internal/log"
 	"github.com/lunarway/release-manager/internal/policy"
 	"github.com/lunarway/release-manager/internal/slack"
@@ -36,9 +37,22 @@ func NewRoot(version string) (*cobra.Command, error) {
 	var userMappings map[string]string
 	var branchRestrictionsList []string
 	var branchRestrictions []policy.BranchRestriction
+	var requiredStagesList []string
+	var requiredStages []policy.RequiredStages
 	var logConfiguration *log.Configuration
 	var slackMuteOpts slack.MuteOptions
+	var slackReleaseChangelog bool
+	var validateManifests bool
+	var injectAnnotations bool
+	var pinImages bool
+	var commitWindow time.Duration
 	var s3storageOpts s3storageOptions
+	var fsstorageOpts fsstorageOptions
+	var releaseStatusOpts releaseStatusOptions
+	var artifactIndexOpts artifactIndexOptions
+	var driftOpts driftOptions
+	var doraOpts doraOptions
+	var artifactSigningOpts artifactSigningOptions
 	var emailSuffix string
 
 	var command = &cobra.Command{
@@ -58,6 +72,10 @@ func NewRoot(version string) (*cobra.Command, error) {
 			if err != nil {
 				return errors.WithMessage(err, "branch restrictions")
 			}
+			requiredStages, err = parseRequiredStages(requiredStagesList)
+			if err != nil {
+				return errors.WithMessage(err, "required stages")
+			}
 
 			logConfiguration.ParseFromEnvironmnet()
 			log.Init(logConfiguration)
@@ -75,13 +93,25 @@ func NewRoot(version string) (*cobra.Command, error) {
 			configRepo:                &configRepoOpts,
 			gitConfigOpts:             &gitConfigOpts,
 			s3storage:                 &s3storageOpts,
+			fsstorage:                 &fsstorageOpts,
+			releaseStatus:             &releaseStatusOpts,
+			artifactIndex:             &artifactIndexOpts,
+			drift:                     &driftOpts,
+			dora:                      &doraOpts,
+			artifactSigning:           &artifactSigningOpts,
 			http:                      &httpOpts,
 			jwtVerifier:               &jwtVerifierOpts,
 			gpgKeyPaths:               &gpgKeyPaths,
 			broker:                    &brokerOpts,
 			slackMutes:                &slackMuteOpts,
+			slackReleaseChangelog:     &slackReleaseChangelog,
+			validateManifests:         &validateManifests,
+			injectAnnotations:         &injectAnnotations,
+			pinImages:                 &pinImages,
+			commitWindow:              &commitWindow,
 			userMappings:              &userMappings,
 			branchRestrictionPolicies: &branchRestrictions,
+			requiredStagesPolicies:    &requiredStages,
 			emailSuffix:               &emailSuffix,
 		}),
 		NewVersion(version),
@@ -94,22 +124,38 @@ func NewRoot(version string) (*cobra.Command, error) {
 	command.PersistentFlags().StringVar(&configRepoOpts.ConfigRepo, "config-repo", os.Getenv("CONFIG_REPO"), "ssh url for the git config repository")
 	command.PersistentFlags().StringVar(&configRepoOpts.ArtifactFileName, "artifact-filename", "artifact.json", "the filename of the artifact to be used")
 	command.PersistentFlags().StringVar(&configRepoOpts.SSHPrivateKeyPath, "ssh-private-key", "/etc/release-manager/ssh/identity", "ssh-private-key for the config repo")
+	command.PersistentFlags().Var(&configRepoOpts.Routes, "config-repo-environment", "route an environment to another config repository than --config-repo with its own ssh private key and optionally git signing key. Use comma separated list or repeat for multiple environments")
+	command.PersistentFlags().StringVar(&configRepoOpts.Layout.Release, "config-repo-layout-release", layout.DefaultRelease, "path template of release directories in the config repository. Placeholders {environment}, {namespace} and {service} are replaced with the release location")
+	command.PersistentFlags().StringVar(&configRepoOpts.Layout.Kustomization, "config-repo-layout-kustomization", layout.DefaultKustomization, "path template of directories in the config repository Flux kustomizations are moved to. Placeholders {environment} and {namespace} are replaced with the release location")
+	command.PersistentFlags().StringVar(&configRepoOpts.Layout.File, "config-repo-layout-file", ".release-manager/layout.yaml", "path of a layout file in the config repository overriding the layout templates. Ignored if it does not exist")
 	command.PersistentFlags().StringVar(&jwtVerifierOpts.JwksLocation, "jwks-urls", "", "URL of the JWKS for the IdP")
 	command.PersistentFlags().StringVar(&jwtVerifierOpts.Audience, "jwt-audience", "release-manager", "the expected audience of the access token")
 	command.PersistentFlags().StringVar(&jwtVerifierOpts.Issuer, "jwt-issuer", "", "the issuer of the access tokens")
 	command.PersistentFlags().StringVar(&httpOpts.GithubWebhookSecret, "github-webhook-secret", os.Getenv("GITHUB_WEBHOOK_SECRET"), "github webhook secret")
-	command.PersistentFlags().StringVar(&githubAPIToken, "github-api-token", os.Getenv("GITHUB_API_TOKEN"), "github api token for tagging releases")
+	command.PersistentFlags().StringVar(&githubAPIToken, "github-api-token", os.Getenv("GITHUB_API_TOKEN"), "github api token for tagging releases and listing changelogs")
 	command.PersistentFlags().StringVar(&slackAuthToken, "slack-token", os.Getenv("SLACK_TOKEN"), "token to be used to communicate with the slack api")
+	command.PersistentFlags().BoolVar(&slackReleaseChangelog, "slack-release-changelog", false, "attach the source repository commits of a release to its release message in slack. Requires github-api-token")
 	command.PersistentFlags().Var(&grafanaOpts, "grafana-annotations", "configuration of Grafana environments to annotate. Use comma separated list for multiple environments")
 	command.PersistentFlags().StringVar(&emailSuffix, "email-suffix", "", "company email suffix to expect. E.g.: '@example.com'")
 	command.PersistentFlags().StringSliceVar(&users, "user-mappings", []string{}, "user mappings between emails used by Git and Slack, key-value pair: <email>=<slack-email>")
 	command.PersistentFlags().StringSliceVar(&branchRestrictionsList, "policy-branch-restrictions", []string{}, "branch restriction policies applied to all releases, key-value pair: <environment>=<branch-regex>")
+	command.PersistentFlags().StringSliceVar(&requiredStagesList, "policy-required-stages", []string{}, "stages required in artifacts released to an environment applied to all services, key-value pair: <environment>=<stage>. Repeat for multiple stages")
+	command.PersistentFlags().BoolVar(&validateManifests, "release-validate-manifests", false, "validate the Kubernetes manifests of releases before committing them to the config repository")
+	command.PersistentFlags().BoolVar(&injectAnnotations, "release-inject-annotations", false, "set the annotations required by the daemon on Deployments, DaemonSets, StatefulSets and Jobs of releases before committing them to the config repository")
+	command.PersistentFlags().DurationVar(&commitWindow, "release-commit-window", 0, "duration releases to a config repository are collected for before they are pushed together in a single commit. Zero commits each release on its own")
+	command.PersistentFlags().BoolVar(&pinImages, "release-pin-images", false, "rewrite container images of releases to the image and digest recorded in the push stage of the artifact and fail releases referencing other tags of the image")
 	command.PersistentFlags().StringSliceVar(&gpgKeyPaths, "git-gpg-key-import-paths", []string{}, "a list of paths for signing keys to import to gpg")
 
 	registerBrokerFlags(command, &brokerOpts)
 	registerSlackNotificationFlags(command, &slackMuteOpts)
 	registerGitFlags(command, &gitConfigOpts)
 	registerS3Flags(command, &s3storageOpts)
+	registerFSStorageFlags(command, &fsstorageOpts)
+	registerReleaseStatusFlags(command, &releaseStatusOpts)
+	registerArtifactIndexFlags(command, &artifactIndexOpts)
+	registerDriftFlags(command, &driftOpts)
+	registerDoraFlags(command, &doraOpts)
+	registerArtifactSigningFlags(command, &artifactSigningOpts)
 	logConfiguration = log.RegisterFlags(command)
 
 	return command, nil
@@ -123,9 +169,12 @@ func registerSlackNotificationFlags(cmd *cobra.Command, opts *slack.MuteOptions)
 
 func registerBrokerFlags(cmd *cobra.Command, c *brokerOptions) {
 	cmd.PersistentFlags().Var(&c.Type, "broker-type", "configure what broker to use. Available values are \"memory\" and \"amqp\"")
+	cmd.PersistentFlags().IntVar(&c.Partitions, "broker-partitions", 1, "number of partitions consumed in parallel. Releases of the same service and environment are always handled in order. Disabling or lowering it requires stopping all servers and draining the removed AMQP partition queues")
+	cmd.PersistentFlags().StringVar(&c.DeadLetterDir, "broker-dead-letter-dir", "", "directory to persist messages dropped after failed handlings in so they can be replayed. If empty dropped messages are only kept in memory")
 
 	// in-memory options
 	cmd.PersistentFlags().IntVar(&c.Memory.QueueSize, "memory-queue-size", 5, "in-memory queue size")
+	cmd.PersistentFlags().StringVar(&c.Memory.OutboxPath, "memory-outbox-path", "", "path of a file persisting queued messages of the in-memory broker until they are handled so they are replayed after restarts. Requires broker-dead-letter-dir. Empty keeps messages in memory only")
 
 	// amqp options
 	cmd.PersistentFlags().StringVar(&c.AMQP.Host, "amqp-host", "localhost", "AMQP host URL")
@@ -150,6 +199,40 @@ func registerGitFlags(cmd *cobra.Command, opts *git.GitConfig) {
 
 func registerS3Flags(cmd *cobra.Command, opts *s3storageOptions) {
 	cmd.PersistentFlags().StringVar(&opts.S3BucketName, "s3-artifact-storage-bucket-name", "", "the S3 bucket to store artifacts in.")
+	cmd.PersistentFlags().StringVar(&opts.Region, "s3-region", "eu-west-1", "the AWS region of the S3 bucket and SQS queue")
+	cmd.PersistentFlags().StringVar(&opts.Endpoint, "s3-endpoint", "", "URL of an S3 compatible store, e.g. http://minio:9000. If empty the AWS endpoint of the region is used")
+	cmd.PersistentFlags().BoolVar(&opts.ForcePathStyle, "s3-force-path-style", false, "use path style addressing of the bucket, i.e. <endpoint>/<bucket>/<key>, as required by most S3 compatible stores")
+	cmd.PersistentFlags().DurationVar(&opts.PollInterval, "s3-poll-interval", 0, "interval between polling the S3 bucket for new artifacts instead of receiving S3 notifications through SQS. Zero uses SQS")
+	cmd.PersistentFlags().DurationVar(&opts.Retention.Interval, "s3-retention-interval", 0, "interval between deleting old artifacts from the S3 bucket. Zero disables deletion")
+	cmd.PersistentFlags().IntVar(&opts.Retention.KeepLast, "s3-retention-keep-last", 20, "number of newest artifacts to keep per service and branch")
+	cmd.PersistentFlags().DurationVar(&opts.Retention.KeepNewerThan, "s3-retention-keep-newer-than", 90*24*time.Hour, "keep all artifacts newer than this duration")
+	cmd.PersistentFlags().IntVar(&opts.Retention.ReleaseHistory, "s3-retention-release-history", 10, "number of releases per service and environment to keep artifacts of besides the currently released ones")
+	cmd.PersistentFlags().BoolVar(&opts.Retention.DryRun, "s3-retention-dry-run", false, "log the artifacts that would be deleted without deleting them")
+}
+
+func registerFSStorageFlags(cmd *cobra.Command, opts *fsstorageOptions) {
+	cmd.PersistentFlags().StringVar(&opts.Directory, "fs-artifact-storage-dir", "", "directory to store artifacts in instead of an S3 bucket")
+	cmd.PersistentFlags().StringVar(&opts.UploadURL, "fs-artifact-upload-url", "", "base URL of this server used in artifact upload URLs when storing artifacts in a directory, e.g. https://release-manager.example.com")
+	cmd.PersistentFlags().StringVar(&opts.UploadSecret, "fs-artifact-upload-secret", os.Getenv("FS_ARTIFACT_UPLOAD_SECRET"), "secret for signing artifact upload URLs when storing artifacts in a directory. If empty a random secret is used")
+}
+
+func registerReleaseStatusFlags(cmd *cobra.Command, opts *releaseStatusOptions) {
+	cmd.PersistentFlags().StringVar(&opts.Directory, "release-status-dir", "", "directory to persist release statuses in. If empty statuses are only kept in memory")
+	cmd.PersistentFlags().DurationVar(&opts.Retention, "release-status-retention", 30*24*time.Hour, "how long to keep release statuses after their last update")
+}
+
+func registerArtifactIndexFlags(cmd *cobra.Command, opts *artifactIndexOptions) {
+	cmd.PersistentFlags().StringVar(&opts.Directory, "artifact-index-dir", "", "directory to persist the artifact search index in. If empty the index is only kept in memory")
+	cmd.PersistentFlags().DurationVar(&opts.Retention, "artifact-index-retention", 90*24*time.Hour, "how long to keep artifacts in the search index after they are built")
+}
+
+func registerDriftFlags(cmd *cobra.Command, opts *driftOptions) {
+	cmd.PersistentFlags().DurationVar(&opts.AlertAfter, "drift-alert-after", 0, "how long a resource must drift from the config repository before alerting in Slack. Zero disables alerts")
+}
+
+func registerDoraFlags(cmd *cobra.Command, opts *doraOptions) {
+	cmd.PersistentFlags().DurationVar(&opts.Interval, "dora-interval", 15*time.Minute, "interval between updating the DORA metrics exposed to Prometheus. Zero disables the metrics")
+	cmd.PersistentFlags().DurationVar(&opts.Window, "dora-window", 30*24*time.Hour, "time window of releases the DORA metrics exposed to Prometheus are computed over")
 }
 
 // parseBranchRestrictions pases a slice of key-value pairs formatted as
@@ -192,3 +275,47 @@ func parseBranchRestrictions(list []string) ([]policy.BranchRestriction, error)
 	log.Infof("Parsed %d global branch restriction policies", len(restrictions))
 	return restrictions, nil
 }
+
+// parseRequiredStages parses a slice of key-value pairs formatted as
+// <environment>=<stage>. Stages for the same environment are combined into a
+// single policy.
+func parseRequiredStages(list []string) ([]policy.RequiredStages, error) {
+	m := make(map[string][]string)
+	var environments []string
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		s := strings.Split(item, "=")
+		if len(s) != 2 {
+			return nil, errors.Errorf("invalid format '%s'", item)
+		}
+		environment := strings.TrimSpace(s[0])
+		stage := strings.TrimSpace(s[1])
+		if environment == "" || stage == "" {
+			return nil, errors.Errorf("invalid mapping '%s'", item)
+		}
+		_, exist := m[environment]
+		if !exist {
+			environments = append(environments, environment)
+		}
+		m[environment] = append(m[environment], stage)
+	}
+
+	var policies []policy.RequiredStages
+	for _, environment := range environments {
+		policies = append(policies, policy.RequiredStages{
+			ID:          "", // effectively protects against attempts to delete the policy.
+			Environment: environment,
+			Stages:      m[environment],
+		})
+	}
+	log.Infof("Parsed %d global required stages policies", len(policies))
+	return policies, nil
+}
+
+func registerArtifactSigningFlags(cmd *cobra.Command, opts *artifactSigningOptions) {
+	cmd.PersistentFlags().StringSliceVar(&opts.TrustedKeys, "artifact-signing-trusted-keys", []string{}, "paths to PEM encoded ed25519 public keys trusted to sign artifacts. If empty artifact signatures are not verified")
+	cmd.PersistentFlags().BoolVar(&opts.Enforce, "artifact-signing-enforce", true, "reject releases of artifacts without a valid signature by a trusted key. If false verification failures are only logged")
+}
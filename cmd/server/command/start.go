@@ -2,9 +2,11 @@ package command
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -13,21 +15,29 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/lunarway/release-manager/cmd/server/gpg"
 	"github.com/lunarway/release-manager/cmd/server/http"
+	"github.com/lunarway/release-manager/internal/artifactindex"
 	"github.com/lunarway/release-manager/internal/broker"
 	"github.com/lunarway/release-manager/internal/broker/amqpextra"
 	"github.com/lunarway/release-manager/internal/broker/memory"
 	"github.com/lunarway/release-manager/internal/copy"
+	"github.com/lunarway/release-manager/internal/deadletter"
 	"github.com/lunarway/release-manager/internal/events"
 	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/fsstorage"
 	"github.com/lunarway/release-manager/internal/git"
 	"github.com/lunarway/release-manager/internal/github"
 	"github.com/lunarway/release-manager/internal/grafana"
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/layout"
 	"github.com/lunarway/release-manager/internal/log"
 	"github.com/lunarway/release-manager/internal/metrics"
 	"github.com/lunarway/release-manager/internal/policy"
+	"github.com/lunarway/release-manager/internal/releasestore"
 	"github.com/lunarway/release-manager/internal/s3storage"
+	"github.com/lunarway/release-manager/internal/signing"
 	intslack "github.com/lunarway/release-manager/internal/slack"
 	"github.com/lunarway/release-manager/internal/tracing"
 	"github.com/nlopes/slack"
@@ -63,9 +73,11 @@ func (t *brokerType) Type() string {
 }
 
 type brokerOptions struct {
-	Type   brokerType
-	AMQP   amqpOptions
-	Memory memoryOptions
+	Type          brokerType
+	Partitions    int
+	DeadLetterDir string
+	AMQP          amqpOptions
+	Memory        memoryOptions
 }
 
 type amqpOptions struct {
@@ -84,17 +96,69 @@ type amqpOptions struct {
 }
 
 type memoryOptions struct {
-	QueueSize int
+	QueueSize  int
+	OutboxPath string
 }
 
 type configRepoOptions struct {
 	ConfigRepo        string
 	ArtifactFileName  string
 	SSHPrivateKeyPath string
+	Layout            configRepoLayoutOptions
+	Routes            configRepoRoutes
+}
+
+type configRepoLayoutOptions struct {
+	Release       string
+	Kustomization string
+	File          string
+}
+
+type fsstorageOptions struct {
+	Directory    string
+	UploadURL    string
+	UploadSecret string
 }
 
 type s3storageOptions struct {
-	S3BucketName string
+	S3BucketName   string
+	Region         string
+	Endpoint       string
+	ForcePathStyle bool
+	PollInterval   time.Duration
+	Retention      artifactRetentionOptions
+}
+
+type artifactRetentionOptions struct {
+	Interval       time.Duration
+	KeepLast       int
+	KeepNewerThan  time.Duration
+	ReleaseHistory int
+	DryRun         bool
+}
+
+type releaseStatusOptions struct {
+	Directory string
+	Retention time.Duration
+}
+
+type artifactIndexOptions struct {
+	Directory string
+	Retention time.Duration
+}
+
+type driftOptions struct {
+	AlertAfter time.Duration
+}
+
+type artifactSigningOptions struct {
+	TrustedKeys []string
+	Enforce     bool
+}
+
+type doraOptions struct {
+	Interval time.Duration
+	Window   time.Duration
 }
 
 type jwtVerifierOptions struct {
@@ -112,11 +176,23 @@ type startOptions struct {
 	http                      *http.Options
 	broker                    *brokerOptions
 	s3storage                 *s3storageOptions
+	fsstorage                 *fsstorageOptions
+	releaseStatus             *releaseStatusOptions
+	artifactIndex             *artifactIndexOptions
+	drift                     *driftOptions
+	dora                      *doraOptions
+	artifactSigning           *artifactSigningOptions
 	slackMutes                *intslack.MuteOptions
+	slackReleaseChangelog     *bool
+	validateManifests         *bool
+	injectAnnotations         *bool
+	pinImages                 *bool
+	commitWindow              *time.Duration
 	jwtVerifier               *jwtVerifierOptions
 	gpgKeyPaths               *[]string
 	userMappings              *map[string]string
 	branchRestrictionPolicies *[]policy.BranchRestriction
+	requiredStagesPolicies    *[]policy.RequiredStages
 	emailSuffix               *string
 }
 
@@ -168,14 +244,27 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				Config:            startOptions.gitConfigOpts,
 				ArtifactFileName:  startOptions.configRepo.ArtifactFileName,
 			}
+			if startOptions.s3storage.S3BucketName != "" && startOptions.fsstorage.Directory != "" {
+				return errors.New("only one artifact storage can be configured: use either --s3-artifact-storage-bucket-name or --fs-artifact-storage-dir")
+			}
+			// artifactReadStorage and artifactWriteStorage are kept as interfaces to
+			// leave them nil when no storage is configured
+			var artifactReadStorage flow.ArtifactReadStorage
+			var artifactWriteStorage http.ArtifactWriteStorage
 			var s3storageSvc *s3storage.Service
+			var fsstorageSvc *fsstorage.Service
 			if startOptions.s3storage.S3BucketName != "" {
-				region := "eu-west-1"
-				sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+				sess, err := session.NewSession(&aws.Config{Region: aws.String(startOptions.s3storage.Region)})
 				if err != nil {
 					return err
 				}
-				s3client := s3.New(sess)
+				// the endpoint only applies to S3 as S3 compatible stores rarely
+				// provide SQS
+				s3Config := aws.NewConfig().WithS3ForcePathStyle(startOptions.s3storage.ForcePathStyle)
+				if startOptions.s3storage.Endpoint != "" {
+					s3Config = s3Config.WithEndpoint(startOptions.s3storage.Endpoint)
+				}
+				s3client := s3.New(sess, s3Config)
 				sqsClient := sqs.New(sess)
 				s3storageSvc, err = s3storage.New(
 					startOptions.s3storage.S3BucketName,
@@ -186,19 +275,76 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				if err != nil {
 					return err
 				}
+				artifactReadStorage = s3storageSvc
+				artifactWriteStorage = s3storageSvc
+			}
+			if startOptions.fsstorage.Directory != "" {
+				fsstorageSvc, err = fsstorage.New(
+					startOptions.fsstorage.Directory,
+					startOptions.fsstorage.UploadURL,
+					[]byte(startOptions.fsstorage.UploadSecret),
+					tracer,
+				)
+				if err != nil {
+					return errors.WithMessage(err, "setup file system artifact storage")
+				}
+				log.Infof("Storing artifacts in directory '%s'", startOptions.fsstorage.Directory)
+				artifactReadStorage = fsstorageSvc
+				artifactWriteStorage = fsstorageSvc
+			}
+			releaseStore, err := releasestore.New(startOptions.releaseStatus.Directory, startOptions.releaseStatus.Retention)
+			if err != nil {
+				return errors.WithMessage(err, "setup release status store")
+			}
+			deadLetterStore, err := deadletter.New(startOptions.broker.DeadLetterDir)
+			if err != nil {
+				return errors.WithMessage(err, "setup dead letter store")
+			}
+			artifactIndex, err := artifactindex.New(startOptions.artifactIndex.Directory, startOptions.artifactIndex.Retention)
+			if err != nil {
+				return errors.WithMessage(err, "setup artifact index")
 			}
 			github := github.Service{Token: *startOptions.githubAPIToken}
+			// the source repository is only available with a token as source
+			// repositories are expected to be private
+			var sourceRepository flow.SourceRepository
+			if *startOptions.githubAPIToken != "" {
+				sourceRepository = &github
+			}
 			ctx := context.Background()
 			close, err := gitSvc.InitMasterRepo(ctx)
 			if err != nil {
 				return err
 			}
 			defer close(ctx)
+			routedGitSvcs, closeRoutes, err := initConfigRepoRoutes(ctx, startOptions.configRepo.Routes, &gitSvc)
+			if err != nil {
+				return errors.WithMessage(err, "setup config repository routes")
+			}
+			defer closeRoutes(ctx)
+			flowRepositories := make(map[string]flow.GitService)
+			policyRepositories := make(map[string]policy.GitService)
+			for env, svc := range routedGitSvcs {
+				flowRepositories[env] = svc
+				policyRepositories[env] = svc
+			}
+			gitSvcs := []*git.Service{&gitSvc}
+			for _, svc := range routedGitSvcs {
+				if !containsGitService(gitSvcs, svc) {
+					gitSvcs = append(gitSvcs, svc)
+				}
+			}
+			configRepoLayout, err := getConfigRepoLayout(startOptions.configRepo.Layout, gitSvc.MasterPath())
+			if err != nil {
+				return errors.WithMessage(err, "setup config repository layout")
+			}
 			policySvc := policy.Service{
 				Tracer:                          tracer,
 				Git:                             &gitSvc,
+				Repositories:                    policyRepositories,
 				MaxRetries:                      3, // retries for comitting changes into config repo can be required for racing writes
 				GlobalBranchRestrictionPolicies: *startOptions.branchRestrictionPolicies,
+				GlobalRequiredStagesPolicies:    *startOptions.requiredStagesPolicies,
 			}
 			brokerImpl, err := getBroker(startOptions.broker)
 			if err != nil {
@@ -206,6 +352,10 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 			}
 			releaseNotifiers := map[string]func(ctx context.Context, opts flow.NotifyReleaseOptions){
 				"slack": func(ctx context.Context, opts flow.NotifyReleaseOptions) {
+					var changelog []intslack.ChangelogGroup
+					if *startOptions.slackReleaseChangelog && opts.PreviousSpec.ID != "" {
+						changelog = slackChangelog(ctx, sourceRepository, opts)
+					}
 					slackClient.NotifyRelease(
 						ctx,
 						intslack.ReleaseOptions{
@@ -219,6 +369,7 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 							CommitSHA:         opts.Spec.Application.SHA,
 							Releaser:          opts.Releaser,
 							Squad:             opts.Spec.Squad,
+							Changelog:         changelog,
 						},
 					)
 				},
@@ -393,6 +544,110 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				},
 			}
 
+			undeployNotifiers := map[string]func(context.Context, flow.NotifyUndeployOptions){
+				"slack": func(ctx context.Context, opts flow.NotifyUndeployOptions) {
+					err := slackClient.NotifyUndeploy(ctx, intslack.UndeployOptions{
+						Service:     opts.Service,
+						Environment: opts.Environment,
+						Namespace:   opts.Namespace,
+						ArtifactID:  opts.Spec.ID,
+						Undeployer:  opts.Undeployer,
+						Squad:       opts.Spec.Squad,
+					})
+					if err != nil {
+						log.WithContext(ctx).Errorf("post undeploy slack message failed: %v", err)
+					}
+				},
+				"log": func(ctx context.Context, opts flow.NotifyUndeployOptions) {
+					logger := log.WithContext(ctx).WithFields(
+						"service", opts.Service,
+						"environment", opts.Environment,
+						"namespace", opts.Namespace,
+						"artifact-id", opts.Spec.ID,
+						"squad", opts.Spec.Squad,
+						"undeployer", opts.Undeployer,
+						"type", "undeploy",
+					)
+					logger.Infof(
+						"Undeploy [%s]: %s (%s) by %s",
+						opts.Environment,
+						opts.Service,
+						opts.Spec.ID,
+						opts.Undeployer,
+					)
+				},
+				"prometheus": func(_ context.Context, opts flow.NotifyUndeployOptions) {
+					metricsObserver.ObserveRelease(
+						metrics.Release{
+							Environment: opts.Environment,
+							Service:     opts.Service,
+							Releaser:    opts.Undeployer,
+							Intent:      intent.TypeUndeploy,
+							Squad:       opts.Spec.Squad,
+						},
+					)
+				},
+			}
+
+			driftNotifiers := map[string]func(context.Context, flow.NotifyDriftOptions){
+				"slack": func(ctx context.Context, opts flow.NotifyDriftOptions) {
+					if len(opts.Alerts) == 0 {
+						return
+					}
+					drifts := make([]intslack.DriftOptions, len(opts.Alerts))
+					for i, d := range opts.Alerts {
+						drifts[i] = intslack.DriftOptions{
+							Namespace:          d.Namespace,
+							Kind:               d.Kind,
+							Name:               d.Name,
+							Type:               string(d.Type),
+							ExpectedArtifactID: d.ExpectedArtifactID,
+							ActualArtifactID:   d.ActualArtifactID,
+							DetectedAt:         d.DetectedAt,
+						}
+					}
+					err := slackClient.NotifyDrift(ctx, intslack.NotifyDriftOptions{
+						Environment: opts.Environment,
+						Drifts:      drifts,
+					})
+					if err != nil {
+						log.WithContext(ctx).Errorf("post drift slack message failed: %v", err)
+					}
+				},
+				"prometheus": func(_ context.Context, opts flow.NotifyDriftOptions) {
+					drift := metrics.Drift{
+						Environment: opts.Environment,
+						Resources: map[string]int{
+							string(flow.DriftTypeArtifactMismatch): 0,
+							string(flow.DriftTypeMissing):          0,
+							string(flow.DriftTypeUnknown):          0,
+							string(flow.DriftTypeUnparseable):      0,
+						},
+					}
+					for _, d := range opts.Drifts {
+						drift.Resources[string(d.Type)]++
+						if drift.Oldest.IsZero() || d.DetectedAt.Before(drift.Oldest) {
+							drift.Oldest = d.DetectedAt
+						}
+					}
+					metricsObserver.ObserveDrift(drift)
+				},
+			}
+
+			var artifactVerifier flow.ArtifactVerifier
+			if len(startOptions.artifactSigning.TrustedKeys) != 0 {
+				var keys []ed25519.PublicKey
+				for _, keyPath := range startOptions.artifactSigning.TrustedKeys {
+					key, err := signing.LoadPublicKey(keyPath)
+					if err != nil {
+						return errors.WithMessagef(err, "load trusted artifact signing key '%s'", keyPath)
+					}
+					keys = append(keys, key)
+				}
+				log.Infof("Verifying artifact signatures with %d trusted keys. Enforced: %t", len(keys), startOptions.artifactSigning.Enforce)
+				artifactVerifier = signing.NewVerifier(startOptions.artifactSigning.Enforce, keys...)
+			}
+
 			// TODO: figure out a better way of splitting the consumer and publisher
 			// to avoid this chicken and egg issue. It is not a real problem as the
 			// consumer is started later on and this we are sure this gets set, it
@@ -403,11 +658,25 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				Slack:                    slackClient,
 				Git:                      &gitSvc,
 				CanRelease:               policySvc.CanRelease,
-				Storage:                  s3storageSvc,
+				RequiredStages:           policySvc.RequiredStages,
+				AutoReleasePolicies:      policySvc.EnvironmentAutoReleases,
+				Storage:                  artifactReadStorage,
 				Policy:                   &policySvc,
 				Tracer:                   tracer,
 				Copier:                   copier,
 				Observer:                 metricsObserver,
+				Releases:                 releaseStore,
+				DeadLetters:              deadLetterStore,
+				ArtifactIndex:            artifactIndex,
+				Layout:                   configRepoLayout,
+				Repositories:             flowRepositories,
+				ValidateManifests:        *startOptions.validateManifests,
+				InjectAnnotations:        *startOptions.injectAnnotations,
+				PinImages:                *startOptions.pinImages,
+				CommitWindow:             *startOptions.commitWindow,
+				DriftTracker:             flow.NewDriftTracker(startOptions.drift.AlertAfter),
+				SourceRepository:         sourceRepository,
+				ArtifactVerifier:         artifactVerifier,
 				PublishReleaseArtifactID: nil,
 				PublishNewArtifact:       nil,
 				MaxRetries:               3, // retries for comitting changes into config repo can be required for racing writes
@@ -441,6 +710,26 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 						span.End()
 					}
 				},
+				NotifyUndeployHook: func(ctx context.Context, opts flow.NotifyUndeployOptions) {
+					span, ctx := tracer.FromCtx(ctx, "flow.NotifyUndeployHook")
+					defer span.End()
+
+					for name, notifier := range undeployNotifiers {
+						span, ctx := tracer.FromCtx(ctx, fmt.Sprintf("notify %s", name))
+						notifier(ctx, opts)
+						span.End()
+					}
+				},
+				NotifyDriftHook: func(ctx context.Context, opts flow.NotifyDriftOptions) {
+					span, ctx := tracer.FromCtx(ctx, "flow.NotifyDriftHook")
+					defer span.End()
+
+					for name, notifier := range driftNotifiers {
+						span, ctx := tracer.FromCtx(ctx, fmt.Sprintf("notify %s", name))
+						notifier(ctx, opts)
+						span.End()
+					}
+				},
 			}
 			eventHandlers := map[string]func([]byte) error{
 				flow.ReleaseArtifactIDEvent{}.Type(): func(d []byte) error {
@@ -484,7 +773,12 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 					return nil
 				},
 			}
-			errorHandler := func(msgType string, msgBody []byte, err error) {
+			errorHandler := func(msgType string, msgBody []byte, err error) error {
+				deadLetterErr := flowSvc.DeadLetterMessage(ctx, msgType, msgBody, err)
+				if deadLetterErr != nil {
+					log.Errorf("storing dead letter of event type %s failed with error %s", msgType, deadLetterErr)
+				}
+				flowSvc.ReleaseDropped(ctx, msgType, msgBody, err)
 				var event flow.GenericEvent
 				unmarshalErr := event.Unmarshal(msgBody)
 				if unmarshalErr != nil {
@@ -493,7 +787,7 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 						msgType,
 						unmarshalErr,
 					)
-					return
+					return deadLetterErr
 				}
 				slackErr := slackClient.NotifyReleaseManagerError(
 					ctx,
@@ -508,6 +802,7 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				if slackErr != nil {
 					log.Errorf("slack notification failed with error %s", slackErr)
 				}
+				return deadLetterErr
 			}
 			flowSvc.PublishReleaseArtifactID = func(ctx context.Context, event flow.ReleaseArtifactIDEvent) error {
 				return brokerImpl.Publish(ctx, &event)
@@ -515,6 +810,13 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 			flowSvc.PublishNewArtifact = func(ctx context.Context, event flow.NewArtifactEvent) error {
 				return brokerImpl.Publish(ctx, &event)
 			}
+			flowSvc.PublishDeadLetter = func(ctx context.Context, letter flow.DeadLetter) error {
+				return brokerImpl.Publish(ctx, &broker.RawMessage{
+					MessageType: letter.Type,
+					Key:         letter.PartitionKey,
+					Body:        letter.Body,
+				})
+			}
 			defer func() {
 				err := brokerImpl.Close()
 				if err != nil {
@@ -532,8 +834,8 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 					slackClient,
 					&flowSvc,
 					&policySvc,
-					&gitSvc,
-					s3storageSvc,
+					gitSvcs,
+					artifactWriteStorage,
 					tracer,
 					jwtVerifier,
 				)
@@ -547,6 +849,15 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				done <- errors.WithMessage(err, "broker")
 			}()
 			if s3storageSvc != nil {
+				newArtifact := func(ctx context.Context, service, artifactID string) error {
+					// the latest index is updated before handling the artifact as
+					// auto-releases read the latest artifact of the branch
+					err := s3storageSvc.IndexArtifact(ctx, service, artifactID)
+					if err != nil {
+						log.WithContext(ctx).Errorf("Failed to index artifact '%s' of service '%s': %v", artifactID, service, err)
+					}
+					return flowSvc.NewArtifact(ctx, service, artifactID)
+				}
 				sqsHandler := func(msg string) error {
 					var s3event s3storage.S3Event
 					err := s3event.Unmarshal([]byte(msg))
@@ -559,6 +870,9 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 						return nil
 					}
 					for _, record := range s3event.Records {
+						if s3storage.IsLatestIndexKey(record.S3.Object.Key) {
+							continue
+						}
 						parts := strings.Split(record.S3.Object.Key, "/")
 						if len(parts) != 2 {
 							log.With("s3event", s3event).
@@ -567,7 +881,7 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 						}
 						service := parts[0]
 						artifactID := parts[1]
-						err = flowSvc.NewArtifact(ctx, service, artifactID)
+						err = newArtifact(ctx, service, artifactID)
 						if err != nil {
 							return errors.WithMessagef(
 								err,
@@ -583,7 +897,11 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 				if err != nil {
 					return err
 				}
-				err = s3storageSvc.InitializeSQS(sqsHandler)
+				if startOptions.s3storage.PollInterval > 0 {
+					err = s3storageSvc.InitializePolling(startOptions.s3storage.PollInterval, newArtifact)
+				} else {
+					err = s3storageSvc.InitializeSQS(sqsHandler)
+				}
 				if err != nil {
 					return err
 				}
@@ -593,6 +911,19 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 						log.Errorf("Failed to close s3 storage: %v", err)
 					}
 				}()
+				if startOptions.s3storage.Retention.Interval > 0 {
+					retentionCtx, stopRetention := context.WithCancel(ctx)
+					defer stopRetention()
+					go runArtifactRetention(retentionCtx, startOptions.s3storage.Retention, s3storageSvc, &flowSvc, metricsObserver)
+				}
+			}
+			if fsstorageSvc != nil {
+				fsstorageSvc.InitializeNotifications(flowSvc.NewArtifact)
+			}
+			if startOptions.dora.Interval > 0 {
+				doraCtx, stopDora := context.WithCancel(ctx)
+				defer stopDora()
+				go runDoraMetrics(doraCtx, *startOptions.dora, &flowSvc, metricsObserver)
 			}
 			sigs := make(chan os.Signal, 1)
 			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -613,6 +944,110 @@ func NewStart(startOptions *startOptions) *cobra.Command {
 	return command
 }
 
+// runArtifactRetention applies the artifact retention policy to the S3 bucket
+// every configured interval until ctx is cancelled.
+func runArtifactRetention(ctx context.Context, opts artifactRetentionOptions, s3storageSvc *s3storage.Service, flowSvc *flow.Service, observer *metrics.Observer) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		released, err := flowSvc.ReleasedArtifacts(ctx, opts.ReleaseHistory)
+		if err != nil {
+			log.Errorf("Artifact retention skipped: locate released artifacts: %v", err)
+			continue
+		}
+		report, err := s3storageSvc.ApplyRetention(ctx, s3storage.RetentionPolicy{
+			KeepLast:      opts.KeepLast,
+			KeepNewerThan: opts.KeepNewerThan,
+		}, released.Contains, opts.DryRun)
+		observer.ObserveArtifactRetention(metrics.ArtifactRetention{
+			DryRun:           report.DryRun,
+			DeletedArtifacts: len(report.Deleted),
+			DeletedBytes:     report.DeletedBytes,
+		})
+		if err != nil {
+			log.Errorf("Artifact retention failed: %v", err)
+		}
+	}
+}
+
+// runDoraMetrics updates the DORA metrics gauges every configured interval
+// until ctx is cancelled.
+func runDoraMetrics(ctx context.Context, opts doraOptions, flowSvc *flow.Service, observer *metrics.Observer) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		report, err := flowSvc.DoraMetrics(ctx, opts.Window)
+		if err != nil {
+			log.Errorf("DORA metrics failed: %v", err)
+		} else {
+			var dora []metrics.Dora
+			for scope, group := range map[string][]flow.DoraMetrics{
+				"service":     report.Services,
+				"squad":       report.Squads,
+				"environment": report.Environments,
+			} {
+				for _, m := range group {
+					dora = append(dora, metrics.Dora{
+						Scope:               scope,
+						Environment:         m.Environment,
+						Service:             m.Service,
+						Squad:               m.Squad,
+						DeploymentFrequency: m.DeploymentFrequency,
+						LeadTime:            m.LeadTime,
+						ChangeFailureRate:   m.ChangeFailureRate,
+						TimeToRestore:       m.TimeToRestore,
+					})
+				}
+			}
+			observer.ObserveDora(dora)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// slackChangelog returns the changes of a release formatted for Slack. Errors
+// are logged and result in no changelog as the release message is more
+// important than its changelog.
+func slackChangelog(ctx context.Context, sourceRepository flow.SourceRepository, opts flow.NotifyReleaseOptions) []intslack.ChangelogGroup {
+	changelog, err := flow.NewChangelog(ctx, sourceRepository, opts.PreviousSpec, opts.Spec)
+	if err != nil {
+		log.WithContext(ctx).Infof("flow.NotifyReleaseHook: skipped changelog of release message: %v", err)
+		return nil
+	}
+	var groups []intslack.ChangelogGroup
+	for _, group := range changelog.Groups {
+		var entries []string
+		for _, commit := range group.Commits {
+			sha := commit.SHA
+			if len(sha) > 7 {
+				sha = sha[:7]
+			}
+			entry := fmt.Sprintf("<%s|%s> %s", commit.URL, sha, commit.Subject)
+			if commit.Scope != "" {
+				entry = fmt.Sprintf("<%s|%s> *%s:* %s", commit.URL, sha, commit.Scope, commit.Subject)
+			}
+			if commit.Breaking {
+				entry += " *BREAKING*"
+			}
+			entries = append(entries, entry)
+		}
+		groups = append(groups, intslack.ChangelogGroup{
+			Title:   group.Title,
+			Entries: entries,
+		})
+	}
+	return groups
+}
+
 func getBroker(c *brokerOptions) (broker.Broker, error) {
 	switch c.Type {
 	case BrokerTypeAMQP:
@@ -634,6 +1069,7 @@ func getBroker(c *brokerOptions) (broker.Broker, error) {
 			RoutingKey:          "#",
 			Prefetch:            amqpOptions.Prefetch,
 			WorkerCount:         amqpOptions.WorkerCount,
+			Partitions:          c.Partitions,
 			Logger:              log.With("system", "amqp"),
 		})
 	case BrokerTypeMemory:
@@ -641,11 +1077,112 @@ func getBroker(c *brokerOptions) (broker.Broker, error) {
 		if queueSize <= 0 {
 			queueSize = 5
 		}
-		log.Infof("Using an in-memory broker with queue size %d", queueSize)
-		return memory.New(log.With("system", "memory"), queueSize), nil
+		logger := log.With("system", "memory")
+		if c.Memory.OutboxPath == "" {
+			log.Infof("Using an in-memory broker with queue size %d and %d partitions", queueSize, c.Partitions)
+			return memory.NewPartitioned(logger, queueSize, c.Partitions, nil), nil
+		}
+		// failed messages are acknowledged once stored as dead letters so they
+		// must be stored on disk to not be lost on restarts
+		if c.DeadLetterDir == "" {
+			return nil, errors.New("memory outbox requires a dead letter directory: set --broker-dead-letter-dir")
+		}
+		outbox, err := memory.OpenOutbox(logger, c.Memory.OutboxPath)
+		if err != nil {
+			return nil, errors.WithMessage(err, "open outbox")
+		}
+		log.Infof("Using an in-memory broker with queue size %d, %d partitions and outbox '%s'", queueSize, c.Partitions, c.Memory.OutboxPath)
+		return memory.NewPartitioned(logger, queueSize, c.Partitions, outbox), nil
 	default:
 		// this should never happen as the flags are validated against available
 		// values
 		return nil, errors.New("no broker selected")
 	}
 }
+
+// getConfigRepoLayout returns the layout of the config repository. A layout
+// file in the config repository takes precedence over the layout configured
+// with flags.
+func getConfigRepoLayout(opts configRepoLayoutOptions, root string) (*layout.Layout, error) {
+	flagLayout := layout.File{
+		Release:       opts.Release,
+		Kustomization: opts.Kustomization,
+	}
+	if opts.File != "" {
+		path, err := securejoin.SecureJoin(root, opts.File)
+		if err != nil {
+			return nil, errors.WithMessage(err, "join layout file path")
+		}
+		l, err := layout.Load(path, flagLayout)
+		switch {
+		case err == nil:
+			log.Infof("Using config repository layout from '%s': releases in '%s' and kustomizations in '%s'", opts.File, l.ReleaseTemplate(), l.KustomizationTemplate())
+			return l, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, errors.WithMessagef(err, "load layout file '%s'", opts.File)
+		}
+	}
+	l, err := layout.New(flagLayout.Release, flagLayout.Kustomization)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Using config repository layout: releases in '%s' and kustomizations in '%s'", l.ReleaseTemplate(), l.KustomizationTemplate())
+	return l, nil
+}
+
+// initConfigRepoRoutes clones the config repositories environments are routed
+// to. Environments routed to the same repository share its master clone. The
+// returned git services are keyed by environment.
+func initConfigRepoRoutes(ctx context.Context, routes configRepoRoutes, defaultSvc *git.Service) (map[string]*git.Service, func(context.Context), error) {
+	svcs := make(map[string]*git.Service)
+	byURL := make(map[string]*git.Service)
+	var closers []func(context.Context)
+	closeAll := func(ctx context.Context) {
+		for _, close := range closers {
+			close(ctx)
+		}
+	}
+	// iterate environments in order to get deterministic clones and errors
+	var environments []string
+	for env := range routes {
+		environments = append(environments, env)
+	}
+	sort.Strings(environments)
+	for _, env := range environments {
+		route := routes[env]
+		svc, ok := byURL[route.URL]
+		if !ok {
+			config := *defaultSvc.Config
+			if route.SigningKey != "" {
+				config.SigningKey = route.SigningKey
+			}
+			svc = &git.Service{
+				Tracer:            defaultSvc.Tracer,
+				Copier:            defaultSvc.Copier,
+				SSHPrivateKeyPath: route.SSHPrivateKeyPath,
+				ConfigRepoURL:     route.URL,
+				Config:            &config,
+				ArtifactFileName:  defaultSvc.ArtifactFileName,
+			}
+			close, err := svc.InitMasterRepo(ctx)
+			if err != nil {
+				closeAll(ctx)
+				return nil, nil, errors.WithMessagef(err, "clone config repository '%s' of environment '%s'", route.URL, env)
+			}
+			closers = append(closers, close)
+			byURL[route.URL] = svc
+		}
+		log.Infof("Releasing environment '%s' to config repository '%s'", env, route.URL)
+		svcs[env] = svc
+	}
+	return svcs, closeAll, nil
+}
+
+func containsGitService(svcs []*git.Service, svc *git.Service) bool {
+	for _, s := range svcs {
+		if s == svc {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func changelog(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		service := muxService(r)
+		environment := muxEnvironment(r)
+
+		values := r.URL.Query()
+		namespace := values.Get("namespace")
+		artifactID := values.Get("artifactId")
+		if emptyString(artifactID) {
+			httpinternal.Error(w, "artifactId query parameter is required", http.StatusBadRequest)
+			return
+		}
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("service", service, "environment", environment, "namespace", namespace, "artifactId", artifactID)
+		resp, err := flowSvc.Changelog(ctx, environment, namespace, service, artifactID)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: changelog: service '%s' environment '%s': request cancelled", service, environment)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case artifact.ErrFileNotFound:
+				httpinternal.Error(w, fmt.Sprintf("no release of service '%s' available in environment '%s'. Are you missing a namespace?", service, environment), http.StatusBadRequest)
+				return
+			case flow.ErrArtifactNotFound:
+				httpinternal.Error(w, fmt.Sprintf("artifact '%s' not found", artifactID), http.StatusBadRequest)
+				return
+			case flow.ErrChangelogNotSupported:
+				logger.Infof("http: changelog: service '%s' environment '%s': changelog not supported: %v", service, environment, err)
+				httpinternal.Error(w, fmt.Sprintf("changelog not supported for service '%s': %v", service, err), http.StatusBadRequest)
+				return
+			default:
+				logger.Errorf("http: changelog: service '%s' environment '%s': failed: %v", service, environment, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		var groups []httpinternal.ChangelogGroup
+		for _, group := range resp.Groups {
+			var commits []httpinternal.ChangelogCommit
+			for _, commit := range group.Commits {
+				commits = append(commits, httpinternal.ChangelogCommit{
+					SHA:        commit.SHA,
+					Scope:      commit.Scope,
+					Subject:    commit.Subject,
+					Breaking:   commit.Breaking,
+					AuthorName: commit.AuthorName,
+					URL:        commit.URL,
+				})
+			}
+			groups = append(groups, httpinternal.ChangelogGroup{
+				Type:    group.Type,
+				Title:   group.Title,
+				Commits: commits,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.ChangelogResponse{
+			Service:        service,
+			Environment:    environment,
+			FromArtifactID: resp.From.ID,
+			FromSHA:        resp.From.Application.SHA,
+			ToArtifactID:   resp.To.ID,
+			ToSHA:          resp.To.Application.SHA,
+			Groups:         groups,
+		})
+		if err != nil {
+			logger.Errorf("http: changelog: service '%s' environment '%s': marshal response failed: %v", service, environment, err)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package http
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"net/url"
 
+	"github.com/gorilla/mux"
 	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/fsstorage"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
 	"github.com/lunarway/release-manager/internal/log"
 )
@@ -48,3 +53,46 @@ type ArtifactWriteStorage interface {
 	// CreateArtifact creates the artifact in the storage and returns an URL for uploading the artifact zipped
 	CreateArtifact(artifactSpec artifact.Spec, md5 string) (string, error)
 }
+
+// ArtifactUploadStorage is implemented by ArtifactWriteStorage implementations
+// that receive artifact uploads through the server instead of a third party
+// like S3.
+type ArtifactUploadStorage interface {
+	// UploadArtifact stores the zipped artifact read from body. query is the
+	// query of the upload URL returned by CreateArtifact and contentMD5 the
+	// base64 encoded MD5 digest of body.
+	UploadArtifact(ctx context.Context, service, artifactID string, query url.Values, contentMD5 string, body io.Reader) error
+}
+
+// maxArtifactUploadSize is the maximum size in bytes of uploaded artifacts.
+const maxArtifactUploadSize = 100 << 20
+
+func uploadArtifact(artifactUploadStorage ArtifactUploadStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		vars := mux.Vars(r)
+		service := vars["service"]
+		artifactID := vars["artifactId"]
+		logger := log.WithContext(ctx).WithFields("service", service, "artifactId", artifactID)
+
+		body := http.MaxBytesReader(w, r.Body, maxArtifactUploadSize)
+		err := artifactUploadStorage.UploadArtifact(ctx, service, artifactID, r.URL.Query(), r.Header.Get("Content-MD5"), body)
+		if err != nil {
+			switch errorCause(err) {
+			case fsstorage.ErrInvalidUploadURL:
+				logger.Infof("http: artifact: upload: service '%s' artifact '%s': rejected: %v", service, artifactID, err)
+				httpinternal.Error(w, "invalid or expired upload url", http.StatusForbidden)
+				return
+			case fsstorage.ErrBadDigest:
+				logger.Infof("http: artifact: upload: service '%s' artifact '%s': rejected: %v", service, artifactID, err)
+				httpinternal.Error(w, "content does not match the provided md5 digest", http.StatusBadRequest)
+				return
+			default:
+				logger.Errorf("http: artifact: upload: service '%s' artifact '%s': failed: %v", service, artifactID, err)
+				unknownError(w)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
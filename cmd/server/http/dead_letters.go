@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func listDeadLetters(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.WithContext(ctx)
+		letters, err := flowSvc.ListDeadLetters(ctx)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: list dead letters: request cancelled")
+				cancelled(w)
+				return
+			}
+			logger.Errorf("http: list dead letters: failed: %v", err)
+			unknownError(w)
+			return
+		}
+
+		resp := httpinternal.ListDeadLettersResponse{
+			DeadLetters: make([]httpinternal.DeadLetter, 0, len(letters)),
+		}
+		for _, letter := range letters {
+			resp.DeadLetters = append(resp.DeadLetters, mapDeadLetter(letter))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, resp)
+		if err != nil {
+			logger.Errorf("http: list dead letters: marshal response failed: %v", err)
+		}
+	}
+}
+
+func replayDeadLetter(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("deadLetterId", id)
+		letter, err := flowSvc.ReplayDeadLetter(ctx, id)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: replay dead letter: dead letter '%s': request cancelled", id)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrDeadLetterNotFound:
+				httpinternal.Error(w, fmt.Sprintf("dead letter '%s' not found", id), http.StatusNotFound)
+				return
+			default:
+				logger.Errorf("http: replay dead letter: dead letter '%s': failed: %v", id, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.ReplayDeadLetterResponse{
+			DeadLetter: mapDeadLetter(letter),
+		})
+		if err != nil {
+			logger.Errorf("http: replay dead letter: dead letter '%s': marshal response failed: %v", id, err)
+		}
+	}
+}
+
+func mapDeadLetter(letter flow.DeadLetter) httpinternal.DeadLetter {
+	return httpinternal.DeadLetter{
+		ID:          letter.ID,
+		Type:        letter.Type,
+		Service:     letter.Service,
+		Environment: letter.Environment,
+		Error:       letter.Error,
+		DroppedAt:   letter.DroppedAt,
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func listEnvironments(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.WithContext(ctx)
+		environments, err := flowSvc.Environments(ctx)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: list environments: request cancelled")
+				cancelled(w)
+				return
+			}
+			logger.Errorf("http: list environments: failed: %v", err)
+			unknownError(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.ListEnvironmentsResponse{
+			Environments: environments,
+		})
+		if err != nil {
+			logger.Errorf("http: list environments: marshal response failed: %v", err)
+		}
+	}
+}
+
+func listNamespaces(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		environment := muxEnvironment(r)
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("environment", environment)
+		namespaces, err := flowSvc.Namespaces(ctx, environment)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: list namespaces: environment '%s': request cancelled", environment)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrUnknownEnvironment:
+				httpinternal.Error(w, fmt.Sprintf("unknown environment: %s", environment), http.StatusNotFound)
+				return
+			default:
+				logger.Errorf("http: list namespaces: environment '%s': failed: %v", environment, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.ListNamespacesResponse{
+			Environment: environment,
+			Namespaces:  namespaces,
+		})
+		if err != nil {
+			logger.Errorf("http: list namespaces: environment '%s': marshal response failed: %v", environment, err)
+		}
+	}
+}
+
+func listServices(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		environment := muxEnvironment(r)
+		namespace := mux.Vars(r)["namespace"]
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("environment", environment, "namespace", namespace)
+		services, err := flowSvc.Services(ctx, environment, namespace)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: list services: environment '%s' namespace '%s': request cancelled", environment, namespace)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrUnknownEnvironment:
+				httpinternal.Error(w, fmt.Sprintf("unknown environment: %s", environment), http.StatusNotFound)
+				return
+			case flow.ErrUnknownNamespace:
+				httpinternal.Error(w, fmt.Sprintf("unknown namespace '%s' in environment '%s'", namespace, environment), http.StatusNotFound)
+				return
+			default:
+				logger.Errorf("http: list services: environment '%s' namespace '%s': failed: %v", environment, namespace, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.ListServicesResponse{
+			Environment: environment,
+			Namespace:   namespace,
+			Services:    services,
+		})
+		if err != nil {
+			logger.Errorf("http: list services: environment '%s' namespace '%s': marshal response failed: %v", environment, namespace, err)
+		}
+	}
+}
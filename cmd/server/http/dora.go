@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+// defaultDoraWindow is the time window DORA metrics are computed over if not
+// specified in the request.
+const defaultDoraWindow = 30 * 24 * time.Hour
+
+func doraMetrics(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := defaultDoraWindow
+		windowParam := r.URL.Query().Get("window")
+		if !emptyString(windowParam) {
+			var err error
+			window, err = time.ParseDuration(windowParam)
+			if err != nil || window <= 0 {
+				httpinternal.Error(w, fmt.Sprintf("invalid value '%s' of window. Must be a positive duration, e.g. 720h.", windowParam), http.StatusBadRequest)
+				return
+			}
+		}
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("window", window.String())
+		report, err := flowSvc.DoraMetrics(ctx, window)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: dora metrics: request cancelled")
+				cancelled(w)
+				return
+			}
+			logger.Errorf("http: dora metrics: failed: %v", err)
+			unknownError(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.DoraMetricsResponse{
+			From:         report.From,
+			To:           report.To,
+			Services:     mapDoraMetrics(report.Services),
+			Squads:       mapDoraMetrics(report.Squads),
+			Environments: mapDoraMetrics(report.Environments),
+		})
+		if err != nil {
+			logger.Errorf("http: dora metrics: marshal response failed: %v", err)
+		}
+	}
+}
+
+func mapDoraMetrics(metrics []flow.DoraMetrics) []httpinternal.DoraMetrics {
+	mapped := make([]httpinternal.DoraMetrics, 0, len(metrics))
+	for _, m := range metrics {
+		mapped = append(mapped, httpinternal.DoraMetrics{
+			Environment:          m.Environment,
+			Service:              m.Service,
+			Squad:                m.Squad,
+			Deployments:          m.Deployments,
+			DeploymentFrequency:  m.DeploymentFrequency,
+			LeadTimeSeconds:      m.LeadTime.Seconds(),
+			Failures:             m.Failures,
+			ChangeFailureRate:    m.ChangeFailureRate,
+			TimeToRestoreSeconds: m.TimeToRestore.Seconds(),
+		})
+	}
+	return mapped
+}
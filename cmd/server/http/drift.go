@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func drift(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		environment := r.URL.Query().Get("environment")
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("environment", environment)
+		drifts := flowSvc.Drift(ctx, environment)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err := payload.encodeResponse(ctx, w, httpinternal.DriftResponse{
+			Drifts: mapDrifts(drifts),
+		})
+		if err != nil {
+			logger.Errorf("http: drift: environment '%s': marshal response failed: %v", environment, err)
+		}
+	}
+}
+
+func mapDrifts(drifts []flow.Drift) []httpinternal.Drift {
+	mapped := make([]httpinternal.Drift, 0, len(drifts))
+	for _, d := range drifts {
+		mapped = append(mapped, httpinternal.Drift{
+			Environment:        d.Environment,
+			Namespace:          d.Namespace,
+			Kind:               d.Kind,
+			Name:               d.Name,
+			Service:            d.Service,
+			Type:               string(d.Type),
+			ExpectedArtifactID: d.ExpectedArtifactID,
+			ActualArtifactID:   d.ActualArtifactID,
+			DetectedAt:         d.DetectedAt,
+		})
+	}
+	return mapped
+}
+
+func daemonk8sStateWebhook(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// copy span from request context but ignore any deadlines on the request context
+		ctx := oteltrace.ContextWithSpan(context.Background(), oteltrace.SpanFromContext(r.Context()))
+		logger := log.WithContext(ctx)
+		var report httpinternal.ClusterStateReport
+		err := payload.decodeResponse(ctx, r.Body, &report)
+		if err != nil {
+			logger.Errorf("http: daemon k8s state webhook: decode request body failed: %v", err)
+			invalidBodyError(w)
+			return
+		}
+		logger = logger.WithFields("environment", report.Environment, "resources", len(report.Resources))
+		err = flowSvc.ReportClusterState(ctx, &report)
+		if err != nil {
+			logger.Errorf("http: daemon k8s state webhook failed: %+v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = payload.encodeResponse(ctx, w, httpinternal.KubernetesNotifyResponse{})
+		if err != nil {
+			logger.Errorf("http: daemon k8s state webhook: environment: '%s' marshal response: %v", report.Environment, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		logger.Infof("http: daemon k8s state webhook: handled")
+	}
+}
@@ -14,7 +14,7 @@ import (
 	"gopkg.in/go-playground/webhooks.v5/github"
 )
 
-func githubWebhook(payload *payload, flowSvc *flow.Service, policySvc *policyinternal.Service, gitSvc *git.Service, slackClient *slack.Client, githubWebhookSecret string) http.HandlerFunc {
+func githubWebhook(payload *payload, flowSvc *flow.Service, policySvc *policyinternal.Service, gitSvcs []*git.Service, slackClient *slack.Client, githubWebhookSecret string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// copy span from request context but ignore any deadlines on the request context
 		ctx := oteltrace.ContextWithSpan(context.Background(), oteltrace.SpanFromContext(r.Context()))
@@ -33,11 +33,11 @@ func githubWebhook(payload *payload, flowSvc *flow.Service, policySvc *policyint
 				w.WriteHeader(http.StatusOK)
 				return
 			}
-			err := gitSvc.SyncMaster(ctx)
-			if err != nil {
-				logger.Errorf("http: github webhook: failed to sync master: %v", err)
-				w.WriteHeader(http.StatusOK)
-				return
+			for _, gitSvc := range pushedConfigRepos(gitSvcs, payload) {
+				err := gitSvc.SyncMaster(ctx)
+				if err != nil {
+					logger.Errorf("http: github webhook: failed to sync master of '%s': %v", gitSvc.ConfigRepoURL, err)
+				}
 			}
 			w.WriteHeader(http.StatusOK)
 			return
@@ -49,6 +49,23 @@ func githubWebhook(payload *payload, flowSvc *flow.Service, policySvc *policyint
 	}
 }
 
+// pushedConfigRepos returns the config repositories matching the repository of
+// a push. If none match all repositories are returned as the URLs might be
+// configured in another form than GitHub reports.
+func pushedConfigRepos(gitSvcs []*git.Service, payload github.PushPayload) []*git.Service {
+	var pushed []*git.Service
+	for _, gitSvc := range gitSvcs {
+		switch gitSvc.ConfigRepoURL {
+		case payload.Repository.SSHURL, payload.Repository.CloneURL, payload.Repository.GitURL:
+			pushed = append(pushed, gitSvc)
+		}
+	}
+	if len(pushed) == 0 {
+		return gitSvcs
+	}
+	return pushed
+}
+
 func isBranchPush(ref string) bool {
 	return strings.HasPrefix(ref, "refs/heads/")
 }
@@ -29,7 +29,7 @@ type Options struct {
 	S3WebhookSecret     string
 }
 
-func NewServer(opts *Options, slackClient *slack.Client, flowSvc *flow.Service, policySvc *policyinternal.Service, gitSvc *git.Service, artifactWriteStorage ArtifactWriteStorage, tracer tracing.Tracer, jwtVerifier *Verifier) error {
+func NewServer(opts *Options, slackClient *slack.Client, flowSvc *flow.Service, policySvc *policyinternal.Service, gitSvcs []*git.Service, artifactWriteStorage ArtifactWriteStorage, tracer tracing.Tracer, jwtVerifier *Verifier) error {
 	payloader := payload{
 		tracer: tracer,
 	}
@@ -46,29 +46,51 @@ func NewServer(opts *Options, slackClient *slack.Client, flowSvc *flow.Service,
 	hamctlMux := m.NewRoute().Subrouter()
 	hamctlMux.Use(jwtVerifier.authentication(opts.HamCtlAuthTokens))
 	hamctlMux.Methods(http.MethodPost).Path("/release").Handler(release(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodPost).Path("/undeploy").Handler(undeploy(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/releases/{id}").Handler(releaseStatus(&payloader, flowSvc))
 	hamctlMux.Methods(http.MethodGet).Path("/status").Handler(status(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/status/matrix").Handler(statusMatrix(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/drift").Handler(drift(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/metrics/dora").Handler(doraMetrics(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/environments").Handler(listEnvironments(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/environments/{environment}/namespaces").Handler(listNamespaces(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/environments/{environment}/namespaces/{namespace}/services").Handler(listServices(&payloader, flowSvc))
 
 	policyMux := hamctlMux.PathPrefix("/policies").Subrouter()
 	policyMux.Methods(http.MethodGet).Handler(listPolicies(&payloader, policySvc))
 	policyMux.Methods(http.MethodDelete).Handler(deletePolicies(&payloader, policySvc))
 	policyMux.Methods(http.MethodPatch).Path("/auto-release").Handler(applyAutoReleasePolicy(&payloader, policySvc))
 	policyMux.Methods(http.MethodPatch).Path("/branch-restriction").Handler(applyBranchRestrictionPolicy(&payloader, policySvc))
+	policyMux.Methods(http.MethodPatch).Path("/required-stages").Handler(applyRequiredStagesPolicy(&payloader, policySvc))
 
 	hamctlMux.Methods(http.MethodGet).Path("/describe/release/{service}/{environment}").Handler(describeRelease(&payloader, flowSvc))
 	hamctlMux.Methods(http.MethodGet).Path("/describe/artifact/{service}").Handler(describeArtifact(&payloader, flowSvc))
 	hamctlMux.Methods(http.MethodGet).Path("/describe/latest-artifact/{service}").Handler(describeLatestArtifacts(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/artifacts/search").Handler(searchArtifacts(&payloader, flowSvc))
+	hamctlMux.Methods(http.MethodGet).Path("/changelog/{service}/{environment}").Handler(changelog(&payloader, flowSvc))
+
+	adminMux := hamctlMux.PathPrefix("/admin").Subrouter()
+	adminMux.Methods(http.MethodGet).Path("/dead-letters").Handler(listDeadLetters(&payloader, flowSvc))
+	adminMux.Methods(http.MethodPost).Path("/dead-letters/{id}/replay").Handler(replayDeadLetter(&payloader, flowSvc))
 
 	daemonMux := m.NewRoute().Subrouter()
 	daemonMux.Use(jwtVerifier.authentication(opts.DaemonAuthTokens))
 	daemonMux.Methods(http.MethodPost).Path("/webhook/daemon/k8s/deploy").Handler(daemonk8sDeployWebhook(&payloader, flowSvc))
 	daemonMux.Methods(http.MethodPost).Path("/webhook/daemon/k8s/error").Handler(daemonk8sPodErrorWebhook(&payloader, flowSvc))
 	daemonMux.Methods(http.MethodPost).Path("/webhook/daemon/k8s/joberror").Handler(daemonk8sJobErrorWebhook(&payloader, flowSvc))
+	daemonMux.Methods(http.MethodPost).Path("/webhook/daemon/k8s/state").Handler(daemonk8sStateWebhook(&payloader, flowSvc))
 
 	// s3 endpoints
 	artifactMux := m.NewRoute().Subrouter()
 	artifactMux.Use(jwtVerifier.authentication(opts.ArtifactAuthTokens))
 	artifactMux.Methods(http.MethodPost).Path("/artifacts/create").Handler(createArtifact(&payloader, artifactWriteStorage))
 
+	// uploads are authenticated by the signature of the URL returned when
+	// creating the artifact
+	if artifactUploadStorage, ok := artifactWriteStorage.(ArtifactUploadStorage); ok {
+		m.Methods(http.MethodPut).Path("/artifacts/upload/{service}/{artifactId}").Handler(uploadArtifact(artifactUploadStorage))
+	}
+
 	// profiling endpoints
 	m.HandleFunc("/debug/pprof/", pprof.Index)
 	m.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -78,7 +100,7 @@ func NewServer(opts *Options, slackClient *slack.Client, flowSvc *flow.Service,
 
 	m.HandleFunc("/ping", ping)
 	m.Handle("/metrics", promhttp.Handler())
-	m.HandleFunc("/webhook/github", githubWebhook(&payloader, flowSvc, policySvc, gitSvc, slackClient, opts.GithubWebhookSecret))
+	m.HandleFunc("/webhook/github", githubWebhook(&payloader, flowSvc, policySvc, gitSvcs, slackClient, opts.GithubWebhookSecret))
 
 	s := http.Server{
 		Addr:              fmt.Sprintf(":%d", opts.Port),
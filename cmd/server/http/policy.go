@@ -150,6 +150,67 @@ func applyBranchRestrictionPolicy(payload *payload, policySvc *policyinternal.Se
 	}
 }
 
+func applyRequiredStagesPolicy(payload *payload, policySvc *policyinternal.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.WithContext(ctx)
+		var req httpinternal.ApplyRequiredStagesPolicyRequest
+		err := payload.decodeResponse(ctx, r.Body, &req)
+		if err != nil {
+			logger.Errorf("http: policy: apply: required-stages: decode request body failed: %v", err)
+			invalidBodyError(w)
+			return
+		}
+
+		if !req.Validate(w) {
+			return
+		}
+
+		actor := policyinternal.Actor{
+			Name:  req.CommitterName,
+			Email: req.CommitterEmail,
+		}
+		subject := UserFromContext(r.Context())
+		if subject != "" {
+			actor.Email = subject
+			actor.Name = subject
+		}
+
+		logger = logger.WithFields("service", req.Service, "req", req)
+		logger.Infof("http: policy: apply: service '%s' stages %v environment '%s': apply required-stages policy started", req.Service, req.Stages, req.Environment)
+		id, err := policySvc.ApplyRequiredStages(ctx, actor, req.Service, req.Environment, req.Stages)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: policy: apply: service '%s' stages %v environment '%s': apply required-stages cancelled", req.Service, req.Stages, req.Environment)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case git.ErrBranchBehindOrigin:
+				logger.Infof("http: policy: apply: service '%s' stages %v environment '%s': apply required-stages: %v", req.Service, req.Stages, req.Environment, err)
+				httpinternal.Error(w, "could not apply policy right now. Please try again in a moment.", http.StatusServiceUnavailable)
+				return
+			default:
+				logger.Errorf("http: policy: apply: service '%s' stages %v environment '%s': apply required-stages failed: %v", req.Service, req.Stages, req.Environment, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		err = payload.encodeResponse(ctx, w, httpinternal.ApplyRequiredStagesPolicyResponse{
+			ID:          id,
+			Service:     req.Service,
+			Environment: req.Environment,
+			Stages:      req.Stages,
+		})
+		if err != nil {
+			logger.Errorf("http: policy: apply: service '%s' stages %v environment '%s': apply required-stages: marshal response failed: %v", req.Service, req.Stages, req.Environment, err)
+		}
+	}
+}
+
 func listPolicies(payload *payload, policySvc *policyinternal.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		values := r.URL.Query()
@@ -183,6 +244,7 @@ func listPolicies(payload *payload, policySvc *policyinternal.Service) http.Hand
 			Service:            policies.Service,
 			AutoReleases:       mapAutoReleasePolicies(policies.AutoReleases),
 			BranchRestrictions: mapBranchRestrictionPolicies(policies.BranchRestrictions),
+			RequiredStages:     mapRequiredStagesPolicies(policies.RequiredStages),
 		})
 		if err != nil {
 			logger.Errorf("http: policy: list: service '%s': marshal response failed: %v", service, err)
@@ -214,6 +276,18 @@ func mapBranchRestrictionPolicies(policies []policyinternal.BranchRestriction) [
 	return h
 }
 
+func mapRequiredStagesPolicies(policies []policyinternal.RequiredStages) []httpinternal.RequiredStagesPolicy {
+	h := make([]httpinternal.RequiredStagesPolicy, len(policies))
+	for i, p := range policies {
+		h[i] = httpinternal.RequiredStagesPolicy{
+			ID:          p.ID,
+			Environment: p.Environment,
+			Stages:      p.Stages,
+		}
+	}
+	return h
+}
+
 func deletePolicies(payload *payload, policySvc *policyinternal.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/lunarway/release-manager/internal/artifact"
 	"github.com/lunarway/release-manager/internal/flow"
 	"github.com/lunarway/release-manager/internal/git"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
 	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
 )
 
 func release(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
@@ -52,6 +54,12 @@ func release(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
 				cancelled(w)
 				return
 			}
+			var missingStagesErr *flow.MissingStagesError
+			if errors.As(err, &missingStagesErr) {
+				logger.Infof("http: release: service '%s' environment '%s' artifact id '%s': release rejected: missing required stages: %v", req.Service, req.Environment, req.ArtifactID, err)
+				httpinternal.Error(w, fmt.Sprintf("cannot release %s to environment '%s' due to required stages policy: missing stages %s", req.Intent.AsArtifactWithIntent(req.ArtifactID), req.Environment, strings.Join(missingStagesErr.Stages, ", ")), http.StatusBadRequest)
+				return
+			}
 			switch errorCause(err) {
 			case flow.ErrReleaseProhibited:
 				logger.Infof("http: release: service '%s' environment '%s' artifact id '%s': release rejected: branch prohibited in environment: %v", req.Service, req.Environment, req.ArtifactID, err)
@@ -93,7 +101,7 @@ func release(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
 			Service:       req.Service,
 			ReleaseID:     releaseID,
 			ToEnvironment: req.Environment,
-			Tag:           releaseID,
+			Tag:           req.ArtifactID,
 			Status:        statusString,
 		})
 		if err != nil {
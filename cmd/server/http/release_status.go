@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func releaseStatus(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("releaseId", id)
+		status, err := flowSvc.ReleaseStatus(ctx, id)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: release status: release '%s': request cancelled", id)
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrReleaseStatusNotFound:
+				httpinternal.Error(w, fmt.Sprintf("release '%s' not found", id), http.StatusNotFound)
+				return
+			default:
+				logger.Errorf("http: release status: release '%s': failed: %v", id, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, mapReleaseStatus(status))
+		if err != nil {
+			logger.Errorf("http: release status: release '%s': marshal response failed: %v", id, err)
+		}
+	}
+}
+
+func mapReleaseStatus(status flow.ReleaseStatus) httpinternal.ReleaseStatusResponse {
+	var transitions []httpinternal.ReleaseStatusTransition
+	for _, t := range status.Transitions {
+		transitions = append(transitions, httpinternal.ReleaseStatusTransition{
+			State:   string(t.State),
+			Message: t.Message,
+			At:      t.At,
+		})
+	}
+	return httpinternal.ReleaseStatusResponse{
+		ID:          status.ID,
+		Service:     status.Service,
+		Environment: status.Environment,
+		Namespace:   status.Namespace,
+		ArtifactID:  status.ArtifactID,
+		ReleasedBy:  status.Actor.Email,
+		Intent:      status.Intent,
+		State:       string(status.State),
+		Error:       status.Error,
+		CreatedAt:   status.CreatedAt,
+		UpdatedAt:   status.UpdatedAt,
+		Transitions: transitions,
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func searchArtifacts(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		countParam := values.Get("count")
+		if emptyString(countParam) {
+			countParam = "20"
+		}
+		count, err := strconv.Atoi(countParam)
+		if err != nil || count <= 0 {
+			httpinternal.Error(w, fmt.Sprintf("invalid value '%s' of count. Must be a positive integer.", countParam), http.StatusBadRequest)
+			return
+		}
+		labels, err := artifact.ParseLabels(values["label"])
+		if err != nil {
+			httpinternal.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := flow.ArtifactQuery{
+			Service: values.Get("service"),
+			SHA:     values.Get("sha"),
+			Author:  values.Get("author"),
+			Labels:  labels,
+			Limit:   count,
+		}
+		if emptyString(query.Service) && emptyString(query.SHA) && emptyString(query.Author) && len(query.Labels) == 0 {
+			httpinternal.Error(w, "at least one of service, sha, author or label must be specified", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("query", query)
+		results, err := flowSvc.SearchArtifacts(ctx, query)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: search artifacts: request cancelled")
+				cancelled(w)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrArtifactIndexNotConfigured:
+				httpinternal.Error(w, "artifact search is not available", http.StatusNotImplemented)
+				return
+			default:
+				logger.Errorf("http: search artifacts: failed: %v", err)
+				unknownError(w)
+				return
+			}
+		}
+
+		resp := httpinternal.SearchArtifactsResponse{
+			Artifacts: make([]httpinternal.SearchArtifactsResult, len(results)),
+		}
+		for i, result := range results {
+			resp.Artifacts[i] = httpinternal.SearchArtifactsResult{
+				Artifact:     result.Artifact,
+				Environments: result.Environments,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, resp)
+		if err != nil {
+			logger.Errorf("http: search artifacts: marshal response failed: %v", err)
+		}
+	}
+}
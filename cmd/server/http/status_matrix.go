@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+)
+
+func statusMatrix(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		squad := r.URL.Query().Get("squad")
+		ctx := r.Context()
+		logger := log.WithContext(ctx).WithFields("squad", squad)
+		matrix, err := flowSvc.StatusMatrix(ctx, squad)
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: status matrix: squad '%s': request cancelled", squad)
+				cancelled(w)
+				return
+			}
+			logger.Errorf("http: status matrix: squad '%s': failed: %v", squad, err)
+			unknownError(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, mapStatusMatrix(matrix))
+		if err != nil {
+			logger.Errorf("http: status matrix: squad '%s': marshal response failed: %v", squad, err)
+		}
+	}
+}
+
+func mapStatusMatrix(matrix flow.StatusMatrix) httpinternal.StatusMatrixResponse {
+	services := make([]httpinternal.StatusMatrixService, 0, len(matrix.Services))
+	for _, service := range matrix.Services {
+		var releases []httpinternal.StatusMatrixRelease
+		for _, release := range service.Releases {
+			releases = append(releases, httpinternal.StatusMatrixRelease{
+				Environment:           release.Environment,
+				Namespace:             release.Namespace,
+				ArtifactID:            release.ArtifactID,
+				Squad:                 release.Squad,
+				ReleasedAt:            release.ReleasedAt,
+				ReleasedByEmail:       release.ReleasedByEmail,
+				ReleasedByName:        release.ReleasedByName,
+				HighVulnerabilities:   release.HighVulnerabilities,
+				MediumVulnerabilities: release.MediumVulnerabilities,
+				LowVulnerabilities:    release.LowVulnerabilities,
+			})
+		}
+		services = append(services, httpinternal.StatusMatrixService{
+			Service:  service.Service,
+			Squad:    service.Squad,
+			Releases: releases,
+		})
+	}
+	return httpinternal.StatusMatrixResponse{
+		Environments: matrix.Environments,
+		Services:     services,
+	}
+}
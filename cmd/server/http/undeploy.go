@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/git"
+	httpinternal "github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+func undeploy(payload *payload, flowSvc *flow.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := log.WithContext(ctx)
+		var req httpinternal.UndeployRequest
+		err := payload.decodeResponse(ctx, r.Body, &req)
+		if err != nil {
+			logger.Errorf("http: undeploy: decode request body failed: %v", err)
+			invalidBodyError(w)
+			return
+		}
+		if !req.Validate(w) {
+			return
+		}
+
+		actor := flow.Actor{
+			Name:  req.CommitterName,
+			Email: req.CommitterEmail,
+		}
+		subject := UserFromContext(r.Context())
+		if subject != "" {
+			actor.Email = subject
+			actor.Name = subject
+		}
+
+		logger = logger.WithFields(
+			"service", req.Service,
+			"req", req)
+
+		logger.Infof("http: undeploy: service '%s' environment '%s' namespace '%s': undeploying service", req.Service, req.Environment, req.Namespace)
+		artifactID, err := flowSvc.Undeploy(ctx, actor, req.Environment, req.Namespace, req.Service)
+
+		var statusString string
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				logger.Infof("http: undeploy: service '%s' environment '%s': undeploy cancelled", req.Service, req.Environment)
+				cancelled(w)
+				return
+			}
+			var autoReleaseErr *flow.AutoReleasePoliciesError
+			if errors.As(err, &autoReleaseErr) {
+				logger.Infof("http: undeploy: service '%s' environment '%s': undeploy rejected: auto-release policies: %v", req.Service, req.Environment, err)
+				httpinternal.Error(w, fmt.Sprintf("cannot undeploy service '%s' from environment '%s' as auto-release policies target it: delete policies %s first", req.Service, req.Environment, strings.Join(autoReleaseErr.PolicyIDs, ", ")), http.StatusBadRequest)
+				return
+			}
+			switch errorCause(err) {
+			case flow.ErrReleaseProhibited:
+				logger.Infof("http: undeploy: service '%s' environment '%s': undeploy rejected: branch prohibited in environment: %v", req.Service, req.Environment, err)
+				httpinternal.Error(w, fmt.Sprintf("cannot undeploy service '%s' from environment '%s' due to branch restriction policy", req.Service, req.Environment), http.StatusBadRequest)
+				return
+			case flow.ErrNothingToUndeploy:
+				statusString = fmt.Sprintf("Service '%s' is not released to environment '%s'", req.Service, req.Environment)
+				logger.Infof("http: undeploy: service '%s' environment '%s': undeploy skipped: service not released: %v", req.Service, req.Environment, err)
+			case git.ErrBranchBehindOrigin:
+				logger.Infof("http: undeploy: service '%s' environment '%s': %v", req.Service, req.Environment, err)
+				httpinternal.Error(w, fmt.Sprintf("could not undeploy service '%s' right now. Please try again in a moment.", req.Service), http.StatusServiceUnavailable)
+				return
+			default:
+				logger.Errorf("http: undeploy: service '%s' environment '%s': undeploy failed: %v", req.Service, req.Environment, err)
+				unknownError(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = payload.encodeResponse(ctx, w, httpinternal.UndeployResponse{
+			Service:     req.Service,
+			Environment: req.Environment,
+			Namespace:   req.Namespace,
+			ArtifactID:  artifactID,
+			Status:      statusString,
+		})
+		if err != nil {
+			logger.Errorf("http: undeploy: service '%s' environment '%s': marshal response failed: %v", req.Service, req.Environment, err)
+		}
+	}
+}
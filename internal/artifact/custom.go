@@ -0,0 +1,153 @@
+package artifact
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidCustomStage indicates that a custom stage does not follow the
+// custom stage schema.
+var ErrInvalidCustomStage = errors.New("invalid custom stage")
+
+// maxCustomDataSize is the maximum size in bytes of JSON encoded custom stage
+// data to keep artifact specifications small.
+const maxCustomDataSize = 16 << 10
+
+// customStageIDPattern matches valid custom stage IDs.
+var customStageIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Custom stage data keys with a fixed type. All other keys are free-form.
+const (
+	// CustomDataStatus is the outcome of the stage. It must be one of the
+	// CustomStatus values.
+	CustomDataStatus = "status"
+	// CustomDataURL is an absolute http(s) URL with details of the stage, e.g.
+	// a report.
+	CustomDataURL = "url"
+	// CustomDataSummary is a short human readable summary of the stage.
+	CustomDataSummary = "summary"
+)
+
+// Outcomes of custom stages.
+const (
+	CustomStatusPassed  = "passed"
+	CustomStatusFailed  = "failed"
+	CustomStatusWarning = "warning"
+	CustomStatusSkipped = "skipped"
+)
+
+// CustomData is the data of custom stages, i.e. stages with an ID not known by
+// the release manager. It is a free-form JSON object except for the keys
+// CustomDataStatus, CustomDataURL and CustomDataSummary that must be strings
+// with the documented formats if set.
+type CustomData map[string]interface{}
+
+// IsBuiltinStage reports whether id is a stage known by the release manager.
+func IsBuiltinStage(id StageID) bool {
+	switch id {
+	case StageIDBuild, StageIDTest, StageIDPush, StageIDSnykCode, StageIDSnykDocker, StageIDSBOM, StageIDProvenance:
+		return true
+	}
+	return false
+}
+
+// ValidateCustomStage validates that id and data follow the custom stage
+// schema.
+func ValidateCustomStage(id StageID, data CustomData) error {
+	if IsBuiltinStage(id) {
+		return errors.WithMessagef(ErrInvalidCustomStage, "id '%s' is reserved for a built-in stage", id)
+	}
+	if !customStageIDPattern.MatchString(string(id)) {
+		return errors.WithMessagef(ErrInvalidCustomStage, "id '%s' must be lowercase alphanumeric characters or '-' and at most 63 characters", id)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return errors.WithMessagef(ErrInvalidCustomStage, "data not JSON encodable: %v", err)
+	}
+	if len(encoded) > maxCustomDataSize {
+		return errors.WithMessagef(ErrInvalidCustomStage, "data is %d bytes which exceeds the maximum of %d bytes", len(encoded), maxCustomDataSize)
+	}
+	for key := range data {
+		if key == "" {
+			return errors.WithMessage(ErrInvalidCustomStage, "data contains an empty key")
+		}
+	}
+	status, err := data.stringField(CustomDataStatus)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case "", CustomStatusPassed, CustomStatusFailed, CustomStatusWarning, CustomStatusSkipped:
+	default:
+		return errors.WithMessagef(ErrInvalidCustomStage, "%s '%s' must be one of %s, %s, %s or %s", CustomDataStatus, status, CustomStatusPassed, CustomStatusFailed, CustomStatusWarning, CustomStatusSkipped)
+	}
+	rawURL, err := data.stringField(CustomDataURL)
+	if err != nil {
+		return err
+	}
+	if rawURL != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.WithMessagef(ErrInvalidCustomStage, "%s '%s' must be an absolute http or https URL", CustomDataURL, rawURL)
+		}
+	}
+	_, err = data.stringField(CustomDataSummary)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Status returns the CustomDataStatus of the stage or an empty string if not
+// set.
+func (d CustomData) Status() string {
+	s, _ := d.stringField(CustomDataStatus)
+	return s
+}
+
+// URL returns the CustomDataURL of the stage or an empty string if not set.
+func (d CustomData) URL() string {
+	s, _ := d.stringField(CustomDataURL)
+	return s
+}
+
+// Summary returns the CustomDataSummary of the stage or an empty string if not
+// set.
+func (d CustomData) Summary() string {
+	s, _ := d.stringField(CustomDataSummary)
+	return s
+}
+
+func (d CustomData) stringField(key string) (string, error) {
+	value, ok := d[key]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.WithMessagef(ErrInvalidCustomStage, "%s must be a string: got %s", key, jsonType(value))
+	}
+	return s, nil
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
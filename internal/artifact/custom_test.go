@@ -0,0 +1,89 @@
+package artifact_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCustomStage(t *testing.T) {
+	tt := []struct {
+		name string
+		id   artifact.StageID
+		data artifact.CustomData
+		err  string
+	}{
+		{
+			name: "free-form data",
+			id:   "lint",
+			data: artifact.CustomData{
+				"warnings": 2,
+				"linters":  []interface{}{"vet"},
+			},
+		},
+		{
+			name: "typed keys",
+			id:   "e2e-tests",
+			data: artifact.CustomData{
+				"status":  "failed",
+				"url":     "https://ci.example.com/e2e/1",
+				"summary": "3 of 120 failed",
+			},
+		},
+		{
+			name: "built-in id",
+			id:   artifact.StageIDBuild,
+			err:  "id 'build' is reserved for a built-in stage: invalid custom stage",
+		},
+		{
+			name: "invalid id",
+			id:   "Lint Results",
+			err:  "id 'Lint Results' must be lowercase alphanumeric characters or '-' and at most 63 characters: invalid custom stage",
+		},
+		{
+			name: "unknown status",
+			id:   "lint",
+			data: artifact.CustomData{
+				"status": "ok",
+			},
+			err: "status 'ok' must be one of passed, failed, warning or skipped: invalid custom stage",
+		},
+		{
+			name: "status not a string",
+			id:   "lint",
+			data: artifact.CustomData{
+				"status": true,
+			},
+			err: "status must be a string: got boolean: invalid custom stage",
+		},
+		{
+			name: "relative url",
+			id:   "lint",
+			data: artifact.CustomData{
+				"url": "/reports/1",
+			},
+			err: "url '/reports/1' must be an absolute http or https URL: invalid custom stage",
+		},
+		{
+			name: "too large",
+			id:   "lint",
+			data: artifact.CustomData{
+				"output": strings.Repeat("a", 20000),
+			},
+			err: "data is 20013 bytes which exceeds the maximum of 16384 bytes: invalid custom stage",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := artifact.ValidateCustomStage(tc.id, tc.data)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err, "error not as expected")
+				return
+			}
+			assert.NoError(t, err, "unexpected error")
+		})
+	}
+}
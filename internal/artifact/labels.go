@@ -0,0 +1,24 @@
+package artifact
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseLabels parses a slice of key-value pairs formatted as <key>=<value>. A
+// nil map is returned if list is empty.
+func ParseLabels(list []string) (map[string]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(list))
+	for _, item := range list {
+		s := strings.SplitN(item, "=", 2)
+		if len(s) != 2 || strings.TrimSpace(s[0]) == "" {
+			return nil, errors.Errorf("invalid label '%s': must be formatted as <key>=<value>", item)
+		}
+		labels[strings.TrimSpace(s[0])] = strings.TrimSpace(s[1])
+	}
+	return labels, nil
+}
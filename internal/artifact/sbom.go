@@ -0,0 +1,89 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownSBOMFormat indicates that a document is neither a CycloneDX nor an
+// SPDX JSON document.
+var ErrUnknownSBOMFormat = errors.New("unknown sbom format")
+
+// SummarizeSBOM detects the format of a CycloneDX or SPDX JSON document and
+// returns its format, spec version, number of components and digest.
+func SummarizeSBOM(document []byte) (SBOMData, error) {
+	var doc struct {
+		// CycloneDX fields
+		BOMFormat   string            `json:"bomFormat"`
+		SpecVersion string            `json:"specVersion"`
+		Components  []json.RawMessage `json:"components"`
+		Metadata    struct {
+			Tools json.RawMessage `json:"tools"`
+		} `json:"metadata"`
+		// SPDX fields
+		SPDXVersion  string            `json:"spdxVersion"`
+		Packages     []json.RawMessage `json:"packages"`
+		CreationInfo struct {
+			Creators []string `json:"creators"`
+		} `json:"creationInfo"`
+	}
+	err := json.Unmarshal(document, &doc)
+	if err != nil {
+		return SBOMData{}, errors.WithMessagef(ErrUnknownSBOMFormat, "not a JSON document: %v", err)
+	}
+	sum := sha256.Sum256(document)
+	data := SBOMData{
+		Digest: "sha256:" + hex.EncodeToString(sum[:]),
+	}
+	switch {
+	case doc.BOMFormat == "CycloneDX":
+		data.Format = SBOMFormatCycloneDX
+		data.SpecVersion = doc.SpecVersion
+		data.Components = len(doc.Components)
+		data.Generator = cycloneDXTool(doc.Metadata.Tools)
+	case strings.HasPrefix(doc.SPDXVersion, "SPDX-"):
+		data.Format = SBOMFormatSPDX
+		data.SpecVersion = strings.TrimPrefix(doc.SPDXVersion, "SPDX-")
+		data.Components = len(doc.Packages)
+		for _, creator := range doc.CreationInfo.Creators {
+			if strings.HasPrefix(creator, "Tool:") {
+				data.Generator = strings.TrimSpace(strings.TrimPrefix(creator, "Tool:"))
+				break
+			}
+		}
+	default:
+		return SBOMData{}, ErrUnknownSBOMFormat
+	}
+	return data, nil
+}
+
+// cycloneDXTool returns the name of the first tool in CycloneDX metadata. Tools
+// are a list of tools before spec version 1.5 and an object with a list of
+// components from 1.5.
+func cycloneDXTool(raw json.RawMessage) string {
+	type tool struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	var tools []tool
+	if json.Unmarshal(raw, &tools) != nil {
+		var toolsObject struct {
+			Components []tool `json:"components"`
+		}
+		if json.Unmarshal(raw, &toolsObject) != nil {
+			return ""
+		}
+		tools = toolsObject.Components
+	}
+	if len(tools) == 0 {
+		return ""
+	}
+	if tools[0].Version == "" {
+		return tools[0].Name
+	}
+	return tools[0].Name + " " + tools[0].Version
+}
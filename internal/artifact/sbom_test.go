@@ -0,0 +1,87 @@
+package artifact_test
+
+import (
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeSBOM(t *testing.T) {
+	tt := []struct {
+		name     string
+		document string
+		data     artifact.SBOMData
+		err      error
+	}{
+		{
+			name: "CycloneDX with tool list",
+			document: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.4",
+				"metadata": {"tools": [{"name": "syft", "version": "0.90.0"}]},
+				"components": [{"name": "a"}, {"name": "b"}]
+			}`,
+			data: artifact.SBOMData{
+				Format:      artifact.SBOMFormatCycloneDX,
+				SpecVersion: "1.4",
+				Generator:   "syft 0.90.0",
+				Components:  2,
+			},
+		},
+		{
+			name: "CycloneDX with tool components",
+			document: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"metadata": {"tools": {"components": [{"name": "cdxgen"}]}},
+				"components": [{"name": "a"}]
+			}`,
+			data: artifact.SBOMData{
+				Format:      artifact.SBOMFormatCycloneDX,
+				SpecVersion: "1.5",
+				Generator:   "cdxgen",
+				Components:  1,
+			},
+		},
+		{
+			name: "SPDX",
+			document: `{
+				"spdxVersion": "SPDX-2.3",
+				"creationInfo": {"creators": ["Organization: lunar", "Tool: trivy-0.45.0"]},
+				"packages": [{"name": "a"}, {"name": "b"}, {"name": "c"}]
+			}`,
+			data: artifact.SBOMData{
+				Format:      artifact.SBOMFormatSPDX,
+				SpecVersion: "2.3",
+				Generator:   "trivy-0.45.0",
+				Components:  3,
+			},
+		},
+		{
+			name:     "unknown JSON document",
+			document: `{"name": "not an sbom"}`,
+			err:      artifact.ErrUnknownSBOMFormat,
+		},
+		{
+			name:     "not JSON",
+			document: `<bom/>`,
+			err:      artifact.ErrUnknownSBOMFormat,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := artifact.SummarizeSBOM([]byte(tc.document))
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err, "error not as expected")
+				return
+			}
+			require.NoError(t, err, "unexpected error")
+			assert.Regexp(t, "^sha256:[0-9a-f]{64}$", data.Digest, "digest not as expected")
+			data.Digest = ""
+			assert.Equal(t, tc.data, data, "data not as expected")
+		})
+	}
+}
@@ -30,6 +30,9 @@ type Spec struct {
 	Squad       string     `json:"squad,omitempty"`
 	Shuttle     Shuttle    `json:"shuttle,omitempty"`
 	Stages      []Stage    `json:"stages,omitempty"`
+	// Labels are free-form key-value pairs used to find artifacts, e.g. the
+	// ticket or team an artifact belongs to.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 type Repository struct {
@@ -64,6 +67,8 @@ const (
 	StageIDPush       StageID = "push"
 	StageIDSnykCode   StageID = "snyk-code"
 	StageIDSnykDocker StageID = "snyk-docker"
+	StageIDSBOM       StageID = "sbom"
+	StageIDProvenance StageID = "provenance"
 )
 
 type Stage struct {
@@ -73,7 +78,8 @@ type Stage struct {
 }
 
 // UnmarshalJSON implements a custom JSON unmarshal method that sets the
-// concrete Data types for each stage type.
+// concrete Data types for each stage type. Data of custom stages is set as
+// CustomData.
 func (s *Stage) UnmarshalJSON(data []byte) error {
 	type genericStage struct {
 		ID   StageID         `json:"id,omitempty"`
@@ -110,6 +116,23 @@ func (s *Stage) UnmarshalJSON(data []byte) error {
 		data := SnykDockerData{}
 		err = json.Unmarshal(gStage.Data, &data)
 		s.Data = data
+	case StageIDSBOM:
+		data := SBOMData{}
+		err = json.Unmarshal(gStage.Data, &data)
+		s.Data = data
+	case StageIDProvenance:
+		data := ProvenanceData{}
+		err = json.Unmarshal(gStage.Data, &data)
+		s.Data = data
+	default:
+		// custom stages keep their data as is to be preserved when the spec is
+		// encoded again
+		if len(gStage.Data) == 0 {
+			break
+		}
+		data := CustomData{}
+		err = json.Unmarshal(gStage.Data, &data)
+		s.Data = data
 	}
 	if err != nil {
 		return err
@@ -127,6 +150,9 @@ type PushData struct {
 	Image         string `json:"image,omitempty"`
 	Tag           string `json:"tag,omitempty"`
 	DockerVersion string `json:"dockerVersion,omitempty"`
+	// Digest is the digest of the pushed image manifest in the format
+	// <algorithm>:<hex>, e.g. sha256:0017d995e3.
+	Digest string `json:"digest,omitempty"`
 }
 
 type TestData struct {
@@ -155,6 +181,49 @@ type SnykCodeData struct {
 	Vulnerabilities VulnerabilityResult `json:"vulnerabilities,omitempty"`
 }
 
+// SBOM document formats.
+const (
+	SBOMFormatCycloneDX = "cyclonedx"
+	SBOMFormatSPDX      = "spdx"
+)
+
+// SBOMData references a software bill of materials document of the artifact.
+type SBOMData struct {
+	// Format is the document format. Either SBOMFormatCycloneDX or
+	// SBOMFormatSPDX.
+	Format      string `json:"format,omitempty"`
+	SpecVersion string `json:"specVersion,omitempty"`
+	// URL is where the document is stored.
+	URL string `json:"url,omitempty"`
+	// Digest is the digest of the document in the format sha256:<hex>.
+	Digest     string `json:"digest,omitempty"`
+	Generator  string `json:"generator,omitempty"`
+	Components int    `json:"components"`
+}
+
+// ProvenanceData describes how the artifact was built following the SLSA
+// provenance model.
+type ProvenanceData struct {
+	// BuilderID identifies the build platform, e.g. a CI system URL.
+	BuilderID string `json:"builderId,omitempty"`
+	// BuildType is a URI describing how the build was run.
+	BuildType string `json:"buildType,omitempty"`
+	// InvocationURL links to the build run, e.g. a CI job.
+	InvocationURL string `json:"invocationUrl,omitempty"`
+	// Source is the source repository and revision the artifact is built from.
+	Source     ProvenanceMaterial   `json:"source,omitempty"`
+	Materials  []ProvenanceMaterial `json:"materials,omitempty"`
+	StartedOn  time.Time            `json:"startedOn,omitempty"`
+	FinishedOn time.Time            `json:"finishedOn,omitempty"`
+}
+
+// ProvenanceMaterial is an input to a build identified by URI and digest.
+type ProvenanceMaterial struct {
+	URI string `json:"uri,omitempty"`
+	// Digest is in the format <algorithm>:<hex>, e.g. sha1:0017d995e3.
+	Digest string `json:"digest,omitempty"`
+}
+
 type VulnerabilityResult struct {
 	High   int `json:"high"`
 	Medium int `json:"medium"`
@@ -103,6 +103,114 @@ func TestDecode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with custom stage",
+			input: `
+			{
+				"id": "stages",
+				"stages": [
+					{
+						"id": "lint",
+						"name": "Lint",
+						"data": {
+							"status": "warning",
+							"warnings": 2,
+							"linters": ["vet", "staticcheck"]
+						}
+					},
+					{
+						"id": "e2e"
+					}
+				]
+			}
+`,
+			output: artifact.Spec{
+				ID: "stages",
+				Stages: []artifact.Stage{
+					{
+						ID:   "lint",
+						Name: "Lint",
+						Data: artifact.CustomData{
+							"status":   "warning",
+							"warnings": float64(2),
+							"linters":  []interface{}{"vet", "staticcheck"},
+						},
+					},
+					{
+						ID: "e2e",
+					},
+				},
+			},
+		},
+		{
+			name: "with sbom and provenance stages",
+			input: `
+			{
+				"id": "supply-chain",
+				"stages": [
+					{
+						"id": "sbom",
+						"name": "SBOM",
+						"data": {
+							"format": "cyclonedx",
+							"specVersion": "1.5",
+							"url": "https://sbom.example.com/product.json",
+							"components": 42
+						}
+					},
+					{
+						"id": "provenance",
+						"name": "Provenance",
+						"data": {
+							"builderId": "https://ci.example.com",
+							"source": {
+								"uri": "https://github.com/lunarway/product",
+								"digest": "sha1:1234"
+							},
+							"materials": [
+								{
+									"uri": "golang:1.21",
+									"digest": "sha256:abcd"
+								}
+							]
+						}
+					}
+				]
+			}
+`,
+			output: artifact.Spec{
+				ID: "supply-chain",
+				Stages: []artifact.Stage{
+					{
+						ID:   "sbom",
+						Name: "SBOM",
+						Data: artifact.SBOMData{
+							Format:      artifact.SBOMFormatCycloneDX,
+							SpecVersion: "1.5",
+							URL:         "https://sbom.example.com/product.json",
+							Components:  42,
+						},
+					},
+					{
+						ID:   "provenance",
+						Name: "Provenance",
+						Data: artifact.ProvenanceData{
+							BuilderID: "https://ci.example.com",
+							Source: artifact.ProvenanceMaterial{
+								URI:    "https://github.com/lunarway/product",
+								Digest: "sha1:1234",
+							},
+							Materials: []artifact.ProvenanceMaterial{
+								{
+									URI:    "golang:1.21",
+									Digest: "sha256:abcd",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -113,3 +221,14 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestEncode_customStageRoundTrip(t *testing.T) {
+	input := `{"id":"stages","application":{},"ci":{"start":"0001-01-01T00:00:00Z","end":"0001-01-01T00:00:00Z"},"shuttle":{"plan":{}},"stages":[{"id":"lint","name":"Lint","data":{"nested":{"key":"value"},"status":"passed"}}]}`
+	spec, err := artifact.Decode(strings.NewReader(input))
+	require.NoError(t, err, "decode error")
+
+	output, err := artifact.Encode(spec, false)
+
+	require.NoError(t, err, "encode error")
+	assert.JSONEq(t, input, output, "encoded spec not as expected")
+}
@@ -0,0 +1,205 @@
+// Package artifactindex implements a searchable index of artifact
+// specifications.
+package artifactindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/jsonfile"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// Index implements flow.ArtifactIndex. Artifacts are kept in memory and, if a
+// directory is configured, persisted as one JSON file per artifact in a
+// directory per service so they survive restarts.
+type Index struct {
+	dir       string
+	retention time.Duration
+
+	mu        sync.RWMutex
+	artifacts map[string]artifact.Spec
+	prunedAt  time.Time
+}
+
+// pruneInterval is the minimum duration between removing expired artifacts of
+// a running Index.
+const pruneInterval = time.Hour
+
+var _ flow.ArtifactIndex = &Index{}
+
+// New allocates an Index persisting artifacts in dir. If dir is empty artifacts
+// are only kept in memory. Existing artifacts in dir are loaded and those built
+// before retention are removed. A retention of zero keeps all artifacts.
+func New(dir string, retention time.Duration) (*Index, error) {
+	i := &Index{
+		dir:       dir,
+		retention: retention,
+		artifacts: make(map[string]artifact.Spec),
+	}
+	if dir == "" {
+		return i, nil
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create directory '%s'", dir)
+	}
+	now := time.Now()
+	err = i.load(now)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load artifacts from '%s'", dir)
+	}
+	i.prunedAt = now
+	return i, nil
+}
+
+func (i *Index) load(now time.Time) error {
+	files, err := filepath.Glob(filepath.Join(i.dir, "*", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return errors.WithMessagef(err, "read '%s'", file)
+		}
+		var spec artifact.Spec
+		err = json.Unmarshal(content, &spec)
+		if err != nil {
+			log.Errorf("artifactindex: skipping unparsable artifact file '%s': %v", file, err)
+			continue
+		}
+		if i.expired(spec, now) {
+			err := os.Remove(file)
+			if err != nil {
+				log.Errorf("artifactindex: remove expired artifact file '%s' failed: %v", file, err)
+			}
+			continue
+		}
+		i.artifacts[key(spec)] = spec
+	}
+	log.Infof("artifactindex: loaded %d artifacts from '%s'", len(i.artifacts), i.dir)
+	return nil
+}
+
+func (i *Index) expired(spec artifact.Spec, now time.Time) bool {
+	if i.retention <= 0 {
+		return false
+	}
+	return buildTime(spec).Add(i.retention).Before(now)
+}
+
+func (i *Index) Index(ctx context.Context, spec artifact.Spec) error {
+	if spec.Service == "" || spec.ID == "" {
+		return errors.New("artifact service and id required")
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.prune(time.Now())
+	if i.dir != "" {
+		err := i.persist(spec)
+		if err != nil {
+			return errors.WithMessagef(err, "persist artifact '%s'", spec.ID)
+		}
+	}
+	i.artifacts[key(spec)] = spec
+	return nil
+}
+
+func (i *Index) Search(ctx context.Context, query flow.ArtifactQuery) ([]artifact.Spec, error) {
+	i.mu.RLock()
+	var matches []artifact.Spec
+	for _, spec := range i.artifacts {
+		if query.Matches(spec) {
+			matches = append(matches, spec)
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(matches, func(a, b int) bool {
+		ta, tb := buildTime(matches[a]), buildTime(matches[b])
+		if ta.Equal(tb) {
+			return key(matches[a]) < key(matches[b])
+		}
+		return ta.After(tb)
+	})
+	if query.Limit > 0 && len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+	return matches, nil
+}
+
+// persist writes spec to its file in the directory of its service. The caller
+// must hold the write lock.
+func (i *Index) persist(spec artifact.Spec) error {
+	path, err := i.path(spec)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return errors.WithMessage(err, "create service directory")
+	}
+	return jsonfile.Write(path, spec)
+}
+
+func (i *Index) path(spec artifact.Spec) (string, error) {
+	serviceDir, err := securejoin.SecureJoin(i.dir, strings.ToLower(spec.Service))
+	if err != nil {
+		return "", errors.WithMessage(err, "join service path")
+	}
+	path, err := securejoin.SecureJoin(serviceDir, fmt.Sprintf("%s.json", strings.ToLower(spec.ID)))
+	if err != nil {
+		return "", errors.WithMessage(err, "join artifact path")
+	}
+	return path, nil
+}
+
+// prune removes artifacts built before retention. It runs at most once per
+// pruneInterval to keep indexing cheap. The caller must hold the write lock.
+func (i *Index) prune(now time.Time) {
+	if i.retention <= 0 || now.Sub(i.prunedAt) < pruneInterval {
+		return
+	}
+	i.prunedAt = now
+	for k, spec := range i.artifacts {
+		if !i.expired(spec, now) {
+			continue
+		}
+		if i.dir != "" {
+			path, err := i.path(spec)
+			if err == nil {
+				err = os.Remove(path)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				log.Errorf("artifactindex: remove expired artifact '%s' failed: %v", k, err)
+				continue
+			}
+		}
+		delete(i.artifacts, k)
+	}
+}
+
+func key(spec artifact.Spec) string {
+	return strings.ToLower(spec.Service + "/" + spec.ID)
+}
+
+// buildTime returns when the artifact was built falling back to when the build
+// started if the artifact was never completed.
+func buildTime(spec artifact.Spec) time.Time {
+	if !spec.CI.End.IsZero() {
+		return spec.CI.End
+	}
+	return spec.CI.Start
+}
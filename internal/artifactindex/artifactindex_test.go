@@ -0,0 +1,147 @@
+package artifactindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&log.Configuration{
+		Level:       log.Level{Level: zapcore.ErrorLevel},
+		Development: false,
+	})
+	os.Exit(m.Run())
+}
+
+func newSpec(service, id, sha, author string, labels map[string]string, end time.Time) artifact.Spec {
+	return artifact.Spec{
+		ID:      id,
+		Service: service,
+		Application: artifact.Repository{
+			SHA:         sha,
+			AuthorName:  author,
+			AuthorEmail: author + "@example.com",
+		},
+		CI: artifact.CI{
+			End: end,
+		},
+		Labels: labels,
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	index, err := New("", 0)
+	require.NoError(t, err)
+	older := newSpec("product", "master-abc1234-1", "abc1234def", "Alice", map[string]string{"ticket": "ABC-1"}, now.Add(-time.Hour))
+	newer := newSpec("api", "master-abc1234-2", "abc1234def", "Bob", map[string]string{"ticket": "ABC-1", "team": "core"}, now)
+	other := newSpec("product", "master-ffff000-3", "ffff000111", "Alice", nil, now.Add(-2*time.Hour))
+	for _, spec := range []artifact.Spec{older, newer, other} {
+		require.NoError(t, index.Index(ctx, spec))
+	}
+
+	tt := []struct {
+		name   string
+		query  flow.ArtifactQuery
+		result []artifact.Spec
+	}{
+		{
+			name:   "sha prefix sorted by newest",
+			query:  flow.ArtifactQuery{SHA: "ABC123"},
+			result: []artifact.Spec{newer, older},
+		},
+		{
+			name:   "author name or email",
+			query:  flow.ArtifactQuery{Author: "alice@"},
+			result: []artifact.Spec{older, other},
+		},
+		{
+			name:   "all labels",
+			query:  flow.ArtifactQuery{Labels: map[string]string{"ticket": "ABC-1", "team": "core"}},
+			result: []artifact.Spec{newer},
+		},
+		{
+			name:   "service and limit",
+			query:  flow.ArtifactQuery{Service: "product", Limit: 1},
+			result: []artifact.Spec{older},
+		},
+		{
+			name:   "no matches",
+			query:  flow.ArtifactQuery{SHA: "0000"},
+			result: nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := index.Search(ctx, tc.query)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+}
+
+func TestIndex_persistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+	spec := newSpec("product", "master-abc1234-1", "abc1234def", "Alice", map[string]string{"ticket": "ABC-1"}, now)
+
+	index, err := New(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, index.Index(ctx, spec))
+
+	restarted, err := New(dir, 0)
+	require.NoError(t, err)
+	result, err := restarted.Search(ctx, flow.ArtifactQuery{SHA: "abc1234"})
+	require.NoError(t, err)
+	assert.Equal(t, []artifact.Spec{spec}, result)
+}
+
+func TestIndex_retention(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	index, err := New(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, index.Index(ctx, newSpec("product", "old", "1", "Alice", nil, now.Add(-48*time.Hour))))
+	require.NoError(t, index.Index(ctx, newSpec("product", "new", "2", "Alice", nil, now)))
+
+	restarted, err := New(dir, 24*time.Hour)
+	require.NoError(t, err)
+	result, err := restarted.Search(ctx, flow.ArtifactQuery{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "new", result[0].ID)
+}
+
+func TestIndex_retentionWhileRunning(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	index, err := New(dir, 24*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, index.Index(ctx, newSpec("product", "old", "1", "Alice", nil, now.Add(-48*time.Hour))))
+	// pretend the last pruning was long ago
+	index.prunedAt = now.Add(-2 * pruneInterval)
+	require.NoError(t, index.Index(ctx, newSpec("product", "new", "2", "Alice", nil, now)))
+
+	result, err := index.Search(ctx, flow.ArtifactQuery{})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, "new", result[0].ID)
+	assert.NoFileExists(t, filepath.Join(dir, "product", "old.json"))
+}
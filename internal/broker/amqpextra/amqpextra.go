@@ -25,6 +25,7 @@ func New(c Config) (*Worker, error) {
 		"exchange", c.Exchange,
 		"queue", c.Queue,
 		"prefetch", c.Prefetch,
+		"partitions", c.Partitions,
 		"reconnectionTimeout", c.ReconnectionTimeout,
 	)
 
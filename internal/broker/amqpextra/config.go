@@ -9,12 +9,18 @@ import (
 
 // Config configures a worker.
 type Config struct {
-	Connection          ConnectionConfig
-	Exchange            string
-	Queue               string
-	RoutingKey          string
-	Prefetch            int
-	WorkerCount         int
+	Connection  ConnectionConfig
+	Exchange    string
+	Queue       string
+	RoutingKey  string
+	Prefetch    int
+	WorkerCount int
+	// Partitions is the number of queues messages are partitioned into by
+	// broker.Partition. Each partition queue is consumed by a single worker so
+	// messages with the same partition key are handled in order. Partitioned
+	// messages are published to the exchange "<Exchange>.partitioned". Values
+	// below 2 consume all messages from Queue.
+	Partitions          int
 	ReconnectionTimeout time.Duration
 	ConnectionTimeout   time.Duration
 	InitTimeout         time.Duration
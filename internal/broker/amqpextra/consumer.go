@@ -2,6 +2,7 @@ package amqpextra
 
 import (
 	"context"
+	"fmt"
 
 	internalamqp "github.com/lunarway/release-manager/internal/amqp"
 	"github.com/lunarway/release-manager/internal/broker"
@@ -15,30 +16,82 @@ import (
 //
 // The workers configured queue is declared on startup along with a binding to
 // the exchange with routing key.
-func (w *Worker) StartConsumer(handlers map[string]func([]byte) error, eventDropped func(msgType string, msgBody []byte, err error)) error {
+func (w *Worker) StartConsumer(handlers map[string]func([]byte) error, eventDropped func(msgType string, msgBody []byte, err error) error) error {
 	m := &mux{
 		handlers:     handlers,
 		log:          w.config.Logger,
 		eventDropped: eventDropped,
 	}
 
+	handle := func(message *amqp.Delivery) error {
+		return m.ServeMsg(context.Background(), *message)
+	}
 	consumer := internalamqp.ConsumerConfig{
 		Exchange:        w.config.Exchange,
 		Queue:           w.config.Queue,
 		DurableQueue:    true,
 		RoutingPatterns: []string{w.config.RoutingKey},
 		Prefetch:        w.config.Prefetch,
-		Handle: func(message *amqp.Delivery) error {
-			return m.ServeMsg(context.Background(), *message)
-		},
-		WorkerCount: w.config.WorkerCount,
+		Handle:          handle,
+		WorkerCount:     w.config.WorkerCount,
+	}
+	consumers := []internalamqp.ConsumerConfig{consumer}
+	if w.config.Partitions > 1 {
+		consumers = w.partitionConsumers(handle)
 	}
 
 	consumersStarted := make(chan struct{})
-	err := w.worker.StartConsumer([]internalamqp.ConsumerConfig{consumer}, consumersStarted)
+	err := w.worker.StartConsumer(consumers, consumersStarted)
 	if err != nil {
 		return err
 	}
 
 	return broker.ErrBrokerClosed
 }
+
+// partitionConsumers returns consumers of each partition queue. Each partition
+// is consumed by a single worker with a prefetch of 1 to handle its messages in
+// order. A larger prefetch would let a requeued message be redelivered after
+// the messages prefetched behind it.
+//
+// Partition queues are bound to their own exchange so unpartitioned workers,
+// e.g. during a rolling deploy, never receive partitioned messages and the
+// other way around. The queue of unpartitioned workers is still consumed to
+// handle messages published by them.
+func (w *Worker) partitionConsumers(handle func(*amqp.Delivery) error) []internalamqp.ConsumerConfig {
+	consumers := []internalamqp.ConsumerConfig{
+		{
+			Exchange:        w.config.Exchange,
+			Queue:           w.config.Queue,
+			DurableQueue:    true,
+			RoutingPatterns: []string{w.config.RoutingKey},
+			Prefetch:        w.config.Prefetch,
+			Handle:          handle,
+			WorkerCount:     w.config.WorkerCount,
+		},
+	}
+	for i := 0; i < w.config.Partitions; i++ {
+		consumers = append(consumers, internalamqp.ConsumerConfig{
+			Exchange:        partitionExchange(w.config.Exchange),
+			Queue:           fmt.Sprintf("%s.%d", w.config.Queue, i),
+			DurableQueue:    true,
+			RoutingPatterns: []string{fmt.Sprintf("%s.#", partitionRoutingPrefix(i))},
+			Prefetch:        1,
+			Handle:          handle,
+			WorkerCount:     1,
+		})
+	}
+	return consumers
+}
+
+// partitionExchange returns the exchange partitioned messages are published
+// to.
+func partitionExchange(exchange string) string {
+	return fmt.Sprintf("%s.partitioned", exchange)
+}
+
+// partitionRoutingPrefix returns the routing key prefix of messages in
+// partition.
+func partitionRoutingPrefix(partition int) string {
+	return fmt.Sprintf("partition.%d", partition)
+}
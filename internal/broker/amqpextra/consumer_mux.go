@@ -14,7 +14,7 @@ import (
 type mux struct {
 	handlers     map[string]func([]byte) error
 	log          *log.Logger
-	eventDropped func(msgType string, msgBody []byte, err error)
+	eventDropped func(msgType string, msgBody []byte, err error) error
 }
 
 func (m mux) ServeMsg(ctx context.Context, msg amqp.Delivery) error {
@@ -27,7 +27,10 @@ func (m mux) ServeMsg(ctx context.Context, msg amqp.Delivery) error {
 	err := handler(msg.Body)
 	if err != nil {
 		if msg.Redelivered {
-			m.eventDropped(msg.Type, msg.Body, err)
+			dropErr := m.eventDropped(msg.Type, msg.Body, err)
+			if dropErr != nil {
+				m.log.Errorf("hand off of dropped event '%s' failed: %v", msg.Type, dropErr)
+			}
 			m.nack(msg, false, "nack without requeue due redelivery failed")
 			return errors.WithMessage(err, "messaged dropped")
 		}
@@ -19,8 +19,8 @@ func (w *Worker) Publish(ctx context.Context, message broker.Publishable) error
 	correlationID := tracing.RequestIDFromContext(ctx)
 
 	err := w.worker.Publish(ctx, amqp.PublishDto{
-		Exchange:      w.config.Exchange,
-		RoutingKey:    fmt.Sprintf("%s.%s", w.config.RoutingKey, message.Type()),
+		Exchange:      w.exchange(),
+		RoutingKey:    w.routingKey(message),
 		MessageType:   message.Type(),
 		CorrelationID: correlationID,
 		Message:       message,
@@ -30,3 +30,21 @@ func (w *Worker) Publish(ctx context.Context, message broker.Publishable) error
 	}
 	return nil
 }
+
+// exchange returns the exchange to publish messages to. Partitioned messages
+// are published to their own exchange.
+func (w *Worker) exchange() string {
+	if w.config.Partitions > 1 {
+		return partitionExchange(w.config.Exchange)
+	}
+	return w.config.Exchange
+}
+
+// routingKey returns the routing key of message. Messages are routed to their
+// partition when the worker is partitioned.
+func (w *Worker) routingKey(message broker.Publishable) string {
+	if w.config.Partitions > 1 {
+		return fmt.Sprintf("%s.%s", partitionRoutingPrefix(broker.Partition(message, w.config.Partitions)), message.Type())
+	}
+	return fmt.Sprintf("%s.%s", w.config.RoutingKey, message.Type())
+}
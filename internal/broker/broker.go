@@ -3,6 +3,7 @@ package broker
 import (
 	"context"
 	"errors"
+	"hash/fnv"
 )
 
 // Broker is capable of publishing and consuming Publishable messages.
@@ -11,7 +12,11 @@ type Broker interface {
 	Publish(ctx context.Context, message Publishable) error
 	// StartConsumer consumes messages on a broker. This method is blocking and
 	// will always return with ErrBrokerClosed after calls to Close.
-	StartConsumer(handlers map[string]func([]byte) error, errorHandler func(msgType string, msgBody []byte, err error)) error
+	//
+	// Messages that cannot be handled are passed to errorHandler. It returns nil
+	// if the message was handed off, e.g. stored as a dead letter, and an error
+	// if not, in which case brokers able to keep the message do so.
+	StartConsumer(handlers map[string]func([]byte) error, errorHandler func(msgType string, msgBody []byte, err error) error) error
 	// Close closes the broker.
 	Close() error
 }
@@ -24,5 +29,59 @@ type Publishable interface {
 	Unmarshal([]byte) error
 }
 
+// Partitioned is implemented by Publishable messages that must be consumed in
+// order with other messages of the same partition key. Brokers consuming
+// messages in parallel only handle messages with the same key one at a time.
+type Partitioned interface {
+	PartitionKey() string
+}
+
+// Partition returns the partition in [0, partitions) of message. Messages with
+// the same partition key are always assigned the same partition. Messages not
+// implementing Partitioned are partitioned by their type.
+func Partition(message Publishable, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+	key := message.Type()
+	partitioned, ok := message.(Partitioned)
+	if ok {
+		key = partitioned.PartitionKey()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(partitions))
+}
+
+// RawMessage is a Publishable message of an already marshalled body, e.g. a
+// message read back from storage. Key is the partition key of the message and
+// defaults to its type if empty.
+type RawMessage struct {
+	MessageType string
+	Key         string
+	Body        []byte
+}
+
+func (m *RawMessage) Type() string {
+	return m.MessageType
+}
+
+// PartitionKey implements Partitioned.
+func (m *RawMessage) PartitionKey() string {
+	if m.Key == "" {
+		return m.MessageType
+	}
+	return m.Key
+}
+
+func (m *RawMessage) Marshal() ([]byte, error) {
+	return m.Body, nil
+}
+
+func (m *RawMessage) Unmarshal(body []byte) error {
+	m.Body = body
+	return nil
+}
+
 // ErrBrokerClosed indicates that the broker was closed by a call to Close.
 var ErrBrokerClosed = errors.New("broker: broker closed")
@@ -3,34 +3,89 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/lunarway/release-manager/internal/broker"
 	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
 )
 
 type Broker struct {
 	logger *log.Logger
-	queue  chan broker.Publishable
+	// partitions are the queues of each partition. Messages are assigned a
+	// partition with broker.Partition and each partition is consumed by its own
+	// worker.
+	partitions []chan queuedMessage
+	// outbox persists published messages until they are handled. May be nil in
+	// which case queued messages are lost on restarts.
+	outbox *Outbox
+}
+
+// queuedMessage is a message in the queue along with its ID in the outbox.
+type queuedMessage struct {
+	broker.Publishable
+	outboxID uint64
 }
 
 // New allocates and returns an in-memory Broker with provided queue size.
 func New(logger *log.Logger, queueSize int) *Broker {
-	return &Broker{
+	return NewWithOutbox(logger, queueSize, nil)
+}
+
+// NewWithOutbox allocates and returns an in-memory Broker with provided queue
+// size persisting published messages in outbox until they are handled.
+// Messages in the outbox not handled before are consumed before any new
+// messages. The outbox is closed when the Broker is closed.
+func NewWithOutbox(logger *log.Logger, queueSize int, outbox *Outbox) *Broker {
+	return NewPartitioned(logger, queueSize, 1, outbox)
+}
+
+// NewPartitioned allocates and returns an in-memory Broker consuming messages
+// of the provided number of partitions in parallel. Messages with the same
+// partition key are consumed in the order they are published. Each partition
+// has its own queue of provided size. outbox may be nil.
+func NewPartitioned(logger *log.Logger, queueSize, partitions int, outbox *Outbox) *Broker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	b := &Broker{
 		logger: logger,
-		queue:  make(chan broker.Publishable, queueSize),
+		outbox: outbox,
+	}
+	for i := 0; i < partitions; i++ {
+		b.partitions = append(b.partitions, make(chan queuedMessage, queueSize))
 	}
+	return b
 }
 
 func (b *Broker) Close() error {
-	close(b.queue)
+	for _, queue := range b.partitions {
+		close(queue)
+	}
+	if b.outbox != nil {
+		return b.outbox.Close()
+	}
 	return nil
 }
 
 func (b *Broker) Publish(ctx context.Context, event broker.Publishable) error {
 	b.logger.WithFields("message", event).Info("Publishing message")
 	now := time.Now()
-	b.queue <- event
+	msg := queuedMessage{
+		Publishable: event,
+	}
+	if b.outbox != nil {
+		body, err := event.Marshal()
+		if err != nil {
+			return errors.WithMessage(err, "marshal message")
+		}
+		msg.outboxID, err = b.outbox.Append(event.Type(), body)
+		if err != nil {
+			return errors.WithMessage(err, "append message to outbox")
+		}
+	}
+	b.partitions[broker.Partition(event, len(b.partitions))] <- msg
 	duration := time.Since(now).Milliseconds()
 	b.logger.With(
 		"eventType", event.Type(),
@@ -41,41 +96,89 @@ func (b *Broker) Publish(ctx context.Context, event broker.Publishable) error {
 	return nil
 }
 
-func (b *Broker) StartConsumer(handlers map[string]func([]byte) error, errorHandler func(msgType string, msgBody []byte, err error)) error {
-	for msg := range b.queue {
-		logger := b.logger.With(
-			"eventType", msg.Type(),
-		)
-		logger.WithFields("message", msg).Infof("Received message type=%s", msg.Type())
-		handler, ok := handlers[msg.Type()]
-		if !ok {
-			logger.With("res", map[string]interface{}{
-				"status": "failed",
-				"error":  "unprocessable",
-			}).Errorf("[consumer] [UNPROCESSABLE] Failed to handle message: no handler registered for event type '%s': dropping it", msg.Type)
-			continue
-		}
-		body, err := msg.Marshal()
-		if err != nil {
-			logger.Errorf("[consumer] [UNPROCESSABLE] Could not get body of message: %v", err)
-			continue
-		}
-		now := time.Now()
-		err = handler(body)
-		duration := time.Since(now).Milliseconds()
-		if err != nil {
-			logger.With("res", map[string]interface{}{
-				"status":       "failed",
-				"responseTime": duration,
-				"error":        fmt.Sprintf("%+v", err),
-			}).Errorf("[consumer] [FAILED] Failed to handle message: trigger error handling and acking: %v", err)
-			errorHandler(msg.Type(), body, err)
-			continue
+func (b *Broker) StartConsumer(handlers map[string]func([]byte) error, errorHandler func(msgType string, msgBody []byte, err error) error) error {
+	if b.outbox != nil {
+		for _, msg := range b.outbox.pending {
+			msg := msg
+			b.logger.Infof("Replaying message %d of type '%s' from outbox", msg.id, msg.msgType)
+			b.handle(queuedMessage{Publishable: &msg, outboxID: msg.id}, handlers, errorHandler)
 		}
+		b.outbox.pending = nil
+	}
+	var wg sync.WaitGroup
+	for _, queue := range b.partitions {
+		wg.Add(1)
+		go func(queue chan queuedMessage) {
+			defer wg.Done()
+			for msg := range queue {
+				b.handle(msg, handlers, errorHandler)
+			}
+		}(queue)
+	}
+	wg.Wait()
+	return broker.ErrBrokerClosed
+}
+
+// handle passes msg to its handler and acknowledges it in the outbox. Failed
+// messages are passed to errorHandler and only acknowledged if it handed them
+// off. Otherwise they are kept in the outbox and replayed on restart.
+func (b *Broker) handle(msg queuedMessage, handlers map[string]func([]byte) error, errorHandler func(msgType string, msgBody []byte, err error) error) {
+	logger := b.logger.With(
+		"eventType", msg.Type(),
+	)
+	logger.WithFields("message", msg.Publishable).Infof("Received message type=%s", msg.Type())
+	body, err := msg.Marshal()
+	if err != nil {
+		// the message can never be handled so it is dropped
+		logger.Errorf("[consumer] [UNPROCESSABLE] Could not get body of message: %v", err)
+		b.ack(logger, msg)
+		return
+	}
+	handler, ok := handlers[msg.Type()]
+	if !ok {
 		logger.With("res", map[string]interface{}{
-			"status":       "ok",
+			"status": "failed",
+			"error":  "unprocessable",
+		}).Errorf("[consumer] [UNPROCESSABLE] Failed to handle message: no handler registered for event type '%s': trigger error handling", msg.Type())
+		b.handOff(logger, msg, body, errors.Errorf("no handler registered for event type '%s'", msg.Type()), errorHandler)
+		return
+	}
+	now := time.Now()
+	err = handler(body)
+	duration := time.Since(now).Milliseconds()
+	if err != nil {
+		logger.With("res", map[string]interface{}{
+			"status":       "failed",
 			"responseTime": duration,
-		}).Info("[OK] Event handled successfully")
+			"error":        fmt.Sprintf("%+v", err),
+		}).Errorf("[consumer] [FAILED] Failed to handle message: trigger error handling: %v", err)
+		b.handOff(logger, msg, body, err, errorHandler)
+		return
+	}
+	logger.With("res", map[string]interface{}{
+		"status":       "ok",
+		"responseTime": duration,
+	}).Info("[OK] Event handled successfully")
+	b.ack(logger, msg)
+}
+
+// handOff passes the failed msg to errorHandler and acknowledges it if it was
+// handed off.
+func (b *Broker) handOff(logger *log.Logger, msg queuedMessage, body []byte, err error, errorHandler func(msgType string, msgBody []byte, err error) error) {
+	handOffErr := errorHandler(msg.Type(), body, err)
+	if handOffErr != nil {
+		logger.Errorf("[consumer] Failed to hand off message %d: it is kept in the outbox and replayed on restart: %v", msg.outboxID, handOffErr)
+		return
+	}
+	b.ack(logger, msg)
+}
+
+func (b *Broker) ack(logger *log.Logger, msg queuedMessage) {
+	if b.outbox == nil {
+		return
+	}
+	err := b.outbox.Ack(msg.outboxID)
+	if err != nil {
+		logger.Errorf("[consumer] Failed to acknowledge message %d in outbox: it will be replayed on restart: %v", msg.outboxID, err)
 	}
-	return broker.ErrBrokerClosed
 }
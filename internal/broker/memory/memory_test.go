@@ -67,7 +67,7 @@ func TestBroker_PublishAndConsumer(t *testing.T) {
 				logger.Infof("Received %s", msg.Message)
 				return nil
 			},
-		}, func(msgType string, msgBody []byte, err error) {})
+		}, func(msgType string, msgBody []byte, err error) error { return nil })
 		assert.EqualError(t, err, broker.ErrBrokerClosed.Error(), "unexpected consumer error")
 	}()
 
@@ -101,3 +101,112 @@ func TestBroker_PublishAndConsumer(t *testing.T) {
 	consumerWg.Wait()
 	assert.Equal(t, publishedMessages, int(receivedCount), "received messages count not as expected")
 }
+
+type partitionedEvent struct {
+	Key      string `json:"key"`
+	Sequence int    `json:"sequence"`
+}
+
+func (partitionedEvent) Type() string {
+	return "partitioned-event"
+}
+
+func (p partitionedEvent) PartitionKey() string {
+	return p.Key
+}
+
+func (p partitionedEvent) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *partitionedEvent) Unmarshal(d []byte) error {
+	return json.Unmarshal(d, p)
+}
+
+// TestBroker_partitions tests that messages of different partitions are
+// consumed in parallel while messages with the same partition key are consumed
+// in order.
+func TestBroker_partitions(t *testing.T) {
+	logger := log.New(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	partitions := 4
+	slowKey := "dev/slow-service"
+	// find a key assigned another partition than the slow key
+	var fastKey string
+	for i := 0; fastKey == ""; i++ {
+		key := fmt.Sprintf("dev/service-%d", i)
+		if broker.Partition(&partitionedEvent{Key: key}, partitions) != broker.Partition(&partitionedEvent{Key: slowKey}, partitions) {
+			fastKey = key
+		}
+	}
+	publishedMessages := 10
+
+	var mu sync.Mutex
+	received := make(map[string][]int)
+	unblockSlow := make(chan struct{})
+	receivedAll := make(chan struct{})
+	memoryBroker := NewPartitioned(logger, publishedMessages, partitions, nil)
+
+	var consumerWg sync.WaitGroup
+	consumerWg.Add(1)
+	go func() {
+		defer consumerWg.Done()
+		err := memoryBroker.StartConsumer(map[string]func([]byte) error{
+			partitionedEvent{}.Type(): func(d []byte) error {
+				var msg partitionedEvent
+				err := json.Unmarshal(d, &msg)
+				if err != nil {
+					return err
+				}
+				if msg.Key == slowKey {
+					<-unblockSlow
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				received[msg.Key] = append(received[msg.Key], msg.Sequence)
+				if len(received[fastKey]) == publishedMessages {
+					// the fast partition is not blocked by the slow one
+					select {
+					case <-unblockSlow:
+					default:
+						close(unblockSlow)
+					}
+				}
+				if len(received[slowKey]) == publishedMessages && len(received[fastKey]) == publishedMessages {
+					close(receivedAll)
+				}
+				return nil
+			},
+		}, func(msgType string, msgBody []byte, err error) error { return nil })
+		assert.EqualError(t, err, broker.ErrBrokerClosed.Error(), "unexpected consumer error")
+	}()
+
+	var expected []int
+	for i := 1; i <= publishedMessages; i++ {
+		expected = append(expected, i)
+		for _, key := range []string{slowKey, fastKey} {
+			err := memoryBroker.Publish(context.Background(), &partitionedEvent{
+				Key:      key,
+				Sequence: i,
+			})
+			assert.NoError(t, err, "unexpected error publishing message")
+		}
+	}
+
+	select {
+	case <-receivedAll:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting to receive all events")
+	}
+
+	err := memoryBroker.Close()
+	assert.NoError(t, err, "unexpected close error")
+	consumerWg.Wait()
+
+	assert.Equal(t, expected, received[slowKey], "slow key messages not in order")
+	assert.Equal(t, expected, received[fastKey], "fast key messages not in order")
+}
@@ -0,0 +1,334 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// ErrOutboxClosed indicates that the outbox was closed by a call to Close.
+var ErrOutboxClosed = errors.New("memory: outbox closed")
+
+// maxOutboxRecordSize is the maximum size of a single record in the outbox
+// file.
+const maxOutboxRecordSize = 16 * 1024 * 1024
+
+// outboxCompactionThreshold is the number of acknowledgements after which the
+// outbox file is compacted.
+const outboxCompactionThreshold = 1000
+
+// Outbox is an append-only file of published messages that are not yet
+// handled. It lets a Broker replay messages accepted before a restart or
+// crash.
+//
+// Each line in the file is a JSON record of either a published message or the
+// acknowledgement of one. Messages are delivered at least once: a message is
+// replayed if the broker stopped before it was acknowledged.
+//
+// The file is truncated when all messages are acknowledged and compacted every
+// compactionThreshold acknowledgements so it does not grow unbounded when
+// messages are continuously published.
+type Outbox struct {
+	logger *log.Logger
+	path   string
+
+	// compactionThreshold is the number of acknowledgements after which the
+	// file is compacted.
+	compactionThreshold int
+
+	mu      sync.Mutex
+	file    *os.File
+	nextID  uint64
+	unacked map[uint64]bool
+	// acked is the number of acknowledgements written since the file was last
+	// compacted or truncated.
+	acked int
+	// pending are the messages not acknowledged when the outbox was opened.
+	pending []outboxMessage
+}
+
+type outboxRecord struct {
+	ID   uint64 `json:"id"`
+	Ack  bool   `json:"ack,omitempty"`
+	Type string `json:"type,omitempty"`
+	Body []byte `json:"body,omitempty"`
+}
+
+// outboxMessage is a message read back from the outbox. It implements
+// broker.Publishable so it can be handled as the original message.
+type outboxMessage struct {
+	id      uint64
+	msgType string
+	body    []byte
+}
+
+func (m outboxMessage) Type() string {
+	return m.msgType
+}
+
+func (m outboxMessage) Marshal() ([]byte, error) {
+	return m.body, nil
+}
+
+func (m *outboxMessage) Unmarshal(body []byte) error {
+	m.body = body
+	return nil
+}
+
+// OpenOutbox opens the outbox file at path creating it if it does not exist.
+// Messages not acknowledged in the file are replayed by a Broker using the
+// outbox. The file is compacted to only contain these messages.
+func OpenOutbox(logger *log.Logger, path string) (*Outbox, error) {
+	o := &Outbox{
+		logger:              logger,
+		path:                path,
+		compactionThreshold: outboxCompactionThreshold,
+		nextID:              1,
+		unacked:             make(map[uint64]bool),
+	}
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create directory of '%s'", path)
+	}
+	err = o.load()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load outbox '%s'", path)
+	}
+	err = o.compact(o.pending)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "compact outbox '%s'", path)
+	}
+	o.file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open outbox '%s'", path)
+	}
+	o.logger.Infof("memory: loaded %d unhandled messages from outbox '%s'", len(o.pending), path)
+	return o, nil
+}
+
+// load reads the records of the outbox file into pending.
+func (o *Outbox) load() error {
+	var published []outboxMessage
+	acked := make(map[uint64]bool)
+	err := o.readRecords(func(record outboxRecord) {
+		if record.ID >= o.nextID {
+			o.nextID = record.ID + 1
+		}
+		if record.Ack {
+			acked[record.ID] = true
+			return
+		}
+		published = append(published, outboxMessage{
+			id:      record.ID,
+			msgType: record.Type,
+			body:    record.Body,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, msg := range published {
+		if acked[msg.id] {
+			continue
+		}
+		o.pending = append(o.pending, msg)
+		o.unacked[msg.id] = true
+	}
+	return nil
+}
+
+// readRecords calls f with each record in the outbox file in the order they
+// were written.
+func (o *Outbox) readRecords(f func(outboxRecord)) error {
+	file, err := os.Open(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxOutboxRecordSize)
+	for scanner.Scan() {
+		var record outboxRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			// a crash while appending leaves a partially written last record
+			o.logger.Errorf("memory: skipping unparsable outbox record in '%s': %v", o.path, err)
+			continue
+		}
+		f(record)
+	}
+	err = scanner.Err()
+	if err != nil {
+		return errors.WithMessage(err, "read records")
+	}
+	return nil
+}
+
+// compact rewrites the outbox file with only messages. The file is written to a
+// temporary file and renamed into place to avoid losing messages on crashes.
+func (o *Outbox) compact(messages []outboxMessage) error {
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), ".outbox-")
+	if err != nil {
+		return errors.WithMessage(err, "create temporary file")
+	}
+	w := bufio.NewWriter(tmp)
+	for _, msg := range messages {
+		err = writeRecord(w, outboxRecord{
+			ID:   msg.id,
+			Type: msg.msgType,
+			Body: msg.body,
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.WithMessage(err, "write temporary file")
+	}
+	err = os.Rename(tmp.Name(), o.path)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.WithMessage(err, "rename temporary file")
+	}
+	return nil
+}
+
+func writeRecord(w interface{ Write([]byte) (int, error) }, record outboxRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithMessage(err, "marshal record")
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// Append writes a published message to the outbox and returns its ID. The
+// message is synced to disk before Append returns.
+func (o *Outbox) Append(msgType string, body []byte) (uint64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return 0, ErrOutboxClosed
+	}
+	id := o.nextID
+	err := writeRecord(o.file, outboxRecord{
+		ID:   id,
+		Type: msgType,
+		Body: body,
+	})
+	if err != nil {
+		return 0, errors.WithMessage(err, "write message")
+	}
+	err = o.file.Sync()
+	if err != nil {
+		return 0, errors.WithMessage(err, "sync message")
+	}
+	o.nextID++
+	o.unacked[id] = true
+	return id, nil
+}
+
+// Ack acknowledges that the message with id is handled so it is not replayed.
+// When all messages are acknowledged the outbox file is truncated and otherwise
+// compacted every compactionThreshold acknowledgements.
+func (o *Outbox) Ack(id uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return ErrOutboxClosed
+	}
+	if !o.unacked[id] {
+		return nil
+	}
+	delete(o.unacked, id)
+	if len(o.unacked) == 0 {
+		err := o.file.Truncate(0)
+		if err != nil {
+			return errors.WithMessage(err, "truncate outbox")
+		}
+		o.acked = 0
+		return nil
+	}
+	// acknowledgements are not synced as a lost acknowledgement only causes the
+	// message to be replayed
+	err := writeRecord(o.file, outboxRecord{
+		ID:  id,
+		Ack: true,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "write acknowledgement")
+	}
+	o.acked++
+	if o.acked < o.compactionThreshold {
+		return nil
+	}
+	err = o.compactUnacked()
+	if err != nil {
+		return errors.WithMessage(err, "compact outbox")
+	}
+	return nil
+}
+
+// compactUnacked rewrites the outbox file with only the messages not yet
+// acknowledged and reopens it for appending.
+func (o *Outbox) compactUnacked() error {
+	var messages []outboxMessage
+	err := o.readRecords(func(record outboxRecord) {
+		if record.Ack || !o.unacked[record.ID] {
+			return
+		}
+		messages = append(messages, outboxMessage{
+			id:      record.ID,
+			msgType: record.Type,
+			body:    record.Body,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	err = o.compact(messages)
+	if err != nil {
+		return err
+	}
+	// the file is replaced so the old file is closed regardless of errors to
+	// not append to it
+	o.file.Close()
+	o.file, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.WithMessage(err, "reopen outbox")
+	}
+	o.acked = 0
+	return nil
+}
+
+// Close closes the outbox file. Messages not acknowledged are replayed when the
+// outbox is opened again.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
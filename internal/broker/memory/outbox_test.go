@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/broker"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOutbox_replay(t *testing.T) {
+	logger := log.New(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	path := filepath.Join(t.TempDir(), "outbox", "messages.jsonl")
+
+	outbox, err := OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected open error")
+	first, err := outbox.Append("test-event", []byte(`{"message":"first"}`))
+	require.NoError(t, err, "unexpected append error")
+	_, err = outbox.Append("test-event", []byte(`{"message":"second"}`))
+	require.NoError(t, err, "unexpected append error")
+	_, err = outbox.Append("test-event", []byte(`{"message":"third"}`))
+	require.NoError(t, err, "unexpected append error")
+	require.NoError(t, outbox.Ack(first), "unexpected ack error")
+	require.NoError(t, outbox.Close(), "unexpected close error")
+
+	// simulate a crash while appending a record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id":4,"type":"test-ev`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	outbox, err = OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected reopen error")
+	defer outbox.Close()
+	assert.Equal(t, []outboxMessage{
+		{id: 2, msgType: "test-event", body: []byte(`{"message":"second"}`)},
+		{id: 3, msgType: "test-event", body: []byte(`{"message":"third"}`)},
+	}, outbox.pending, "pending messages not as expected")
+
+	id, err := outbox.Append("test-event", []byte(`{"message":"fourth"}`))
+	require.NoError(t, err, "unexpected append error")
+	assert.Equal(t, uint64(4), id, "ids of pending messages must not be reused")
+
+	for _, id := range []uint64{2, 3, 4} {
+		require.NoError(t, outbox.Ack(id), "unexpected ack error")
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "outbox not truncated when all messages are acknowledged")
+}
+
+// TestOutbox_compaction tests that acknowledged messages are removed from the
+// outbox file while other messages are still not acknowledged.
+func TestOutbox_compaction(t *testing.T) {
+	logger := log.New(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	outbox, err := OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected open error")
+	outbox.compactionThreshold = 3
+	for _, message := range []string{"first", "second", "third", "fourth", "fifth"} {
+		_, err := outbox.Append("test-event", []byte(fmt.Sprintf(`{"message":"%s"}`, message)))
+		require.NoError(t, err, "unexpected append error")
+	}
+	for _, id := range []uint64{1, 2, 4} {
+		require.NoError(t, outbox.Ack(id), "unexpected ack error")
+	}
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":3,"type":"test-event","body":"eyJtZXNzYWdlIjoidGhpcmQifQ=="}
+{"id":5,"type":"test-event","body":"eyJtZXNzYWdlIjoiZmlmdGgifQ=="}
+`, string(content), "outbox not compacted")
+
+	// the compacted file is still appended to
+	id, err := outbox.Append("test-event", []byte(`{"message":"sixth"}`))
+	require.NoError(t, err, "unexpected append error")
+	assert.Equal(t, uint64(6), id, "ids must not be reused after compaction")
+	require.NoError(t, outbox.Ack(3), "unexpected ack error")
+	require.NoError(t, outbox.Close(), "unexpected close error")
+
+	outbox, err = OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected reopen error")
+	defer outbox.Close()
+	assert.Equal(t, []outboxMessage{
+		{id: 5, msgType: "test-event", body: []byte(`{"message":"fifth"}`)},
+		{id: 6, msgType: "test-event", body: []byte(`{"message":"sixth"}`)},
+	}, outbox.pending, "pending messages not as expected")
+}
+
+// TestBroker_outbox tests that messages published but not handled before the
+// broker is closed are handled when a new broker is started on the outbox.
+func TestBroker_outbox(t *testing.T) {
+	logger := log.New(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	outbox, err := OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected open error")
+	memoryBroker := NewWithOutbox(logger, 5, outbox)
+	for _, message := range []string{"first", "second"} {
+		err := memoryBroker.Publish(context.Background(), &testEvent{Message: message})
+		require.NoError(t, err, "unexpected publish error")
+	}
+	// the broker stops before the messages are consumed
+	require.NoError(t, memoryBroker.Close(), "unexpected close error")
+
+	outbox, err = OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected reopen error")
+	memoryBroker = NewWithOutbox(logger, 5, outbox)
+	require.NoError(t, memoryBroker.Publish(context.Background(), &testEvent{Message: "third"}), "unexpected publish error")
+
+	var received []string
+	consumerDone := make(chan error)
+	go func() {
+		consumerDone <- memoryBroker.StartConsumer(map[string]func([]byte) error{
+			testEvent{}.Type(): func(d []byte) error {
+				var msg testEvent
+				err := json.Unmarshal(d, &msg)
+				if err != nil {
+					return err
+				}
+				received = append(received, msg.Message)
+				if len(received) == 3 {
+					// stop the consumer once all messages are received
+					go memoryBroker.Close()
+				}
+				return nil
+			},
+		}, func(msgType string, msgBody []byte, err error) error { return nil })
+	}()
+	select {
+	case err := <-consumerDone:
+		assert.EqualError(t, err, broker.ErrBrokerClosed.Error(), "unexpected consumer error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for consumer")
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, received, "received messages not as expected")
+}
+
+// TestBroker_outbox_failedHandling tests that messages failing to be handled
+// are only acknowledged if they are handed off by the error handler and
+// otherwise replayed when a new broker is started on the outbox.
+func TestBroker_outbox_failedHandling(t *testing.T) {
+	logger := log.New(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	outbox, err := OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected open error")
+	memoryBroker := NewWithOutbox(logger, 5, outbox)
+	for _, message := range []string{"handed-off", "kept"} {
+		err := memoryBroker.Publish(context.Background(), &testEvent{Message: message})
+		require.NoError(t, err, "unexpected publish error")
+	}
+	handlers := map[string]func([]byte) error{
+		testEvent{}.Type(): func([]byte) error {
+			return errors.New("handler failed")
+		},
+	}
+	var handedOff []string
+	errorHandler := func(msgType string, msgBody []byte, err error) error {
+		var msg testEvent
+		require.NoError(t, json.Unmarshal(msgBody, &msg), "unexpected unmarshal error")
+		if msg.Message == "kept" {
+			return errors.New("hand off failed")
+		}
+		handedOff = append(handedOff, msg.Message)
+		return nil
+	}
+	// handle the messages synchronously to know they are acknowledged before
+	// the broker is closed
+	for i := 0; i < 2; i++ {
+		memoryBroker.handle(<-memoryBroker.partitions[0], handlers, errorHandler)
+	}
+	require.NoError(t, memoryBroker.Close(), "unexpected close error")
+	assert.Equal(t, []string{"handed-off"}, handedOff, "handed off messages not as expected")
+
+	outbox, err = OpenOutbox(logger, path)
+	require.NoError(t, err, "unexpected reopen error")
+	memoryBroker = NewWithOutbox(logger, 5, outbox)
+	var received []string
+	consumerDone := make(chan error)
+	go func() {
+		consumerDone <- memoryBroker.StartConsumer(map[string]func([]byte) error{
+			testEvent{}.Type(): func(d []byte) error {
+				var msg testEvent
+				err := json.Unmarshal(d, &msg)
+				if err != nil {
+					return err
+				}
+				received = append(received, msg.Message)
+				// stop the consumer once the replayed message is received
+				go memoryBroker.Close()
+				return nil
+			},
+		}, func(msgType string, msgBody []byte, err error) error {
+			t.Errorf("unexpected error handling: %v", err)
+			return nil
+		})
+	}()
+	select {
+	case err := <-consumerDone:
+		assert.EqualError(t, err, broker.ErrBrokerClosed.Error(), "unexpected consumer error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for consumer")
+	}
+	assert.Equal(t, []string{"kept"}, received, "replayed messages not as expected")
+}
@@ -2,6 +2,7 @@ package commitinfo
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lunarway/release-manager/internal/intent"
 	"github.com/lunarway/release-manager/internal/regexp"
@@ -32,6 +33,8 @@ func (i CommitInfo) String() string {
 		releaseType = "rollback"
 	case intent.TypeAutoRelease:
 		releaseType = "auto release"
+	case intent.TypeUndeploy:
+		releaseType = "undeploy"
 	default:
 		releaseType = "release"
 	}
@@ -62,6 +65,9 @@ func (i CommitInfo) String() string {
 // * Environment and Service name is extracted from the `[<env>/<service>]`-brackets in the title
 // * User email in title is interpreted as releaser
 func ParseCommitInfo(commitMessage string) (CommitInfo, error) {
+	if isBatch(commitMessage) {
+		return CommitInfo{}, errors.Wrap(ErrNoMatch, "commit message is a batch of releases")
+	}
 	convInfo, err := ParseConventionalCommit(commitMessage)
 	if err != nil {
 		return CommitInfo{}, err
@@ -112,6 +118,44 @@ func ParseCommitInfo(commitMessage string) (CommitInfo, error) {
 	}, nil
 }
 
+const (
+	// batchTitlePrefix prefixes the title of batch release commits.
+	batchTitlePrefix = "Release batch of "
+	// batchSeparator separates the release messages of batch release commits.
+	batchSeparator = "\n\n---\n\n"
+)
+
+func isBatch(commitMessage string) bool {
+	return strings.HasPrefix(commitMessage, batchTitlePrefix)
+}
+
+// ParseCommitInfos returns the release information of a commit message. Batch
+// release commits returned by BatchReleaseCommitMessage contain a CommitInfo
+// for each release and all other commits a single one as returned by
+// ParseCommitInfo.
+func ParseCommitInfos(commitMessage string) ([]CommitInfo, error) {
+	if !isBatch(commitMessage) {
+		info, err := ParseCommitInfo(commitMessage)
+		if err != nil {
+			return nil, err
+		}
+		return []CommitInfo{info}, nil
+	}
+	parts := strings.SplitN(commitMessage, "\n\n", 2)
+	if len(parts) != 2 {
+		return nil, errors.Wrap(ErrNoMatch, "batch commit message has no releases")
+	}
+	var infos []CommitInfo
+	for _, releaseMessage := range strings.Split(parts[1], batchSeparator) {
+		info, err := ParseCommitInfo(releaseMessage)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 var parseCommitInfoFromCommitMessageRegexLookup = struct {
 	Environment        int
 	Service            int
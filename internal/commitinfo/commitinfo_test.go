@@ -170,6 +170,27 @@ func TestParseCommitInfo(t *testing.T) {
 				Intent:            intent.NewAutoRelease(),
 			},
 		},
+		{
+			name: "Undeploy intent should match",
+			commitMessage: []string{
+				"[prod/product] undeploy master-937e50b532-c27bd51ad3 by bso@lunar.app",
+				"",
+				"Service: product",
+				"Environment: prod",
+				"Artifact-ID: master-937e50b532-c27bd51ad3",
+				"Artifact-released-by: Bjørn Hald Sørensen <bso@lunar.app>",
+				"Artifact-created-by: Emil Ingerslev <eki@lunar.app>",
+				"Release-intent: Undeploy",
+			},
+			commitInfo: CommitInfo{
+				ArtifactID:        "master-937e50b532-c27bd51ad3",
+				Environment:       "prod",
+				Service:           "product",
+				ArtifactCreatedBy: NewPersonInfo("Emil Ingerslev", "eki@lunar.app"),
+				ReleasedBy:        NewPersonInfo("Bjørn Hald Sørensen", "bso@lunar.app"),
+				Intent:            intent.NewUndeploy(),
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
@@ -199,3 +220,66 @@ func TestParseCommitInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommitInfos(t *testing.T) {
+	api := CommitInfo{
+		ArtifactID:        "master-1234-5678",
+		ArtifactCreatedBy: NewPersonInfo("Foo Bar", "foo@lunar.app"),
+		ReleasedBy:        NewPersonInfo("Hest", "hest@lunar.app"),
+		Service:           "api",
+		Environment:       "dev",
+		Intent:            intent.NewReleaseArtifact(),
+	}
+	web := CommitInfo{
+		ArtifactID:        "master-abcd-efgh",
+		ArtifactCreatedBy: NewPersonInfo("Foo Bar", "foo@lunar.app"),
+		ReleasedBy:        NewPersonInfo("", ""),
+		Service:           "web",
+		Environment:       "dev",
+		Intent:            intent.NewAutoRelease(),
+	}
+	tt := []struct {
+		name          string
+		commitMessage string
+		commitInfos   []CommitInfo
+		err           error
+	}{
+		{
+			name:          "single release",
+			commitMessage: api.String(),
+			commitInfos:   []CommitInfo{api},
+		},
+		{
+			name:          "batch release",
+			commitMessage: BatchReleaseCommitMessage([]string{api.String(), web.String()}),
+			commitInfos:   []CommitInfo{api, web},
+		},
+		{
+			name:          "invalid message",
+			commitMessage: "[product] build something",
+			err:           ErrNoMatch,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			infos, err := ParseCommitInfos(tc.commitMessage)
+			if tc.err != nil {
+				assert.EqualError(t, errors.Cause(err), tc.err.Error(), "output error not as expected")
+				return
+			}
+			assert.NoError(t, err, "no output error expected")
+			assert.Equal(t, tc.commitInfos, infos, "commitInfos not as expected")
+		})
+	}
+}
+
+func TestParseCommitInfo_batch(t *testing.T) {
+	message := BatchReleaseCommitMessage([]string{
+		ReleaseCommitMessage("dev", "api", "master-1234-5678", intent.NewReleaseArtifact(), NewPersonInfo("", "foo@lunar.app"), NewPersonInfo("", "hest@lunar.app")),
+		ReleaseCommitMessage("dev", "web", "master-abcd-efgh", intent.NewReleaseArtifact(), NewPersonInfo("", "foo@lunar.app"), NewPersonInfo("", "hest@lunar.app")),
+	})
+
+	_, err := ParseCommitInfo(message)
+
+	assert.EqualError(t, errors.Cause(err), ErrNoMatch.Error(), "batch commits must not parse as a single release")
+}
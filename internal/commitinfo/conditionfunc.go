@@ -1,13 +1,26 @@
 package commitinfo
 
+import "github.com/lunarway/release-manager/internal/intent"
+
 type conditionFunc = func(commitMsg string) bool
 
+// LocateRelease returns a condition matching commits of releases accepted by
+// validator. Undeploy commits are never matched as they do not release an
+// artifact.
 func LocateRelease(validator func(CommitInfo) bool) conditionFunc {
 	return func(commitMsg string) bool {
-		commitInfo, err := ParseCommitInfo(commitMsg)
+		commitInfos, err := ParseCommitInfos(commitMsg)
 		if err != nil {
 			return false
 		}
-		return validator(commitInfo)
+		for _, commitInfo := range commitInfos {
+			if commitInfo.Intent.Type == intent.TypeUndeploy {
+				continue
+			}
+			if validator(commitInfo) {
+				return true
+			}
+		}
+		return false
 	}
 }
@@ -21,6 +21,8 @@ func parseIntent(cci ConventionalCommitInfo, commitMessageMatches []string) inte
 		return intent.NewRollback(cci.Field(FieldRollbackOfArtifactId))
 	case intent.TypeAutoRelease:
 		return intent.NewAutoRelease()
+	case intent.TypeUndeploy:
+		return intent.NewUndeploy()
 	default:
 		// A check for compatability reasons, for back when only the message was saying it was a rollback.
 		if commitMessageMatches != nil && commitMessageMatches[parseCommitInfoFromCommitMessageRegexLookup.Type] == "rollback" {
@@ -39,7 +41,7 @@ func addIntentToConventionalCommitInfo(intentObj intent.Intent, cci *Conventiona
 		cci.SetField(FieldPromotedFromEnvironment, intentObj.Promote.FromEnvironment)
 	case intent.TypeRollback:
 		cci.SetField(FieldRollbackOfArtifactId, intentObj.Rollback.PreviousArtifactID)
-	case intent.TypeAutoRelease:
+	case intent.TypeAutoRelease, intent.TypeUndeploy:
 		// nothing yet
 	}
 }
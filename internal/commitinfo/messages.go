@@ -2,6 +2,7 @@ package commitinfo
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lunarway/release-manager/internal/intent"
 )
@@ -18,6 +19,15 @@ func ReleaseCommitMessage(env, service, artifactID string, intent intent.Intent,
 	}.String()
 }
 
+// BatchReleaseCommitMessage returns the commit message of several releases
+// committed together. releaseMessages are the messages returned by
+// ReleaseCommitMessage for each release and can be parsed individually from
+// the message with ParseCommitInfos.
+func BatchReleaseCommitMessage(releaseMessages []string) string {
+	title := fmt.Sprintf("%s%d artifacts", batchTitlePrefix, len(releaseMessages))
+	return title + "\n\n" + strings.Join(releaseMessages, batchSeparator)
+}
+
 // PolicyUpdateApplyCommitMessage returns an apply policy commit message.
 func PolicyUpdateApplyCommitMessage(env, service, policy string) string {
 	return fmt.Sprintf("[%s] policy update: apply %s in '%s'", service, policy, env)
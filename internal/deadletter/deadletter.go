@@ -0,0 +1,154 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/jsonfile"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// Store implements flow.DeadLetterStorage. Dead letters are kept in memory
+// and, if a directory is configured, persisted as one JSON file per dead letter
+// so they survive restarts.
+type Store struct {
+	dir string
+
+	mu      sync.RWMutex
+	letters map[string]flow.DeadLetter
+}
+
+var _ flow.DeadLetterStorage = &Store{}
+
+// New allocates a Store persisting dead letters in dir. If dir is empty dead
+// letters are only kept in memory. Existing dead letters in dir are loaded.
+func New(dir string) (*Store, error) {
+	s := &Store{
+		dir:     dir,
+		letters: make(map[string]flow.DeadLetter),
+	}
+	if dir == "" {
+		return s, nil
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create directory '%s'", dir)
+	}
+	err = s.load()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load dead letters from '%s'", dir)
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return errors.WithMessagef(err, "read '%s'", file)
+		}
+		var letter flow.DeadLetter
+		err = json.Unmarshal(content, &letter)
+		if err != nil {
+			log.Errorf("deadletter: skipping unparsable dead letter file '%s': %v", file, err)
+			continue
+		}
+		s.letters[letter.ID] = letter
+	}
+	log.Infof("deadletter: loaded %d dead letters from '%s'", len(s.letters), s.dir)
+	return nil
+}
+
+func (s *Store) Add(ctx context.Context, letter flow.DeadLetter) error {
+	if letter.ID == "" {
+		return errors.New("dead letter id required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.letters[letter.ID]; ok {
+		return errors.Errorf("dead letter '%s' already exists", letter.ID)
+	}
+	if s.dir != "" {
+		err := s.persist(letter)
+		if err != nil {
+			return errors.WithMessagef(err, "persist dead letter '%s'", letter.ID)
+		}
+	}
+	s.letters[letter.ID] = letter
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, id string) (flow.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	letter, ok := s.letters[id]
+	if !ok {
+		return flow.DeadLetter{}, flow.ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+func (s *Store) List(ctx context.Context) ([]flow.DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	letters := make([]flow.DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		if letters[i].DroppedAt.Equal(letters[j].DroppedAt) {
+			return letters[i].ID < letters[j].ID
+		}
+		return letters[i].DroppedAt.Before(letters[j].DroppedAt)
+	})
+	return letters, nil
+}
+
+func (s *Store) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.letters[id]; !ok {
+		return flow.ErrDeadLetterNotFound
+	}
+	if s.dir != "" {
+		path, err := s.path(id)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.WithMessagef(err, "remove dead letter file '%s'", path)
+		}
+	}
+	delete(s.letters, id)
+	return nil
+}
+
+func (s *Store) path(id string) (string, error) {
+	path, err := securejoin.SecureJoin(s.dir, fmt.Sprintf("%s.json", id))
+	if err != nil {
+		return "", errors.WithMessage(err, "join dead letter path")
+	}
+	return path, nil
+}
+
+// persist writes letter to its file in the directory.
+func (s *Store) persist(letter flow.DeadLetter) error {
+	path, err := s.path(letter.ID)
+	if err != nil {
+		return err
+	}
+	return jsonfile.Write(path, letter)
+}
@@ -0,0 +1,69 @@
+package deadletter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&log.Configuration{
+		Level:       log.Level{Level: zapcore.ErrorLevel},
+		Development: false,
+	})
+	os.Exit(m.Run())
+}
+
+func newLetter(id string, droppedAt time.Time) flow.DeadLetter {
+	return flow.DeadLetter{
+		ID:        id,
+		Type:      "release.artifactId",
+		Body:      []byte(`{"service":"svc","environment":"dev"}`),
+		Error:     "clone failed",
+		DroppedAt: droppedAt,
+	}
+}
+
+func TestStore_persistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now()
+
+	store, err := New(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.Add(ctx, newLetter("2", now)))
+	require.NoError(t, store.Add(ctx, newLetter("1", now.Add(-time.Minute))))
+	require.NoError(t, store.Add(ctx, newLetter("3", now.Add(time.Minute))))
+	require.NoError(t, store.Remove(ctx, "3"))
+
+	restarted, err := New(dir)
+	require.NoError(t, err)
+	letters, err := restarted.List(ctx)
+	require.NoError(t, err)
+	var ids []string
+	for _, letter := range letters {
+		ids = append(ids, letter.ID)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids, "dead letters not ordered by when they were dropped")
+	assert.Equal(t, []byte(`{"service":"svc","environment":"dev"}`), letters[0].Body)
+	assert.NoFileExists(t, filepath.Join(dir, "3.json"))
+}
+
+func TestStore_notFound(t *testing.T) {
+	ctx := context.Background()
+	store, err := New("")
+	require.NoError(t, err)
+
+	_, err = store.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, flow.ErrDeadLetterNotFound)
+	err = store.Remove(ctx, "unknown")
+	assert.ErrorIs(t, err, flow.ErrDeadLetterNotFound)
+}
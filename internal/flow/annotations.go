@@ -0,0 +1,190 @@
+package flow
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Annotations required by the daemon to report the state of workloads.
+const (
+	artifactIDAnnotationKey = "lunarway.com/artifact-id"
+	authorAnnotationKey     = "lunarway.com/author"
+	controlledAnnotationKey = "lunarway.com/controlled-by-release-manager"
+)
+
+// controlledKinds are the workload kinds the daemon reports the state of and
+// thus must be annotated.
+var controlledKinds = map[string]bool{
+	"Deployment":  true,
+	"DaemonSet":   true,
+	"StatefulSet": true,
+	"Job":         true,
+}
+
+// injectReleaseAnnotations sets the annotations required by the daemon on all
+// controlled workloads and their pod templates in YAML files in directory.
+// Files without controlled workloads or that cannot be parsed are left
+// untouched.
+func injectReleaseAnnotations(directory, artifactID, authorEmail string) error {
+	annotations := map[string]string{
+		controlledAnnotationKey: "true",
+		artifactIDAnnotationKey: artifactID,
+		authorAnnotationKey:     authorEmail,
+	}
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.WithMessagef(err, "read file '%s'", path)
+		}
+		annotated, changed, err := annotateManifests(content, annotations)
+		if err != nil {
+			// invalid files are reported by manifest validation
+			return nil
+		}
+		if !changed {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return errors.WithMessagef(err, "stat file '%s'", path)
+		}
+		err = os.WriteFile(path, annotated, info.Mode().Perm())
+		if err != nil {
+			return errors.WithMessagef(err, "write file '%s'", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "walk directory '%s'", directory)
+	}
+	return nil
+}
+
+// annotateManifests sets annotations on all controlled workloads in the YAML
+// documents of content. The re-encoded documents are returned along with
+// whether any of them were changed.
+func annotateManifests(content []byte, annotations map[string]string) ([]byte, bool, error) {
+	var docs []*yaml.Node
+	changed := false
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			docs = append(docs, &doc)
+			continue
+		}
+		object := doc.Content[0]
+		kind := mappingValue(object, "kind")
+		if kind == nil || !controlledKinds[kind.Value] {
+			docs = append(docs, &doc)
+			continue
+		}
+		if setAnnotations(object, annotations) {
+			changed = true
+		}
+		// pods are reported by the daemon as well so their templates must be
+		// annotated too
+		spec := mappingValue(object, "spec")
+		if spec != nil && spec.Kind == yaml.MappingNode {
+			template := mappingValue(spec, "template")
+			if template != nil && template.Kind == yaml.MappingNode && setAnnotations(template, annotations) {
+				changed = true
+			}
+		}
+		docs = append(docs, &doc)
+	}
+	if !changed {
+		return content, false, nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		err := encoder.Encode(doc)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	err := encoder.Close()
+	if err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// setAnnotations sets annotations in metadata.annotations of the object
+// mapping node creating the mappings if needed. Annotations with empty values
+// are skipped. It reports whether the object was changed.
+func setAnnotations(object *yaml.Node, annotations map[string]string) bool {
+	changed := false
+	metadata := mappingValue(object, "metadata")
+	if metadata == nil || metadata.Kind != yaml.MappingNode {
+		metadata = setMappingValue(object, "metadata", &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		changed = true
+	}
+	existing := mappingValue(metadata, "annotations")
+	if existing == nil || existing.Kind != yaml.MappingNode {
+		existing = setMappingValue(metadata, "annotations", &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		changed = true
+	}
+	// iterate the keys in a fixed order to get stable output
+	for _, key := range []string{controlledAnnotationKey, artifactIDAnnotationKey, authorAnnotationKey} {
+		value, ok := annotations[key]
+		if !ok || value == "" {
+			continue
+		}
+		current := mappingValue(existing, key)
+		if current != nil && current.Kind == yaml.ScalarNode && current.Value == value && current.Tag == "!!str" {
+			continue
+		}
+		setMappingValue(existing, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+		changed = true
+	}
+	return changed
+}
+
+// mappingValue returns the value of key in mapping or nil if not found.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key to value in mapping replacing any existing value.
+// value is returned for convenience.
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return value
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
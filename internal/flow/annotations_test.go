@@ -0,0 +1,153 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateManifests(t *testing.T) {
+	annotations := map[string]string{
+		controlledAnnotationKey: "true",
+		artifactIDAnnotationKey: "master-1234-5678",
+		authorAnnotationKey:     "jane@example.com",
+	}
+	tt := []struct {
+		name    string
+		input   string
+		output  string
+		changed bool
+	}{
+		{
+			name: "deployment without annotations",
+			input: `# the product deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+spec:
+  template:
+    metadata:
+      labels:
+        app: product
+    spec:
+      containers:
+        - name: product
+          image: product:master-1234-5678
+`,
+			output: `# the product deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+  annotations:
+    lunarway.com/controlled-by-release-manager: "true"
+    lunarway.com/artifact-id: master-1234-5678
+    lunarway.com/author: jane@example.com
+spec:
+  template:
+    metadata:
+      labels:
+        app: product
+      annotations:
+        lunarway.com/controlled-by-release-manager: "true"
+        lunarway.com/artifact-id: master-1234-5678
+        lunarway.com/author: jane@example.com
+    spec:
+      containers:
+        - name: product
+          image: product:master-1234-5678
+`,
+			changed: true,
+		},
+		{
+			name: "job with stale annotations and other documents",
+			input: `apiVersion: v1
+kind: Service
+metadata:
+  name: migrate
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    lunarway.com/artifact-id: master-0000-0000
+    lunarway.com/controlled-by-release-manager: true
+    team: core
+`,
+			output: `apiVersion: v1
+kind: Service
+metadata:
+  name: migrate
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    lunarway.com/artifact-id: master-1234-5678
+    lunarway.com/controlled-by-release-manager: "true"
+    team: core
+    lunarway.com/author: jane@example.com
+`,
+			changed: true,
+		},
+		{
+			name: "already annotated statefulset",
+			input: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: product
+  annotations: {lunarway.com/controlled-by-release-manager: "true", lunarway.com/artifact-id: master-1234-5678, lunarway.com/author: jane@example.com}
+`,
+			changed: false,
+		},
+		{
+			name: "no workloads",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: product
+`,
+			changed: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			output, changed, err := annotateManifests([]byte(tc.input), annotations)
+
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.changed, changed, "changed not as expected")
+			if !tc.changed {
+				assert.Equal(t, tc.input, string(output), "unchanged output not as expected")
+				return
+			}
+			assert.Equal(t, tc.output, string(output), "output not as expected")
+		})
+	}
+}
+
+func TestInjectReleaseAnnotations_passesValidation(t *testing.T) {
+	dir := t.TempDir()
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+`
+	invalid := "kind: [Service\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "01-deployment.yaml"), []byte(deployment), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "02-invalid.yaml"), []byte(invalid), 0644))
+
+	err := injectReleaseAnnotations(dir, "master-1234-5678", "jane@example.com")
+	require.NoError(t, err, "unexpected error")
+
+	content, err := os.ReadFile(filepath.Join(dir, "02-invalid.yaml"))
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, invalid, string(content), "invalid file changed")
+	require.NoError(t, os.Remove(filepath.Join(dir, "02-invalid.yaml")))
+	assert.NoError(t, validateManifests(dir, "dev", "master-1234-5678"), "annotated manifests not valid")
+}
@@ -0,0 +1,119 @@
+package flow
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// ErrArtifactIndexNotConfigured indicates that artifacts cannot be searched as
+// no ArtifactIndex is configured.
+var ErrArtifactIndexNotConfigured = errors.New("artifact index not configured")
+
+// ArtifactIndex indexes artifact specifications for searching by other
+// properties than service and branch.
+type ArtifactIndex interface {
+	// Index adds or replaces spec in the index.
+	Index(ctx context.Context, spec artifact.Spec) error
+
+	// Search returns indexed artifacts matching query sorted by newest first.
+	Search(ctx context.Context, query ArtifactQuery) ([]artifact.Spec, error)
+}
+
+// ArtifactQuery filters artifacts. Empty fields match all artifacts.
+type ArtifactQuery struct {
+	Service string
+	// SHA matches artifacts built from a commit starting with SHA.
+	SHA string
+	// Author matches artifacts with a commit author name or email containing
+	// Author.
+	Author string
+	// Labels matches artifacts with all labels.
+	Labels map[string]string
+	// Limit is the maximum number of artifacts to return. Zero returns all
+	// matches.
+	Limit int
+}
+
+// Matches reports whether spec matches the query. String comparisons are case
+// insensitive.
+func (q ArtifactQuery) Matches(spec artifact.Spec) bool {
+	if q.Service != "" && !strings.EqualFold(q.Service, spec.Service) {
+		return false
+	}
+	if q.SHA != "" && !strings.HasPrefix(strings.ToLower(spec.Application.SHA), strings.ToLower(q.SHA)) {
+		return false
+	}
+	if q.Author != "" {
+		author := strings.ToLower(q.Author)
+		if !strings.Contains(strings.ToLower(spec.Application.AuthorName), author) && !strings.Contains(strings.ToLower(spec.Application.AuthorEmail), author) {
+			return false
+		}
+	}
+	for key, value := range q.Labels {
+		labelValue, ok := spec.Labels[key]
+		if !ok || labelValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ArtifactSearchResult is an artifact matching a search along with the
+// environments it is currently released to.
+type ArtifactSearchResult struct {
+	Artifact     artifact.Spec
+	Environments []string
+}
+
+// SearchArtifacts returns indexed artifacts matching query and where they are
+// currently released.
+func (s *Service) SearchArtifacts(ctx context.Context, query ArtifactQuery) ([]ArtifactSearchResult, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.SearchArtifacts")
+	defer span.End()
+	if s.ArtifactIndex == nil {
+		return nil, ErrArtifactIndexNotConfigured
+	}
+	specs, err := s.ArtifactIndex.Search(ctx, query)
+	if err != nil {
+		return nil, errors.WithMessage(err, "search artifact index")
+	}
+
+	// releases are looked up once per service as multiple artifacts of the same
+	// service are typically found
+	releases := make(map[string][]ReleaseSpec)
+	results := make([]ArtifactSearchResult, len(specs))
+	for i, spec := range specs {
+		results[i].Artifact = spec
+		key := spec.Namespace + "/" + spec.Service
+		serviceReleases, ok := releases[key]
+		if !ok {
+			serviceReleases, err = s.releaseSpecifications(ctx, spec.Namespace, spec.Service)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "locate releases of service '%s'", spec.Service)
+			}
+			releases[key] = serviceReleases
+		}
+		for _, release := range serviceReleases {
+			if release.Spec.ID == spec.ID {
+				results[i].Environments = append(results[i].Environments, release.Environment)
+			}
+		}
+	}
+	return results, nil
+}
+
+// indexArtifact adds spec to the artifact index if one is configured. Errors
+// are logged as the index is not critical for handling new artifacts.
+func (s *Service) indexArtifact(ctx context.Context, spec artifact.Spec) {
+	if s.ArtifactIndex == nil {
+		return
+	}
+	err := s.ArtifactIndex.Index(ctx, spec)
+	if err != nil {
+		log.WithContext(ctx).Errorf("flow: index artifact '%s' of service '%s' failed: %v", spec.ID, spec.Service, err)
+	}
+}
@@ -0,0 +1,11 @@
+package flow
+
+import "context"
+
+// ArtifactVerifier verifies the authenticity of artifacts before they are
+// released.
+type ArtifactVerifier interface {
+	// VerifyArtifact verifies the artifact stored in directory root, i.e. the
+	// directory with the artifact specification.
+	VerifyArtifact(ctx context.Context, root string) error
+}
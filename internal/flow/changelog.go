@@ -0,0 +1,174 @@
+package flow
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/github"
+	"github.com/pkg/errors"
+)
+
+// ErrChangelogNotSupported is returned when a changelog cannot be produced
+// for the source repository of an artifact.
+var ErrChangelogNotSupported = errors.New("changelog not supported")
+
+// SourceRepository provides commits of the source repositories artifacts are
+// built from.
+type SourceRepository interface {
+	// CompareCommits returns the commits reachable from head but not from base
+	// ordered from oldest to newest.
+	CompareCommits(ctx context.Context, repository, base, head string) ([]github.Commit, error)
+}
+
+// Changelog describes the source repository changes between two artifacts.
+type Changelog struct {
+	From   artifact.Spec
+	To     artifact.Spec
+	Groups []ChangelogGroup
+}
+
+// ChangelogGroup is a set of commits of the same conventional commit type.
+type ChangelogGroup struct {
+	Type    string
+	Title   string
+	Commits []ChangelogCommit
+}
+
+type ChangelogCommit struct {
+	SHA        string
+	Scope      string
+	Subject    string
+	Breaking   bool
+	AuthorName string
+	URL        string
+}
+
+// changelogTypes are the known conventional commit types in the order they
+// are listed in changelogs. Commits of other types are grouped under the
+// empty type.
+var changelogTypes = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"style", "Styles"},
+	{"chore", "Chores"},
+	{"", "Other Changes"},
+}
+
+// Changelog returns the changes between the artifact currently released to
+// service in environment and the candidate artifact artifactID.
+func (s *Service) Changelog(ctx context.Context, environment, namespace, service, artifactID string) (Changelog, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.Changelog")
+	defer span.End()
+
+	current, err := s.releaseSpecification(ctx, releaseLocation{
+		Environment: environment,
+		Namespace:   namespace,
+		Service:     service,
+	})
+	if err != nil {
+		return Changelog{}, errors.WithMessage(err, "get current release")
+	}
+	candidate, err := s.Storage.ArtifactSpecification(ctx, service, artifactID)
+	if err != nil {
+		return Changelog{}, errors.WithMessagef(err, "get artifact '%s'", artifactID)
+	}
+	return NewChangelog(ctx, s.SourceRepository, current, candidate)
+}
+
+// NewChangelog returns the changes between the artifacts from and to by
+// comparing their commits in the source repository. Commits are grouped by
+// their conventional commit type.
+func NewChangelog(ctx context.Context, sourceRepository SourceRepository, from, to artifact.Spec) (Changelog, error) {
+	changelog := Changelog{
+		From: from,
+		To:   to,
+	}
+	if sourceRepository == nil {
+		return Changelog{}, errors.WithMessage(ErrChangelogNotSupported, "no source repository configured")
+	}
+	if !strings.EqualFold(to.Application.Provider, "github") {
+		return Changelog{}, errors.WithMessagef(ErrChangelogNotSupported, "source repository provider '%s'", to.Application.Provider)
+	}
+	if from.Application.Name != to.Application.Name {
+		return Changelog{}, errors.WithMessagef(ErrChangelogNotSupported, "artifacts built from different repositories '%s' and '%s'", from.Application.Name, to.Application.Name)
+	}
+	if from.Application.SHA == to.Application.SHA {
+		return changelog, nil
+	}
+	commits, err := sourceRepository.CompareCommits(ctx, to.Application.Name, from.Application.SHA, to.Application.SHA)
+	if err != nil {
+		return Changelog{}, errors.WithMessagef(err, "compare commits '%s' and '%s' of repository '%s'", from.Application.SHA, to.Application.SHA, to.Application.Name)
+	}
+	changelog.Groups = groupCommits(commits)
+	return changelog, nil
+}
+
+// groupCommits groups commits by conventional commit type in the order of
+// changelogTypes with the newest commits first. Empty groups are omitted.
+func groupCommits(commits []github.Commit) []ChangelogGroup {
+	byType := make(map[string][]ChangelogCommit)
+	for i := len(commits) - 1; i >= 0; i-- {
+		commitType, commit := parseConventionalCommit(commits[i])
+		byType[commitType] = append(byType[commitType], commit)
+	}
+	var groups []ChangelogGroup
+	for _, t := range changelogTypes {
+		if len(byType[t.Type]) == 0 {
+			continue
+		}
+		groups = append(groups, ChangelogGroup{
+			Type:    t.Type,
+			Title:   t.Title,
+			Commits: byType[t.Type],
+		})
+	}
+	return groups
+}
+
+var conventionalCommitPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?: *(.+)$`)
+
+// parseConventionalCommit returns the conventional commit type of commit and
+// its changelog entry. Commits not following the conventional commit format or
+// of an unknown type are returned with an empty type.
+func parseConventionalCommit(commit github.Commit) (string, ChangelogCommit) {
+	subject := strings.TrimSpace(strings.SplitN(commit.Message, "\n", 2)[0])
+	entry := ChangelogCommit{
+		SHA:        commit.SHA,
+		Subject:    subject,
+		AuthorName: commit.AuthorName,
+		URL:        commit.URL,
+	}
+	matches := conventionalCommitPattern.FindStringSubmatch(subject)
+	if matches == nil {
+		return "", entry
+	}
+	commitType := strings.ToLower(matches[1])
+	if !knownChangelogType(commitType) {
+		return "", entry
+	}
+	entry.Scope = matches[2]
+	entry.Subject = matches[4]
+	entry.Breaking = matches[3] == "!" || strings.Contains(commit.Message, "BREAKING CHANGE:")
+	return commitType, entry
+}
+
+func knownChangelogType(commitType string) bool {
+	for _, t := range changelogTypes {
+		if t.Type != "" && t.Type == commitType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sourceRepositoryFunc func(ctx context.Context, repository, base, head string) ([]github.Commit, error)
+
+func (f sourceRepositoryFunc) CompareCommits(ctx context.Context, repository, base, head string) ([]github.Commit, error) {
+	return f(ctx, repository, base, head)
+}
+
+func TestNewChangelog(t *testing.T) {
+	spec := func(repository, provider, sha string) artifact.Spec {
+		return artifact.Spec{
+			Application: artifact.Repository{
+				Name:     repository,
+				Provider: provider,
+				SHA:      sha,
+			},
+		}
+	}
+	// commits are returned from oldest to newest
+	commits := []github.Commit{
+		{SHA: "1", Message: "feat(api): add endpoint\n\nSome description", AuthorName: "Foo"},
+		{SHA: "2", Message: "fix: handle nil"},
+		{SHA: "3", Message: "Merge pull request #1 from lunarway/branch"},
+		{SHA: "4", Message: "feat!: drop old endpoint"},
+		{SHA: "5", Message: "unknown: something"},
+		{SHA: "6", Message: "chore: bump\n\nBREAKING CHANGE: requires go 1.22"},
+	}
+	tt := []struct {
+		name   string
+		from   artifact.Spec
+		to     artifact.Spec
+		groups []ChangelogGroup
+		err    error
+	}{
+		{
+			name: "grouped by type",
+			from: spec("product", "GitHub", "base"),
+			to:   spec("product", "GitHub", "head"),
+			groups: []ChangelogGroup{
+				{
+					Type:  "feat",
+					Title: "Features",
+					Commits: []ChangelogCommit{
+						{SHA: "4", Subject: "drop old endpoint", Breaking: true},
+						{SHA: "1", Scope: "api", Subject: "add endpoint", AuthorName: "Foo"},
+					},
+				},
+				{
+					Type:  "fix",
+					Title: "Bug Fixes",
+					Commits: []ChangelogCommit{
+						{SHA: "2", Subject: "handle nil"},
+					},
+				},
+				{
+					Type:  "chore",
+					Title: "Chores",
+					Commits: []ChangelogCommit{
+						{SHA: "6", Subject: "bump", Breaking: true},
+					},
+				},
+				{
+					Type:  "",
+					Title: "Other Changes",
+					Commits: []ChangelogCommit{
+						{SHA: "5", Subject: "unknown: something"},
+						{SHA: "3", Subject: "Merge pull request #1 from lunarway/branch"},
+					},
+				},
+			},
+		},
+		{
+			name: "same sha",
+			from: spec("product", "github", "head"),
+			to:   spec("product", "github", "head"),
+		},
+		{
+			name: "unsupported provider",
+			from: spec("product", "bitbucket", "base"),
+			to:   spec("product", "bitbucket", "head"),
+			err:  ErrChangelogNotSupported,
+		},
+		{
+			name: "different repositories",
+			from: spec("product", "github", "base"),
+			to:   spec("other", "github", "head"),
+			err:  ErrChangelogNotSupported,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sourceRepository := sourceRepositoryFunc(func(ctx context.Context, repository, base, head string) ([]github.Commit, error) {
+				assert.Equal(t, "product", repository, "repository not as expected")
+				assert.Equal(t, "base", base, "base not as expected")
+				assert.Equal(t, "head", head, "head not as expected")
+				return commits, nil
+			})
+
+			changelog, err := NewChangelog(context.Background(), sourceRepository, tc.from, tc.to)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.groups, changelog.Groups, "groups not as expected")
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// ErrDeadLetterNotFound should be returned by implementations of
+// DeadLetterStorage to indicate that a dead letter is not known.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a message dropped by the broker after failed handlings along
+// with the error of its last handling.
+type DeadLetter struct {
+	ID          string `json:"id,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Service     string `json:"service,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	// PartitionKey is the partition key of the message to keep its order with
+	// other messages when replayed.
+	PartitionKey string    `json:"partitionKey,omitempty"`
+	DroppedAt    time.Time `json:"droppedAt,omitempty"`
+}
+
+type DeadLetterStorage interface {
+	// Add stores a new dead letter.
+	Add(ctx context.Context, letter DeadLetter) error
+
+	// Get returns the dead letter with id. If it is not known
+	// ErrDeadLetterNotFound is returned.
+	Get(ctx context.Context, id string) (DeadLetter, error)
+
+	// List returns all dead letters ordered by when they were dropped.
+	List(ctx context.Context) ([]DeadLetter, error)
+
+	// Remove deletes the dead letter with id. It returns ErrDeadLetterNotFound
+	// if id is not known.
+	Remove(ctx context.Context, id string) error
+}
+
+// DeadLetterMessage stores a message of msgType dropped by the broker after
+// failing with err. It is a no-op if no DeadLetters storage is configured.
+func (s *Service) DeadLetterMessage(ctx context.Context, msgType string, body []byte, err error) error {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.DeadLetterMessage")
+	defer span.End()
+	if s.DeadLetters == nil {
+		return nil
+	}
+	id, idErr := uuid.NewRandom()
+	if idErr != nil {
+		return errors.WithMessage(idErr, "generate dead letter id")
+	}
+	letter := DeadLetter{
+		ID:           id.String(),
+		Type:         msgType,
+		Body:         body,
+		Error:        err.Error(),
+		PartitionKey: partitionKey(msgType, body),
+		DroppedAt:    time.Now(),
+	}
+	var event GenericEvent
+	// the body is stored as is even if it does not describe a service
+	if event.Unmarshal(body) == nil {
+		letter.Service = event.Service
+		letter.Environment = event.Environment
+	}
+	storeErr := s.DeadLetters.Add(ctx, letter)
+	if storeErr != nil {
+		return errors.WithMessagef(storeErr, "store dead letter of type '%s'", msgType)
+	}
+	log.WithContext(ctx).Infof("flow: dead letter '%s' stored for message of type '%s'", letter.ID, msgType)
+	return nil
+}
+
+// partitionKey returns the partition key of a message of msgType with body. It
+// is empty if the message is not partitioned or cannot be unmarshalled.
+func partitionKey(msgType string, body []byte) string {
+	var event interface {
+		Unmarshal([]byte) error
+		PartitionKey() string
+	}
+	switch msgType {
+	case ReleaseArtifactIDEvent{}.Type():
+		event = &ReleaseArtifactIDEvent{}
+	case NewArtifactEvent{}.Type():
+		event = &NewArtifactEvent{}
+	default:
+		return ""
+	}
+	if event.Unmarshal(body) != nil {
+		return ""
+	}
+	return event.PartitionKey()
+}
+
+// ListDeadLetters returns all stored dead letters. If no DeadLetters storage is
+// configured no dead letters are returned.
+func (s *Service) ListDeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ListDeadLetters")
+	defer span.End()
+	if s.DeadLetters == nil {
+		return nil, nil
+	}
+	return s.DeadLetters.List(ctx)
+}
+
+// ReplayDeadLetter publishes the message of the dead letter with id again and
+// removes it from the store. If the message fails again it is stored as a new
+// dead letter.
+//
+// The release of a replayed release event is moved back to queued as it was
+// marked as failed when the event was dropped.
+func (s *Service) ReplayDeadLetter(ctx context.Context, id string) (DeadLetter, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReplayDeadLetter")
+	defer span.End()
+	if s.DeadLetters == nil {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+	letter, err := s.DeadLetters.Get(ctx, id)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	// dead letters stored before partition keys were recorded
+	if letter.PartitionKey == "" {
+		letter.PartitionKey = partitionKey(letter.Type, letter.Body)
+	}
+	var releaseID string
+	if letter.Type == (ReleaseArtifactIDEvent{}).Type() {
+		var event ReleaseArtifactIDEvent
+		if event.Unmarshal(letter.Body) == nil {
+			releaseID = event.ReleaseID
+		}
+	}
+	// the release is requeued before publishing as the event may be handled
+	// before the publish returns
+	s.requeueRelease(ctx, releaseID, fmt.Sprintf("dead letter '%s' replayed", id))
+	err = s.PublishDeadLetter(ctx, letter)
+	if err != nil {
+		s.transitionRelease(ctx, releaseID, ReleaseStateFailed, fmt.Sprintf("publish event: %v", err))
+		return DeadLetter{}, errors.WithMessagef(err, "publish message of type '%s'", letter.Type)
+	}
+	err = s.DeadLetters.Remove(ctx, id)
+	if err != nil {
+		// the message is already published so the replay is reported as
+		// successful
+		log.WithContext(ctx).Errorf("flow: replay dead letter '%s': remove failed: %v", id, err)
+	}
+	log.WithContext(ctx).Infof("flow: dead letter '%s' of type '%s' replayed", id, letter.Type)
+	return letter, nil
+}
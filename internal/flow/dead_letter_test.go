@@ -0,0 +1,163 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDeadLetters is a DeadLetterStorage keeping dead letters in a map.
+type memoryDeadLetters map[string]DeadLetter
+
+func (m memoryDeadLetters) Add(ctx context.Context, letter DeadLetter) error {
+	m[letter.ID] = letter
+	return nil
+}
+
+func (m memoryDeadLetters) Get(ctx context.Context, id string) (DeadLetter, error) {
+	letter, ok := m[id]
+	if !ok {
+		return DeadLetter{}, ErrDeadLetterNotFound
+	}
+	return letter, nil
+}
+
+func (m memoryDeadLetters) List(ctx context.Context) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	for _, letter := range m {
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+func (m memoryDeadLetters) Remove(ctx context.Context, id string) error {
+	if _, ok := m[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(m, id)
+	return nil
+}
+
+func TestService_ReplayDeadLetter(t *testing.T) {
+	body := []byte(`{"releaseId":"1","service":"product","environment":"dev","artifactId":"master-1-2"}`)
+	tt := []struct {
+		name       string
+		publishErr error
+		err        string
+		remaining  int
+		state      ReleaseState
+	}{
+		{
+			name:      "published",
+			remaining: 0,
+			state:     ReleaseStateQueued,
+		},
+		{
+			name:       "publish failed",
+			publishErr: errors.New("broker closed"),
+			err:        "publish message of type 'release.artifactId': broker closed",
+			remaining:  1,
+			state:      ReleaseStateFailed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := make(memoryDeadLetters)
+			releases := &memoryReleases{}
+			var published []DeadLetter
+			s := Service{
+				Tracer:      tracing.NewNoop(),
+				DeadLetters: store,
+				Releases:    releases,
+				PublishDeadLetter: func(ctx context.Context, letter DeadLetter) error {
+					// the release must be queued before the event is published
+					status, err := releases.Get(ctx, "1")
+					require.NoError(t, err, "unexpected release status error")
+					assert.Equal(t, ReleaseStateQueued, status.State, "release state when published")
+					published = append(published, letter)
+					return tc.publishErr
+				},
+			}
+			require.NoError(t, releases.Create(ctx, ReleaseStatus{ID: "1", State: ReleaseStateQueued}))
+			err := s.DeadLetterMessage(ctx, "release.artifactId", body, errors.New("clone failed"))
+			require.NoError(t, err, "unexpected dead letter error")
+			s.ReleaseDropped(ctx, "release.artifactId", body, errors.New("clone failed"))
+			require.NoError(t, err, "unexpected dead letter error")
+			letters, err := s.ListDeadLetters(ctx)
+			require.NoError(t, err, "unexpected list error")
+			require.Len(t, letters, 1)
+			assert.Equal(t, "product", letters[0].Service, "service")
+			assert.Equal(t, "dev", letters[0].Environment, "environment")
+			assert.Equal(t, "clone failed", letters[0].Error, "error")
+			assert.Equal(t, "dev/product", letters[0].PartitionKey, "partition key")
+
+			letter, err := s.ReplayDeadLetter(ctx, letters[0].ID)
+
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err, "unexpected replay error")
+				assert.Equal(t, body, letter.Body, "body")
+			}
+			require.Len(t, published, 1, "published messages")
+			assert.Equal(t, "release.artifactId", published[0].Type, "published type")
+			assert.Equal(t, "dev/product", published[0].PartitionKey, "published partition key")
+			assert.Len(t, store, tc.remaining, "remaining dead letters")
+			status, err := releases.Get(ctx, "1")
+			require.NoError(t, err, "unexpected release status error")
+			assert.Equal(t, tc.state, status.State, "release state")
+		})
+	}
+}
+
+func TestService_ReplayDeadLetter_notFound(t *testing.T) {
+	s := Service{
+		Tracer: tracing.NewNoop(),
+	}
+	_, err := s.ReplayDeadLetter(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+}
+
+func TestPartitionKey(t *testing.T) {
+	tt := []struct {
+		name    string
+		msgType string
+		body    string
+		key     string
+	}{
+		{
+			name:    "release",
+			msgType: ReleaseArtifactIDEvent{}.Type(),
+			body:    `{"service":"product","environment":"dev"}`,
+			key:     "dev/product",
+		},
+		{
+			name:    "new artifact",
+			msgType: NewArtifactEvent{}.Type(),
+			body:    `{"service":"product","artifactId":"master-1-2"}`,
+			key:     "product",
+		},
+		{
+			name:    "unknown type",
+			msgType: "unknown",
+			body:    `{"service":"product"}`,
+			key:     "",
+		},
+		{
+			name:    "invalid body",
+			msgType: ReleaseArtifactIDEvent{}.Type(),
+			body:    `not json`,
+			key:     "",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.key, partitionKey(tc.msgType, []byte(tc.body)))
+		})
+	}
+}
@@ -3,7 +3,8 @@ package flow
 import (
 	"context"
 	"fmt"
-	"regexp"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -11,6 +12,7 @@ import (
 	"github.com/lunarway/release-manager/internal/commitinfo"
 	"github.com/lunarway/release-manager/internal/git"
 	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/layout"
 	"github.com/lunarway/release-manager/internal/log"
 	"github.com/lunarway/release-manager/internal/tracing"
 	"github.com/pkg/errors"
@@ -44,7 +46,7 @@ func (s *Service) DescribeRelease(ctx context.Context, environment, service stri
 	defer close(ctx)
 
 	log.WithContext(ctx).Debugf("Cloning source config repo into %s", sourceConfigRepoPath)
-	sourceRepo, err := s.Git.Clone(ctx, sourceConfigRepoPath)
+	sourceRepo, err := s.configRepo(environment).Clone(ctx, sourceConfigRepoPath)
 	if err != nil {
 		return DescribeReleaseResponse{}, errors.WithMessagef(err, "clone into '%s'", sourceConfigRepoPath)
 	}
@@ -59,7 +61,7 @@ func (s *Service) DescribeRelease(ctx context.Context, environment, service stri
 	var releases []Release
 
 	for currentOffset := 0; currentOffset < count; currentOffset++ {
-		hash, err := s.Git.LocateServiceReleaseRollbackSkip(ctx, sourceRepo, environment, service, uint(currentOffset))
+		hash, err := s.configRepo(environment).LocateServiceReleaseRollbackSkip(ctx, sourceRepo, environment, service, uint(currentOffset))
 		if err != nil {
 			if errors.Is(err, git.ErrReleaseNotFound) {
 				break
@@ -72,24 +74,28 @@ func (s *Service) DescribeRelease(ctx context.Context, environment, service stri
 			return DescribeReleaseResponse{}, errors.WithMessagef(err, "get commit at hash '%s'", hash)
 		}
 
-		commitInfo, err := commitinfo.ParseCommitInfo(commitObj.Message)
+		commitInfo, err := serviceCommitInfo(commitObj.Message, environment, service)
 		if err != nil {
 			return DescribeReleaseResponse{}, errors.WithMessagef(err, "parse commit info at hash '%s'", hash)
 		}
 
-		namespace, err := findNamespaceFromCommit(ctx, s.Tracer, commitObj, s.ArtifactFileName)
+		namespace, err := findNamespaceFromCommit(ctx, s.Tracer, s.layout(), commitObj, s.ArtifactFileName, environment, service)
 		if err != nil {
 			return DescribeReleaseResponse{}, errors.WithMessagef(err, "could not find namespace for %s", commitObj.Hash.String())
 		}
 
-		err = s.Git.Checkout(ctx, sourceConfigRepoPath, hash)
+		err = s.configRepo(environment).Checkout(ctx, sourceConfigRepoPath, hash)
 		if err != nil {
 			return DescribeReleaseResponse{}, errors.WithMessagef(err, "checkout of commit %s", hash)
 		}
-		spec, err := envSpec(sourceConfigRepoPath, s.ArtifactFileName, service, environment, namespace)
+		spec, err := s.envSpec(sourceConfigRepoPath, service, environment, namespace)
 		if err != nil {
 			return DescribeReleaseResponse{}, errors.WithMessagef(err, "reading artifact for commit %s", hash)
 		}
+		// layouts without namespaces only know the namespace from the artifact
+		if !s.layout().HasNamespace() {
+			namespace = firstNonEmpty(spec.Namespace, environment)
+		}
 
 		releases = append(releases, Release{
 			Artifact:          spec,
@@ -102,7 +108,7 @@ func (s *Service) DescribeRelease(ctx context.Context, environment, service stri
 		})
 
 		// checkout master again to reset HEAD
-		err = s.Git.Checkout(ctx, sourceConfigRepoPath, masterHead.Hash())
+		err = s.configRepo(environment).Checkout(ctx, sourceConfigRepoPath, masterHead.Hash())
 		if err != nil {
 			return DescribeReleaseResponse{}, errors.WithMessage(err, "checkout master")
 		}
@@ -113,20 +119,41 @@ func (s *Service) DescribeRelease(ctx context.Context, environment, service stri
 	}, nil
 }
 
-func findNamespaceFromCommit(ctx context.Context, tracer tracing.Tracer, commitObj *object.Commit, artifactFileName string) (string, error) {
+// serviceCommitInfo returns the commit info of service in environment from a
+// release commit message. Batch release commits contain the releases of
+// several services.
+func serviceCommitInfo(commitMessage, environment, service string) (commitinfo.CommitInfo, error) {
+	commitInfos, err := commitinfo.ParseCommitInfos(commitMessage)
+	if err != nil {
+		return commitinfo.CommitInfo{}, err
+	}
+	for _, commitInfo := range commitInfos {
+		if strings.EqualFold(commitInfo.Environment, environment) && strings.EqualFold(commitInfo.Service, service) {
+			return commitInfo, nil
+		}
+	}
+	return commitinfo.CommitInfo{}, errors.Errorf("no release of service '%s' in environment '%s'", service, environment)
+}
+
+// findNamespaceFromCommit returns the namespace of the release of service in
+// environment changed by commitObj. For layouts without namespaces an empty
+// namespace is returned.
+func findNamespaceFromCommit(ctx context.Context, tracer tracing.Tracer, l *layout.Layout, commitObj *object.Commit, artifactFileName, environment, service string) (string, error) {
 	span, _ := tracer.FromCtx(ctx, "flow.findNamespace")
 	defer span.End()
 	span.SetAttributes(attribute.String("gitcommit", commitObj.Hash.String()))
 
-	r := regexp.MustCompile(fmt.Sprintf(`^(?P<environment>[^/]+)/releases/(?P<namespace>[^/]+)/(?P<service>[^/]+)/%s$`, regexp.QuoteMeta(artifactFileName)))
 	stats, err := commitObj.Stats()
 	if err != nil {
 		return "", errors.WithMessagef(err, "could not find commit stats")
 	}
 	for _, stat := range stats {
-		match := r.FindStringSubmatch(stat.Name)
-		if match != nil {
-			return match[2], nil
+		if path.Base(stat.Name) != artifactFileName {
+			continue
+		}
+		location, ok := l.ParseReleasePath(path.Dir(stat.Name))
+		if ok && location.Environment == environment && location.Service == service {
+			return location.Namespace, nil
 		}
 	}
 
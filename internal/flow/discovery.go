@@ -0,0 +1,138 @@
+package flow
+
+import (
+	"context"
+	"os"
+	"sort"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/layout"
+	"github.com/pkg/errors"
+)
+
+var ErrUnknownNamespace = errors.New("unknown namespace")
+
+// Environments returns the names of all environments with releases in the
+// config repository, ie. all directories matching the environment of the
+// release layout, e.g. top level directories containing a releases directory.
+func (s *Service) Environments(ctx context.Context) ([]string, error) {
+	span, _ := s.Tracer.FromCtx(ctx, "flow.Environments")
+	defer span.End()
+	environments, err := s.environments()
+	if err != nil {
+		return nil, errors.WithMessage(err, "read environments")
+	}
+	return environments, nil
+}
+
+// Namespaces returns the namespaces with releases in environment. If the
+// environment is not known ErrUnknownEnvironment is returned.
+func (s *Service) Namespaces(ctx context.Context, environment string) ([]string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.Namespaces")
+	defer span.End()
+	if !s.layout().HasNamespace() {
+		locations, err := s.environmentReleases(ctx, environment)
+		if err != nil {
+			return nil, err
+		}
+		var namespaces []string
+		for _, location := range locations {
+			if !contains(namespaces, location.Namespace) {
+				namespaces = append(namespaces, location.Namespace)
+			}
+		}
+		sort.Strings(namespaces)
+		return namespaces, nil
+	}
+	namespaces, err := s.layout().Namespaces(s.configRepo(environment).MasterPath(), environment)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrUnknownEnvironment
+		}
+		return nil, errors.WithMessagef(err, "read namespaces of environment '%s'", environment)
+	}
+	return namespaces, nil
+}
+
+// Services returns the services released to namespace in environment. If the
+// environment is not known ErrUnknownEnvironment is returned and if the
+// namespace is not known ErrUnknownNamespace.
+func (s *Service) Services(ctx context.Context, environment, namespace string) ([]string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.Services")
+	defer span.End()
+	namespaces, err := s.Namespaces(ctx, environment)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(namespaces, namespace) {
+		return nil, ErrUnknownNamespace
+	}
+	var locations []layout.Location
+	if s.layout().HasNamespace() {
+		locations, err = s.layout().Releases(s.configRepo(environment).MasterPath(), layout.Location{
+			Environment: environment,
+			Namespace:   namespace,
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "read services of namespace '%s' in environment '%s'", namespace, environment)
+		}
+	} else {
+		locations, err = s.environmentReleases(ctx, environment)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var services []string
+	for _, location := range locations {
+		if location.Namespace == namespace && !contains(services, location.Service) {
+			services = append(services, location.Service)
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// environmentReleases returns the releases in environment for layouts without
+// namespaces. The namespace of each release is read from its artifact
+// defaulting to the environment. If the environment is not known
+// ErrUnknownEnvironment is returned.
+func (s *Service) environmentReleases(ctx context.Context, environment string) ([]layout.Location, error) {
+	environments, err := s.Environments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(environments, environment) {
+		return nil, ErrUnknownEnvironment
+	}
+	locations, err := s.layout().Releases(s.configRepo(environment).MasterPath(), layout.Location{
+		Environment: environment,
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "read releases of environment '%s'", environment)
+	}
+	var releases []layout.Location
+	for _, location := range locations {
+		spec, err := s.envSpec(s.configRepo(environment).MasterPath(), location.Service, environment, "")
+		if err != nil {
+			if errors.Is(err, artifact.ErrFileNotFound) {
+				continue
+			}
+			return nil, errors.WithMessagef(err, "read artifact of service '%s' in environment '%s'", location.Service, environment)
+		}
+		location.Namespace = spec.Namespace
+		if location.Namespace == "" {
+			location.Namespace = environment
+		}
+		releases = append(releases, location)
+	}
+	return releases, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
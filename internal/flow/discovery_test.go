@@ -0,0 +1,126 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/layout"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("MasterPath").Return("testdata")
+	s := Service{
+		Git:    &gitService,
+		Tracer: tracing.NewNoop(),
+	}
+	ctx := context.Background()
+
+	t.Run("environments", func(t *testing.T) {
+		environments, err := s.Environments(ctx)
+		assert.NoError(t, err, "unexpected error")
+		assert.Equal(t, []string{"dev"}, environments)
+	})
+
+	tt := []struct {
+		name        string
+		environment string
+		namespace   string
+		namespaces  []string
+		services    []string
+		err         error
+	}{
+		{
+			name:        "known namespace",
+			environment: "dev",
+			namespace:   "other",
+			namespaces:  []string{"dev", "other"},
+			services:    []string{"a"},
+		},
+		{
+			name:        "unknown environment",
+			environment: "prod",
+			namespace:   "prod",
+			err:         ErrUnknownEnvironment,
+		},
+		{
+			name:        "unknown namespace",
+			environment: "dev",
+			namespace:   "unknown",
+			namespaces:  []string{"dev", "other"},
+			err:         ErrUnknownNamespace,
+		},
+		{
+			name:        "path traversal vulnerability",
+			environment: "../..",
+			namespace:   "dev",
+			err:         ErrUnknownEnvironment,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaces, err := s.Namespaces(ctx, tc.environment)
+			if tc.namespaces == nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err, "unexpected error")
+			}
+			assert.Equal(t, tc.namespaces, namespaces, "namespaces not as expected")
+
+			services, err := s.Services(ctx, tc.environment, tc.namespace)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err, "unexpected error")
+			}
+			assert.Equal(t, tc.services, services, "services not as expected")
+		})
+	}
+}
+
+func TestDiscovery_layoutWithoutNamespaces(t *testing.T) {
+	root := t.TempDir()
+	for path, namespace := range map[string]string{
+		"apps/product/dev":  "",
+		"apps/product/prod": "",
+		"apps/api/dev":      "team",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, path), os.ModePerm))
+		spec := fmt.Sprintf(`{"id": "master-1234-5678", "namespace": "%s"}`, namespace)
+		require.NoError(t, os.WriteFile(filepath.Join(root, path, "artifact.json"), []byte(spec), 0644))
+	}
+	l, err := layout.New("apps/{service}/{environment}", "clusters/{environment}")
+	require.NoError(t, err, "unexpected layout error")
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("MasterPath").Return(root)
+	s := Service{
+		ArtifactFileName: "artifact.json",
+		Git:              &gitService,
+		Tracer:           tracing.NewNoop(),
+		Layout:           l,
+	}
+	ctx := context.Background()
+
+	environments, err := s.Environments(ctx)
+	require.NoError(t, err, "unexpected environments error")
+	assert.Equal(t, []string{"dev", "prod"}, environments, "environments not as expected")
+
+	namespaces, err := s.Namespaces(ctx, "dev")
+	require.NoError(t, err, "unexpected namespaces error")
+	assert.Equal(t, []string{"dev", "team"}, namespaces, "namespaces not as expected")
+
+	services, err := s.Services(ctx, "dev", "team")
+	require.NoError(t, err, "unexpected services error")
+	assert.Equal(t, []string{"api"}, services, "services not as expected")
+
+	_, err = s.Namespaces(ctx, "staging")
+	assert.ErrorIs(t, err, ErrUnknownEnvironment, "unknown environment error not as expected")
+}
@@ -0,0 +1,257 @@
+package flow
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// DoraMetrics are the DORA metrics of the deployments of a service, squad or
+// environment over a time window.
+type DoraMetrics struct {
+	Environment string
+	// Service is empty for squad and environment metrics.
+	Service string
+	// Squad is empty for environment metrics.
+	Squad string
+
+	Deployments int
+	// DeploymentFrequency is the average number of deployments per day.
+	DeploymentFrequency float64
+	// LeadTime is the median duration from the build of an artifact started to
+	// it was released. Rollbacks are not included.
+	LeadTime time.Duration
+	// Failures is the number of deployments that were rolled back or reported
+	// as failed by the daemon.
+	Failures int
+	// ChangeFailureRate is the ratio of deployments that failed.
+	ChangeFailureRate float64
+	// TimeToRestore is the median duration from a failed deployment to the next
+	// deployment of the service.
+	TimeToRestore time.Duration
+}
+
+// DoraReport is the DORA metrics of deployments between From and To.
+type DoraReport struct {
+	From         time.Time
+	To           time.Time
+	Services     []DoraMetrics
+	Squads       []DoraMetrics
+	Environments []DoraMetrics
+}
+
+// deployment is a single release of an artifact found in the config
+// repository.
+type deployment struct {
+	info       commitinfo.CommitInfo
+	releasedAt time.Time
+	spec       artifact.Spec
+	failed     bool
+	restoredAt time.Time
+}
+
+// DoraMetrics computes the DORA metrics of deployments released within window
+// until now.
+//
+// Deployments are read from the release commits of the config repository.
+// Lead times are based on the build start time of artifacts and a deployment
+// is considered failed if it is rolled back or the daemon reported errors for
+// it after it was pushed.
+func (s *Service) DoraMetrics(ctx context.Context, window time.Duration) (DoraReport, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.DoraMetrics")
+	defer span.End()
+
+	to := time.Now()
+	from := to.Add(-window)
+	// deployments are grouped by environment and service ordered by newest
+	// first
+	deployments := make(map[string][]*deployment)
+	var keys []string
+	err := s.walkReleases(ctx, func(info commitinfo.CommitInfo, releasedAt time.Time) bool {
+		if releasedAt.Before(from) {
+			return false
+		}
+		if info.Intent.Type == intent.TypeUndeploy {
+			return true
+		}
+		key := info.Environment + "/" + info.Service
+		if _, ok := deployments[key]; !ok {
+			keys = append(keys, key)
+		}
+		deployments[key] = append(deployments[key], &deployment{
+			info:       info,
+			releasedAt: releasedAt,
+		})
+		return true
+	})
+	if err != nil {
+		return DoraReport{}, errors.WithMessage(err, "locate release commits")
+	}
+
+	specs := make(map[string]artifact.Spec)
+	for _, key := range keys {
+		serviceDeployments := deployments[key]
+		for i, d := range serviceDeployments {
+			d.spec = s.doraArtifactSpecification(ctx, specs, d.info.Service, d.info.ArtifactID)
+			// the deployment released after d is at i-1 as deployments are ordered
+			// newest first
+			if i == 0 {
+				d.failed = s.doraDeploymentFailed(ctx, d)
+				continue
+			}
+			next := serviceDeployments[i-1]
+			d.failed = s.doraDeploymentFailed(ctx, d) || rolledBack(d, next)
+			if d.failed {
+				d.restoredAt = next.releasedAt
+			}
+		}
+	}
+
+	report := DoraReport{
+		From: from,
+		To:   to,
+	}
+	days := window.Hours() / 24
+	services := make(map[doraKey][]*deployment)
+	squads := make(map[doraKey][]*deployment)
+	environments := make(map[doraKey][]*deployment)
+	for _, key := range keys {
+		for _, d := range deployments[key] {
+			serviceKey := doraKey{Environment: d.info.Environment, Service: d.info.Service, Squad: d.spec.Squad}
+			squadKey := doraKey{Environment: d.info.Environment, Squad: d.spec.Squad}
+			environmentKey := doraKey{Environment: d.info.Environment}
+			services[serviceKey] = append(services[serviceKey], d)
+			squads[squadKey] = append(squads[squadKey], d)
+			environments[environmentKey] = append(environments[environmentKey], d)
+		}
+	}
+	report.Services = doraMetrics(services, days)
+	report.Squads = doraMetrics(squads, days)
+	report.Environments = doraMetrics(environments, days)
+	return report, nil
+}
+
+// doraKey identifies a group of deployments DORA metrics are computed for.
+type doraKey struct {
+	Environment string
+	Service     string
+	Squad       string
+}
+
+// doraArtifactSpecification returns the specification of an artifact. As
+// artifacts may have been removed from storage errors are logged and an empty
+// specification is returned. This is also the case when no artifact storage is
+// configured.
+func (s *Service) doraArtifactSpecification(ctx context.Context, cache map[string]artifact.Spec, service, artifactID string) artifact.Spec {
+	key := service + "/" + artifactID
+	spec, ok := cache[key]
+	if ok {
+		return spec
+	}
+	if s.Storage == nil {
+		return artifact.Spec{}
+	}
+	spec, err := s.Storage.ArtifactSpecification(ctx, service, artifactID)
+	if err != nil {
+		log.WithContext(ctx).Infof("flow: dora metrics: artifact '%s' of service '%s' not available: %v", artifactID, service, err)
+	}
+	cache[key] = spec
+	return spec
+}
+
+// doraDeploymentFailed reports whether the daemon reported errors for d after
+// it was pushed to the config repository.
+//
+// Release commits do not reference the release that created them so the
+// release created closest to the commit is used. This keeps later releases of
+// the same artifact, e.g. rollbacks, from changing the outcome of d.
+func (s *Service) doraDeploymentFailed(ctx context.Context, d *deployment) bool {
+	if s.Releases == nil {
+		return false
+	}
+	release, err := s.Releases.ClosestByArtifact(ctx, d.info.Environment, d.info.ArtifactID, d.releasedAt)
+	if err != nil {
+		if !errors.Is(err, ErrReleaseStatusNotFound) {
+			log.WithContext(ctx).Errorf("flow: dora metrics: lookup release of artifact '%s' in '%s' failed: %v", d.info.ArtifactID, d.info.Environment, err)
+		}
+		return false
+	}
+	if release.State != ReleaseStateFailed {
+		return false
+	}
+	for _, transition := range release.Transitions {
+		if transition.State == ReleaseStatePushed {
+			return true
+		}
+	}
+	return false
+}
+
+// rolledBack reports whether d was rolled back by next.
+func rolledBack(d, next *deployment) bool {
+	return next.info.Intent.Type == intent.TypeRollback && strings.EqualFold(next.info.Intent.Rollback.PreviousArtifactID, d.info.ArtifactID)
+}
+
+// doraMetrics computes the metrics of each group of deployments sorted by
+// environment, service and squad.
+func doraMetrics(groups map[doraKey][]*deployment, days float64) []DoraMetrics {
+	var metrics []DoraMetrics
+	for key, deployments := range groups {
+		m := DoraMetrics{
+			Environment: key.Environment,
+			Service:     key.Service,
+			Squad:       key.Squad,
+			Deployments: len(deployments),
+		}
+		if days > 0 {
+			m.DeploymentFrequency = float64(len(deployments)) / days
+		}
+		var leadTimes, restoreTimes []time.Duration
+		for _, d := range deployments {
+			if d.info.Intent.Type != intent.TypeRollback && !d.spec.CI.Start.IsZero() {
+				leadTimes = append(leadTimes, d.releasedAt.Sub(d.spec.CI.Start))
+			}
+			if d.failed {
+				m.Failures++
+				if !d.restoredAt.IsZero() {
+					restoreTimes = append(restoreTimes, d.restoredAt.Sub(d.releasedAt))
+				}
+			}
+		}
+		m.ChangeFailureRate = float64(m.Failures) / float64(m.Deployments)
+		m.LeadTime = median(leadTimes)
+		m.TimeToRestore = median(restoreTimes)
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Environment != metrics[j].Environment {
+			return metrics[i].Environment < metrics[j].Environment
+		}
+		if metrics[i].Service != metrics[j].Service {
+			return metrics[i].Service < metrics[j].Service
+		}
+		return metrics[i].Squad < metrics[j].Squad
+	})
+	return metrics
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	middle := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[middle-1] + durations[middle]) / 2
+	}
+	return durations[middle]
+}
@@ -0,0 +1,255 @@
+package flow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// specStorage is an ArtifactReadStorage returning specifications by service
+// and artifact ID.
+type specStorage struct {
+	fakeStorage
+	specs map[string]artifact.Spec
+}
+
+func (s *specStorage) ArtifactSpecification(_ context.Context, service, artifactID string) (artifact.Spec, error) {
+	spec, ok := s.specs[service+"/"+artifactID]
+	if !ok {
+		return artifact.Spec{}, ErrArtifactNotFound
+	}
+	return spec, nil
+}
+
+// failedReleases is a ReleaseStatusStorage only knowing of releases by
+// environment and artifact ID.
+type failedReleases []ReleaseStatus
+
+func (f failedReleases) Create(context.Context, ReleaseStatus) error { return nil }
+func (f failedReleases) Get(context.Context, string) (ReleaseStatus, error) {
+	return ReleaseStatus{}, ErrReleaseStatusNotFound
+}
+func (f failedReleases) Update(context.Context, string, func(*ReleaseStatus)) error { return nil }
+func (f failedReleases) LatestByArtifact(ctx context.Context, environment, artifactID string) (ReleaseStatus, error) {
+	return f.ClosestByArtifact(ctx, environment, artifactID, time.Now())
+}
+func (f failedReleases) ClosestByArtifact(_ context.Context, environment, artifactID string, at time.Time) (ReleaseStatus, error) {
+	var closest ReleaseStatus
+	var found bool
+	for _, status := range f {
+		if status.Environment != environment || status.ArtifactID != artifactID {
+			continue
+		}
+		if !found || absDuration(status.CreatedAt.Sub(at)) < absDuration(closest.CreatedAt.Sub(at)) {
+			closest = status
+			found = true
+		}
+	}
+	if !found {
+		return ReleaseStatus{}, ErrReleaseStatusNotFound
+	}
+	return closest, nil
+}
+
+func TestDoraMetrics(t *testing.T) {
+	now := time.Now()
+	ago := func(hours int) time.Time {
+		return now.Add(-time.Duration(hours) * time.Hour)
+	}
+	spec := func(squad string, ciStart time.Time) artifact.Spec {
+		return artifact.Spec{
+			Squad: squad,
+			CI: artifact.CI{
+				Start: ciStart,
+			},
+		}
+	}
+	storage := &specStorage{
+		specs: map[string]artifact.Spec{
+			"a/master-1": spec("alpha", ago(12)),
+			"a/master-2": spec("alpha", ago(9)),
+			"a/master-3": spec("alpha", ago(5)),
+			"a/master-4": spec("alpha", ago(4)),
+			"b/master-1": spec("beta", ago(6)),
+		},
+	}
+	releases := failedReleases{
+		{
+			Environment: "prod",
+			ArtifactID:  "master-3",
+			CreatedAt:   ago(4).Add(-time.Minute),
+			State:       ReleaseStateFailed,
+			Transitions: []ReleaseTransition{
+				{State: ReleaseStateQueued},
+				{State: ReleaseStatePushed},
+				{State: ReleaseStateFailed},
+			},
+		},
+		// failed before it was pushed so never deployed
+		{
+			Environment: "prod",
+			ArtifactID:  "master-4",
+			CreatedAt:   ago(3).Add(-time.Minute),
+			State:       ReleaseStateFailed,
+			Transitions: []ReleaseTransition{
+				{State: ReleaseStateQueued},
+				{State: ReleaseStateFailed},
+			},
+		},
+	}
+	// release commits from newest to oldest
+	type release struct {
+		info       commitinfo.CommitInfo
+		releasedAt time.Time
+	}
+	history := []release{
+		{commitinfo.CommitInfo{Environment: "prod", Service: "b", ArtifactID: "master-1"}, ago(2)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-4"}, ago(3)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-3"}, ago(4)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "c", Intent: intent.NewUndeploy()}, ago(5)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1", Intent: intent.NewRollback("master-2")}, ago(6)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-2"}, ago(8)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1"}, ago(10)},
+		// outside the window
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-0"}, ago(48)},
+	}
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("WalkReleases", mock.Anything, mock.Anything).Return(func(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+		for _, r := range history {
+			if !f(r.info, r.releasedAt) {
+				return nil
+			}
+		}
+		return nil
+	})
+	s := Service{
+		Git:      &gitService,
+		Tracer:   tracing.NewNoop(),
+		Storage:  storage,
+		Releases: releases,
+	}
+
+	report, err := s.DoraMetrics(context.Background(), 24*time.Hour)
+
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, 24*time.Hour, report.To.Sub(report.From), "window not as expected")
+	serviceA := DoraMetrics{
+		Environment:         "prod",
+		Service:             "a",
+		Squad:               "alpha",
+		Deployments:         5,
+		DeploymentFrequency: 5,
+		LeadTime:            time.Hour,
+		Failures:            2,
+		ChangeFailureRate:   0.4,
+		TimeToRestore:       90 * time.Minute,
+	}
+	serviceB := DoraMetrics{
+		Environment:         "prod",
+		Service:             "b",
+		Squad:               "beta",
+		Deployments:         1,
+		DeploymentFrequency: 1,
+		LeadTime:            4 * time.Hour,
+	}
+	assert.Equal(t, []DoraMetrics{serviceA, serviceB}, report.Services, "service metrics not as expected")
+	squadA := serviceA
+	squadA.Service = ""
+	squadB := serviceB
+	squadB.Service = ""
+	assert.Equal(t, []DoraMetrics{squadA, squadB}, report.Squads, "squad metrics not as expected")
+	assert.Equal(t, []DoraMetrics{
+		{
+			Environment:         "prod",
+			Deployments:         6,
+			DeploymentFrequency: 6,
+			LeadTime:            time.Hour,
+			Failures:            2,
+			ChangeFailureRate:   2.0 / 6,
+			TimeToRestore:       90 * time.Minute,
+		},
+	}, report.Environments, "environment metrics not as expected")
+}
+
+func TestDoraMetrics_rereleasedArtifact(t *testing.T) {
+	now := time.Now()
+	ago := func(hours int) time.Time {
+		return now.Add(-time.Duration(hours) * time.Hour)
+	}
+	pushed := []ReleaseTransition{
+		{State: ReleaseStateQueued},
+		{State: ReleaseStatePushed},
+	}
+	// master-1 is deployed successfully and later fails when released again
+	releases := failedReleases{
+		{Environment: "prod", ArtifactID: "master-1", CreatedAt: ago(10).Add(-time.Minute), State: ReleaseStatePushed, Transitions: pushed},
+		{Environment: "prod", ArtifactID: "master-2", CreatedAt: ago(8).Add(-time.Minute), State: ReleaseStatePushed, Transitions: pushed},
+		{Environment: "prod", ArtifactID: "master-1", CreatedAt: ago(6).Add(-time.Minute), State: ReleaseStateFailed, Transitions: append(pushed, ReleaseTransition{State: ReleaseStateFailed})},
+	}
+	history := []struct {
+		info       commitinfo.CommitInfo
+		releasedAt time.Time
+	}{
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1"}, ago(6)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-2"}, ago(8)},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1"}, ago(10)},
+	}
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("WalkReleases", mock.Anything, mock.Anything).Return(func(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+		for _, r := range history {
+			if !f(r.info, r.releasedAt) {
+				return nil
+			}
+		}
+		return nil
+	})
+	s := Service{
+		Git:      &gitService,
+		Tracer:   tracing.NewNoop(),
+		Storage:  &specStorage{},
+		Releases: releases,
+	}
+
+	report, err := s.DoraMetrics(context.Background(), 24*time.Hour)
+
+	require.NoError(t, err, "unexpected error")
+	require.Len(t, report.Services, 1, "service metrics not as expected")
+	assert.Equal(t, 3, report.Services[0].Deployments, "deployments not as expected")
+	assert.Equal(t, 1, report.Services[0].Failures, "only the failed release of master-1 should count")
+}
+
+func TestDoraMetrics_withoutStorage(t *testing.T) {
+	now := time.Now()
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("WalkReleases", mock.Anything, mock.Anything).Return(func(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+		f(commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1"}, now.Add(-time.Hour))
+		return nil
+	})
+	s := Service{
+		Git:    &gitService,
+		Tracer: tracing.NewNoop(),
+	}
+
+	report, err := s.DoraMetrics(context.Background(), 24*time.Hour)
+
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, []DoraMetrics{
+		{
+			Environment:         "prod",
+			Service:             "a",
+			Deployments:         1,
+			DeploymentFrequency: 1,
+		},
+	}, report.Services, "service metrics not as expected")
+}
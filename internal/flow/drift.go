@@ -0,0 +1,409 @@
+package flow
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// DriftType describes how a resource in a cluster differs from the config
+// repository.
+type DriftType string
+
+const (
+	// DriftTypeArtifactMismatch is used when a resource runs another artifact
+	// than the one released in the config repository.
+	DriftTypeArtifactMismatch DriftType = "artifactMismatch"
+	// DriftTypeMissing is used when a resource released in the config
+	// repository is not running in the cluster.
+	DriftTypeMissing DriftType = "missing"
+	// DriftTypeUnknown is used when a release manager controlled resource runs
+	// in the cluster but is not released in the config repository.
+	DriftTypeUnknown DriftType = "unknown"
+	// DriftTypeUnparseable is used when a manifest file released in the config
+	// repository cannot be parsed so drift of its resources cannot be detected.
+	// The Kind of the drift is "File" and the Name is the path of the file in
+	// the config repository.
+	DriftTypeUnparseable DriftType = "unparseable"
+)
+
+// documentSeparator matches the lines separating documents in a YAML file.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// driftKinds are the resource kinds reported by the daemon and thus the only
+// ones drift can be detected for.
+var driftKinds = map[string]bool{
+	"Deployment":  true,
+	"DaemonSet":   true,
+	"StatefulSet": true,
+}
+
+// Drift is a single resource where the cluster state differs from the config
+// repository.
+type Drift struct {
+	Environment        string
+	Namespace          string
+	Kind               string
+	Name               string
+	Service            string
+	Type               DriftType
+	ExpectedArtifactID string
+	ActualArtifactID   string
+	// DetectedAt is the time the drift was first detected. It is kept as long
+	// as the resource is drifting even if the expected or actual artifacts
+	// change.
+	DetectedAt time.Time
+}
+
+func (d Drift) key() string {
+	return strings.Join([]string{d.Namespace, d.Kind, d.Name}, "/")
+}
+
+type NotifyDriftOptions struct {
+	Environment string
+	// Drifts is all currently drifting resources in the environment.
+	Drifts []Drift
+	// Alerts is the drifts that have exceeded the alert threshold since the
+	// last report.
+	Alerts []Drift
+}
+
+// DriftTracker keeps the drift of each environment between cluster state
+// reports.
+type DriftTracker struct {
+	alertAfter time.Duration
+
+	mu           sync.RWMutex
+	environments map[string]map[string]trackedDrift
+}
+
+type trackedDrift struct {
+	Drift
+	alerted bool
+}
+
+// NewDriftTracker allocates a DriftTracker. Drifts lasting longer than
+// alertAfter are reported as alerts once. An alertAfter of zero disables
+// alerts.
+func NewDriftTracker(alertAfter time.Duration) *DriftTracker {
+	return &DriftTracker{
+		alertAfter:   alertAfter,
+		environments: make(map[string]map[string]trackedDrift),
+	}
+}
+
+// update replaces the drifts of environment keeping the detection time of
+// already known drifts. It returns the current drifts and the ones that
+// should be alerted.
+func (t *DriftTracker) update(environment string, drifts []Drift, now time.Time) ([]Drift, []Drift) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous := t.environments[environment]
+	next := make(map[string]trackedDrift, len(drifts))
+	var current, alerts []Drift
+	for _, d := range drifts {
+		tracked := trackedDrift{Drift: d}
+		tracked.DetectedAt = now
+		if p, ok := previous[d.key()]; ok {
+			tracked.DetectedAt = p.DetectedAt
+			tracked.alerted = p.alerted
+		}
+		if t.alertAfter > 0 && !tracked.alerted && now.Sub(tracked.DetectedAt) >= t.alertAfter {
+			tracked.alerted = true
+			alerts = append(alerts, tracked.Drift)
+		}
+		next[d.key()] = tracked
+		current = append(current, tracked.Drift)
+	}
+	t.environments[environment] = next
+	return current, alerts
+}
+
+// drifts returns the current drifts of environment or all environments if
+// environment is empty.
+func (t *DriftTracker) drifts(environment string) []Drift {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var drifts []Drift
+	for env, tracked := range t.environments {
+		if environment != "" && env != environment {
+			continue
+		}
+		for _, d := range tracked {
+			drifts = append(drifts, d.Drift)
+		}
+	}
+	sortDrifts(drifts)
+	return drifts
+}
+
+func sortDrifts(drifts []Drift) {
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Environment != drifts[j].Environment {
+			return drifts[i].Environment < drifts[j].Environment
+		}
+		return drifts[i].key() < drifts[j].key()
+	})
+}
+
+// Drift returns the resources where the cluster state of environment differs
+// from the config repository. If environment is empty drift of all
+// environments is returned.
+func (s *Service) Drift(ctx context.Context, environment string) []Drift {
+	span, _ := s.Tracer.FromCtx(ctx, "flow.Drift")
+	defer span.End()
+	if s.DriftTracker == nil {
+		return nil
+	}
+	return s.DriftTracker.drifts(environment)
+}
+
+// ReportClusterState compares the resources running in an environment with
+// the artifacts released in the config repository and records any drift
+// between them.
+func (s *Service) ReportClusterState(ctx context.Context, report *http.ClusterStateReport) error {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReportClusterState")
+	defer span.End()
+	if s.DriftTracker == nil {
+		return nil
+	}
+	if report.Environment == "" {
+		return errors.New("environment required")
+	}
+	expected, unparseable, err := s.releasedResources(ctx, report.Environment)
+	if err != nil {
+		return errors.WithMessagef(err, "locate released resources in environment '%s'", report.Environment)
+	}
+	drifts := append(detectDrift(report.Environment, expected, report.Resources), unparseable...)
+	current, alerts := s.DriftTracker.update(report.Environment, drifts, time.Now())
+	sortDrifts(current)
+	log.WithContext(ctx).Infof("flow: report cluster state: environment '%s': %d resources reported: %d drifting", report.Environment, len(report.Resources), len(current))
+	if s.NotifyDriftHook != nil {
+		go s.NotifyDriftHook(noCancel{ctx: ctx}, NotifyDriftOptions{
+			Environment: report.Environment,
+			Drifts:      current,
+			Alerts:      alerts,
+		})
+	}
+	return nil
+}
+
+// releasedResource is a resource released to an environment in the config
+// repository.
+type releasedResource struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Service    string
+	ArtifactID string
+}
+
+func (r releasedResource) key() string {
+	return strings.Join([]string{r.Namespace, r.Kind, r.Name}, "/")
+}
+
+// releasedResources returns the resources of kinds reported by the daemon
+// released to environment in the config repository. Manifest files that cannot
+// be parsed are returned as drifts of type DriftTypeUnparseable.
+func (s *Service) releasedResources(ctx context.Context, environment string) ([]releasedResource, []Drift, error) {
+	namespaces, err := s.Namespaces(ctx, environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := s.configRepo(environment).MasterPath()
+	var resources []releasedResource
+	var unparseable []Drift
+	for _, namespace := range namespaces {
+		services, err := s.Services(ctx, environment, namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, service := range services {
+			location := releaseLocation{
+				Environment: environment,
+				Namespace:   namespace,
+				Service:     service,
+			}
+			spec, err := s.releaseSpecification(ctx, location)
+			if err != nil {
+				if errors.Is(err, artifact.ErrFileNotFound) {
+					continue
+				}
+				return nil, nil, errors.WithMessagef(err, "read artifact of service '%s' in namespace '%s'", service, namespace)
+			}
+			path, err := s.releasePath(root, service, environment, namespace)
+			if err != nil {
+				return nil, nil, errors.WithMessage(err, "get release path")
+			}
+			manifests, failedFiles, err := manifestResources(path)
+			if err != nil {
+				return nil, nil, errors.WithMessagef(err, "read manifests of service '%s' in namespace '%s'", service, namespace)
+			}
+			for _, file := range failedFiles {
+				name, err := filepath.Rel(root, file.path)
+				if err != nil {
+					name = file.path
+				}
+				log.WithContext(ctx).Errorf("flow: drift: manifest '%s' of service '%s' in '%s' cannot be parsed: %v", name, service, environment, file.err)
+				unparseable = append(unparseable, Drift{
+					Environment:        environment,
+					Namespace:          namespace,
+					Kind:               "File",
+					Name:               name,
+					Service:            service,
+					Type:               DriftTypeUnparseable,
+					ExpectedArtifactID: spec.ID,
+				})
+			}
+			for _, m := range manifests {
+				if !driftKinds[m.Kind] {
+					continue
+				}
+				resources = append(resources, releasedResource{
+					Kind:       m.Kind,
+					Namespace:  firstNonEmpty(m.Metadata.Namespace, namespace),
+					Name:       m.Metadata.Name,
+					Service:    service,
+					ArtifactID: spec.ID,
+				})
+			}
+		}
+	}
+	return resources, unparseable, nil
+}
+
+type manifestResource struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+// unparseableFile is a YAML file with documents that cannot be parsed.
+type unparseableFile struct {
+	path string
+	err  error
+}
+
+// manifestResources returns all Kubernetes resources in YAML files in
+// directory. Documents that cannot be parsed are skipped and their files
+// returned as unparseable so the remaining documents are still read.
+func manifestResources(directory string) ([]manifestResource, []unparseableFile, error) {
+	var resources []manifestResource
+	var unparseable []unparseableFile
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.WithMessagef(err, "read file '%s'", path)
+		}
+		var fileErr error
+		for _, document := range documentSeparator.Split(string(content), -1) {
+			var resource manifestResource
+			err := yaml.Unmarshal([]byte(document), &resource)
+			if err != nil {
+				if fileErr == nil {
+					fileErr = err
+				}
+				continue
+			}
+			if resource.Kind == "" || resource.Metadata.Name == "" {
+				continue
+			}
+			resources = append(resources, resource)
+		}
+		if fileErr != nil {
+			unparseable = append(unparseable, unparseableFile{path: path, err: fileErr})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resources, unparseable, nil
+}
+
+// detectDrift compares the expected resources from the config repository with
+// the actual resources reported from the cluster.
+func detectDrift(environment string, expected []releasedResource, actual []http.ClusterResource) []Drift {
+	expectedByKey := make(map[string]releasedResource, len(expected))
+	for _, r := range expected {
+		expectedByKey[r.key()] = r
+	}
+	var drifts []Drift
+	seen := make(map[string]bool, len(actual))
+	for _, a := range actual {
+		key := releasedResource{Kind: a.Kind, Namespace: a.Namespace, Name: a.Name}.key()
+		seen[key] = true
+		e, ok := expectedByKey[key]
+		if !ok {
+			drifts = append(drifts, Drift{
+				Environment:      environment,
+				Namespace:        a.Namespace,
+				Kind:             a.Kind,
+				Name:             a.Name,
+				Type:             DriftTypeUnknown,
+				ActualArtifactID: a.ArtifactID,
+			})
+			continue
+		}
+		if e.ArtifactID != a.ArtifactID {
+			drifts = append(drifts, Drift{
+				Environment:        environment,
+				Namespace:          a.Namespace,
+				Kind:               a.Kind,
+				Name:               a.Name,
+				Service:            e.Service,
+				Type:               DriftTypeArtifactMismatch,
+				ExpectedArtifactID: e.ArtifactID,
+				ActualArtifactID:   a.ArtifactID,
+			})
+		}
+	}
+	for _, e := range expected {
+		if seen[e.key()] {
+			continue
+		}
+		drifts = append(drifts, Drift{
+			Environment:        environment,
+			Namespace:          e.Namespace,
+			Kind:               e.Kind,
+			Name:               e.Name,
+			Service:            e.Service,
+			Type:               DriftTypeMissing,
+			ExpectedArtifactID: e.ArtifactID,
+		})
+	}
+	return drifts
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
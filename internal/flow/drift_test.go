@@ -0,0 +1,142 @@
+package flow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/http"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportClusterState(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(path, content string) {
+		path = filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	writeFile("dev/releases/dev/a/artifact.json", `{"id": "master-2-a"}`)
+	writeFile("dev/releases/dev/a/40-deployment.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: a
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: a-worker
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: a
+`)
+	writeFile("dev/releases/dev/b/artifact.json", `{"id": "master-1-b"}`)
+	writeFile("dev/releases/dev/b/40-statefulset.yaml", `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: b
+`)
+	writeFile("dev/releases/dev/c/artifact.json", `{"id": "master-1-c"}`)
+	// documents after an unparseable one are still read
+	writeFile("dev/releases/dev/c/40-deployment.yaml", `{{ invalid
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: c
+`)
+
+	gitService := MockGitService{}
+	gitService.Test(t)
+	gitService.On("MasterPath").Return(root)
+	var notified []NotifyDriftOptions
+	done := make(chan struct{}, 1)
+	s := Service{
+		Git:              &gitService,
+		Tracer:           tracing.NewNoop(),
+		ArtifactFileName: "artifact.json",
+		DriftTracker:     NewDriftTracker(time.Nanosecond),
+		NotifyDriftHook: func(ctx context.Context, options NotifyDriftOptions) {
+			notified = append(notified, options)
+			done <- struct{}{}
+		},
+	}
+	report := http.ClusterStateReport{
+		Environment: "dev",
+		Resources: []http.ClusterResource{
+			{Kind: "Deployment", Namespace: "dev", Name: "a", ArtifactID: "master-2-a"},
+			{Kind: "Deployment", Namespace: "dev", Name: "a-worker", ArtifactID: "master-1-a"},
+			{Kind: "Deployment", Namespace: "dev", Name: "d", ArtifactID: "master-1-d"},
+		},
+	}
+
+	err := s.ReportClusterState(context.Background(), &report)
+	require.NoError(t, err, "unexpected error")
+	<-done
+
+	drifts := s.Drift(context.Background(), "dev")
+	for i := range drifts {
+		assert.False(t, drifts[i].DetectedAt.IsZero(), "detected at not set")
+		drifts[i].DetectedAt = time.Time{}
+	}
+	assert.Equal(t, []Drift{
+		{Environment: "dev", Namespace: "dev", Kind: "Deployment", Name: "a-worker", Service: "a", Type: DriftTypeArtifactMismatch, ExpectedArtifactID: "master-2-a", ActualArtifactID: "master-1-a"},
+		{Environment: "dev", Namespace: "dev", Kind: "Deployment", Name: "c", Service: "c", Type: DriftTypeMissing, ExpectedArtifactID: "master-1-c"},
+		{Environment: "dev", Namespace: "dev", Kind: "Deployment", Name: "d", Type: DriftTypeUnknown, ActualArtifactID: "master-1-d"},
+		{Environment: "dev", Namespace: "dev", Kind: "File", Name: "dev/releases/dev/c/40-deployment.yaml", Service: "c", Type: DriftTypeUnparseable, ExpectedArtifactID: "master-1-c"},
+		{Environment: "dev", Namespace: "dev", Kind: "StatefulSet", Name: "b", Service: "b", Type: DriftTypeMissing, ExpectedArtifactID: "master-1-b"},
+	}, drifts, "drifts not as expected")
+	assert.Empty(t, s.Drift(context.Background(), "prod"), "drift of other environment")
+
+	// a second report resolving the drift alerts on nothing
+	writeFile("dev/releases/dev/c/40-deployment.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: c
+`)
+	report.Resources = []http.ClusterResource{
+		{Kind: "Deployment", Namespace: "dev", Name: "a", ArtifactID: "master-2-a"},
+		{Kind: "Deployment", Namespace: "dev", Name: "a-worker", ArtifactID: "master-2-a"},
+		{Kind: "Deployment", Namespace: "dev", Name: "c", ArtifactID: "master-1-c"},
+		{Kind: "StatefulSet", Namespace: "dev", Name: "b", ArtifactID: "master-1-b"},
+	}
+	err = s.ReportClusterState(context.Background(), &report)
+	require.NoError(t, err, "unexpected error")
+	<-done
+
+	assert.Empty(t, s.Drift(context.Background(), "dev"), "drift not resolved")
+	require.Len(t, notified, 2)
+	assert.Len(t, notified[0].Drifts, 5)
+	assert.Empty(t, notified[1].Drifts)
+	assert.Empty(t, notified[1].Alerts)
+}
+
+func TestDriftTracker_update(t *testing.T) {
+	now := time.Now()
+	drift := Drift{Environment: "dev", Namespace: "dev", Kind: "Deployment", Name: "a", Type: DriftTypeArtifactMismatch}
+	tracker := NewDriftTracker(time.Hour)
+
+	current, alerts := tracker.update("dev", []Drift{drift}, now)
+	assert.Equal(t, now, current[0].DetectedAt, "first detection")
+	assert.Empty(t, alerts, "alerts before threshold")
+
+	current, alerts = tracker.update("dev", []Drift{drift}, now.Add(time.Hour))
+	assert.Equal(t, now, current[0].DetectedAt, "detection time kept")
+	assert.Len(t, alerts, 1, "alerts after threshold")
+
+	_, alerts = tracker.update("dev", []Drift{drift}, now.Add(2*time.Hour))
+	assert.Empty(t, alerts, "alerts are only sent once")
+
+	current, _ = tracker.update("dev", nil, now.Add(3*time.Hour))
+	assert.Empty(t, current, "resolved drift")
+
+	current, alerts = tracker.update("dev", []Drift{drift}, now.Add(4*time.Hour))
+	assert.Equal(t, now.Add(4*time.Hour), current[0].DetectedAt, "detection time reset after resolve")
+	assert.Empty(t, alerts, "alerts after reset")
+}
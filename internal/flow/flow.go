@@ -14,13 +14,14 @@ import (
 	"path/filepath"
 	"time"
 
-	securejoin "github.com/cyphar/filepath-securejoin"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
 	"github.com/lunarway/release-manager/internal/copy"
 	httpinternal "github.com/lunarway/release-manager/internal/http"
 	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/layout"
 	"github.com/lunarway/release-manager/internal/log"
 	"github.com/lunarway/release-manager/internal/policy"
 	"github.com/lunarway/release-manager/internal/slack"
@@ -62,8 +63,70 @@ type Service struct {
 	// Observer records flow operation durations. May be nil; calls must be nil-safe.
 	Observer FlowObserver
 
+	// Releases tracks the lifecycle of releases. May be nil in which case
+	// releases are not tracked.
+	Releases ReleaseStatusStorage
+
+	// DriftTracker keeps drift between cluster state and the config repository.
+	// May be nil in which case cluster state reports are ignored.
+	DriftTracker *DriftTracker
+
+	// SourceRepository provides commits of source repositories for changelogs.
+	// May be nil in which case changelogs are not available.
+	SourceRepository SourceRepository
+
+	// ArtifactVerifier verifies artifacts before their resources are released.
+	// May be nil in which case artifacts are not verified.
+	ArtifactVerifier ArtifactVerifier
+
+	// RequiredStages returns the stages an artifact of service svc must contain
+	// to be released to environment env. May be nil in which case no stages are
+	// required.
+	RequiredStages func(ctx context.Context, svc, env string) ([]string, error)
+
+	// AutoReleasePolicies returns the IDs of the auto-release policies of service
+	// svc releasing to environment env. May be nil in which case undeploys are
+	// not checked against auto-release policies.
+	AutoReleasePolicies func(ctx context.Context, svc, env string) ([]string, error)
+
+	// ValidateManifests controls whether the resources of releases are validated
+	// as Kubernetes manifests before they are committed.
+	ValidateManifests bool
+
+	// InjectAnnotations controls whether the annotations required by the daemon
+	// are set on the workloads of releases before they are committed.
+	InjectAnnotations bool
+
+	// PinImages controls whether container images of releases are pinned to the
+	// image pushed by the artifact before they are committed.
+	PinImages bool
+
+	// ArtifactIndex indexes new artifacts for searching. May be nil in which
+	// case artifacts cannot be searched.
+	ArtifactIndex ArtifactIndex
+
+	// Layout locates releases in the config repository. If nil the default
+	// layout is used.
+	Layout *layout.Layout
+
+	// Repositories are the config repositories by environment. Environments not
+	// in Repositories are released to the default repository in Git.
+	Repositories map[string]GitService
+
+	// CommitWindow is the duration releases to a config repository are
+	// collected for before they are pushed together in a single commit. If zero
+	// each release is committed on its own.
+	CommitWindow time.Duration
+
+	batches releaseBatches
+
+	// DeadLetters stores messages dropped by the broker so they can be replayed.
+	// May be nil in which case dropped messages are lost.
+	DeadLetters DeadLetterStorage
+
 	PublishReleaseArtifactID func(context.Context, ReleaseArtifactIDEvent) error
 	PublishNewArtifact       func(context.Context, NewArtifactEvent) error
+	PublishDeadLetter        func(ctx context.Context, letter DeadLetter) error
 
 	MaxRetries int
 
@@ -78,6 +141,14 @@ type Service struct {
 
 	// NotifyReleaseFailedHook is trigger in a Go routine when a release has failed.
 	NotifyReleaseFailedHook func(ctx context.Context, options NotifyReleaseFailedOptions)
+
+	// NotifyUndeployHook is triggered in a Go routine when a service is
+	// undeployed from an environment.
+	NotifyUndeployHook func(ctx context.Context, options NotifyUndeployOptions)
+
+	// NotifyDriftHook is triggered in a Go routine when a cluster state report
+	// is processed.
+	NotifyDriftHook func(ctx context.Context, options NotifyDriftOptions)
 }
 
 type NotifyReleaseOptions struct {
@@ -87,6 +158,9 @@ type NotifyReleaseOptions struct {
 	Releaser    string
 	Spec        artifact.Spec
 	Intent      intent.Intent
+	// PreviousSpec is the artifact released before Spec. It is empty if the
+	// service was not released to the environment before.
+	PreviousSpec artifact.Spec
 }
 
 type NotifyReleaseSucceededOptions struct {
@@ -118,8 +192,11 @@ type GitService interface {
 	ShallowClone(ctx context.Context, destination string) error
 	MasterPath() string
 	Commit(ctx context.Context, rootPath, changesPath, msg string) error
+	StageChanges(ctx context.Context, rootPath string) (bool, error)
+	DiscardUnstagedChanges(ctx context.Context, rootPath string) error
 	LocateServiceReleaseRollbackSkip(ctx context.Context, r *git.Repository, env, service string, n uint) (plumbing.Hash, error)
 	Checkout(ctx context.Context, rootPath string, hash plumbing.Hash) error
+	WalkReleases(ctx context.Context, f func(info commitinfo.CommitInfo, releasedAt time.Time) bool) error
 }
 
 // retry tries the function f until max attempts is reached.
@@ -243,21 +320,21 @@ type ReleaseSpec struct {
 }
 
 func (s *Service) releaseSpecifications(ctx context.Context, configuredNamespace, service string) ([]ReleaseSpec, error) {
-	directories, err := os.ReadDir(s.Git.MasterPath())
+	environments, err := s.environments()
 	if err != nil {
-		return nil, errors.WithMessagef(err, "read root directories")
+		return nil, errors.WithMessage(err, "read environments")
 	}
 
 	var releases []ReleaseSpec
-	for _, dir := range directories {
+	for _, environment := range environments {
 		// handle default namespaces
 		namespace := configuredNamespace
 		if namespace == "" {
-			namespace = dir.Name()
+			namespace = environment
 		}
 
 		spec, err := s.releaseSpecification(ctx, releaseLocation{
-			Environment: dir.Name(),
+			Environment: environment,
 			Namespace:   namespace,
 			Service:     service,
 		})
@@ -265,12 +342,12 @@ func (s *Service) releaseSpecifications(ctx context.Context, configuredNamespace
 			if errors.Is(err, artifact.ErrFileNotFound) {
 				continue
 			}
-			return nil, errors.WithMessagef(err, "read spec from '%s'", dir.Name())
+			return nil, errors.WithMessagef(err, "read spec from '%s'", environment)
 		}
 
 		releases = append(releases, ReleaseSpec{
 			Spec:        spec,
-			Environment: dir.Name(),
+			Environment: environment,
 		})
 	}
 
@@ -278,7 +355,7 @@ func (s *Service) releaseSpecifications(ctx context.Context, configuredNamespace
 }
 
 func (s *Service) releaseSpecification(ctx context.Context, location releaseLocation) (artifact.Spec, error) {
-	releasePath, err := releasePath(s.Git.MasterPath(), location.Service, location.Environment, location.Namespace)
+	releasePath, err := s.releasePath(s.configRepo(location.Environment).MasterPath(), location.Service, location.Environment, location.Namespace)
 	if err != nil {
 		return artifact.Spec{}, errors.WithMessage(err, "get release path")
 	}
@@ -290,31 +367,41 @@ func (s *Service) releaseSpecification(ctx context.Context, location releaseLoca
 	return spec, nil
 }
 
-func envSpec(root, artifactFileName, service, env, namespace string) (artifact.Spec, error) {
-	releasePath, err := releasePath(root, service, env, namespace)
+func (s *Service) envSpec(root, service, env, namespace string) (artifact.Spec, error) {
+	releasePath, err := s.releasePath(root, service, env, namespace)
 	if err != nil {
 		return artifact.Spec{}, errors.WithMessage(err, "get release path")
 	}
-	return artifact.Get(path.Join(releasePath, artifactFileName))
+	return artifact.Get(path.Join(releasePath, s.ArtifactFileName))
 }
 
-// releasePath returns the path of a specific release.
-func releasePath(root, service, env, namespace string) (string, error) {
-	releasePath := root // start with root
-	pathsToJoin := []string{
-		env,
-		"releases",
-		namespace,
-		service,
-	}
-	var err error
-	for _, p := range pathsToJoin {
-		releasePath, err = securejoin.SecureJoin(releasePath, p)
-		if err != nil {
-			return "", errors.WithMessagef(err, "join '%s' to path", p)
-		}
+var defaultLayout = layout.Default()
+
+// layout returns the configured layout of the config repository falling back
+// to the default layout.
+func (s *Service) layout() *layout.Layout {
+	if s.Layout == nil {
+		return defaultLayout
 	}
-	return releasePath, nil
+	return s.Layout
+}
+
+// releasePath returns the path of a specific release.
+func (s *Service) releasePath(root, service, env, namespace string) (string, error) {
+	return s.layout().ReleasePath(root, layout.Location{
+		Environment: env,
+		Namespace:   namespace,
+		Service:     service,
+	})
+}
+
+// kustomizationPath returns the directory of Flux kustomizations of namespace
+// in env.
+func (s *Service) kustomizationPath(root, env, namespace string) (string, error) {
+	return s.layout().KustomizationPath(root, layout.Location{
+		Environment: env,
+		Namespace:   namespace,
+	})
 }
 
 // PushArtifactToReleaseManager pushes an artifact to the release manager
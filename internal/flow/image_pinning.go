@@ -0,0 +1,233 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// pushData returns the data of the push stage of spec if any.
+func pushData(spec artifact.Spec) (artifact.PushData, bool) {
+	for _, stage := range spec.Stages {
+		if stage.ID != artifact.StageIDPush {
+			continue
+		}
+		data, ok := stage.Data.(artifact.PushData)
+		if !ok || data.Image == "" || data.Tag == "" {
+			return artifact.PushData{}, false
+		}
+		return data, true
+	}
+	return artifact.PushData{}, false
+}
+
+// imageReference is a parsed container image reference of the form
+// <name>[:<tag>][@<digest>].
+type imageReference struct {
+	Name   string
+	Tag    string
+	Digest string
+}
+
+func parseImageReference(ref string) imageReference {
+	var image imageReference
+	if i := strings.Index(ref, "@"); i != -1 {
+		image.Digest = ref[i+1:]
+		ref = ref[:i]
+	}
+	// a colon before the last slash separates a registry port and not a tag
+	if i := strings.LastIndex(ref, ":"); i != -1 && i > strings.LastIndex(ref, "/") {
+		image.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+	image.Name = ref
+	return image
+}
+
+func (i imageReference) String() string {
+	ref := i.Name
+	if i.Tag != "" {
+		ref += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		ref += "@" + i.Digest
+	}
+	return ref
+}
+
+// pinImages rewrites all container image references to the image pushed by
+// push in YAML files in directory. References to the pushed image are pinned to
+// its tag and digest if known. Images of other repositories, e.g. sidecars, are
+// left untouched.
+//
+// A *ManifestValidationError is returned if a reference to the pushed image
+// has another tag or digest than the pushed one, or if no container references
+// the pushed image at all, as the manifests then do not belong to the
+// artifact.
+func pinImages(directory string, push artifact.PushData) error {
+	pinned := imageReference{
+		Name:   push.Image,
+		Tag:    push.Tag,
+		Digest: push.Digest,
+	}
+	var problems []ManifestError
+	referenced := false
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.WithMessagef(err, "read file '%s'", relativePath)
+		}
+		output, changed, fileReferenced, fileProblems, err := pinImagesInManifests(content, pinned)
+		if err != nil {
+			// invalid files are reported by manifest validation
+			return nil
+		}
+		referenced = referenced || fileReferenced
+		for _, p := range fileProblems {
+			p.File = filepath.ToSlash(relativePath)
+			problems = append(problems, p)
+		}
+		if !changed || len(fileProblems) != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return errors.WithMessagef(err, "stat file '%s'", relativePath)
+		}
+		err = os.WriteFile(path, output, info.Mode().Perm())
+		if err != nil {
+			return errors.WithMessagef(err, "write file '%s'", relativePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "walk directory '%s'", directory)
+	}
+	if len(problems) == 0 && !referenced {
+		problems = append(problems, ManifestError{
+			Message: fmt.Sprintf("no container references image '%s' pushed by the artifact", push.Image),
+		})
+	}
+	if len(problems) != 0 {
+		return &ManifestValidationError{
+			Errors: problems,
+		}
+	}
+	return nil
+}
+
+// pinImagesInManifests pins all references to the image of pinned in the
+// YAML documents of content. The re-encoded documents are returned along with
+// whether any of them were changed and whether any container references the
+// image. Problems are reported without a file name.
+func pinImagesInManifests(content []byte, pinned imageReference) ([]byte, bool, bool, []ManifestError, error) {
+	var docs []*yaml.Node
+	var problems []ManifestError
+	changed := false
+	referenced := false
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for index := 1; ; index++ {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, false, nil, err
+		}
+		docs = append(docs, &doc)
+		walkContainers(&doc, func(container *yaml.Node) {
+			image := mappingValue(container, "image")
+			if image == nil || image.Kind != yaml.ScalarNode {
+				return
+			}
+			ref := parseImageReference(image.Value)
+			if ref.Name != pinned.Name {
+				return
+			}
+			referenced = true
+			if (ref.Tag != "" && ref.Tag != pinned.Tag) || (ref.Digest != "" && pinned.Digest != "" && ref.Digest != pinned.Digest) {
+				name := ""
+				if n := mappingValue(container, "name"); n != nil {
+					name = n.Value
+				}
+				problems = append(problems, ManifestError{
+					Document: index,
+					Line:     image.Line,
+					Message:  fmt.Sprintf("container '%s' references image '%s' but artifact pushed '%s'", name, image.Value, pinned),
+				})
+				return
+			}
+			if image.Value == pinned.String() {
+				return
+			}
+			image.Value = pinned.String()
+			image.Style = 0
+			changed = true
+		})
+	}
+	if !changed || len(problems) != 0 {
+		return content, false, referenced, problems, nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, doc := range docs {
+		err := encoder.Encode(doc)
+		if err != nil {
+			return nil, false, false, nil, err
+		}
+	}
+	err := encoder.Close()
+	if err != nil {
+		return nil, false, false, nil, err
+	}
+	return buf.Bytes(), true, referenced, nil, nil
+}
+
+// walkContainers calls fn for each container in containers and
+// initContainers lists found anywhere in node. This covers pod specs of all
+// workload kinds including CronJobs.
+func walkContainers(node *yaml.Node, fn func(container *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			walkContainers(child, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if (key.Value == "containers" || key.Value == "initContainers") && value.Kind == yaml.SequenceNode {
+				for _, container := range value.Content {
+					if container.Kind == yaml.MappingNode {
+						fn(container)
+					}
+				}
+				continue
+			}
+			walkContainers(value, fn)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageReference(t *testing.T) {
+	tt := []struct {
+		ref   string
+		image imageReference
+	}{
+		{ref: "product", image: imageReference{Name: "product"}},
+		{ref: "quay.io/lunarway/product:master-1234", image: imageReference{Name: "quay.io/lunarway/product", Tag: "master-1234"}},
+		{ref: "localhost:5000/product", image: imageReference{Name: "localhost:5000/product"}},
+		{ref: "localhost:5000/product:v1@sha256:abc", image: imageReference{Name: "localhost:5000/product", Tag: "v1", Digest: "sha256:abc"}},
+		{ref: "product@sha256:abc", image: imageReference{Name: "product", Digest: "sha256:abc"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.ref, func(t *testing.T) {
+			image := parseImageReference(tc.ref)
+
+			assert.Equal(t, tc.image, image, "image not as expected")
+			assert.Equal(t, tc.ref, image.String(), "string not as expected")
+		})
+	}
+}
+
+func TestPinImages(t *testing.T) {
+	push := artifact.PushData{
+		Image:  "quay.io/lunarway/product",
+		Tag:    "master-1234-5678",
+		Digest: "sha256:abc",
+	}
+	tt := []struct {
+		name   string
+		input  string
+		output string
+		errors []ManifestError
+	}{
+		{
+			name: "pins images of the artifact and ignores sidecars",
+			input: `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: product
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: migrate
+              image: quay.io/lunarway/product
+          containers:
+            - name: product
+              image: "quay.io/lunarway/product:master-1234-5678"
+            - name: proxy
+              image: envoyproxy/envoy:v1.20.0
+`,
+			output: `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: product
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+            - name: migrate
+              image: quay.io/lunarway/product:master-1234-5678@sha256:abc
+          containers:
+            - name: product
+              image: quay.io/lunarway/product:master-1234-5678@sha256:abc
+            - name: proxy
+              image: envoyproxy/envoy:v1.20.0
+`,
+		},
+		{
+			name: "already pinned",
+			input: `apiVersion: v1
+kind: Pod
+metadata:
+  name: product
+spec:
+  containers:
+  - name: product
+    image: quay.io/lunarway/product:master-1234-5678@sha256:abc
+`,
+			output: `apiVersion: v1
+kind: Pod
+metadata:
+  name: product
+spec:
+  containers:
+  - name: product
+    image: quay.io/lunarway/product:master-1234-5678@sha256:abc
+`,
+		},
+		{
+			name: "other tag of the artifact image",
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+spec:
+  template:
+    spec:
+      containers:
+        - name: product
+          image: quay.io/lunarway/product:master-0000-0000
+`,
+			errors: []ManifestError{
+				{File: "deployment.yaml", Document: 1, Line: 10, Message: "container 'product' references image 'quay.io/lunarway/product:master-0000-0000' but artifact pushed 'quay.io/lunarway/product:master-1234-5678@sha256:abc'"},
+			},
+		},
+		{
+			name: "artifact image not referenced",
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+spec:
+  template:
+    spec:
+      containers:
+        - name: proxy
+          image: envoyproxy/envoy:v1.28.0
+`,
+			errors: []ManifestError{
+				{Message: "no container references image 'quay.io/lunarway/product' pushed by the artifact"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "deployment.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.input), 0644))
+
+			err := pinImages(dir, push)
+
+			content, readErr := os.ReadFile(path)
+			require.NoError(t, readErr, "unexpected read error")
+			if len(tc.errors) == 0 {
+				require.NoError(t, err, "unexpected error")
+				assert.Equal(t, tc.output, string(content), "output not as expected")
+				return
+			}
+			var validationErr *ManifestValidationError
+			require.ErrorAs(t, err, &validationErr, "error not as expected")
+			assert.Equal(t, tc.errors, validationErr.Errors, "manifest errors not as expected")
+			assert.Equal(t, tc.input, string(content), "file changed on error")
+		})
+	}
+}
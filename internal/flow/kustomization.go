@@ -64,13 +64,8 @@ func kustomizationExists(directory string) (string, error) {
 	return filePath, nil
 }
 
-func moveKustomizationToClusters(ctx context.Context, srcPath, root, service, env, namespace string) error {
-	destDir, err := kustomizationPath(root, env, namespace)
-	if err != nil {
-		return errors.WithMessage(err, "assemble kustomization path")
-	}
-
-	err = os.MkdirAll(destDir, os.ModePerm)
+func moveKustomizationToClusters(ctx context.Context, srcPath, destDir, service string) error {
+	err := os.MkdirAll(destDir, os.ModePerm)
 	if err != nil {
 		return errors.WithMessagef(err, "create dest dir '%s'", destDir)
 	}
@@ -89,21 +84,3 @@ func moveKustomizationToClusters(ctx context.Context, srcPath, root, service, en
 
 	return nil
 }
-
-func kustomizationPath(root, env, namespace string) (string, error) {
-	kustomizationPath := root // start with root
-	pathsToJoin := []string{
-		"clusters",
-		env,
-		namespace,
-	}
-	var err error
-	for _, p := range pathsToJoin {
-		kustomizationPath, err = securejoin.SecureJoin(kustomizationPath, p)
-		if err != nil {
-			return "", errors.WithMessagef(err, "join '%s' to path", p)
-		}
-	}
-
-	return kustomizationPath, nil
-}
@@ -0,0 +1,185 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ManifestError is a problem with a single YAML document in a release.
+type ManifestError struct {
+	// File is the path of the file relative to the release directory. It is
+	// empty if the problem concerns the release as a whole.
+	File string
+	// Document is the 1-based index of the document in File. It is zero if the
+	// file could not be parsed.
+	Document int
+	// Line is the line in File the problem is found at, e.g. the start of the
+	// document. It is zero if unknown.
+	Line    int
+	Message string
+}
+
+func (e ManifestError) String() string {
+	switch {
+	case e.File == "":
+		return e.Message
+	case e.Document == 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	case e.Line == 0:
+		return fmt.Sprintf("%s: document %d: %s", e.File, e.Document, e.Message)
+	default:
+		return fmt.Sprintf("%s: document %d (line %d): %s", e.File, e.Document, e.Line, e.Message)
+	}
+}
+
+// ManifestValidationError indicates that the resources of a release are not
+// valid Kubernetes manifests and would fail when applied to the cluster.
+type ManifestValidationError struct {
+	Errors []ManifestError
+}
+
+func (e *ManifestValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.String()
+	}
+	return fmt.Sprintf("invalid manifests: %s", strings.Join(messages, "; "))
+}
+
+type manifestDocument struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name        string            `yaml:"name"`
+		Namespace   string            `yaml:"namespace"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+}
+
+// validateManifests validates all YAML files in directory as Kubernetes
+// manifests released in namespace for artifact artifactID. A
+// *ManifestValidationError listing all problems is returned if any manifest is
+// invalid.
+func validateManifests(directory, namespace, artifactID string) error {
+	var problems []ManifestError
+	err := filepath.WalkDir(directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		relativePath, err := filepath.Rel(directory, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.WithMessagef(err, "read file '%s'", relativePath)
+		}
+		problems = append(problems, validateManifestFile(filepath.ToSlash(relativePath), content, namespace, artifactID)...)
+		return nil
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "walk directory '%s'", directory)
+	}
+	if len(problems) != 0 {
+		return &ManifestValidationError{
+			Errors: problems,
+		}
+	}
+	return nil
+}
+
+func validateManifestFile(file string, content []byte, namespace, artifactID string) []ManifestError {
+	var problems []ManifestError
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for index := 1; ; index++ {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			return problems
+		}
+		if err != nil {
+			// the decoder cannot continue after syntax errors
+			return append(problems, ManifestError{
+				File:     file,
+				Document: index,
+				Message:  fmt.Sprintf("not valid YAML: %v", err),
+			})
+		}
+		if len(node.Content) == 0 || node.Content[0].Tag == "!!null" {
+			// empty documents, e.g. after a trailing separator, are ignored by
+			// kubectl as well
+			continue
+		}
+		problem := func(format string, args ...interface{}) {
+			problems = append(problems, ManifestError{
+				File:     file,
+				Document: index,
+				Line:     node.Content[0].Line,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+		if node.Content[0].Kind != yaml.MappingNode {
+			problem("document is not a YAML mapping")
+			continue
+		}
+		var doc manifestDocument
+		err = node.Decode(&doc)
+		if err != nil {
+			problem("not a Kubernetes object: %v", err)
+			continue
+		}
+		for _, p := range validateManifestDocument(doc, namespace, artifactID) {
+			problem("%s", p)
+		}
+	}
+}
+
+func validateManifestDocument(doc manifestDocument, namespace, artifactID string) []string {
+	var problems []string
+	if doc.APIVersion == "" {
+		problems = append(problems, "missing apiVersion")
+	}
+	if doc.Kind == "" {
+		problems = append(problems, "missing kind")
+	}
+	// kustomize configuration files are not applied to the cluster but read by
+	// kustomize and do not have any metadata
+	if strings.HasPrefix(doc.APIVersion, "kustomize.config.k8s.io/") {
+		return problems
+	}
+	if doc.Metadata.Name == "" {
+		problems = append(problems, "missing metadata.name")
+	}
+	if doc.Metadata.Namespace != "" && doc.Metadata.Namespace != namespace {
+		problems = append(problems, fmt.Sprintf("%s '%s' has namespace '%s' but is released to namespace '%s'", doc.Kind, doc.Metadata.Name, doc.Metadata.Namespace, namespace))
+	}
+	if controlledKinds[doc.Kind] {
+		if doc.Metadata.Annotations[controlledAnnotationKey] != "true" {
+			problems = append(problems, fmt.Sprintf("%s '%s' must have annotation %s: \"true\"", doc.Kind, doc.Metadata.Name, controlledAnnotationKey))
+		}
+		annotatedArtifactID := doc.Metadata.Annotations[artifactIDAnnotationKey]
+		switch annotatedArtifactID {
+		case "":
+			problems = append(problems, fmt.Sprintf("%s '%s' must have annotation %s", doc.Kind, doc.Metadata.Name, artifactIDAnnotationKey))
+		case artifactID:
+		default:
+			problems = append(problems, fmt.Sprintf("%s '%s' has annotation %s '%s' but artifact '%s' is released", doc.Kind, doc.Metadata.Name, artifactIDAnnotationKey, annotatedArtifactID, artifactID))
+		}
+	}
+	return problems
+}
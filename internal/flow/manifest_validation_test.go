@@ -0,0 +1,124 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: product
+  namespace: dev
+  annotations:
+    lunarway.com/artifact-id: master-1234-5678
+    lunarway.com/controlled-by-release-manager: "true"
+`
+
+func TestValidateManifests(t *testing.T) {
+	tt := []struct {
+		name   string
+		files  map[string]string
+		errors []ManifestError
+	}{
+		{
+			name: "valid manifests",
+			files: map[string]string{
+				"01-deployment.yaml": validDeployment + "---\n",
+				"02-service.yml": `apiVersion: v1
+kind: Service
+metadata:
+  name: product
+`,
+				"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- 01-deployment.yaml
+`,
+				"artifact.json": `{"id": "master-1234-5678"}`,
+			},
+		},
+		{
+			name: "invalid yaml",
+			files: map[string]string{
+				"01-deployment.yaml": validDeployment + "---\nkind: [Service\n",
+			},
+			errors: []ManifestError{
+				{File: "01-deployment.yaml", Document: 2, Message: "not valid YAML: yaml: line 9: did not find expected ',' or ']'"},
+			},
+		},
+		{
+			name: "missing required fields",
+			files: map[string]string{
+				"02-service.yaml": `metadata:
+  namespace: dev
+---
+- a list
+`,
+			},
+			errors: []ManifestError{
+				{File: "02-service.yaml", Document: 1, Line: 1, Message: "missing apiVersion"},
+				{File: "02-service.yaml", Document: 1, Line: 1, Message: "missing kind"},
+				{File: "02-service.yaml", Document: 1, Line: 1, Message: "missing metadata.name"},
+				{File: "02-service.yaml", Document: 2, Line: 4, Message: "document is not a YAML mapping"},
+			},
+		},
+		{
+			name: "wrong namespace and annotations",
+			files: map[string]string{
+				"sub/01-deployment.yaml": `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: product
+  namespace: prod
+  annotations:
+    lunarway.com/artifact-id: master-0000-0000
+`,
+			},
+			errors: []ManifestError{
+				{File: "sub/01-deployment.yaml", Document: 1, Line: 1, Message: "StatefulSet 'product' has namespace 'prod' but is released to namespace 'dev'"},
+				{File: "sub/01-deployment.yaml", Document: 1, Line: 1, Message: "StatefulSet 'product' must have annotation lunarway.com/controlled-by-release-manager: \"true\""},
+				{File: "sub/01-deployment.yaml", Document: 1, Line: 1, Message: "StatefulSet 'product' has annotation lunarway.com/artifact-id 'master-0000-0000' but artifact 'master-1234-5678' is released"},
+			},
+		},
+		{
+			name: "missing artifact id annotation",
+			files: map[string]string{
+				"01-job.yaml": `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    lunarway.com/controlled-by-release-manager: "true"
+`,
+			},
+			errors: []ManifestError{
+				{File: "01-job.yaml", Document: 1, Line: 1, Message: "Job 'migrate' must have annotation lunarway.com/artifact-id"},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			}
+
+			err := validateManifests(dir, "dev", "master-1234-5678")
+
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err, "unexpected error")
+				return
+			}
+			var validationErr *ManifestValidationError
+			require.ErrorAs(t, err, &validationErr, "error not as expected")
+			assert.Equal(t, tc.errors, validationErr.Errors, "manifest errors not as expected")
+		})
+	}
+}
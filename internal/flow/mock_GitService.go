@@ -3,12 +3,16 @@
 package flow
 
 import (
+	commitinfo "github.com/lunarway/release-manager/internal/commitinfo"
+
 	context "context"
 
 	git "github.com/go-git/go-git/v5"
 	mock "github.com/stretchr/testify/mock"
 
 	plumbing "github.com/go-git/go-git/v5/plumbing"
+
+	time "time"
 )
 
 // MockGitService is an autogenerated mock type for the GitService type
@@ -81,6 +85,41 @@ func (_m *MockGitService) Commit(ctx context.Context, rootPath string, changesPa
 	return r0
 }
 
+// StageChanges provides a mock function with given fields: ctx, rootPath
+func (_m *MockGitService) StageChanges(ctx context.Context, rootPath string) (bool, error) {
+	ret := _m.Called(ctx, rootPath)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, rootPath)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, rootPath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DiscardUnstagedChanges provides a mock function with given fields: ctx, rootPath
+func (_m *MockGitService) DiscardUnstagedChanges(ctx context.Context, rootPath string) error {
+	ret := _m.Called(ctx, rootPath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, rootPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LocateServiceReleaseRollbackSkip provides a mock function with given fields: ctx, r, env, service, n
 func (_m *MockGitService) LocateServiceReleaseRollbackSkip(ctx context.Context, r *git.Repository, env string, service string, n uint) (plumbing.Hash, error) {
 	ret := _m.Called(ctx, r, env, service, n)
@@ -131,3 +170,17 @@ func (_m *MockGitService) SyncMaster(_a0 context.Context) error {
 
 	return r0
 }
+
+// WalkReleases provides a mock function with given fields: ctx, f
+func (_m *MockGitService) WalkReleases(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+	ret := _m.Called(ctx, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(commitinfo.CommitInfo, time.Time) bool) error); ok {
+		r0 = rf(ctx, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -39,6 +39,12 @@ func (NewArtifactEvent) Type() string {
 	return "newArtifact"
 }
 
+// PartitionKey implements broker.Partitioned. New artifacts of a service are
+// handled in order.
+func (p NewArtifactEvent) PartitionKey() string {
+	return p.Service
+}
+
 func (p NewArtifactEvent) Marshal() ([]byte, error) {
 	return json.Marshal(p)
 }
@@ -58,6 +64,7 @@ func (s *Service) ExecNewArtifact(ctx context.Context, e NewArtifactEvent) error
 	if err != nil {
 		return errors.WithMessagef(err, "fetch artifact specification for service '%s' artifactId '%s'", e.Service, e.ArtifactID)
 	}
+	s.indexArtifact(ctx, artifactSpec)
 
 	logger = logger.WithFields("branch", artifactSpec.Application.Branch, "service", artifactSpec.Service, "commit", artifactSpec.Application.SHA)
 	// lookup policies for branch
@@ -73,7 +80,7 @@ func (s *Service) ExecNewArtifact(ctx context.Context, e NewArtifactEvent) error
 	logger.Infof("flow: exec new artifact: service '%s' branch '%s': found %d release policies", artifactSpec.Service, artifactSpec.Application.Branch, len(autoReleases))
 	var errs error
 	for _, autoRelease := range autoReleases {
-		releaseID, err := s.ReleaseArtifactID(ctx, Actor{
+		_, err := s.ReleaseArtifactID(ctx, Actor{
 			Name:  artifactSpec.Application.AuthorName,
 			Email: artifactSpec.Application.AuthorEmail,
 		}, autoRelease.Environment, artifactSpec.Service, artifactSpec.ID, intent.NewAutoRelease())
@@ -90,13 +97,13 @@ func (s *Service) ExecNewArtifact(ctx context.Context, e NewArtifactEvent) error
 			continue
 		}
 		//TODO: Parse and switch to signoff user
-		err = s.Slack.NotifySlackPolicySucceeded(ctx, artifactSpec.Application.AuthorEmail, ":rocket: Release Manager :white_check_mark:", fmt.Sprintf("Service *%s* will be auto released to *%s*\nArtifact: <%s|*%s*>", artifactSpec.Service, autoRelease.Environment, artifactSpec.Application.URL, releaseID))
+		err = s.Slack.NotifySlackPolicySucceeded(ctx, artifactSpec.Application.AuthorEmail, ":rocket: Release Manager :white_check_mark:", fmt.Sprintf("Service *%s* will be auto released to *%s*\nArtifact: <%s|*%s*>", artifactSpec.Service, autoRelease.Environment, artifactSpec.Application.URL, artifactSpec.ID))
 		if err != nil {
 			if errors.Cause(err) != slack.ErrUnknownEmail {
 				logger.Errorf("flow: exec new artifact: auto-release succeeded: error notifying slack: %v", err)
 			}
 		}
-		logger.Infof("flow: exec new artifact: service '%s': auto-release from policy '%s' of %s to %s", artifactSpec.Service, autoRelease.ID, releaseID, autoRelease.Environment)
+		logger.Infof("flow: exec new artifact: service '%s': auto-release from policy '%s' of %s to %s", artifactSpec.Service, autoRelease.ID, artifactSpec.ID, autoRelease.Environment)
 	}
 	if errs != nil {
 		logger.Errorf("flow: exec new artifact: service '%s' branch '%s': auto-release failed with one or more errors: %v", artifactSpec.Service, artifactSpec.Application.Branch, errs)
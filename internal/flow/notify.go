@@ -2,6 +2,8 @@ package flow
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/lunarway/release-manager/internal/http"
 
@@ -11,6 +13,7 @@ import (
 func (s *Service) NotifyK8SDeployEvent(ctx context.Context, event *http.ReleaseEvent) error {
 	span, ctx := s.Tracer.FromCtx(ctx, "flow.NotifyK8SDeployment")
 	defer span.End()
+	s.transitionReleaseByArtifact(ctx, event.Environment, event.ArtifactID, ReleaseStateRolledOut, fmt.Sprintf("%s %s available with %d/%d pods", event.ResourceType, event.Name, event.AvailablePods, event.DesiredPods))
 	if s.NotifyReleaseSucceededHook != nil {
 		go s.NotifyReleaseSucceededHook(noCancel{ctx: ctx}, NotifyReleaseSucceededOptions{
 			Name:          event.Name,
@@ -31,6 +34,7 @@ func (s *Service) NotifyK8SDeployEvent(ctx context.Context, event *http.ReleaseE
 func (s *Service) NotifyK8SPodErrorEvent(ctx context.Context, event *http.PodErrorEvent) error {
 	span, ctx := s.Tracer.FromCtx(ctx, "flow.NotifyK8SPodErrorEvent")
 	defer span.End()
+	s.transitionReleaseByArtifact(ctx, event.Environment, event.ArtifactID, ReleaseStateFailed, fmt.Sprintf("pod %s failed: %s", event.PodName, strings.Join(event.ErrorStrings(), "; ")))
 	span, _ = s.Tracer.FromCtx(ctx, "post k8s NotifyK8SPodErrorEvent slack message")
 	err := s.Slack.NotifyK8SPodErrorEvent(ctx, event)
 	span.End()
@@ -56,6 +60,7 @@ func (s *Service) NotifyK8SPodErrorEvent(ctx context.Context, event *http.PodErr
 func (s *Service) NotifyK8SJobErrorEvent(ctx context.Context, event *http.JobErrorEvent) error {
 	span, ctx := s.Tracer.FromCtx(ctx, "flow.NotifyK8SJobErrorEvent")
 	defer span.End()
+	s.transitionReleaseByArtifact(ctx, event.Environment, event.ArtifactID, ReleaseStateFailed, fmt.Sprintf("job %s failed: %s", event.JobName, jobErrorMessages(event.Errors)))
 	span, _ = s.Tracer.FromCtx(ctx, "post k8s NotifyK8SJobErrorEvent slack message")
 	err := s.Slack.NotifyK8SJobErrorEvent(ctx, event)
 	span.End()
@@ -64,3 +69,11 @@ func (s *Service) NotifyK8SJobErrorEvent(ctx context.Context, event *http.JobErr
 	}
 	return nil
 }
+
+func jobErrorMessages(errs []http.JobConditionError) string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, fmt.Sprintf("%s: %s", err.Reason, err.Message))
+	}
+	return strings.Join(messages, "; ")
+}
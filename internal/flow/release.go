@@ -8,6 +8,7 @@ import (
 	"path"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/lunarway/release-manager/internal/artifact"
 	"github.com/lunarway/release-manager/internal/commitinfo"
 	"github.com/lunarway/release-manager/internal/git"
@@ -31,6 +32,7 @@ func releaseConfigurationExists(resourcePath string) error {
 }
 
 type ReleaseArtifactIDEvent struct {
+	ReleaseID   string        `json:"releaseId,omitempty"`
 	Service     string        `json:"service,omitempty"`
 	Environment string        `json:"environment,omitempty"`
 	Namespace   string        `json:"namespace,omitempty"`
@@ -45,6 +47,12 @@ func (ReleaseArtifactIDEvent) Type() string {
 	return "release.artifactId"
 }
 
+// PartitionKey implements broker.Partitioned. Releases of a service to an
+// environment are handled in order.
+func (p ReleaseArtifactIDEvent) PartitionKey() string {
+	return fmt.Sprintf("%s/%s", p.Environment, p.Service)
+}
+
 func (p ReleaseArtifactIDEvent) Marshal() ([]byte, error) {
 	return json.Marshal(p)
 }
@@ -62,6 +70,9 @@ func (p *ReleaseArtifactIDEvent) Unmarshal(data []byte) error {
 //
 // Copy resources from the artifact commit into the environment and commit
 // the changes
+//
+// The returned string is a release ID that can be used to follow the release
+// through its lifecycle with ReleaseStatus.
 func (s *Service) ReleaseArtifactID(ctx context.Context, actor Actor, environment, service, artifactID string, intent intent.Intent) (string, error) {
 	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReleaseArtifactID")
 	defer span.End()
@@ -79,6 +90,10 @@ func (s *Service) ReleaseArtifactID(ctx context.Context, actor Actor, environmen
 	if !ok {
 		return "", ErrReleaseProhibited
 	}
+	err = s.verifyRequiredStages(ctx, service, environment, sourceSpec)
+	if err != nil {
+		return "", err
+	}
 
 	logger := log.WithContext(ctx)
 	logger.Infof("flow: ReleaseArtifactID: id '%s'", sourceSpec.ID)
@@ -110,11 +125,11 @@ func (s *Service) ReleaseArtifactID(ctx context.Context, actor Actor, environmen
 		return "", err
 	}
 	defer closeDestinationSource(ctx)
-	err = s.Git.ShallowClone(ctx, destinationConfigRepoPath)
+	err = s.configRepo(environment).ShallowClone(ctx, destinationConfigRepoPath)
 	if err != nil {
 		return "", errors.WithMessagef(err, "clone into '%s'", destinationConfigRepoPath)
 	}
-	currentSpec, err := envSpec(destinationConfigRepoPath, s.ArtifactFileName, service, environment, namespace)
+	currentSpec, err := s.envSpec(destinationConfigRepoPath, service, environment, namespace)
 	if err != nil && errors.Cause(err) != artifact.ErrFileNotFound {
 		return "", errors.WithMessage(err, "get current released spec")
 	}
@@ -123,7 +138,12 @@ func (s *Service) ReleaseArtifactID(ctx context.Context, actor Actor, environmen
 		return "", ErrNothingToRelease
 	}
 
-	err = s.PublishReleaseArtifactID(ctx, ReleaseArtifactIDEvent{
+	releaseID, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.WithMessage(err, "generate release id")
+	}
+	event := ReleaseArtifactIDEvent{
+		ReleaseID:   releaseID.String(),
 		ArtifactID:  artifactID,
 		Actor:       actor,
 		Branch:      branch,
@@ -132,11 +152,14 @@ func (s *Service) ReleaseArtifactID(ctx context.Context, actor Actor, environmen
 		Service:     service,
 		Intent:      intent,
 		EnqueuedAt:  time.Now(),
-	})
+	}
+	s.trackRelease(ctx, event)
+	err = s.PublishReleaseArtifactID(ctx, event)
 	if err != nil {
+		s.transitionRelease(ctx, event.ReleaseID, ReleaseStateFailed, fmt.Sprintf("publish event: %v", err))
 		return "", errors.WithMessage(err, "publish event")
 	}
-	return artifactID, nil
+	return event.ReleaseID, nil
 }
 
 // ExecReleaseArtifactID executes the release of a specific artifact ID to the
@@ -157,100 +180,220 @@ func (s *Service) ExecReleaseArtifactID(ctx context.Context, event ReleaseArtifa
 	}()
 	span, ctx := s.Tracer.FromCtx(ctx, "flow.ExecReleaseArtifactID")
 	defer span.End()
-	err = s.retry(ctx, func(ctx context.Context, attempt int) (bool, error) {
-		service := event.Service
-		branch := event.Branch
-		environment := event.Environment
-		namespace := event.Namespace
-		actor := event.Actor
-		artifactID := event.ArtifactID
+	if s.CommitWindow > 0 {
+		var staged stagedRelease
+		var skipped bool
+		staged, skipped, err = s.commitBatched(ctx, event)
+		if err == nil {
+			if skipped {
+				event.EnqueuedAt = time.Time{}
+				s.transitionRelease(ctx, event.ReleaseID, ReleaseStateSkipped, "environment is up to date")
+			} else {
+				s.releasePushed(ctx, event, staged)
+			}
+		}
+	} else {
+		err = s.retry(ctx, func(ctx context.Context, attempt int) (bool, error) {
+			logger := log.WithContext(ctx)
 
-		logger := log.WithContext(ctx)
+			artifactSourcePath, sourcePath, closeSource, err := s.releaseSource(ctx, event)
+			if err != nil {
+				return true, err
+			}
+			defer closeSource(ctx)
 
-		artifactSourcePath, sourcePath, closeSource, err := s.Storage.ArtifactPaths(ctx, service, environment, branch, artifactID)
-		if err != nil {
-			return true, errors.WithMessage(err, "get artifact paths")
-		}
-		defer closeSource(ctx)
+			destinationConfigRepoPath, closeDestination, err := git.TempDirAsync(ctx, s.Tracer, "k8s-config-release-artifact-destination")
+			if err != nil {
+				return true, err
+			}
+			defer closeDestination(ctx)
 
-		destinationConfigRepoPath, closeDestination, err := git.TempDirAsync(ctx, s.Tracer, "k8s-config-release-artifact-destination")
-		if err != nil {
-			return true, err
-		}
-		defer closeDestination(ctx)
+			err = s.configRepo(event.Environment).ShallowClone(ctx, destinationConfigRepoPath)
+			if err != nil {
+				return true, errors.WithMessagef(err, "clone destination repo into '%s'", destinationConfigRepoPath)
+			}
 
-		err = s.Git.ShallowClone(ctx, destinationConfigRepoPath)
-		if err != nil {
-			return true, errors.WithMessagef(err, "clone destination repo into '%s'", destinationConfigRepoPath)
-		}
+			staged, err := s.stageRelease(ctx, destinationConfigRepoPath, event, artifactSourcePath, sourcePath)
+			if err != nil {
+				return true, err
+			}
+
+			s.transitionRelease(ctx, event.ReleaseID, ReleaseStateCommitted, fmt.Sprintf("committing resources to '%s'", staged.destinationPath))
+			err = s.configRepo(event.Environment).Commit(ctx, destinationConfigRepoPath, ".", staged.message)
+			if err != nil {
+				if errors.Cause(err) == git.ErrNothingToCommit {
+					logger.Infof("Environment is up to date: dropping event: %v", err)
+					// TODO: notify actor that there was nothing to commit
+					event.EnqueuedAt = time.Time{}
+					s.transitionRelease(ctx, event.ReleaseID, ReleaseStateSkipped, "environment is up to date")
+					return true, nil
+				}
+				// we can see races here where other changes are committed to the master repo
+				// after we cloned. Because of this we retry on any error.
+				return false, errors.WithMessage(err, fmt.Sprintf("commit changes from path '%s'", staged.destinationPath))
+			}
+			s.releasePushed(ctx, event, staged)
+			return true, nil
+		})
+	}
+	// the release is not marked as failed here as the broker may redeliver the
+	// event. See ReleaseDropped.
+	return err
+}
+
+// stagedRelease is a release copied into a working tree of the config
+// repository ready to be committed.
+type stagedRelease struct {
+	message         string
+	destinationPath string
+	sourceSpec      artifact.Spec
+	previousSpec    artifact.Spec
+	artifactAuthor  commitinfo.PersonInfo
+	releaseAuthor   commitinfo.PersonInfo
+}
+
+// releaseSource returns the paths of the artifact spec and resources released
+// by event after verifying the artifact. Errors are deterministic so retrying
+// the release will not help.
+func (s *Service) releaseSource(ctx context.Context, event ReleaseArtifactIDEvent) (string, string, func(context.Context), error) {
+	artifactSourcePath, sourcePath, closeSource, err := s.Storage.ArtifactPaths(ctx, event.Service, event.Environment, event.Branch, event.ArtifactID)
+	if err != nil {
+		return "", "", nil, errors.WithMessage(err, "get artifact paths")
+	}
 
-		// release service to env from original release
-		destinationPath, err := releasePath(destinationConfigRepoPath, service, environment, namespace)
+	if s.ArtifactVerifier != nil {
+		err = s.ArtifactVerifier.VerifyArtifact(ctx, path.Dir(artifactSourcePath))
 		if err != nil {
-			return true, errors.WithMessage(err, "get release path")
+			closeSource(ctx)
+			// verification is deterministic so retrying will not help
+			return "", "", nil, errors.WithMessagef(err, "verify artifact '%s'", event.ArtifactID)
 		}
-		logger.Infof("flow: ReleaseArtifactID: copy resources from %s to %s", sourcePath, destinationPath)
+	}
+	return artifactSourcePath, sourcePath, closeSource, nil
+}
+
+// stageRelease copies the resources of the artifact released by event from
+// sourcePath and artifactSourcePath into the config repository working tree at
+// root. Errors are deterministic so retrying the release will not help.
+func (s *Service) stageRelease(ctx context.Context, root string, event ReleaseArtifactIDEvent, artifactSourcePath, sourcePath string) (stagedRelease, error) {
+	service := event.Service
+	environment := event.Environment
+	namespace := event.Namespace
+	actor := event.Actor
+	artifactID := event.ArtifactID
+
+	logger := log.WithContext(ctx)
+
+	// release service to env from original release
+	destinationPath, err := s.releasePath(root, service, environment, namespace)
+	if err != nil {
+		return stagedRelease{}, errors.WithMessage(err, "get release path")
+	}
+	// read the currently released artifact before it is replaced to let
+	// notifiers describe the changes of the release
+	previousSpec, err := s.envSpec(root, service, environment, namespace)
+	if err != nil && !errors.Is(err, artifact.ErrFileNotFound) {
+		logger.Infof("flow: ReleaseArtifactID: failed to read currently released artifact: %v", err)
+	}
+
+	logger.Infof("flow: ReleaseArtifactID: copy resources from %s to %s", sourcePath, destinationPath)
+
+	err = s.cleanCopy(ctx, sourcePath, destinationPath)
+	if err != nil {
+		return stagedRelease{}, errors.WithMessagef(err, "copy resources from '%s' to '%s'", sourcePath, destinationPath)
+	}
+
+	// copy artifact spec
+	artifactDestinationPath := path.Join(destinationPath, s.ArtifactFileName)
+	logger.Infof("flow: ReleaseArtifactID: copy artifact from %s to %s", artifactSourcePath, artifactDestinationPath)
+	err = s.Copier.CopyFile(ctx, artifactSourcePath, artifactDestinationPath)
+	if err != nil {
+		return stagedRelease{}, errors.WithMessage(err, fmt.Sprintf("copy artifact spec from '%s' to '%s'", artifactSourcePath, artifactDestinationPath))
+	}
+
+	kustomizationExistsSpan, _ := s.Tracer.FromCtx(ctx, "flow.kustomizationExists")
+	kustomizationPath, err := kustomizationExists(destinationPath)
+	kustomizationExistsSpan.End()
+	if err != nil {
+		return stagedRelease{}, errors.WithMessagef(err, "lookup kustomization in '%s'", destinationPath)
+	}
 
-		err = s.cleanCopy(ctx, sourcePath, destinationPath)
+	logger.Infof("flow: ReleaseArtifactID: kustomization path '%s'", kustomizationPath)
+
+	if kustomizationPath != "" {
+		kustomizationDir, err := s.kustomizationPath(root, environment, namespace)
 		if err != nil {
-			return true, errors.WithMessagef(err, "copy resources from '%s' to '%s'", sourcePath, destinationPath)
+			return stagedRelease{}, errors.WithMessage(err, "get kustomization path")
 		}
-
-		// copy artifact spec
-		artifactDestinationPath := path.Join(destinationPath, s.ArtifactFileName)
-		logger.Infof("flow: ReleaseArtifactID: copy artifact from %s to %s", artifactSourcePath, artifactDestinationPath)
-		err = s.Copier.CopyFile(ctx, artifactSourcePath, artifactDestinationPath)
+		moveKustomizationToClustersSpan, moveKustomizationToClustersCtx := s.Tracer.FromCtx(ctx, "flow.moveKustomizationToClusters")
+		err = moveKustomizationToClusters(moveKustomizationToClustersCtx, kustomizationPath, kustomizationDir, service)
+		moveKustomizationToClustersSpan.End()
 		if err != nil {
-			return true, errors.WithMessage(err, fmt.Sprintf("copy artifact spec from '%s' to '%s'", artifactSourcePath, artifactDestinationPath))
+			return stagedRelease{}, errors.WithMessage(err, "move kustomization to clusters")
 		}
+	}
+
+	sourceSpec, err := artifact.Get(artifactSourcePath)
+	if err != nil {
+		return stagedRelease{}, errors.WithMessage(err, "locate source spec")
+	}
 
-		kustomizationExistsSpan, _ := s.Tracer.FromCtx(ctx, "flow.kustomizationExists")
-		kustomizationPath, err := kustomizationExists(destinationPath)
-		kustomizationExistsSpan.End()
+	if s.InjectAnnotations {
+		authorEmail := actor.Email
+		if authorEmail == "" {
+			authorEmail = sourceSpec.Application.AuthorEmail
+		}
+		err = injectReleaseAnnotations(destinationPath, artifactID, authorEmail)
 		if err != nil {
-			return true, errors.WithMessagef(err, "lookup kustomization in '%s'", destinationPath)
+			return stagedRelease{}, errors.WithMessagef(err, "inject annotations into resources of artifact '%s'", artifactID)
 		}
+	}
 
-		logger.Infof("flow: ReleaseArtifactID: kustomization path '%s'", kustomizationPath)
-
-		if kustomizationPath != "" {
-			moveKustomizationToClustersSpan, moveKustomizationToClustersCtx := s.Tracer.FromCtx(ctx, "flow.moveKustomizationToClusters")
-			err := moveKustomizationToClusters(moveKustomizationToClustersCtx, kustomizationPath, destinationConfigRepoPath, service, environment, namespace)
-			moveKustomizationToClustersSpan.End()
+	if s.PinImages {
+		push, ok := pushData(sourceSpec)
+		if ok {
+			err = pinImages(destinationPath, push)
 			if err != nil {
-				return true, errors.WithMessage(err, "move kustomization to clusters")
+				// pinning is deterministic so retrying will not help
+				return stagedRelease{}, errors.WithMessagef(err, "pin images of artifact '%s'", artifactID)
 			}
+		} else {
+			logger.Infof("flow: ReleaseArtifactID: artifact '%s' has no push stage: skipping image pinning", artifactID)
 		}
+	}
 
-		sourceSpec, err := artifact.Get(artifactSourcePath)
+	if s.ValidateManifests {
+		err = validateManifests(destinationPath, namespace, artifactID)
 		if err != nil {
-			return true, errors.WithMessage(err, "locate source spec")
+			// validation is deterministic so retrying will not help
+			return stagedRelease{}, errors.WithMessagef(err, "validate resources of artifact '%s'", artifactID)
 		}
-		artifactAuthor := commitinfo.NewPersonInfo(sourceSpec.Application.AuthorName, sourceSpec.Application.AuthorEmail)
-		releaseAuthor := commitinfo.NewPersonInfo(actor.Name, actor.Email)
-		releaseMessage := commitinfo.ReleaseCommitMessage(environment, service, artifactID, event.Intent, artifactAuthor, releaseAuthor)
+	}
+	artifactAuthor := commitinfo.NewPersonInfo(sourceSpec.Application.AuthorName, sourceSpec.Application.AuthorEmail)
+	releaseAuthor := commitinfo.NewPersonInfo(actor.Name, actor.Email)
+	releaseMessage := commitinfo.ReleaseCommitMessage(environment, service, artifactID, event.Intent, artifactAuthor, releaseAuthor)
 
-		err = s.Git.Commit(ctx, destinationConfigRepoPath, ".", releaseMessage)
-		if err != nil {
-			if errors.Cause(err) == git.ErrNothingToCommit {
-				logger.Infof("Environment is up to date: dropping event: %v", err)
-				// TODO: notify actor that there was nothing to commit
-				event.EnqueuedAt = time.Time{}
-				return true, nil
-			}
-			// we can see races here where other changes are committed to the master repo
-			// after we cloned. Because of this we retry on any error.
-			return false, errors.WithMessage(err, fmt.Sprintf("commit changes from path '%s'", destinationPath))
-		}
-		s.notifyRelease(ctx, NotifyReleaseOptions{
-			Service:     service,
-			Environment: environment,
-			Namespace:   namespace,
-			Spec:        sourceSpec,
-			Releaser:    actor.Name,
-		})
-		logger.Infof("flow: ReleaseArtifactID: release committed: %s, ArtifactAuthor: %s, ReleaseAuthor: %s", releaseMessage, artifactAuthor, releaseAuthor)
-		return true, nil
+	return stagedRelease{
+		message:         releaseMessage,
+		destinationPath: destinationPath,
+		sourceSpec:      sourceSpec,
+		previousSpec:    previousSpec,
+		artifactAuthor:  artifactAuthor,
+		releaseAuthor:   releaseAuthor,
+	}, nil
+}
+
+// releasePushed records that the release of event staged in staged is pushed
+// to the config repository and notifies about it.
+func (s *Service) releasePushed(ctx context.Context, event ReleaseArtifactIDEvent, staged stagedRelease) {
+	s.transitionRelease(ctx, event.ReleaseID, ReleaseStatePushed, "release commit pushed")
+	s.notifyRelease(ctx, NotifyReleaseOptions{
+		Service:      event.Service,
+		Environment:  event.Environment,
+		Namespace:    event.Namespace,
+		Spec:         staged.sourceSpec,
+		PreviousSpec: staged.previousSpec,
+		Releaser:     event.Actor.Name,
 	})
-	return err
+	log.WithContext(ctx).Infof("flow: ReleaseArtifactID: release committed: %s, ArtifactAuthor: %s, ReleaseAuthor: %s", staged.message, staged.artifactAuthor, staged.releaseAuthor)
 }
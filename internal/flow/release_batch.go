@@ -0,0 +1,236 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/git"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// releaseBatches collects releases to config repositories into batches
+// committed together. See Service.CommitWindow.
+type releaseBatches struct {
+	mu sync.Mutex
+	// pending is the batch collecting releases of each config repository.
+	pending map[GitService]*releaseBatch
+	// last is the most recent batch of each config repository. New batches
+	// wait for it to be committed to keep releases in order.
+	last map[GitService]*releaseBatch
+}
+
+// releaseBatch is a set of releases to a config repository committed in a
+// single commit.
+type releaseBatch struct {
+	repo     GitService
+	releases []*batchedRelease
+	// services are the environment and service pairs of releases in the batch.
+	// A service is only released once per batch as a later release would
+	// overwrite the resources of the former.
+	services map[string]bool
+	// previous is the batch of the repository created before this one. It must
+	// be committed first.
+	previous *releaseBatch
+	// closed is closed when no more releases are added to the batch.
+	closed chan struct{}
+	// done is closed when the batch is committed or failed.
+	done chan struct{}
+}
+
+type batchedRelease struct {
+	event ReleaseArtifactIDEvent
+	// result fields are set before the batch is done.
+	staged  stagedRelease
+	skipped bool
+	err     error
+}
+
+func batchServiceKey(event ReleaseArtifactIDEvent) string {
+	return fmt.Sprintf("%s/%s", event.Environment, event.Service)
+}
+
+// commitBatched adds the release of event to the pending batch of its config
+// repository and waits for the batch to be committed. It reports whether the
+// release was skipped as the environment was up to date.
+func (s *Service) commitBatched(ctx context.Context, event ReleaseArtifactIDEvent) (stagedRelease, bool, error) {
+	release := &batchedRelease{
+		event: event,
+	}
+	batch, err := s.addToBatch(ctx, release)
+	if err != nil {
+		return stagedRelease{}, false, err
+	}
+	select {
+	case <-batch.done:
+		return release.staged, release.skipped, release.err
+	case <-ctx.Done():
+		return stagedRelease{}, false, ctx.Err()
+	}
+}
+
+// addToBatch adds release to the pending batch of its config repository. If
+// no batch is pending or the service is already released in it, a new batch is
+// started and committed after the commit window.
+func (s *Service) addToBatch(ctx context.Context, release *batchedRelease) (*releaseBatch, error) {
+	repo := s.configRepo(release.event.Environment)
+	key := batchServiceKey(release.event)
+	for {
+		s.batches.mu.Lock()
+		if s.batches.pending == nil {
+			s.batches.pending = make(map[GitService]*releaseBatch)
+			s.batches.last = make(map[GitService]*releaseBatch)
+		}
+		batch, ok := s.batches.pending[repo]
+		if ok && batch.services[key] {
+			s.batches.mu.Unlock()
+			select {
+			case <-batch.closed:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if !ok {
+			batch = &releaseBatch{
+				repo:     repo,
+				services: make(map[string]bool),
+				previous: s.batches.last[repo],
+				closed:   make(chan struct{}),
+				done:     make(chan struct{}),
+			}
+			s.batches.pending[repo] = batch
+			s.batches.last[repo] = batch
+			// the batch outlives the release that started it so it must not be
+			// cancelled with it
+			batchCtx := context.WithoutCancel(ctx)
+			time.AfterFunc(s.CommitWindow, func() {
+				s.commitBatch(batchCtx, batch)
+			})
+		}
+		batch.releases = append(batch.releases, release)
+		batch.services[key] = true
+		s.batches.mu.Unlock()
+		return batch, nil
+	}
+}
+
+// commitBatch closes batch for new releases and commits it once the previous
+// batch of the config repository is done.
+func (s *Service) commitBatch(ctx context.Context, batch *releaseBatch) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.commitBatch")
+	defer span.End()
+	s.batches.mu.Lock()
+	delete(s.batches.pending, batch.repo)
+	close(batch.closed)
+	s.batches.mu.Unlock()
+
+	if batch.previous != nil {
+		<-batch.previous.done
+		// release the reference to let finished batches be garbage collected
+		batch.previous = nil
+	}
+	defer func() {
+		s.batches.mu.Lock()
+		if s.batches.last[batch.repo] == batch {
+			delete(s.batches.last, batch.repo)
+		}
+		s.batches.mu.Unlock()
+		close(batch.done)
+	}()
+
+	log.WithContext(ctx).Infof("flow: commitBatch: committing batch of %d releases", len(batch.releases))
+	err := s.retry(ctx, func(ctx context.Context, attempt int) (bool, error) {
+		return s.tryCommitBatch(ctx, batch)
+	})
+	if err != nil {
+		for _, release := range batch.releases {
+			if release.err == nil {
+				release.err = err
+			}
+		}
+	}
+}
+
+// tryCommitBatch stages the releases of batch in a single working tree and
+// commits them together. Releases failing to stage are left out of the commit
+// and fail on their own.
+func (s *Service) tryCommitBatch(ctx context.Context, batch *releaseBatch) (bool, error) {
+	logger := log.WithContext(ctx)
+
+	root, closeRoot, err := git.TempDirAsync(ctx, s.Tracer, "k8s-config-release-batch")
+	if err != nil {
+		return true, err
+	}
+	defer closeRoot(ctx)
+
+	err = batch.repo.ShallowClone(ctx, root)
+	if err != nil {
+		return true, errors.WithMessagef(err, "clone destination repo into '%s'", root)
+	}
+
+	var committed []*batchedRelease
+	for _, release := range batch.releases {
+		// staging errors are deterministic so releases failing in a previous
+		// attempt are not tried again
+		if release.err != nil {
+			continue
+		}
+		release.skipped = false
+		staged, err := s.stageBatchedRelease(ctx, root, release.event)
+		if err != nil {
+			logger.Infof("flow: commitBatch: release of artifact '%s' failed: %v", release.event.ArtifactID, err)
+			release.err = err
+			err = batch.repo.DiscardUnstagedChanges(ctx, root)
+			if err != nil {
+				return false, errors.WithMessage(err, "discard changes of failed release")
+			}
+			continue
+		}
+		changed, err := batch.repo.StageChanges(ctx, root)
+		if err != nil {
+			return false, errors.WithMessagef(err, "stage changes of artifact '%s'", release.event.ArtifactID)
+		}
+		if !changed {
+			logger.Infof("flow: commitBatch: environment is up to date: skipping artifact '%s'", release.event.ArtifactID)
+			release.skipped = true
+			continue
+		}
+		release.staged = staged
+		committed = append(committed, release)
+	}
+	if len(committed) == 0 {
+		return true, nil
+	}
+
+	var messages []string
+	for _, release := range committed {
+		messages = append(messages, release.staged.message)
+		s.transitionRelease(ctx, release.event.ReleaseID, ReleaseStateCommitted, fmt.Sprintf("committing resources to '%s' in a batch of %d releases", release.staged.destinationPath, len(committed)))
+	}
+	message := messages[0]
+	if len(messages) > 1 {
+		message = commitinfo.BatchReleaseCommitMessage(messages)
+	}
+	err = batch.repo.Commit(ctx, root, ".", message)
+	if err != nil {
+		// we can see races here where other changes are committed to the master
+		// repo after we cloned. Because of this we retry on any error.
+		return false, errors.WithMessagef(err, "commit batch of %d releases", len(committed))
+	}
+	return true, nil
+}
+
+// stageBatchedRelease stages the release of event into the working tree at
+// root of a batch.
+func (s *Service) stageBatchedRelease(ctx context.Context, root string, event ReleaseArtifactIDEvent) (stagedRelease, error) {
+	artifactSourcePath, sourcePath, closeSource, err := s.releaseSource(ctx, event)
+	if err != nil {
+		return stagedRelease{}, err
+	}
+	defer closeSource(ctx)
+	return s.stageRelease(ctx, root, event, artifactSourcePath, sourcePath)
+}
@@ -0,0 +1,244 @@
+package flow
+
+import (
+	"context"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// ErrReleaseStatusNotFound should be returned by implementations of
+// ReleaseStatusStorage to indicate that a release is not known.
+var ErrReleaseStatusNotFound = errors.New("release status not found")
+
+// ReleaseState describes where a release is in its lifecycle.
+type ReleaseState string
+
+const (
+	// ReleaseStateQueued is set when a release is accepted and published to the
+	// broker.
+	ReleaseStateQueued ReleaseState = "queued"
+	// ReleaseStateCommitted is set when a worker has copied the artifact
+	// resources into the config repository and is committing them.
+	ReleaseStateCommitted ReleaseState = "committed"
+	// ReleaseStatePushed is set when the release commit is pushed to the config
+	// repository origin.
+	ReleaseStatePushed ReleaseState = "pushed"
+	// ReleaseStateRolledOut is set when the daemon reports resources of the
+	// released artifact as available in the environment.
+	ReleaseStateRolledOut ReleaseState = "rolledOut"
+	// ReleaseStateSkipped is set when the environment already contained the
+	// artifact when the release was executed.
+	ReleaseStateSkipped ReleaseState = "skipped"
+	// ReleaseStateFailed is set when the release could not be committed or the
+	// daemon reports errors for the released artifact.
+	ReleaseStateFailed ReleaseState = "failed"
+)
+
+// Terminal reports whether no further transitions are expected from state s.
+func (s ReleaseState) Terminal() bool {
+	switch s {
+	case ReleaseStateRolledOut, ReleaseStateSkipped, ReleaseStateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReleaseStatus is the tracked lifecycle of a single release identified by
+// ID.
+type ReleaseStatus struct {
+	ID          string              `json:"id,omitempty"`
+	Service     string              `json:"service,omitempty"`
+	Environment string              `json:"environment,omitempty"`
+	Namespace   string              `json:"namespace,omitempty"`
+	ArtifactID  string              `json:"artifactId,omitempty"`
+	Actor       Actor               `json:"actor,omitempty"`
+	Intent      intent.Intent       `json:"intent,omitempty"`
+	State       ReleaseState        `json:"state,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	CreatedAt   time.Time           `json:"createdAt,omitempty"`
+	UpdatedAt   time.Time           `json:"updatedAt,omitempty"`
+	Transitions []ReleaseTransition `json:"transitions,omitempty"`
+}
+
+// ReleaseTransition records a single state change of a release.
+type ReleaseTransition struct {
+	State   ReleaseState `json:"state,omitempty"`
+	Message string       `json:"message,omitempty"`
+	At      time.Time    `json:"at,omitempty"`
+}
+
+// Transition moves the release to state. Terminal releases are left untouched
+// and false is returned.
+func (r *ReleaseStatus) Transition(state ReleaseState, message string, at time.Time) bool {
+	if r.State.Terminal() {
+		return false
+	}
+	r.State = state
+	r.UpdatedAt = at
+	if state == ReleaseStateFailed {
+		r.Error = message
+	}
+	r.Transitions = append(r.Transitions, ReleaseTransition{
+		State:   state,
+		Message: message,
+		At:      at,
+	})
+	return true
+}
+
+type ReleaseStatusStorage interface {
+	// Create stores a new release status.
+	Create(ctx context.Context, status ReleaseStatus) error
+
+	// Get returns the release status with id. If it is not known
+	// ErrReleaseStatusNotFound is returned.
+	Get(ctx context.Context, id string) (ReleaseStatus, error)
+
+	// Update applies f to the release status with id and stores the result. It
+	// returns ErrReleaseStatusNotFound if id is not known.
+	Update(ctx context.Context, id string, f func(*ReleaseStatus)) error
+
+	// LatestByArtifact returns the most recently created release of artifactID
+	// into environment. If none is known ErrReleaseStatusNotFound is returned.
+	LatestByArtifact(ctx context.Context, environment, artifactID string) (ReleaseStatus, error)
+
+	// ClosestByArtifact returns the release of artifactID into environment
+	// created closest to at. If none is known ErrReleaseStatusNotFound is
+	// returned.
+	ClosestByArtifact(ctx context.Context, environment, artifactID string, at time.Time) (ReleaseStatus, error)
+}
+
+// Requeue moves the release back to queued, e.g. when a dropped event of it is
+// published again. Unlike Transition it also applies to terminal releases.
+func (r *ReleaseStatus) Requeue(message string, at time.Time) {
+	r.State = ReleaseStateQueued
+	r.Error = ""
+	r.UpdatedAt = at
+	r.Transitions = append(r.Transitions, ReleaseTransition{
+		State:   ReleaseStateQueued,
+		Message: message,
+		At:      at,
+	})
+}
+
+// ReleaseStatus returns the tracked lifecycle of the release with id.
+func (s *Service) ReleaseStatus(ctx context.Context, id string) (ReleaseStatus, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReleaseStatus")
+	defer span.End()
+	if s.Releases == nil {
+		return ReleaseStatus{}, ErrReleaseStatusNotFound
+	}
+	return s.Releases.Get(ctx, id)
+}
+
+// trackRelease starts tracking a release that is about to be queued. Tracking
+// is best effort and failures are only logged as they must never block a
+// release.
+func (s *Service) trackRelease(ctx context.Context, event ReleaseArtifactIDEvent) {
+	if s.Releases == nil || event.ReleaseID == "" {
+		return
+	}
+	status := ReleaseStatus{
+		ID:          event.ReleaseID,
+		Service:     event.Service,
+		Environment: event.Environment,
+		Namespace:   event.Namespace,
+		ArtifactID:  event.ArtifactID,
+		Actor:       event.Actor,
+		Intent:      event.Intent,
+		CreatedAt:   event.EnqueuedAt,
+	}
+	status.Transition(ReleaseStateQueued, "release accepted", event.EnqueuedAt)
+	err := s.Releases.Create(ctx, status)
+	if err != nil {
+		log.WithContext(ctx).Errorf("flow: track release '%s': create status failed: %v", event.ReleaseID, err)
+	}
+}
+
+// ReleaseDropped marks the release of an event dropped by the broker after
+// failed handlings as failed. Releases are only failed when their event is
+// dropped as earlier failed handlings may be followed by a successful
+// redelivery. Events of other types are ignored.
+func (s *Service) ReleaseDropped(ctx context.Context, msgType string, body []byte, err error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReleaseDropped")
+	defer span.End()
+	if msgType != (ReleaseArtifactIDEvent{}).Type() {
+		return
+	}
+	var event ReleaseArtifactIDEvent
+	unmarshalErr := event.Unmarshal(body)
+	if unmarshalErr != nil {
+		log.WithContext(ctx).Errorf("flow: release dropped: unmarshal event failed: %v", unmarshalErr)
+		return
+	}
+	s.transitionRelease(ctx, event.ReleaseID, ReleaseStateFailed, err.Error())
+}
+
+// transitionRelease moves the release with id to state. Like trackRelease
+// failures are only logged.
+func (s *Service) transitionRelease(ctx context.Context, id string, state ReleaseState, message string) {
+	if s.Releases == nil || id == "" {
+		return
+	}
+	err := s.Releases.Update(ctx, id, func(r *ReleaseStatus) {
+		r.Transition(state, message, time.Now())
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("flow: transition release '%s' to '%s' failed: %v", id, state, err)
+	}
+}
+
+// requeueRelease moves the release with id back to queued. Like
+// transitionRelease failures are only logged.
+func (s *Service) requeueRelease(ctx context.Context, id string, message string) {
+	if s.Releases == nil || id == "" {
+		return
+	}
+	err := s.Releases.Update(ctx, id, func(r *ReleaseStatus) {
+		r.Requeue(message, time.Now())
+	})
+	if err != nil {
+		log.WithContext(ctx).Errorf("flow: requeue release '%s' failed: %v", id, err)
+	}
+}
+
+// transitionReleaseByArtifact moves the latest release of artifactID into
+// environment to state. It is used to correlate daemon events, that only know
+// of artifact IDs, with tracked releases.
+//
+// Only pushed releases are moved as the daemon cannot report on resources that
+// are not yet in the config repository. Events for releases in other states,
+// e.g. still queued, are logged and ignored.
+func (s *Service) transitionReleaseByArtifact(ctx context.Context, environment, artifactID string, state ReleaseState, message string) {
+	if s.Releases == nil || environment == "" || artifactID == "" {
+		return
+	}
+	logger := log.WithContext(ctx)
+	release, err := s.Releases.LatestByArtifact(ctx, environment, artifactID)
+	if err != nil {
+		if errors.Is(err, ErrReleaseStatusNotFound) {
+			return
+		}
+		logger.Errorf("flow: lookup release of artifact '%s' in '%s' failed: %v", artifactID, environment, err)
+		return
+	}
+	var current ReleaseState
+	err = s.Releases.Update(ctx, release.ID, func(r *ReleaseStatus) {
+		current = r.State
+		if r.State != ReleaseStatePushed {
+			return
+		}
+		r.Transition(state, message, time.Now())
+	})
+	if err != nil {
+		logger.Errorf("flow: transition release '%s' to '%s' failed: %v", release.ID, state, err)
+		return
+	}
+	if current != ReleaseStatePushed {
+		logger.Infof("flow: release '%s' of artifact '%s' in '%s' not moved to '%s' as it is '%s' and not pushed", release.ID, artifactID, environment, state, current)
+	}
+}
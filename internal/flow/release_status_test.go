@@ -0,0 +1,245 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryReleases is a ReleaseStatusStorage keeping statuses in a map.
+type memoryReleases struct {
+	mu       sync.Mutex
+	releases map[string]ReleaseStatus
+}
+
+func (m *memoryReleases) Create(_ context.Context, status ReleaseStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.releases == nil {
+		m.releases = make(map[string]ReleaseStatus)
+	}
+	m.releases[status.ID] = status
+	return nil
+}
+
+func (m *memoryReleases) Get(_ context.Context, id string) (ReleaseStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status, ok := m.releases[id]
+	if !ok {
+		return ReleaseStatus{}, ErrReleaseStatusNotFound
+	}
+	return status, nil
+}
+
+func (m *memoryReleases) Update(_ context.Context, id string, f func(*ReleaseStatus)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status, ok := m.releases[id]
+	if !ok {
+		return ErrReleaseStatusNotFound
+	}
+	f(&status)
+	m.releases[id] = status
+	return nil
+}
+
+func (m *memoryReleases) LatestByArtifact(_ context.Context, environment, artifactID string) (ReleaseStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest ReleaseStatus
+	var found bool
+	for _, status := range m.releases {
+		if status.Environment != environment || status.ArtifactID != artifactID {
+			continue
+		}
+		if !found || status.CreatedAt.After(latest.CreatedAt) {
+			latest = status
+			found = true
+		}
+	}
+	if !found {
+		return ReleaseStatus{}, ErrReleaseStatusNotFound
+	}
+	return latest, nil
+}
+
+func (m *memoryReleases) ClosestByArtifact(_ context.Context, environment, artifactID string, at time.Time) (ReleaseStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var closest ReleaseStatus
+	var found bool
+	for _, status := range m.releases {
+		if status.Environment != environment || status.ArtifactID != artifactID {
+			continue
+		}
+		if !found || absDuration(status.CreatedAt.Sub(at)) < absDuration(closest.CreatedAt.Sub(at)) {
+			closest = status
+			found = true
+		}
+	}
+	if !found {
+		return ReleaseStatus{}, ErrReleaseStatusNotFound
+	}
+	return closest, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func TestReleaseStatus_Transition(t *testing.T) {
+	now := time.Now()
+	tt := []struct {
+		name        string
+		from        ReleaseState
+		to          ReleaseState
+		transitions bool
+		err         string
+	}{
+		{name: "queued to committed", from: ReleaseStateQueued, to: ReleaseStateCommitted, transitions: true},
+		{name: "pushed to rolled out", from: ReleaseStatePushed, to: ReleaseStateRolledOut, transitions: true},
+		{name: "pushed to failed", from: ReleaseStatePushed, to: ReleaseStateFailed, transitions: true, err: "message"},
+		{name: "rolled out is terminal", from: ReleaseStateRolledOut, to: ReleaseStateFailed, transitions: false},
+		{name: "skipped is terminal", from: ReleaseStateSkipped, to: ReleaseStateRolledOut, transitions: false},
+		{name: "failed is terminal", from: ReleaseStateFailed, to: ReleaseStateRolledOut, transitions: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			status := ReleaseStatus{State: tc.from}
+			ok := status.Transition(tc.to, "message", now)
+			assert.Equal(t, tc.transitions, ok, "transitioned")
+			assert.Equal(t, tc.err, status.Error, "error")
+			if tc.transitions {
+				assert.Equal(t, tc.to, status.State, "state")
+				assert.Equal(t, now, status.UpdatedAt, "updated at")
+				assert.Len(t, status.Transitions, 1)
+			} else {
+				assert.Equal(t, tc.from, status.State, "state")
+				assert.Empty(t, status.Transitions)
+			}
+		})
+	}
+}
+
+// TestService_transitionReleaseByArtifact tests that daemon events only move
+// releases that are pushed to the config repository.
+func TestService_transitionReleaseByArtifact(t *testing.T) {
+	tt := []struct {
+		name  string
+		from  ReleaseState
+		to    ReleaseState
+		state ReleaseState
+	}{
+		{name: "pushed to rolled out", from: ReleaseStatePushed, to: ReleaseStateRolledOut, state: ReleaseStateRolledOut},
+		{name: "pushed to failed", from: ReleaseStatePushed, to: ReleaseStateFailed, state: ReleaseStateFailed},
+		{name: "queued not rolled out", from: ReleaseStateQueued, to: ReleaseStateRolledOut, state: ReleaseStateQueued},
+		{name: "queued not failed", from: ReleaseStateQueued, to: ReleaseStateFailed, state: ReleaseStateQueued},
+		{name: "committed not rolled out", from: ReleaseStateCommitted, to: ReleaseStateRolledOut, state: ReleaseStateCommitted},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			releases := &memoryReleases{}
+			require.NoError(t, releases.Create(ctx, ReleaseStatus{
+				ID:          "1",
+				Environment: "dev",
+				ArtifactID:  "master-1-2",
+				State:       tc.from,
+			}))
+			s := Service{
+				Tracer:   tracing.NewNoop(),
+				Releases: releases,
+			}
+
+			s.transitionReleaseByArtifact(ctx, "dev", "master-1-2", tc.to, "daemon event")
+
+			status, err := releases.Get(ctx, "1")
+			require.NoError(t, err, "unexpected get error")
+			assert.Equal(t, tc.state, status.State, "state not as expected")
+		})
+	}
+}
+
+// TestExecReleaseArtifactID_redelivery tests that a release is only marked as
+// failed when its event is dropped so a successful redelivery of the event
+// completes the release.
+func TestExecReleaseArtifactID_redelivery(t *testing.T) {
+	commitErr := errors.New("push rejected")
+	tt := []struct {
+		name       string
+		commitErrs []error
+		dropped    bool
+		state      ReleaseState
+	}{
+		{
+			name:       "fail then succeed",
+			commitErrs: []error{commitErr, nil},
+			state:      ReleaseStatePushed,
+		},
+		{
+			name:       "fail and dropped",
+			commitErrs: []error{commitErr, commitErr},
+			dropped:    true,
+			state:      ReleaseStateFailed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			storage := setupArtifactStorage(t)
+			gitSvc := &MockGitService{}
+			gitSvc.Test(t)
+			gitSvc.On("ShallowClone", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+			for _, err := range tc.commitErrs {
+				gitSvc.On("Commit", mock.Anything, mock.AnythingOfType("string"), ".", mock.AnythingOfType("string")).Return(err).Once()
+			}
+			releases := &memoryReleases{}
+			svc := newTestService(t, nil, gitSvc, storage)
+			svc.Releases = releases
+			event := ReleaseArtifactIDEvent{
+				ReleaseID:   "release-1",
+				Service:     "svc",
+				Environment: "dev",
+				Namespace:   "dev",
+				ArtifactID:  "master-test-1234",
+				Branch:      "master",
+				Intent:      intent.NewReleaseArtifact(),
+				EnqueuedAt:  time.Now(),
+			}
+			svc.trackRelease(ctx, event)
+
+			// the first delivery fails and the event is redelivered
+			err := svc.ExecReleaseArtifactID(ctx, event)
+			require.Error(t, err, "first delivery must fail")
+			status, err := releases.Get(ctx, event.ReleaseID)
+			require.NoError(t, err)
+			assert.False(t, status.State.Terminal(), "release must not be terminal before the event is dropped")
+
+			err = svc.ExecReleaseArtifactID(ctx, event)
+			if tc.dropped {
+				require.Error(t, err, "redelivery must fail")
+				body, marshalErr := event.Marshal()
+				require.NoError(t, marshalErr)
+				svc.ReleaseDropped(ctx, event.Type(), body, err)
+			} else {
+				require.NoError(t, err, "redelivery must succeed")
+			}
+
+			status, err = releases.Get(ctx, event.ReleaseID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.state, status.State, "release state")
+		})
+	}
+}
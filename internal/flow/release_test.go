@@ -6,10 +6,12 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
 	"github.com/lunarway/release-manager/internal/copy"
 	internalgit "github.com/lunarway/release-manager/internal/git"
 	"github.com/lunarway/release-manager/internal/intent"
@@ -238,3 +240,146 @@ func TestExecReleaseArtifactID_observationWiring(t *testing.T) {
 		})
 	}
 }
+
+// artifactVerifierFunc is an ArtifactVerifier calling the func.
+type artifactVerifierFunc func(ctx context.Context, root string) error
+
+func (f artifactVerifierFunc) VerifyArtifact(ctx context.Context, root string) error {
+	return f(ctx, root)
+}
+
+// TestExecReleaseArtifactID_verification tests that artifacts are verified
+// before they are released and that failed verifications are not retried.
+func TestExecReleaseArtifactID_verification(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		verifyErr   error
+		wantErr     string
+		wantRelease bool
+	}{
+		{
+			name:        "verified artifact is released",
+			verifyErr:   nil,
+			wantRelease: true,
+		},
+		{
+			name:        "unverified artifact is not released",
+			verifyErr:   errors.New("artifact signature invalid"),
+			wantErr:     "verify artifact 'master-test-1234': artifact signature invalid",
+			wantRelease: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := setupArtifactStorage(t)
+
+			gitSvc := &MockGitService{}
+			gitSvc.Test(t)
+			if tc.wantRelease {
+				gitSvc.On("ShallowClone", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+				gitSvc.On("Commit", mock.Anything, mock.AnythingOfType("string"), ".", mock.AnythingOfType("string")).Return(nil)
+			}
+
+			svc := newTestService(t, nil, gitSvc, storage)
+			svc.MaxRetries = 3
+			var verifiedRoots []string
+			svc.ArtifactVerifier = artifactVerifierFunc(func(_ context.Context, root string) error {
+				verifiedRoots = append(verifiedRoots, root)
+				return tc.verifyErr
+			})
+
+			err := svc.ExecReleaseArtifactID(context.Background(), ReleaseArtifactIDEvent{
+				Service:     "svc",
+				Environment: "dev",
+				Namespace:   "dev",
+				ArtifactID:  "master-test-1234",
+				Branch:      "master",
+				Intent:      intent.NewReleaseArtifact(),
+			})
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []string{filepath.Dir(storage.specPath)}, verifiedRoots, "verified artifact roots")
+			gitSvc.AssertExpectations(t)
+		})
+	}
+}
+
+// TestExecReleaseArtifactID_commitWindow tests that releases within the commit
+// window are pushed in a single commit unless they release the same service.
+func TestExecReleaseArtifactID_commitWindow(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		services    []string
+		wantCommits [][]string
+	}{
+		{
+			name:        "different services are committed together",
+			services:    []string{"api", "web"},
+			wantCommits: [][]string{{"api", "web"}},
+		},
+		{
+			name:        "same service is committed in order",
+			services:    []string{"api", "api"},
+			wantCommits: [][]string{{"api"}, {"api"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := setupArtifactStorage(t)
+
+			gitSvc := &MockGitService{}
+			gitSvc.Test(t)
+			gitSvc.On("ShallowClone", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+			gitSvc.On("StageChanges", mock.Anything, mock.AnythingOfType("string")).Return(true, nil)
+			var commits [][]string
+			gitSvc.On("Commit", mock.Anything, mock.AnythingOfType("string"), ".", mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+				infos, err := commitinfo.ParseCommitInfos(args.String(3))
+				require.NoError(t, err, "unexpected commit message parse error")
+				var services []string
+				for _, info := range infos {
+					services = append(services, info.Service)
+				}
+				commits = append(commits, services)
+			})
+
+			svc := newTestService(t, nil, gitSvc, storage)
+			svc.CommitWindow = 50 * time.Millisecond
+
+			errs := make(chan error, len(tc.services))
+			for _, service := range tc.services {
+				go func(service string) {
+					errs <- svc.ExecReleaseArtifactID(context.Background(), ReleaseArtifactIDEvent{
+						Service:     service,
+						Environment: "dev",
+						Namespace:   "dev",
+						ArtifactID:  "master-test-1234",
+						Branch:      "master",
+						Intent:      intent.NewReleaseArtifact(),
+					})
+				}(service)
+			}
+			for range tc.services {
+				assert.NoError(t, <-errs, "unexpected release error")
+			}
+
+			for i := range commits {
+				sort.Strings(commits[i])
+			}
+			assert.Equal(t, tc.wantCommits, commits, "committed services not as expected")
+		})
+	}
+}
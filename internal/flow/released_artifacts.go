@@ -0,0 +1,92 @@
+package flow
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/pkg/errors"
+)
+
+// ReleasedArtifacts is a set of artifact IDs by service.
+type ReleasedArtifacts map[string]map[string]bool
+
+// Contains reports whether artifactID of service is in the set.
+func (r ReleasedArtifacts) Contains(service, artifactID string) bool {
+	return r[strings.ToLower(service)][strings.ToLower(artifactID)]
+}
+
+func (r ReleasedArtifacts) add(service, artifactID string) {
+	if service == "" || artifactID == "" {
+		return
+	}
+	service = strings.ToLower(service)
+	if r[service] == nil {
+		r[service] = make(map[string]bool)
+	}
+	r[service][strings.ToLower(artifactID)] = true
+}
+
+// ReleasedArtifacts returns the artifacts currently released in any
+// environment along with the artifacts of the last history releases of each
+// service in each environment.
+//
+// Any error reading the current releases is returned as callers use the result
+// to decide what is safe to delete.
+func (s *Service) ReleasedArtifacts(ctx context.Context, history int) (ReleasedArtifacts, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.ReleasedArtifacts")
+	defer span.End()
+
+	released := make(ReleasedArtifacts)
+	environments, err := s.Environments(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "list environments")
+	}
+	for _, environment := range environments {
+		namespaces, err := s.Namespaces(ctx, environment)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "list namespaces of environment '%s'", environment)
+		}
+		for _, namespace := range namespaces {
+			services, err := s.Services(ctx, environment, namespace)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "list services of namespace '%s' in environment '%s'", namespace, environment)
+			}
+			for _, service := range services {
+				spec, err := s.releaseSpecification(ctx, releaseLocation{
+					Environment: environment,
+					Namespace:   namespace,
+					Service:     service,
+				})
+				if err != nil {
+					if errors.Is(err, artifact.ErrFileNotFound) {
+						continue
+					}
+					return nil, errors.WithMessagef(err, "read artifact of service '%s' in namespace '%s' of environment '%s'", service, namespace, environment)
+				}
+				released.add(firstNonEmpty(spec.Service, service), spec.ID)
+			}
+		}
+	}
+
+	if history <= 0 {
+		return released, nil
+	}
+	counts := make(map[string]int)
+	err = s.walkReleases(ctx, func(info commitinfo.CommitInfo, _ time.Time) bool {
+		key := strings.ToLower(info.Environment + "/" + info.Service)
+		if counts[key] >= history {
+			return true
+		}
+		counts[key]++
+		released.add(info.Service, info.ArtifactID)
+		released.add(info.Service, info.Intent.Rollback.PreviousArtifactID)
+		return true
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "locate release commits")
+	}
+	return released, nil
+}
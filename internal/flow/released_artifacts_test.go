@@ -0,0 +1,92 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleasedArtifacts(t *testing.T) {
+	root := t.TempDir()
+	writeSpec := func(env, namespace, service string, spec artifact.Spec) {
+		dir := filepath.Join(root, env, "releases", namespace, service)
+		require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+		content, err := json.Marshal(spec)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "artifact.json"), content, 0644))
+	}
+	writeSpec("dev", "dev", "a", artifact.Spec{ID: "master-4-a", Service: "a"})
+	writeSpec("prod", "other", "b", artifact.Spec{ID: "master-1-b", Service: "b"})
+
+	commits := []commitinfo.CommitInfo{
+		{Environment: "dev", Service: "a", ArtifactID: "master-4-a"},
+		{Environment: "dev", Service: "a", ArtifactID: "master-2-a", Intent: intent.NewRollback("master-3-a")},
+		{Environment: "dev", Service: "a", ArtifactID: "master-1-a"},
+		{Environment: "prod", Service: "b", ArtifactID: "master-1-b"},
+	}
+
+	tt := []struct {
+		name        string
+		history     int
+		released    []string
+		notReleased []string
+	}{
+		{
+			name:        "no history",
+			history:     0,
+			released:    []string{"a/master-4-a", "b/master-1-b"},
+			notReleased: []string{"a/master-3-a", "a/master-2-a", "a/master-1-a"},
+		},
+		{
+			name:        "history",
+			history:     2,
+			released:    []string{"a/master-4-a", "a/master-3-a", "a/master-2-a", "b/master-1-b"},
+			notReleased: []string{"a/master-1-a"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gitService := MockGitService{}
+			gitService.Test(t)
+			gitService.On("MasterPath").Return(root)
+			gitService.On("WalkReleases", mock.Anything, mock.Anything).Return(func(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+				for _, c := range commits {
+					if !f(c, time.Time{}) {
+						return nil
+					}
+				}
+				return nil
+			})
+			s := Service{
+				Git:              &gitService,
+				Tracer:           tracing.NewNoop(),
+				ArtifactFileName: "artifact.json",
+			}
+
+			released, err := s.ReleasedArtifacts(context.Background(), tc.history)
+
+			require.NoError(t, err, "unexpected error")
+			contains := func(key string) bool {
+				service, artifactID := filepath.Split(key)
+				return released.Contains(filepath.Clean(service), artifactID)
+			}
+			for _, key := range tc.released {
+				assert.True(t, contains(key), "expected '%s' to be released", key)
+			}
+			for _, key := range tc.notReleased {
+				assert.False(t, contains(key), "expected '%s' not to be released", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/commitinfo"
+)
+
+// configRepo returns the config repository environment is released to.
+func (s *Service) configRepo(environment string) GitService {
+	if repo, ok := s.Repositories[environment]; ok {
+		return repo
+	}
+	return s.Git
+}
+
+// repositories returns all config repositories starting with the default one.
+func (s *Service) repositories() []GitService {
+	repos := []GitService{s.Git}
+	for _, repo := range s.Repositories {
+		if !containsRepository(repos, repo) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func containsRepository(repos []GitService, repo GitService) bool {
+	for _, r := range repos {
+		if r == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// environments returns the sorted names of all environments with releases in
+// any config repository. Environments are only included from the repository
+// they are routed to.
+func (s *Service) environments() ([]string, error) {
+	var environments []string
+	for _, repo := range s.repositories() {
+		repoEnvironments, err := s.layout().Environments(repo.MasterPath())
+		if err != nil {
+			return nil, err
+		}
+		for _, environment := range repoEnvironments {
+			if s.configRepo(environment) == repo && !contains(environments, environment) {
+				environments = append(environments, environment)
+			}
+		}
+	}
+	sort.Strings(environments)
+	return environments, nil
+}
+
+// walkReleases calls f with the release commits of all config repositories
+// from newest to oldest in each repository. Commits of environments routed to
+// another repository are skipped. Returning false from f stops the traversal
+// of the current repository only.
+func (s *Service) walkReleases(ctx context.Context, f func(info commitinfo.CommitInfo, releasedAt time.Time) bool) error {
+	for _, repo := range s.repositories() {
+		err := repo.WalkReleases(ctx, func(info commitinfo.CommitInfo, releasedAt time.Time) bool {
+			if s.configRepo(info.Environment) != repo {
+				return true
+			}
+			return f(info, releasedAt)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
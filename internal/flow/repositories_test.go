@@ -0,0 +1,44 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_environments_routed(t *testing.T) {
+	defaultRoot := t.TempDir()
+	prodRoot := t.TempDir()
+	for root, dirs := range map[string][]string{
+		// prod is left behind in the default repository after being routed
+		defaultRoot: {"dev/releases", "staging/releases", "prod/releases"},
+		// dev in the prod repository is not routed there
+		prodRoot: {"prod/releases", "dev/releases"},
+	} {
+		for _, dir := range dirs {
+			require.NoError(t, os.MkdirAll(filepath.Join(root, dir), os.ModePerm))
+		}
+	}
+	defaultRepo := MockGitService{}
+	defaultRepo.Test(t)
+	defaultRepo.On("MasterPath").Return(defaultRoot)
+	prodRepo := MockGitService{}
+	prodRepo.Test(t)
+	prodRepo.On("MasterPath").Return(prodRoot)
+	s := Service{
+		Git: &defaultRepo,
+		Repositories: map[string]GitService{
+			"prod": &prodRepo,
+		},
+	}
+
+	assert.Equal(t, &defaultRepo, s.configRepo("dev"), "dev repository not as expected")
+	assert.Equal(t, &prodRepo, s.configRepo("prod"), "prod repository not as expected")
+
+	environments, err := s.environments()
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, []string{"dev", "prod", "staging"}, environments, "environments not as expected")
+}
@@ -0,0 +1,54 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/pkg/errors"
+)
+
+// MissingStagesError indicates that an artifact cannot be released as it does
+// not contain all stages required by policies.
+type MissingStagesError struct {
+	Stages []string
+}
+
+func (e *MissingStagesError) Error() string {
+	return fmt.Sprintf("artifact missing required stages: %s", strings.Join(e.Stages, ", "))
+}
+
+// verifyRequiredStages returns a *MissingStagesError if spec does not contain
+// all stages required for releasing service svc to environment env.
+func (s *Service) verifyRequiredStages(ctx context.Context, svc, env string, spec artifact.Spec) error {
+	if s.RequiredStages == nil {
+		return nil
+	}
+	required, err := s.RequiredStages(ctx, svc, env)
+	if err != nil {
+		return errors.WithMessage(err, "get required stages")
+	}
+	missing := missingStages(spec, required)
+	if len(missing) != 0 {
+		return &MissingStagesError{
+			Stages: missing,
+		}
+	}
+	return nil
+}
+
+func missingStages(spec artifact.Spec, required []string) []string {
+	present := make(map[artifact.StageID]struct{}, len(spec.Stages))
+	for _, stage := range spec.Stages {
+		present[stage.ID] = struct{}{}
+	}
+	var missing []string
+	for _, stage := range required {
+		_, ok := present[artifact.StageID(stage)]
+		if !ok {
+			missing = append(missing, stage)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,60 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_verifyRequiredStages(t *testing.T) {
+	spec := artifact.Spec{
+		ID: "master-1234-5678",
+		Stages: []artifact.Stage{
+			{ID: artifact.StageIDBuild},
+			{ID: artifact.StageIDSBOM},
+		},
+	}
+	tt := []struct {
+		name     string
+		required func(ctx context.Context, svc, env string) ([]string, error)
+		missing  []string
+	}{
+		{
+			name:     "no required stages function",
+			required: nil,
+		},
+		{
+			name: "all stages present",
+			required: func(ctx context.Context, svc, env string) ([]string, error) {
+				return []string{"build", "sbom"}, nil
+			},
+		},
+		{
+			name: "missing stages",
+			required: func(ctx context.Context, svc, env string) ([]string, error) {
+				return []string{"provenance", "sbom", "snyk-code"}, nil
+			},
+			missing: []string{"provenance", "snyk-code"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := Service{
+				RequiredStages: tc.required,
+			}
+
+			err := s.verifyRequiredStages(context.Background(), "product", "prod", spec)
+
+			if len(tc.missing) == 0 {
+				assert.NoError(t, err, "unexpected error")
+				return
+			}
+			var missingStagesErr *MissingStagesError
+			require.ErrorAs(t, err, &missingStagesErr, "error not as expected")
+			assert.Equal(t, tc.missing, missingStagesErr.Stages, "missing stages not as expected")
+		})
+	}
+}
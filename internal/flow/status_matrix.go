@@ -0,0 +1,162 @@
+package flow
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// StatusMatrix is the current release of all services in all environments.
+type StatusMatrix struct {
+	Environments []string
+	Services     []StatusMatrixService
+}
+
+// StatusMatrixService is the current releases of a single service.
+type StatusMatrixService struct {
+	Service  string
+	Squad    string
+	Releases []StatusMatrixRelease
+}
+
+// StatusMatrixRelease is the current release of a service in an environment.
+type StatusMatrixRelease struct {
+	Environment           string
+	Namespace             string
+	ArtifactID            string
+	Squad                 string
+	ReleasedAt            time.Time
+	ReleasedByEmail       string
+	ReleasedByName        string
+	HighVulnerabilities   int64
+	MediumVulnerabilities int64
+	LowVulnerabilities    int64
+}
+
+// StatusMatrix returns the current release of every service in every
+// environment. If squad is not empty only releases of artifacts owned by squad
+// are included.
+//
+// Releases are located in a single pass over the master repository and release
+// times and releasers in a single traversal of its git log.
+func (s *Service) StatusMatrix(ctx context.Context, squad string) (StatusMatrix, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.StatusMatrix")
+	defer span.End()
+
+	environments, err := s.Environments(ctx)
+	if err != nil {
+		return StatusMatrix{}, errors.WithMessage(err, "list environments")
+	}
+
+	span, _ = s.Tracer.FromCtx(ctx, "read release specifications")
+	var services []StatusMatrixService
+	serviceIndex := make(map[string]int)
+	// releases indexes releases by environment, service and artifact id to
+	// correlate them with release commits
+	releases := make(map[string][]*StatusMatrixRelease)
+	for _, environment := range environments {
+		namespaces, err := s.Namespaces(ctx, environment)
+		if err != nil {
+			span.End()
+			return StatusMatrix{}, errors.WithMessagef(err, "list namespaces of environment '%s'", environment)
+		}
+		for _, namespace := range namespaces {
+			names, err := s.Services(ctx, environment, namespace)
+			if err != nil {
+				span.End()
+				return StatusMatrix{}, errors.WithMessagef(err, "list services of namespace '%s' in environment '%s'", namespace, environment)
+			}
+			for _, service := range names {
+				spec, err := s.releaseSpecification(ctx, releaseLocation{
+					Environment: environment,
+					Namespace:   namespace,
+					Service:     service,
+				})
+				if err != nil {
+					if errors.Is(err, artifact.ErrFileNotFound) {
+						continue
+					}
+					log.WithContext(ctx).Errorf("flow: status matrix: skipping service '%s' in namespace '%s' of environment '%s': %v", service, namespace, environment, err)
+					continue
+				}
+				if squad != "" && !strings.EqualFold(spec.Squad, squad) {
+					continue
+				}
+				i, ok := serviceIndex[service]
+				if !ok {
+					i = len(services)
+					serviceIndex[service] = i
+					services = append(services, StatusMatrixService{
+						Service: service,
+					})
+				}
+				if spec.Squad != "" {
+					services[i].Squad = spec.Squad
+				}
+				services[i].Releases = append(services[i].Releases, StatusMatrixRelease{
+					Environment:           environment,
+					Namespace:             namespace,
+					ArtifactID:            spec.ID,
+					Squad:                 spec.Squad,
+					HighVulnerabilities:   calculateHighTotalVulnerabilties(spec),
+					MediumVulnerabilities: calculateMediumTotalVulnerabilties(spec),
+					LowVulnerabilities:    calculateLowTotalVulnerabilties(spec),
+				})
+			}
+		}
+	}
+	span.End()
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Service < services[j].Service
+	})
+
+	for i := range services {
+		for j := range services[i].Releases {
+			release := &services[i].Releases[j]
+			key := statusMatrixKey(release.Environment, services[i].Service, release.ArtifactID)
+			releases[key] = append(releases[key], release)
+		}
+	}
+	if len(releases) == 0 {
+		return StatusMatrix{
+			Environments: environments,
+		}, nil
+	}
+
+	unresolved := len(releases)
+	err = s.walkReleases(ctx, func(info commitinfo.CommitInfo, releasedAt time.Time) bool {
+		key := statusMatrixKey(info.Environment, info.Service, info.ArtifactID)
+		matches, ok := releases[key]
+		if !ok {
+			return true
+		}
+		for _, release := range matches {
+			release.ReleasedAt = releasedAt
+			release.ReleasedByEmail = info.ReleasedBy.Email
+			release.ReleasedByName = info.ReleasedBy.Name
+		}
+		// only the newest release commit is of interest
+		delete(releases, key)
+		unresolved--
+		return unresolved > 0
+	})
+	if err != nil {
+		return StatusMatrix{}, errors.WithMessage(err, "locate release commits")
+	}
+
+	return StatusMatrix{
+		Environments: environments,
+		Services:     services,
+	}, nil
+}
+
+func statusMatrixKey(environment, service, artifactID string) string {
+	return strings.ToLower(path.Join(environment, service, artifactID))
+}
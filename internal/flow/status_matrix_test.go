@@ -0,0 +1,114 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusMatrix(t *testing.T) {
+	root := t.TempDir()
+	writeSpec := func(env, namespace, service string, spec artifact.Spec) {
+		dir := filepath.Join(root, env, "releases", namespace, service)
+		require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+		content, err := json.Marshal(spec)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "artifact.json"), content, 0644))
+	}
+	writeSpec("dev", "dev", "a", artifact.Spec{ID: "master-2-a", Squad: "x"})
+	writeSpec("prod", "prod", "a", artifact.Spec{ID: "master-1-a", Squad: "x"})
+	writeSpec("dev", "dev", "b", artifact.Spec{ID: "master-1-b", Squad: "y"})
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "clusters", "dev"), os.ModePerm))
+
+	now := time.Now()
+	commits := []struct {
+		info commitinfo.CommitInfo
+		at   time.Time
+	}{
+		{commitinfo.CommitInfo{Environment: "dev", Service: "a", ArtifactID: "master-2-a", ReleasedBy: commitinfo.PersonInfo{Name: "Jane", Email: "jane@example.com"}}, now},
+		{commitinfo.CommitInfo{Environment: "prod", Service: "a", ArtifactID: "master-1-a", ReleasedBy: commitinfo.PersonInfo{Name: "John", Email: "john@example.com"}}, now.Add(-time.Hour)},
+		{commitinfo.CommitInfo{Environment: "dev", Service: "a", ArtifactID: "master-1-a", ReleasedBy: commitinfo.PersonInfo{Name: "John", Email: "john@example.com"}}, now.Add(-2 * time.Hour)},
+		{commitinfo.CommitInfo{Environment: "dev", Service: "b", ArtifactID: "master-1-b", ReleasedBy: commitinfo.PersonInfo{Name: "Jane", Email: "jane@example.com"}}, now.Add(-3 * time.Hour)},
+	}
+
+	tt := []struct {
+		name   string
+		squad  string
+		matrix StatusMatrix
+	}{
+		{
+			name: "all squads",
+			matrix: StatusMatrix{
+				Environments: []string{"dev", "prod"},
+				Services: []StatusMatrixService{
+					{
+						Service: "a",
+						Squad:   "x",
+						Releases: []StatusMatrixRelease{
+							{Environment: "dev", Namespace: "dev", ArtifactID: "master-2-a", Squad: "x", ReleasedAt: now, ReleasedByName: "Jane", ReleasedByEmail: "jane@example.com"},
+							{Environment: "prod", Namespace: "prod", ArtifactID: "master-1-a", Squad: "x", ReleasedAt: now.Add(-time.Hour), ReleasedByName: "John", ReleasedByEmail: "john@example.com"},
+						},
+					},
+					{
+						Service: "b",
+						Squad:   "y",
+						Releases: []StatusMatrixRelease{
+							{Environment: "dev", Namespace: "dev", ArtifactID: "master-1-b", Squad: "y", ReleasedAt: now.Add(-3 * time.Hour), ReleasedByName: "Jane", ReleasedByEmail: "jane@example.com"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "single squad",
+			squad: "y",
+			matrix: StatusMatrix{
+				Environments: []string{"dev", "prod"},
+				Services: []StatusMatrixService{
+					{
+						Service: "b",
+						Squad:   "y",
+						Releases: []StatusMatrixRelease{
+							{Environment: "dev", Namespace: "dev", ArtifactID: "master-1-b", Squad: "y", ReleasedAt: now.Add(-3 * time.Hour), ReleasedByName: "Jane", ReleasedByEmail: "jane@example.com"},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gitService := MockGitService{}
+			gitService.Test(t)
+			gitService.On("MasterPath").Return(root)
+			gitService.On("WalkReleases", mock.Anything, mock.Anything).Return(func(ctx context.Context, f func(commitinfo.CommitInfo, time.Time) bool) error {
+				for _, c := range commits {
+					if !f(c.info, c.at) {
+						return nil
+					}
+				}
+				return nil
+			})
+			s := Service{
+				Git:              &gitService,
+				Tracer:           tracing.NewNoop(),
+				ArtifactFileName: "artifact.json",
+			}
+
+			matrix, err := s.StatusMatrix(context.Background(), tc.squad)
+
+			assert.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.matrix, matrix, "matrix not as expected")
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/lunarway/release-manager/internal/git"
+	"github.com/lunarway/release-manager/internal/intent"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+var ErrNothingToUndeploy = errors.New("nothing to undeploy")
+
+// AutoReleasePoliciesError indicates that a service cannot be undeployed from
+// an environment as auto-release policies would release it again.
+type AutoReleasePoliciesError struct {
+	PolicyIDs []string
+}
+
+func (e *AutoReleasePoliciesError) Error() string {
+	return fmt.Sprintf("auto-release policies target the environment: %s", strings.Join(e.PolicyIDs, ", "))
+}
+
+type NotifyUndeployOptions struct {
+	Environment string
+	Namespace   string
+	Service     string
+	Undeployer  string
+	Spec        artifact.Spec
+}
+
+// Undeploy removes a service from an environment.
+//
+// # Flow
+//
+// Verify that no auto-release policies of the service target the environment
+// as they would release the service again on the next artifact.
+//
+// Locate the artifact currently released to the environment and verify that
+// the release policies allow changing the environment.
+//
+// Remove the release directory and the cluster kustomization of the service
+// and commit the changes in a single commit.
+//
+// The returned artifact ID is the one that was running before the service was
+// undeployed.
+func (s *Service) Undeploy(ctx context.Context, actor Actor, environment, namespace, service string) (string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "flow.Undeploy")
+	defer span.End()
+
+	// default to environment name for the namespace if none is specified
+	if namespace == "" {
+		namespace = environment
+	}
+	if s.AutoReleasePolicies != nil {
+		policyIDs, err := s.AutoReleasePolicies(ctx, service, environment)
+		if err != nil {
+			return "", errors.WithMessage(err, "get auto-release policies")
+		}
+		if len(policyIDs) != 0 {
+			return "", &AutoReleasePoliciesError{
+				PolicyIDs: policyIDs,
+			}
+		}
+	}
+
+	var artifactID string
+	err := s.retry(ctx, func(ctx context.Context, attempt int) (bool, error) {
+		logger := log.WithContext(ctx)
+
+		destinationConfigRepoPath, closeDestination, err := git.TempDirAsync(ctx, s.Tracer, "k8s-config-undeploy")
+		if err != nil {
+			return true, err
+		}
+		defer closeDestination(ctx)
+
+		err = s.configRepo(environment).ShallowClone(ctx, destinationConfigRepoPath)
+		if err != nil {
+			return true, errors.WithMessagef(err, "clone into '%s'", destinationConfigRepoPath)
+		}
+
+		currentSpec, err := s.envSpec(destinationConfigRepoPath, service, environment, namespace)
+		if err != nil {
+			if errors.Cause(err) == artifact.ErrFileNotFound {
+				return true, ErrNothingToUndeploy
+			}
+			return true, errors.WithMessage(err, "get current released spec")
+		}
+		artifactID = currentSpec.ID
+
+		ok, err := s.CanRelease(ctx, service, currentSpec.Application.Branch, environment)
+		if err != nil {
+			return true, errors.WithMessage(err, "validate release policies")
+		}
+		if !ok {
+			return true, ErrReleaseProhibited
+		}
+
+		destinationPath, err := s.releasePath(destinationConfigRepoPath, service, environment, namespace)
+		if err != nil {
+			return true, errors.WithMessage(err, "get release path")
+		}
+		logger.Infof("flow: Undeploy: remove resources in %s", destinationPath)
+		err = os.RemoveAll(destinationPath)
+		if err != nil {
+			return true, errors.WithMessagef(err, "remove release path '%s'", destinationPath)
+		}
+
+		kustomizationDir, err := s.kustomizationPath(destinationConfigRepoPath, environment, namespace)
+		if err != nil {
+			return true, errors.WithMessage(err, "get kustomization path")
+		}
+		kustomizationFile, err := securejoin.SecureJoin(kustomizationDir, fmt.Sprintf("%s.yaml", service))
+		if err != nil {
+			return true, errors.WithMessage(err, "secure join kustomization path")
+		}
+		logger.Infof("flow: Undeploy: remove kustomization %s", kustomizationFile)
+		err = os.Remove(kustomizationFile)
+		if err != nil && !os.IsNotExist(err) {
+			return true, errors.WithMessagef(err, "remove kustomization '%s'", kustomizationFile)
+		}
+
+		artifactAuthor := commitinfo.NewPersonInfo(currentSpec.Application.AuthorName, currentSpec.Application.AuthorEmail)
+		releaseAuthor := commitinfo.NewPersonInfo(actor.Name, actor.Email)
+		releaseMessage := commitinfo.ReleaseCommitMessage(environment, service, currentSpec.ID, intent.NewUndeploy(), artifactAuthor, releaseAuthor)
+		err = s.configRepo(environment).Commit(ctx, destinationConfigRepoPath, ".", releaseMessage)
+		if err != nil {
+			if errors.Cause(err) == git.ErrNothingToCommit {
+				return true, ErrNothingToUndeploy
+			}
+			// we can see races here where other changes are committed to the master repo
+			// after we cloned. Because of this we retry on any error.
+			return false, errors.WithMessage(err, "commit changes")
+		}
+		logger.Infof("flow: Undeploy: undeploy committed: %s, ReleaseAuthor: %s", releaseMessage, releaseAuthor)
+		if s.NotifyUndeployHook != nil {
+			go s.NotifyUndeployHook(noCancel{ctx: ctx}, NotifyUndeployOptions{
+				Environment: environment,
+				Namespace:   namespace,
+				Service:     service,
+				Undeployer:  actor.Name,
+				Spec:        currentSpec,
+			})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return artifactID, nil
+}
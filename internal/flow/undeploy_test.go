@@ -0,0 +1,132 @@
+package flow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndeploy(t *testing.T) {
+	tt := []struct {
+		name       string
+		service    string
+		canRelease bool
+		artifactID string
+		removed    []string
+		err        error
+	}{
+		{
+			name:       "released service",
+			service:    "a",
+			canRelease: true,
+			artifactID: "master-1-a",
+			removed: []string{
+				"dev/releases/dev/a",
+				"clusters/dev/dev/a.yaml",
+			},
+		},
+		{
+			name:       "released service without kustomization",
+			service:    "b",
+			canRelease: true,
+			artifactID: "master-1-b",
+			removed: []string{
+				"dev/releases/dev/b",
+			},
+		},
+		{
+			name:       "unknown service",
+			service:    "unknown",
+			canRelease: true,
+			err:        ErrNothingToUndeploy,
+		},
+		{
+			name:       "prohibited by policy",
+			service:    "a",
+			canRelease: false,
+			err:        ErrReleaseProhibited,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var root string
+			gitService := MockGitService{}
+			gitService.Test(t)
+			gitService.On("ShallowClone", mock.Anything, mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+				root = args.String(1)
+				writeFile := func(path, content string) {
+					path = filepath.Join(root, path)
+					require.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+					require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+				}
+				writeFile("dev/releases/dev/a/artifact.json", `{"id": "master-1-a", "application": {"branch": "master"}}`)
+				writeFile("dev/releases/dev/a/40-deployment.yaml", "kind: Deployment")
+				writeFile("dev/releases/dev/b/artifact.json", `{"id": "master-1-b", "application": {"branch": "master"}}`)
+				writeFile("clusters/dev/dev/a.yaml", "kind: Kustomization")
+				writeFile("clusters/dev/dev/b-other.yaml", "kind: Kustomization")
+			}).Return(nil)
+			var commitMessage string
+			gitService.On("Commit", mock.Anything, mock.AnythingOfType("string"), ".", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+				commitMessage = args.String(3)
+				for _, path := range tc.removed {
+					assert.NoFileExists(t, filepath.Join(root, path), "path not removed")
+				}
+				// other services must be left untouched
+				assert.FileExists(t, filepath.Join(root, "clusters/dev/dev/b-other.yaml"))
+			}).Return(nil)
+			s := Service{
+				Git:              &gitService,
+				Tracer:           tracing.NewNoop(),
+				ArtifactFileName: "artifact.json",
+				MaxRetries:       1,
+				CanRelease: func(ctx context.Context, svc, branch, env string) (bool, error) {
+					assert.Equal(t, "master", branch, "branch used for policies not as expected")
+					return tc.canRelease, nil
+				},
+			}
+
+			artifactID, err := s.Undeploy(context.Background(), Actor{Name: "Foo", Email: "foo@example.com"}, "dev", "", tc.service)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				gitService.AssertNotCalled(t, "Commit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.artifactID, artifactID, "artifact id not as expected")
+			assert.Contains(t, commitMessage, "Release-intent: Undeploy", "commit message not as expected")
+		})
+	}
+}
+
+func TestUndeploy_autoReleasePolicies(t *testing.T) {
+	gitService := MockGitService{}
+	gitService.Test(t)
+	s := Service{
+		Git:              &gitService,
+		Tracer:           tracing.NewNoop(),
+		ArtifactFileName: "artifact.json",
+		MaxRetries:       1,
+		CanRelease: func(ctx context.Context, svc, branch, env string) (bool, error) {
+			return true, nil
+		},
+		AutoReleasePolicies: func(ctx context.Context, svc, env string) ([]string, error) {
+			assert.Equal(t, "a", svc, "service not as expected")
+			assert.Equal(t, "dev", env, "environment not as expected")
+			return []string{"auto-release-master-dev"}, nil
+		},
+	}
+
+	_, err := s.Undeploy(context.Background(), Actor{Name: "Foo", Email: "foo@example.com"}, "dev", "", "a")
+
+	var autoReleaseErr *AutoReleasePoliciesError
+	require.ErrorAs(t, err, &autoReleaseErr)
+	assert.Equal(t, []string{"auto-release-master-dev"}, autoReleaseErr.PolicyIDs, "policy ids not as expected")
+	gitService.AssertNotCalled(t, "ShallowClone", mock.Anything, mock.Anything)
+}
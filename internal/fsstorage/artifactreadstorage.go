@@ -0,0 +1,177 @@
+package fsstorage
+
+import (
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+func (s *Service) ArtifactExists(ctx context.Context, service, artifactID string) (bool, error) {
+	artifactPath, err := s.artifactPath(service, artifactID)
+	if err != nil {
+		return false, nil
+	}
+	_, err = os.Stat(artifactPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "stat artifact '%s'", artifactPath)
+	}
+	return true, nil
+}
+
+func (s *Service) ArtifactSpecification(ctx context.Context, service string, artifactID string) (artifact.Spec, error) {
+	artifactPath, err := s.artifactPath(service, artifactID)
+	if err != nil {
+		return artifact.Spec{}, flow.ErrArtifactNotFound
+	}
+	return s.artifactSpecification(artifactPath)
+}
+
+// ArtifactPaths returns the paths of the artifact within the storage
+// directory. The returned close function is a no-op as the files must not be
+// removed.
+func (s *Service) ArtifactPaths(ctx context.Context, service string, environment string, branch string, artifactID string) (specPath string, resourcesPath string, close func(context.Context), err error) {
+	artifactPath, err := s.artifactPath(service, artifactID)
+	if err != nil {
+		return "", "", nil, flow.ErrArtifactNotFound
+	}
+	return s.paths(artifactPath, environment)
+}
+
+func (s *Service) LatestArtifactSpecification(ctx context.Context, service string, branch string) (artifact.Spec, error) {
+	artifactPath, err := s.latestArtifactPath(ctx, service, branch)
+	if err != nil {
+		return artifact.Spec{}, errors.WithMessage(err, "get latest artifact path")
+	}
+	return s.artifactSpecification(artifactPath)
+}
+
+func (s *Service) LatestArtifactPaths(ctx context.Context, service string, environment string, branch string) (specPath string, resourcesPath string, close func(context.Context), err error) {
+	artifactPath, err := s.latestArtifactPath(ctx, service, branch)
+	if err != nil {
+		return "", "", nil, errors.WithMessage(err, "get latest artifact path")
+	}
+	return s.paths(artifactPath, environment)
+}
+
+func (s *Service) ArtifactSpecifications(ctx context.Context, service string, n int, branch string) ([]artifact.Spec, error) {
+	var prefix string
+	if branch != "" {
+		prefix = branchPrefix(branch)
+	}
+	artifacts, err := s.listArtifacts(service, prefix)
+	if err != nil {
+		return nil, err
+	}
+	log.WithContext(ctx).WithFields("service", service, "count", n).Infof("Found %d artifacts for service '%s'", len(artifacts), service)
+
+	var artifactSpecs []artifact.Spec
+	for _, a := range artifacts {
+		artifactSpec, err := s.artifactSpecification(a.path)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting artifact '%s'", a.path)
+		}
+		if branch != "" && artifactSpec.Application.Branch != branch {
+			continue
+		}
+		artifactSpecs = append(artifactSpecs, artifactSpec)
+		if len(artifactSpecs) >= n {
+			break
+		}
+	}
+	return artifactSpecs, nil
+}
+
+func (s *Service) artifactSpecification(artifactPath string) (artifact.Spec, error) {
+	spec, err := artifact.Get(path.Join(artifactPath, "artifact.json"))
+	if err != nil {
+		if errors.Is(err, artifact.ErrFileNotFound) {
+			return artifact.Spec{}, flow.ErrArtifactNotFound
+		}
+		return artifact.Spec{}, errors.WithMessage(err, "read artifact.json file")
+	}
+	return spec, nil
+}
+
+func (s *Service) paths(artifactPath, environment string) (string, string, func(context.Context), error) {
+	_, err := os.Stat(artifactPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil, flow.ErrArtifactNotFound
+		}
+		return "", "", nil, errors.Wrapf(err, "stat artifact '%s'", artifactPath)
+	}
+	resourcesPath, err := securejoin.SecureJoin(artifactPath, environment)
+	if err != nil {
+		return "", "", nil, errors.WithMessagef(err, "resources path invalid for '%s'", environment)
+	}
+	return path.Join(artifactPath, "artifact.json"), resourcesPath, func(context.Context) {}, nil
+}
+
+func (s *Service) latestArtifactPath(ctx context.Context, service, branch string) (string, error) {
+	artifacts, err := s.listArtifacts(service, branchPrefix(branch))
+	if err != nil {
+		return "", err
+	}
+	if len(artifacts) == 0 {
+		return "", flow.ErrArtifactNotFound
+	}
+	log.WithContext(ctx).WithFields("path", artifacts[0].path).Infof("Latest artifact for service '%s' and branch '%s' is at '%s'", service, branch, artifacts[0].path)
+	return artifacts[0].path, nil
+}
+
+type storedArtifact struct {
+	path    string
+	modTime time.Time
+}
+
+// listArtifacts returns the artifacts of service with an ID starting with
+// prefix ordered by newest first.
+func (s *Service) listArtifacts(service, prefix string) ([]storedArtifact, error) {
+	if !validName(service) {
+		return nil, nil
+	}
+	servicePath := path.Join(s.directory, service)
+	entries, err := os.ReadDir(servicePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "read service directory '%s'", servicePath)
+	}
+	var artifacts []storedArtifact
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, errors.Wrapf(err, "stat artifact '%s'", entry.Name())
+		}
+		artifacts = append(artifacts, storedArtifact{
+			path:    path.Join(servicePath, entry.Name()),
+			modTime: info.ModTime(),
+		})
+	}
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].modTime.After(artifacts[j].modTime)
+	})
+	return artifacts, nil
+}
+
+// branchPrefix returns the artifact ID prefix of artifacts built from branch.
+// It matches the S3 storage key naming.
+func branchPrefix(branch string) string {
+	return strings.ReplaceAll(branch, "/", "_") + "-"
+}
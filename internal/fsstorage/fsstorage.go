@@ -0,0 +1,235 @@
+// Package fsstorage implements artifact storage in a local directory.
+//
+// Artifacts are stored unzipped in <directory>/<service>/<artifactID>. Uploads
+// are received by the server on URLs returned by CreateArtifact that are
+// signed to allow the artifact CLI to upload without further authentication
+// like with S3 presigned URLs.
+package fsstorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/archive"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidUploadURL is returned when an upload is made to a URL not
+	// created by CreateArtifact or if it has expired.
+	ErrInvalidUploadURL = errors.New("invalid upload url")
+	// ErrBadDigest is returned when the MD5 digest of an uploaded artifact does
+	// not match the one provided when it was created.
+	ErrBadDigest = errors.New("bad digest")
+)
+
+// uploadURLExpiry is how long upload URLs returned by CreateArtifact are
+// valid. It matches the expiry of S3 presigned URLs.
+const uploadURLExpiry = 15 * time.Minute
+
+// uploadsDirectory is the directory within the storage directory where
+// uploads are extracted before they are moved into place.
+const uploadsDirectory = ".uploads"
+
+type Service struct {
+	directory string
+	uploadURL *url.URL
+	secret    []byte
+	tracer    tracing.Tracer
+	now       func() time.Time
+
+	handlerMutex sync.RWMutex
+	handler      func(ctx context.Context, service, artifactID string) error
+}
+
+// New returns a Service storing artifacts in directory. uploadURL is the base
+// URL of the server that artifacts are uploaded to and secret is used for
+// signing upload URLs. If secret is empty a random one is generated and upload
+// URLs are invalidated on restarts.
+func New(directory, uploadURL string, secret []byte, tracer tracing.Tracer) (*Service, error) {
+	if directory == "" {
+		return nil, errors.New("directory required")
+	}
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse upload url '%s'", uploadURL)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("upload url '%s' must be absolute", uploadURL)
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, errors.Wrap(err, "generate upload secret")
+		}
+	}
+	err = os.MkdirAll(path.Join(directory, uploadsDirectory), os.ModePerm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create directory '%s'", directory)
+	}
+	return &Service{
+		directory: directory,
+		uploadURL: u,
+		secret:    secret,
+		tracer:    tracer,
+		now:       time.Now,
+	}, nil
+}
+
+// InitializeNotifications registers handler to be called when an artifact is
+// uploaded.
+func (s *Service) InitializeNotifications(handler func(ctx context.Context, service, artifactID string) error) {
+	s.handlerMutex.Lock()
+	defer s.handlerMutex.Unlock()
+	s.handler = handler
+}
+
+// CreateArtifact returns a signed URL on the server for uploading the zipped
+// artifact. The upload must provide a Content-MD5 header matching md5.
+func (s *Service) CreateArtifact(artifactSpec artifact.Spec, md5 string) (string, error) {
+	if !validName(artifactSpec.Service) || !validName(artifactSpec.ID) {
+		return "", errors.Errorf("invalid service '%s' or artifact id '%s'", artifactSpec.Service, artifactSpec.ID)
+	}
+	expires := s.now().Add(uploadURLExpiry).Unix()
+	u := *s.uploadURL
+	u.Path = path.Join(u.Path, "artifacts", "upload", artifactSpec.Service, artifactSpec.ID)
+	query := url.Values{}
+	query.Set("md5", md5)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(artifactSpec.Service, artifactSpec.ID, md5, expires))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// UploadArtifact stores the zipped artifact in body. query is the query of
+// the URL returned by CreateArtifact and contentMD5 the base64 encoded MD5
+// digest of body.
+func (s *Service) UploadArtifact(ctx context.Context, service, artifactID string, query url.Values, contentMD5 string, body io.Reader) error {
+	span, ctx := s.tracer.FromCtx(ctx, "fsstorage.UploadArtifact")
+	defer span.End()
+	logger := log.WithContext(ctx).WithFields("service", service, "artifactId", artifactID)
+
+	expectedMD5 := query.Get("md5")
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !validName(service) || !validName(artifactID) {
+		return ErrInvalidUploadURL
+	}
+	signature := s.sign(service, artifactID, expectedMD5, expires)
+	if !hmac.Equal([]byte(signature), []byte(query.Get("signature"))) || s.now().Unix() > expires {
+		return ErrInvalidUploadURL
+	}
+	if contentMD5 != expectedMD5 {
+		return errors.WithMessage(ErrBadDigest, "Content-MD5 header does not match upload url")
+	}
+
+	zipFile, err := os.CreateTemp(path.Join(s.directory, uploadsDirectory), "artifact-*.zip")
+	if err != nil {
+		return errors.Wrap(err, "create temp file for zip")
+	}
+	defer func() {
+		err := os.Remove(zipFile.Name())
+		if err != nil {
+			logger.Errorf("fsstorage: failed to remove zip file '%s': %v", zipFile.Name(), err)
+		}
+	}()
+	digest := md5.New()
+	_, err = io.Copy(io.MultiWriter(zipFile, digest), body)
+	closeErr := zipFile.Close()
+	if err != nil {
+		return errors.Wrap(err, "write zip file")
+	}
+	if closeErr != nil {
+		return errors.Wrap(closeErr, "close zip file")
+	}
+	if base64.StdEncoding.EncodeToString(digest.Sum(nil)) != expectedMD5 {
+		return ErrBadDigest
+	}
+
+	extractPath, err := os.MkdirTemp(path.Join(s.directory, uploadsDirectory), "artifact-")
+	if err != nil {
+		return errors.Wrap(err, "create temp dir for artifact")
+	}
+	files, err := archive.Unzip(zipFile.Name(), extractPath)
+	if err != nil {
+		s.removeAll(ctx, extractPath)
+		return errors.WithMessage(err, "unzip artifact")
+	}
+	destination, err := s.artifactPath(service, artifactID)
+	if err != nil {
+		s.removeAll(ctx, extractPath)
+		return err
+	}
+	err = os.MkdirAll(path.Dir(destination), os.ModePerm)
+	if err != nil {
+		s.removeAll(ctx, extractPath)
+		return errors.Wrapf(err, "create service directory '%s'", path.Dir(destination))
+	}
+	// replace existing artifacts like uploading to an existing S3 key does
+	s.removeAll(ctx, destination)
+	err = os.Rename(extractPath, destination)
+	if err != nil {
+		s.removeAll(ctx, extractPath)
+		return errors.Wrapf(err, "move artifact to '%s'", destination)
+	}
+	logger.WithFields("files", files).Infof("fsstorage: stored artifact '%s' of service '%s' with %d files", artifactID, service, len(files))
+
+	s.handlerMutex.RLock()
+	handler := s.handler
+	s.handlerMutex.RUnlock()
+	if handler != nil {
+		err = handler(ctx, service, artifactID)
+		if err != nil {
+			logger.Errorf("fsstorage: handle artifact '%s' of service '%s' failed: %v", artifactID, service, err)
+		}
+	}
+	return nil
+}
+
+// sign returns the signature of an upload URL.
+func (s *Service) sign(service, artifactID, md5 string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d", service, artifactID, md5, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// artifactPath returns the directory of an artifact.
+func (s *Service) artifactPath(service, artifactID string) (string, error) {
+	if !validName(service) || !validName(artifactID) {
+		return "", errors.Errorf("invalid service '%s' or artifact id '%s'", service, artifactID)
+	}
+	p, err := securejoin.SecureJoin(s.directory, path.Join(service, artifactID))
+	if err != nil {
+		return "", errors.Wrapf(err, "join artifact path of service '%s' and artifact id '%s'", service, artifactID)
+	}
+	return p, nil
+}
+
+func (s *Service) removeAll(ctx context.Context, p string) {
+	err := os.RemoveAll(p)
+	if err != nil {
+		log.WithContext(ctx).Errorf("fsstorage: failed to remove '%s': %v", p, err)
+	}
+}
+
+// validName reports whether name can be used as a single path element.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && name[0] != '.' && path.Base(name) == name
+}
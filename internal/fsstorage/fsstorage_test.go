@@ -0,0 +1,225 @@
+package fsstorage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func initLogger() {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+}
+
+// zipArtifact returns a zipped artifact of spec with a dev resource and its
+// base64 encoded MD5 digest.
+func zipArtifact(t *testing.T, spec artifact.Spec) ([]byte, string) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, err := w.Create("artifact.json")
+	require.NoError(t, err, "create artifact.json")
+	require.NoError(t, json.NewEncoder(f).Encode(spec), "encode artifact.json")
+	f, err = w.Create("dev/40-deployment.yaml")
+	require.NoError(t, err, "create resource")
+	_, err = f.Write([]byte("kind: Deployment"))
+	require.NoError(t, err, "write resource")
+	require.NoError(t, w.Close(), "close zip")
+	digest := md5.Sum(buf.Bytes())
+	return buf.Bytes(), base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// upload uploads spec through the URL returned by CreateArtifact.
+func upload(t *testing.T, s *Service, spec artifact.Spec) {
+	t.Helper()
+	content, md5 := zipArtifact(t, spec)
+	uploadURL, err := s.CreateArtifact(spec, md5)
+	require.NoError(t, err, "create artifact")
+	u, err := url.Parse(uploadURL)
+	require.NoError(t, err, "parse upload url")
+	err = s.UploadArtifact(context.Background(), spec.Service, spec.ID, u.Query(), md5, bytes.NewReader(content))
+	require.NoError(t, err, "upload artifact")
+}
+
+func newService(t *testing.T) *Service {
+	t.Helper()
+	s, err := New(t.TempDir(), "https://release-manager.example.com/base", []byte("secret"), tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+	return s
+}
+
+func TestService_UploadArtifact(t *testing.T) {
+	initLogger()
+	spec := artifact.Spec{
+		ID:      "master-1",
+		Service: "product",
+		Application: artifact.Repository{
+			Branch: "master",
+		},
+	}
+	content, md5 := zipArtifact(t, spec)
+
+	tt := []struct {
+		name       string
+		service    string
+		artifactID string
+		contentMD5 string
+		content    []byte
+		after      time.Duration
+		err        error
+	}{
+		{
+			name:       "valid upload",
+			service:    "product",
+			artifactID: "master-1",
+			contentMD5: md5,
+			content:    content,
+		},
+		{
+			name:       "expired url",
+			service:    "product",
+			artifactID: "master-1",
+			contentMD5: md5,
+			content:    content,
+			after:      uploadURLExpiry + time.Minute,
+			err:        ErrInvalidUploadURL,
+		},
+		{
+			name:       "other artifact",
+			service:    "product",
+			artifactID: "master-2",
+			contentMD5: md5,
+			content:    content,
+			err:        ErrInvalidUploadURL,
+		},
+		{
+			name:       "path traversal",
+			service:    "..",
+			artifactID: "master-1",
+			contentMD5: md5,
+			content:    content,
+			err:        ErrInvalidUploadURL,
+		},
+		{
+			name:       "content md5 header mismatch",
+			service:    "product",
+			artifactID: "master-1",
+			contentMD5: "other",
+			content:    content,
+			err:        ErrBadDigest,
+		},
+		{
+			name:       "content mismatch",
+			service:    "product",
+			artifactID: "master-1",
+			contentMD5: md5,
+			content:    []byte("other content"),
+			err:        ErrBadDigest,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newService(t)
+			var notified []string
+			s.InitializeNotifications(func(ctx context.Context, service, artifactID string) error {
+				notified = append(notified, service+"/"+artifactID)
+				return nil
+			})
+			uploadURL, err := s.CreateArtifact(spec, md5)
+			require.NoError(t, err, "create artifact")
+			u, err := url.Parse(uploadURL)
+			require.NoError(t, err, "parse upload url")
+			assert.Equal(t, "/base/artifacts/upload/product/master-1", u.Path, "upload path not as expected")
+			now := time.Now()
+			s.now = func() time.Time {
+				return now.Add(tc.after)
+			}
+
+			err = s.UploadArtifact(context.Background(), tc.service, tc.artifactID, u.Query(), tc.contentMD5, bytes.NewReader(tc.content))
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.Empty(t, notified, "unexpected notifications")
+				exists, err := s.ArtifactExists(context.Background(), "product", "master-1")
+				require.NoError(t, err, "artifact exists")
+				assert.False(t, exists, "artifact stored")
+				return
+			}
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, []string{"product/master-1"}, notified, "notifications not as expected")
+			storedSpec, err := s.ArtifactSpecification(context.Background(), "product", "master-1")
+			require.NoError(t, err, "get specification")
+			assert.Equal(t, spec, storedSpec, "stored specification not as expected")
+			specPath, resourcesPath, close, err := s.ArtifactPaths(context.Background(), "product", "dev", "master", "master-1")
+			require.NoError(t, err, "get artifact paths")
+			defer close(context.Background())
+			assert.FileExists(t, specPath, "spec path")
+			assert.FileExists(t, path.Join(resourcesPath, "40-deployment.yaml"), "resources path")
+		})
+	}
+}
+
+func TestService_ArtifactSpecifications(t *testing.T) {
+	initLogger()
+	s := newService(t)
+	newArtifact := func(id, branch string) artifact.Spec {
+		return artifact.Spec{
+			ID:      id,
+			Service: "product",
+			Application: artifact.Repository{
+				Branch: branch,
+			},
+		}
+	}
+	// artifacts are uploaded from oldest to newest with distinct modification
+	// times
+	base := time.Now().Add(-time.Hour)
+	for i, spec := range []artifact.Spec{
+		newArtifact("master-1", "master"),
+		newArtifact("feature_a-1", "feature/a"),
+		newArtifact("master-2", "master"),
+	} {
+		upload(t, s, spec)
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(path.Join(s.directory, "product", spec.ID), modTime, modTime), "set modification time")
+	}
+	ctx := context.Background()
+
+	specs, err := s.ArtifactSpecifications(ctx, "product", 5, "")
+	require.NoError(t, err, "list all")
+	assert.Equal(t, []artifact.Spec{newArtifact("master-2", "master"), newArtifact("feature_a-1", "feature/a"), newArtifact("master-1", "master")}, specs)
+
+	specs, err = s.ArtifactSpecifications(ctx, "product", 1, "master")
+	require.NoError(t, err, "list master")
+	assert.Equal(t, []artifact.Spec{newArtifact("master-2", "master")}, specs)
+
+	latest, err := s.LatestArtifactSpecification(ctx, "product", "feature/a")
+	require.NoError(t, err, "latest feature")
+	assert.Equal(t, newArtifact("feature_a-1", "feature/a"), latest)
+
+	_, err = s.LatestArtifactSpecification(ctx, "product", "unknown")
+	assert.ErrorIs(t, err, flow.ErrArtifactNotFound)
+
+	_, err = s.ArtifactSpecification(ctx, "unknown", "master-1")
+	assert.ErrorIs(t, err, flow.ErrArtifactNotFound)
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -328,6 +329,48 @@ func locateEnvReleaseCondition(env, artifactID string) conditionFunc {
 	})
 }
 
+// WalkReleases traverses the git log of the master repository calling f with
+// each release commit from newest to oldest. Traversal stops when f returns
+// false or the log is exhausted.
+func (s *Service) WalkReleases(ctx context.Context, f func(info commitinfo.CommitInfo, releasedAt time.Time) bool) error {
+	span, _ := s.Tracer.FromCtx(ctx, "git.WalkReleases")
+	defer span.End()
+	s.masterMutex.RLock()
+	defer s.masterMutex.RUnlock()
+	if s.master == nil {
+		return errors.New("master repository not initialized")
+	}
+	ref, err := s.master.Head()
+	if err != nil {
+		return errors.WithMessage(err, "retrieve HEAD branch")
+	}
+	cIter, err := s.master.Log(&git.LogOptions{
+		From: ref.Hash(),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "retrieve commit history")
+	}
+	defer cIter.Close()
+	for {
+		commit, err := cIter.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.WithMessage(err, "retrieve commit")
+		}
+		infos, err := commitinfo.ParseCommitInfos(commit.Message)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			if !f(info, commit.Committer.When) {
+				return nil
+			}
+		}
+	}
+}
+
 // LocateServiceReleaseRollbackSkip traverses the git log to find the nth
 // release or rollback commit for a specified service and environment.
 //
@@ -456,6 +499,43 @@ func (s *Service) Commit(ctx context.Context, rootPath, changesPath, msg string)
 	return s.gitPush(ctx, rootPath)
 }
 
+// StageChanges adds all changes in the working tree at rootPath to the index.
+// It reports whether the index changed, ie. false if the working tree had no
+// changes compared to the index.
+func (s *Service) StageChanges(ctx context.Context, rootPath string) (bool, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "git.StageChanges")
+	defer span.End()
+	before, err := commandOutput(ctx, rootPath, "git", "write-tree")
+	if err != nil {
+		return false, errors.WithMessage(err, "write tree before adding changes")
+	}
+	err = execCommand(ctx, rootPath, "git", "add", ".")
+	if err != nil {
+		return false, errors.WithMessage(err, "add changes")
+	}
+	after, err := commandOutput(ctx, rootPath, "git", "write-tree")
+	if err != nil {
+		return false, errors.WithMessage(err, "write tree after adding changes")
+	}
+	return !bytes.Equal(before, after), nil
+}
+
+// DiscardUnstagedChanges resets the working tree at rootPath to the index
+// removing all changes not added with StageChanges.
+func (s *Service) DiscardUnstagedChanges(ctx context.Context, rootPath string) error {
+	span, ctx := s.Tracer.FromCtx(ctx, "git.DiscardUnstagedChanges")
+	defer span.End()
+	err := execCommand(ctx, rootPath, "git", "checkout", "--", ".")
+	if err != nil {
+		return errors.WithMessage(err, "checkout index")
+	}
+	err = execCommand(ctx, rootPath, "git", "clean", "-fdq")
+	if err != nil {
+		return errors.WithMessage(err, "remove untracked files")
+	}
+	return nil
+}
+
 // advanceMirror fast-forwards the local master mirror to the commit just pushed
 // from rootPath via a local (no-network) push; updateInstead, set in
 // InitMasterRepo, moves the mirror's ref and working tree atomically.
@@ -593,6 +673,20 @@ func execCommand(ctx context.Context, rootPath string, cmdName string, args ...s
 	return knownErr
 }
 
+// commandOutput runs a command in rootPath and returns its standard output.
+func commandOutput(ctx context.Context, rootPath string, cmdName string, args ...string) ([]byte, error) {
+	log.WithContext(ctx).WithFields("root", rootPath).Infof("git/commandOutput: running: %s %s", cmdName, strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, cmdName, args...)
+	cmd.Dir = rootPath
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.Output()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "execute command failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout, nil
+}
+
 // knownGitErrors contains error messages that should be considered as errors by
 // release-manager and because of this return an error.
 var knownGitErrors = []string{
@@ -15,6 +15,12 @@ func TestLocateReleaseCondition(t *testing.T) {
 		message    string
 		output     bool
 	}{
+		{
+			name:       "undeploy commit",
+			artifactID: "master-1234567890-1234567890",
+			message:    "[env/service-name] undeploy master-1234567890-1234567890 by test@lunar.app\n\nService: service-name\nEnvironment: env\nArtifact-ID: master-1234567890-1234567890\nArtifact-released-by: test <test@lunar.app>\nRelease-intent: Undeploy",
+			output:     false,
+		},
 		{
 			name:       "empty artifact ID",
 			artifactID: "",
@@ -104,6 +110,13 @@ func TestLocateServiceReleaseCondition(t *testing.T) {
 		message string
 		output  bool
 	}{
+		{
+			name:    "undeploy commit",
+			env:     "env",
+			service: "service-name",
+			message: "[env/service-name] undeploy master-1234567890-1234567890 by test@lunar.app\n\nService: service-name\nEnvironment: env\nArtifact-ID: master-1234567890-1234567890\nArtifact-released-by: test <test@lunar.app>\nRelease-intent: Undeploy",
+			output:  false,
+		},
 		{
 			name:    "empty env",
 			env:     "",
@@ -321,6 +334,17 @@ func TestLocateServiceReleaseRollbackSkipCondition(t *testing.T) {
 				{"[env/service-name] rollback master-1234567890-1234567890 to master-0123456789-0123456789 by test@lunar.app", true},
 			},
 		},
+		{
+			name:    "undeploy commit is skipped for rollbacks",
+			env:     "env",
+			service: "service-name",
+			skip:    1,
+			cases: []result{
+				{"[env/service-name] undeploy master-2345678901-2345678901 by test@lunar.app\n\nService: service-name\nEnvironment: env\nArtifact-ID: master-2345678901-2345678901\nArtifact-released-by: test <test@lunar.app>\nRelease-intent: Undeploy", false},
+				{"[env/service-name] release master-1234567890-1234567890 by test@lunar.app", false},
+				{"[env/service-name] release master-0123456789-0123456789 by test@lunar.app", true},
+			},
+		},
 		{
 			name:    "wrong case service rollback commit on second case and 1 skip and author email",
 			env:     "env",
@@ -357,6 +381,13 @@ func TestLocateEnvReleaseCondition(t *testing.T) {
 		message    string
 		output     bool
 	}{
+		{
+			name:       "undeploy commit",
+			env:        "env",
+			artifactID: "master-1234567890-1234567890",
+			message:    "[env/service-name] undeploy master-1234567890-1234567890 by test@lunar.app\n\nService: service-name\nEnvironment: env\nArtifact-ID: master-1234567890-1234567890\nArtifact-released-by: test <test@lunar.app>\nRelease-intent: Undeploy",
+			output:     false,
+		},
 		{
 			name:       "empty env",
 			env:        "",
@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStageChanges verifies that releases staged into a shared working tree
+// are kept while the unstaged changes of a failed release are discarded.
+func TestStageChanges(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_SYSTEM", os.DevNull)
+
+	ctx := context.Background()
+	workDir := t.TempDir()
+	runGit(t, workDir, "init", "-b", "master")
+	configureIdentity(t, workDir)
+	writeFile(t, workDir, "a.txt", "a")
+	runGit(t, workDir, "add", ".")
+	runGit(t, workDir, "commit", "-m", "add a")
+
+	s := &Service{
+		Tracer: tracing.NewNoop(),
+	}
+
+	// a successful release
+	writeFile(t, workDir, "b.txt", "b")
+	changed, err := s.StageChanges(ctx, workDir)
+	require.NoError(t, err, "unexpected stage error")
+	assert.True(t, changed, "changes of release not staged")
+
+	// a release already up to date
+	changed, err = s.StageChanges(ctx, workDir)
+	require.NoError(t, err, "unexpected stage error")
+	assert.False(t, changed, "unchanged working tree reported as changed")
+
+	// a failed release leaving partial changes
+	require.NoError(t, os.Remove(filepath.Join(workDir, "a.txt")))
+	writeFile(t, workDir, "b.txt", "partial")
+	writeFile(t, workDir, "c.txt", "c")
+	err = s.DiscardUnstagedChanges(ctx, workDir)
+	require.NoError(t, err, "unexpected discard error")
+
+	content, err := os.ReadFile(filepath.Join(workDir, "b.txt"))
+	require.NoError(t, err, "staged release removed")
+	assert.Equal(t, "b", string(content), "staged release content not as expected")
+	assert.FileExists(t, filepath.Join(workDir, "a.txt"), "removed file not restored")
+	assert.NoFileExists(t, filepath.Join(workDir, "c.txt"), "untracked file not removed")
+}
@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// Commit is a commit in a source repository.
+type Commit struct {
+	SHA         string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+	URL         string
+}
+
+type compareResponse struct {
+	TotalCommits int             `json:"total_commits"`
+	Commits      []compareCommit `json:"commits"`
+}
+
+type compareCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// comparePageSize is the number of commits requested per page when comparing
+// commits. 100 is the maximum allowed by GitHub.
+const comparePageSize = 100
+
+// CompareCommits returns the commits reachable from head but not from base in
+// repository. Commits are ordered from oldest to newest.
+func (s *Service) CompareCommits(ctx context.Context, repository, base, head string) ([]Commit, error) {
+	var commits []Commit
+	for page := 1; ; page++ {
+		resp, err := s.compare(ctx, repository, base, head, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range resp.Commits {
+			commits = append(commits, Commit{
+				SHA:         c.SHA,
+				Message:     c.Commit.Message,
+				AuthorName:  c.Commit.Author.Name,
+				AuthorEmail: c.Commit.Author.Email,
+				URL:         c.HTMLURL,
+			})
+		}
+		if len(resp.Commits) < comparePageSize || len(commits) >= resp.TotalCommits {
+			return commits, nil
+		}
+	}
+}
+
+func (s *Service) compare(ctx context.Context, repository, base, head string, page int) (compareResponse, error) {
+	req, err := s.req(ctx, http.MethodGet, fmt.Sprintf("repos/lunarway/%s/compare/%s...%s", repository, base, head), nil)
+	if err != nil {
+		return compareResponse{}, err
+	}
+	query := req.URL.Query()
+	query.Set("per_page", strconv.Itoa(comparePageSize))
+	query.Set("page", strconv.Itoa(page))
+	req.URL.RawQuery = query.Encode()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return compareResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errorBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.WithContext(ctx).Errorf("internal/github: failed to read error body of request")
+		}
+		return compareResponse{}, fmt.Errorf("internal/github: http request failed: %s %s: status %v: body: %s", req.Method, req.URL, resp.Status, errorBody)
+	}
+	var body compareResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return compareResponse{}, errors.WithMessage(err, "decode response body")
+	}
+	return body, nil
+}
@@ -0,0 +1,64 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CompareCommits(t *testing.T) {
+	totalCommits := comparePageSize + 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/lunarway/product/compare/base...head", r.URL.Path, "path not as expected")
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		require.NoError(t, err, "parse page")
+		var resp compareResponse
+		resp.TotalCommits = totalCommits
+		for i := (page - 1) * comparePageSize; i < totalCommits && i < page*comparePageSize; i++ {
+			c := compareCommit{
+				SHA: fmt.Sprintf("sha-%d", i),
+			}
+			c.Commit.Message = fmt.Sprintf("commit %d", i)
+			resp.Commits = append(resp.Commits, c)
+		}
+		err = json.NewEncoder(w).Encode(resp)
+		require.NoError(t, err, "encode response")
+	}))
+	defer server.Close()
+	apiURL, err := url.Parse(server.URL)
+	require.NoError(t, err, "parse server url")
+	s := Service{
+		apiURL: apiURL,
+	}
+
+	commits, err := s.CompareCommits(context.Background(), "product", "base", "head")
+
+	require.NoError(t, err, "unexpected error")
+	require.Len(t, commits, totalCommits, "number of commits not as expected")
+	assert.Equal(t, Commit{SHA: "sha-0", Message: "commit 0"}, commits[0], "first commit not as expected")
+	assert.Equal(t, fmt.Sprintf("sha-%d", comparePageSize), commits[comparePageSize].SHA, "last commit not as expected")
+}
+
+func TestService_CompareCommits_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	apiURL, err := url.Parse(server.URL)
+	require.NoError(t, err, "parse server url")
+	s := Service{
+		apiURL: apiURL,
+	}
+
+	_, err = s.CompareCommits(context.Background(), "product", "base", "head")
+
+	assert.Error(t, err, "expected an error")
+}
@@ -15,6 +15,9 @@ import (
 
 type Service struct {
 	Token string
+
+	// apiURL overrides the GitHub API location. Used in tests.
+	apiURL *url.URL
 }
 
 func (s *Service) req(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
@@ -23,6 +26,10 @@ func (s *Service) req(ctx context.Context, method, path string, body io.Reader)
 		Scheme: "https",
 		Path:   path,
 	}
+	if s.apiURL != nil {
+		url.Host = s.apiURL.Host
+		url.Scheme = s.apiURL.Scheme
+	}
 	req, err := http.NewRequestWithContext(ctx, method, url.String(), body)
 	if err != nil {
 		return nil, err
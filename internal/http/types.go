@@ -31,6 +31,51 @@ type Environment struct {
 	LowVulnerabilities    int64  `json:"lowVulnerabilities,omitempty"`
 }
 
+// StatusMatrixResponse is the current release of all services in all
+// environments.
+type StatusMatrixResponse struct {
+	Environments []string              `json:"environments"`
+	Services     []StatusMatrixService `json:"services"`
+}
+
+type StatusMatrixService struct {
+	Service  string                `json:"service,omitempty"`
+	Squad    string                `json:"squad,omitempty"`
+	Releases []StatusMatrixRelease `json:"releases,omitempty"`
+}
+
+type StatusMatrixRelease struct {
+	Environment           string    `json:"environment,omitempty"`
+	Namespace             string    `json:"namespace,omitempty"`
+	ArtifactID            string    `json:"artifactId,omitempty"`
+	Squad                 string    `json:"squad,omitempty"`
+	ReleasedAt            time.Time `json:"releasedAt,omitempty"`
+	ReleasedByEmail       string    `json:"releasedByEmail,omitempty"`
+	ReleasedByName        string    `json:"releasedByName,omitempty"`
+	HighVulnerabilities   int64     `json:"highVulnerabilities"`
+	MediumVulnerabilities int64     `json:"mediumVulnerabilities"`
+	LowVulnerabilities    int64     `json:"lowVulnerabilities"`
+}
+
+// ListEnvironmentsResponse lists the environments known by the release
+// manager.
+type ListEnvironmentsResponse struct {
+	Environments []string `json:"environments"`
+}
+
+// ListNamespacesResponse lists the namespaces of an environment.
+type ListNamespacesResponse struct {
+	Environment string   `json:"environment,omitempty"`
+	Namespaces  []string `json:"namespaces"`
+}
+
+// ListServicesResponse lists the services of a namespace in an environment.
+type ListServicesResponse struct {
+	Environment string   `json:"environment,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+	Services    []string `json:"services"`
+}
+
 type ReleaseRequest struct {
 	Service        string        `json:"service,omitempty"`
 	Environment    string        `json:"environment,omitempty"`
@@ -68,6 +113,55 @@ type ReleaseResponse struct {
 	Tag           string `json:"tag,omitempty"`
 }
 
+type UndeployRequest struct {
+	Service        string `json:"service,omitempty"`
+	Environment    string `json:"environment,omitempty"`
+	Namespace      string `json:"namespace,omitempty"`
+	CommitterName  string `json:"committerName,omitempty"`
+	CommitterEmail string `json:"committerEmail,omitempty"`
+}
+
+func (r UndeployRequest) Validate(w http.ResponseWriter) bool {
+	var errs validationErrors
+	if emptyString(r.Service) {
+		errs.Append(requiredField("service"))
+	}
+	if emptyString(r.Environment) {
+		errs.Append(requiredField("environment"))
+	}
+	return errs.Evaluate(w)
+}
+
+type UndeployResponse struct {
+	Service     string `json:"service,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	ArtifactID  string `json:"artifactId,omitempty"`
+	Status      string `json:"status,omitempty"`
+}
+
+// ReleaseStatusResponse describes the lifecycle of a single release.
+type ReleaseStatusResponse struct {
+	ID          string                    `json:"id,omitempty"`
+	Service     string                    `json:"service,omitempty"`
+	Environment string                    `json:"environment,omitempty"`
+	Namespace   string                    `json:"namespace,omitempty"`
+	ArtifactID  string                    `json:"artifactId,omitempty"`
+	ReleasedBy  string                    `json:"releasedBy,omitempty"`
+	Intent      intent.Intent             `json:"intent,omitempty"`
+	State       string                    `json:"state,omitempty"`
+	Error       string                    `json:"error,omitempty"`
+	CreatedAt   time.Time                 `json:"createdAt,omitempty"`
+	UpdatedAt   time.Time                 `json:"updatedAt,omitempty"`
+	Transitions []ReleaseStatusTransition `json:"transitions,omitempty"`
+}
+
+type ReleaseStatusTransition struct {
+	State   string    `json:"state,omitempty"`
+	Message string    `json:"message,omitempty"`
+	At      time.Time `json:"at,omitempty"`
+}
+
 type ReleaseEvent struct {
 	Name          string `json:"name,omitempty"`
 	Namespace     string `json:"namespace,omitempty"`
@@ -126,6 +220,38 @@ type JobErrorEvent struct {
 	Squad       string              `json:"squad,omitempty"`
 }
 
+// ClusterStateReport is the set of release manager controlled resources
+// running in an environment as reported by the daemon.
+type ClusterStateReport struct {
+	Environment string            `json:"environment,omitempty"`
+	Resources   []ClusterResource `json:"resources,omitempty"`
+}
+
+type ClusterResource struct {
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	ArtifactID string `json:"artifactId,omitempty"`
+}
+
+// DriftResponse lists resources where the cluster state differs from the
+// config repository.
+type DriftResponse struct {
+	Drifts []Drift `json:"drifts"`
+}
+
+type Drift struct {
+	Environment        string    `json:"environment,omitempty"`
+	Namespace          string    `json:"namespace,omitempty"`
+	Kind               string    `json:"kind,omitempty"`
+	Name               string    `json:"name,omitempty"`
+	Service            string    `json:"service,omitempty"`
+	Type               string    `json:"type,omitempty"`
+	ExpectedArtifactID string    `json:"expectedArtifactId,omitempty"`
+	ActualArtifactID   string    `json:"actualArtifactId,omitempty"`
+	DetectedAt         time.Time `json:"detectedAt,omitempty"`
+}
+
 type KubernetesNotifyResponse struct {
 }
 
@@ -133,6 +259,7 @@ type ListPoliciesResponse struct {
 	Service            string                    `json:"service,omitempty"`
 	AutoReleases       []AutoReleasePolicy       `json:"autoReleases,omitempty"`
 	BranchRestrictions []BranchRestrictionPolicy `json:"branchRestrictions,omitempty"`
+	RequiredStages     []RequiredStagesPolicy    `json:"requiredStages,omitempty"`
 }
 
 type AutoReleasePolicy struct {
@@ -147,6 +274,47 @@ type BranchRestrictionPolicy struct {
 	BranchRegex string `json:"branchRegex,omitempty"`
 }
 
+type RequiredStagesPolicy struct {
+	ID          string   `json:"id,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Stages      []string `json:"stages,omitempty"`
+}
+
+type ApplyRequiredStagesPolicyRequest struct {
+	Service        string   `json:"service,omitempty"`
+	Environment    string   `json:"environment,omitempty"`
+	Stages         []string `json:"stages,omitempty"`
+	CommitterName  string   `json:"committerName,omitempty"`
+	CommitterEmail string   `json:"committerEmail,omitempty"`
+}
+
+func (r ApplyRequiredStagesPolicyRequest) Validate(w http.ResponseWriter) bool {
+	var errs validationErrors
+	if emptyString(r.Service) {
+		errs.Append(requiredField("service"))
+	}
+	if emptyString(r.Environment) {
+		errs.Append(requiredField("environment"))
+	}
+	if len(r.Stages) == 0 {
+		errs.Append(requiredField("stages"))
+	}
+	for _, stage := range r.Stages {
+		if emptyString(stage) {
+			errs.Append("stages must not contain empty values")
+			break
+		}
+	}
+	return errs.Evaluate(w)
+}
+
+type ApplyRequiredStagesPolicyResponse struct {
+	ID          string   `json:"id,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Stages      []string `json:"stages,omitempty"`
+}
+
 type ApplyBranchRestrictionPolicyRequest struct {
 	Service        string `json:"service,omitempty"`
 	Environment    string `json:"environment,omitempty"`
@@ -247,11 +415,71 @@ type DescribeReleaseResponseRelease struct {
 	Intent          intent.Intent `json:"intent,omitempty"`
 }
 
+// DoraMetricsResponse contains the DORA metrics of deployments between From
+// and To by service, squad and environment.
+type DoraMetricsResponse struct {
+	From         time.Time     `json:"from,omitempty"`
+	To           time.Time     `json:"to,omitempty"`
+	Services     []DoraMetrics `json:"services,omitempty"`
+	Squads       []DoraMetrics `json:"squads,omitempty"`
+	Environments []DoraMetrics `json:"environments,omitempty"`
+}
+
+type DoraMetrics struct {
+	Environment          string  `json:"environment,omitempty"`
+	Service              string  `json:"service,omitempty"`
+	Squad                string  `json:"squad,omitempty"`
+	Deployments          int     `json:"deployments"`
+	DeploymentFrequency  float64 `json:"deploymentFrequency"`
+	LeadTimeSeconds      float64 `json:"leadTimeSeconds"`
+	Failures             int     `json:"failures"`
+	ChangeFailureRate    float64 `json:"changeFailureRate"`
+	TimeToRestoreSeconds float64 `json:"timeToRestoreSeconds"`
+}
+
+// ChangelogResponse describes the source repository changes between the
+// artifact released to an environment and a candidate artifact. Groups are
+// ordered by conventional commit type with commits listed newest first.
+type ChangelogResponse struct {
+	Service        string           `json:"service,omitempty"`
+	Environment    string           `json:"environment,omitempty"`
+	FromArtifactID string           `json:"fromArtifactId,omitempty"`
+	FromSHA        string           `json:"fromSha,omitempty"`
+	ToArtifactID   string           `json:"toArtifactId,omitempty"`
+	ToSHA          string           `json:"toSha,omitempty"`
+	Groups         []ChangelogGroup `json:"groups,omitempty"`
+}
+
+type ChangelogGroup struct {
+	Type    string            `json:"type,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	Commits []ChangelogCommit `json:"commits,omitempty"`
+}
+
+type ChangelogCommit struct {
+	SHA        string `json:"sha,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	Subject    string `json:"subject,omitempty"`
+	Breaking   bool   `json:"breaking,omitempty"`
+	AuthorName string `json:"authorName,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
 type DescribeArtifactResponse struct {
 	Service   string          `json:"service,omitempty"`
 	Artifacts []artifact.Spec `json:"artifacts,omitempty"`
 }
 
+type SearchArtifactsResponse struct {
+	Artifacts []SearchArtifactsResult `json:"artifacts,omitempty"`
+}
+
+type SearchArtifactsResult struct {
+	Artifact artifact.Spec `json:"artifact,omitempty"`
+	// Environments are the environments the artifact is currently released to.
+	Environments []string `json:"environments,omitempty"`
+}
+
 type ArtifactUploadRequest struct {
 	Artifact artifact.Spec `json:"artifact,omitempty"`
 	MD5      string        `json:"md5,omitempty"`
@@ -274,3 +502,22 @@ func (r ArtifactUploadRequest) Validate(w http.ResponseWriter) bool {
 type ArtifactUploadResponse struct {
 	ArtifactUploadURL string `json:"artifactUploadUrl,omitempty"`
 }
+
+// DeadLetter describes a message dropped by the release manager after failed
+// handlings.
+type DeadLetter struct {
+	ID          string    `json:"id,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	Service     string    `json:"service,omitempty"`
+	Environment string    `json:"environment,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DroppedAt   time.Time `json:"droppedAt,omitempty"`
+}
+
+type ListDeadLettersResponse struct {
+	DeadLetters []DeadLetter `json:"deadLetters,omitempty"`
+}
+
+type ReplayDeadLetterResponse struct {
+	DeadLetter DeadLetter `json:"deadLetter,omitempty"`
+}
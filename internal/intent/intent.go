@@ -8,6 +8,7 @@ const (
 	TypePromote         = "Promote"
 	TypeRollback        = "Rollback"
 	TypeAutoRelease     = "AutoRelease"
+	TypeUndeploy        = "Undeploy"
 )
 
 type Intent struct {
@@ -68,6 +69,12 @@ func NewRollback(previousArtifactID string) Intent {
 	}
 }
 
+func NewUndeploy() Intent {
+	return Intent{
+		Type: TypeUndeploy,
+	}
+}
+
 func (intent *Intent) Valid() bool {
 	return !intent.Empty()
 }
@@ -88,6 +95,8 @@ func (intent *Intent) AsArtifactWithIntent(artifactID string) string {
 		return fmt.Sprintf("rollback to artifact '%s' from artifact '%s'", artifactID, intent.Rollback.PreviousArtifactID)
 	case TypeAutoRelease:
 		return fmt.Sprintf("autorelease artifact '%s'", artifactID)
+	case TypeUndeploy:
+		return fmt.Sprintf("undeploy of artifact '%s'", artifactID)
 	default:
 		return fmt.Sprintf("invalid intent with artifact '%s'", artifactID)
 	}
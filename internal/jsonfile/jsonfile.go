@@ -0,0 +1,41 @@
+// Package jsonfile persists values as JSON files.
+package jsonfile
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Write marshals v as JSON and writes it to path. The content is written to a
+// temporary file in the directory of path and renamed into place to avoid
+// partially written files on crashes.
+func Write(path string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithMessage(err, "marshal")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return errors.WithMessage(err, "create temporary file")
+	}
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.WithMessage(err, "write temporary file")
+	}
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.WithMessage(err, "close temporary file")
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.WithMessage(err, "rename temporary file")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "value.json")
+
+	require.NoError(t, Write(path, map[string]string{"key": "first"}))
+	require.NoError(t, Write(path, map[string]string{"key": "second"}))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"second"}`, string(content))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files must not be left behind")
+}
+
+func TestWrite_missingDirectory(t *testing.T) {
+	err := Write(filepath.Join(t.TempDir(), "missing", "value.json"), "value")
+	assert.Error(t, err)
+}
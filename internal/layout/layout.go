@@ -0,0 +1,371 @@
+// Package layout implements the directory layout of releases in the config
+// repository.
+//
+// A layout consists of two path templates relative to the root of the config
+// repository. The release template locates the directory the resources of a
+// service are released to and the kustomization template the directory Flux
+// kustomizations are moved to. Templates are slash separated paths where a
+// segment is either a literal directory name or one of the placeholders
+// {environment}, {namespace} and {service}.
+package layout
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Default templates matching the layout used by the release manager since its
+// beginning.
+const (
+	DefaultRelease       = "{environment}/releases/{namespace}/{service}"
+	DefaultKustomization = "clusters/{environment}/{namespace}"
+)
+
+// ErrInvalidTemplate indicates that a layout template is not valid.
+var ErrInvalidTemplate = errors.New("invalid layout template")
+
+type placeholder string
+
+const (
+	placeholderEnvironment placeholder = "{environment}"
+	placeholderNamespace   placeholder = "{namespace}"
+	placeholderService     placeholder = "{service}"
+)
+
+// Location identifies a release in the config repository.
+type Location struct {
+	Environment string
+	Namespace   string
+	Service     string
+}
+
+func (l Location) value(p placeholder) string {
+	switch p {
+	case placeholderEnvironment:
+		return l.Environment
+	case placeholderNamespace:
+		return l.Namespace
+	case placeholderService:
+		return l.Service
+	}
+	return ""
+}
+
+func (l *Location) set(p placeholder, value string) {
+	switch p {
+	case placeholderEnvironment:
+		l.Environment = value
+	case placeholderNamespace:
+		l.Namespace = value
+	case placeholderService:
+		l.Service = value
+	}
+}
+
+type segment struct {
+	literal     string
+	placeholder placeholder
+}
+
+type template []segment
+
+func (t template) String() string {
+	parts := make([]string, len(t))
+	for i, s := range t {
+		if s.placeholder != "" {
+			parts[i] = string(s.placeholder)
+			continue
+		}
+		parts[i] = s.literal
+	}
+	return strings.Join(parts, "/")
+}
+
+func (t template) index(p placeholder) int {
+	for i, s := range t {
+		if s.placeholder == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseTemplate(text string, required ...placeholder) (template, error) {
+	var t template
+	seen := make(map[placeholder]bool)
+	for _, part := range strings.Split(strings.Trim(text, "/"), "/") {
+		switch {
+		case part == "" || part == "." || part == "..":
+			return nil, errors.WithMessagef(ErrInvalidTemplate, "'%s': segment '%s' not allowed", text, part)
+		case strings.HasPrefix(part, "{"):
+			p := placeholder(part)
+			if p != placeholderEnvironment && p != placeholderNamespace && p != placeholderService {
+				return nil, errors.WithMessagef(ErrInvalidTemplate, "'%s': unknown placeholder '%s'", text, part)
+			}
+			if seen[p] {
+				return nil, errors.WithMessagef(ErrInvalidTemplate, "'%s': placeholder '%s' used more than once", text, part)
+			}
+			seen[p] = true
+			t = append(t, segment{placeholder: p})
+		case strings.ContainsAny(part, "{}"):
+			return nil, errors.WithMessagef(ErrInvalidTemplate, "'%s': placeholders must be whole path segments", text)
+		default:
+			t = append(t, segment{literal: part})
+		}
+	}
+	for _, p := range required {
+		if !seen[p] {
+			return nil, errors.WithMessagef(ErrInvalidTemplate, "'%s': placeholder '%s' required", text, p)
+		}
+	}
+	return t, nil
+}
+
+// Layout locates releases in the config repository.
+type Layout struct {
+	release       template
+	kustomization template
+}
+
+// New allocates a Layout from a release and kustomization template. The
+// release template must contain the {environment} and {service} placeholders
+// and the kustomization template the {environment} placeholder. Empty
+// templates default to DefaultRelease and DefaultKustomization.
+func New(release, kustomization string) (*Layout, error) {
+	if release == "" {
+		release = DefaultRelease
+	}
+	if kustomization == "" {
+		kustomization = DefaultKustomization
+	}
+	releaseTemplate, err := parseTemplate(release, placeholderEnvironment, placeholderService)
+	if err != nil {
+		return nil, errors.WithMessage(err, "release template")
+	}
+	kustomizationTemplate, err := parseTemplate(kustomization, placeholderEnvironment)
+	if err != nil {
+		return nil, errors.WithMessage(err, "kustomization template")
+	}
+	return &Layout{
+		release:       releaseTemplate,
+		kustomization: kustomizationTemplate,
+	}, nil
+}
+
+// Default returns the default layout of DefaultRelease and
+// DefaultKustomization.
+func Default() *Layout {
+	l, err := New(DefaultRelease, DefaultKustomization)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// File is the format of a layout file in the config repository.
+type File struct {
+	Release       string `yaml:"release"`
+	Kustomization string `yaml:"kustomization"`
+}
+
+// Load reads a layout file at path. Templates not set in the file default to
+// fallback. If the file does not exist an error satisfying
+// errors.Is(err, os.ErrNotExist) is returned.
+func Load(path string, fallback File) (*Layout, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	err = yaml.Unmarshal(content, &file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "parse layout file '%s'", path)
+	}
+	if file.Release == "" {
+		file.Release = fallback.Release
+	}
+	if file.Kustomization == "" {
+		file.Kustomization = fallback.Kustomization
+	}
+	return New(file.Release, file.Kustomization)
+}
+
+// ReleaseTemplate returns the release template of the layout.
+func (l *Layout) ReleaseTemplate() string {
+	return l.release.String()
+}
+
+// KustomizationTemplate returns the kustomization template of the layout.
+func (l *Layout) KustomizationTemplate() string {
+	return l.kustomization.String()
+}
+
+// HasNamespace reports whether releases are located by namespace. If not, the
+// namespace of a release is only known from its artifact.
+func (l *Layout) HasNamespace() bool {
+	return l.release.index(placeholderNamespace) != -1
+}
+
+// ReleasePath returns the directory of the release at location in root.
+func (l *Layout) ReleasePath(root string, location Location) (string, error) {
+	return join(root, l.release, location)
+}
+
+// KustomizationPath returns the directory of Flux kustomizations of location
+// in root.
+func (l *Layout) KustomizationPath(root string, location Location) (string, error) {
+	return join(root, l.kustomization, location)
+}
+
+func join(root string, t template, location Location) (string, error) {
+	p := root
+	var err error
+	for _, s := range t {
+		value := s.literal
+		if s.placeholder != "" {
+			value = location.value(s.placeholder)
+		}
+		p, err = securejoin.SecureJoin(p, value)
+		if err != nil {
+			return "", errors.WithMessagef(err, "join '%s' to path", value)
+		}
+	}
+	return p, nil
+}
+
+// ParseReleasePath returns the location of a slash separated release
+// directory relative to the root of the config repository. It reports false if
+// the path is not a release directory of the layout.
+func (l *Layout) ParseReleasePath(p string) (Location, bool) {
+	parts := strings.Split(strings.Trim(path.Clean(p), "/"), "/")
+	if len(parts) != len(l.release) {
+		return Location{}, false
+	}
+	var location Location
+	for i, s := range l.release {
+		if s.placeholder == "" {
+			if parts[i] != s.literal {
+				return Location{}, false
+			}
+			continue
+		}
+		location.set(s.placeholder, parts[i])
+	}
+	return location, true
+}
+
+// Environments returns the sorted names of all environments with a release
+// directory in root.
+func (l *Layout) Environments(root string) ([]string, error) {
+	// include literal segments following the environment to only match
+	// directories that can contain releases, e.g. <env>/releases
+	end := l.release.index(placeholderEnvironment) + 1
+	for end < len(l.release) && l.release[end].placeholder == "" {
+		end++
+	}
+	matches, err := expand(root, l.release[:end], Location{})
+	if err != nil {
+		return nil, err
+	}
+	return distinct(matches, func(l Location) string { return l.Environment }), nil
+}
+
+// Namespaces returns the sorted names of all namespaces in environment. If the
+// environment does not exist an error satisfying errors.Is(err,
+// os.ErrNotExist) is returned. It must only be called for layouts with
+// namespaces.
+func (l *Layout) Namespaces(root, environment string) ([]string, error) {
+	index := l.release.index(placeholderNamespace)
+	if index == -1 {
+		return nil, errors.New("layout has no namespaces")
+	}
+	filter := Location{Environment: environment}
+	parents, err := expand(root, l.release[:index], filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) == 0 {
+		return nil, os.ErrNotExist
+	}
+	matches, err := expand(root, l.release[:index+1], filter)
+	if err != nil {
+		return nil, err
+	}
+	return distinct(matches, func(l Location) string { return l.Namespace }), nil
+}
+
+// Releases returns the locations of all release directories in root matching
+// the non-empty fields of filter. The namespace of the locations are empty for
+// layouts without namespaces.
+func (l *Layout) Releases(root string, filter Location) ([]Location, error) {
+	return expand(root, l.release, filter)
+}
+
+// expand returns the locations of all directories in root matching t with
+// placeholders set in filter fixed to their value.
+func expand(root string, t template, filter Location) ([]Location, error) {
+	if len(t) == 0 {
+		return []Location{filter}, nil
+	}
+	s := t[0]
+	value := s.literal
+	if s.placeholder != "" {
+		value = filter.value(s.placeholder)
+	}
+	if value != "" {
+		if strings.ContainsAny(value, `/\`) {
+			return nil, nil
+		}
+		p, err := securejoin.SecureJoin(root, value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "join '%s' to path", value)
+		}
+		info, err := os.Stat(p)
+		if err != nil || !info.IsDir() {
+			return nil, nil
+		}
+		return expand(p, t[1:], filter)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errors.WithMessagef(err, "read directory '%s'", root)
+	}
+	var locations []Location
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		child := filter
+		child.set(s.placeholder, entry.Name())
+		matches, err := expand(path.Join(root, entry.Name()), t[1:], child)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, matches...)
+	}
+	return locations, nil
+}
+
+func distinct(locations []Location, f func(Location) string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, l := range locations {
+		v := f(l)
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,188 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tt := []struct {
+		name          string
+		release       string
+		kustomization string
+		err           error
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:          "apps layout",
+			release:       "apps/{service}/{environment}",
+			kustomization: "clusters/{environment}",
+		},
+		{
+			name:    "missing service",
+			release: "{environment}/releases/{namespace}",
+			err:     ErrInvalidTemplate,
+		},
+		{
+			name:    "unknown placeholder",
+			release: "{environment}/{team}/{service}",
+			err:     ErrInvalidTemplate,
+		},
+		{
+			name:    "partial segment placeholder",
+			release: "{environment}/app-{service}",
+			err:     ErrInvalidTemplate,
+		},
+		{
+			name:    "parent directory",
+			release: "../{environment}/{service}",
+			err:     ErrInvalidTemplate,
+		},
+		{
+			name:    "duplicate placeholder",
+			release: "{environment}/{service}/{service}",
+			err:     ErrInvalidTemplate,
+		},
+		{
+			name:          "kustomization missing environment",
+			kustomization: "clusters/{namespace}",
+			err:           ErrInvalidTemplate,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.release, tc.kustomization)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err, "error not as expected")
+				return
+			}
+			assert.NoError(t, err, "unexpected error")
+		})
+	}
+}
+
+func TestLayout_paths(t *testing.T) {
+	location := Location{
+		Environment: "dev",
+		Namespace:   "team",
+		Service:     "product",
+	}
+	tt := []struct {
+		name          string
+		layout        *Layout
+		release       string
+		kustomization string
+		hasNamespace  bool
+	}{
+		{
+			name:          "default",
+			layout:        Default(),
+			release:       "/root/dev/releases/team/product",
+			kustomization: "/root/clusters/dev/team",
+			hasNamespace:  true,
+		},
+		{
+			name:          "apps layout",
+			layout:        mustNew(t, "apps/{service}/{environment}", "clusters/{environment}"),
+			release:       "/root/apps/product/dev",
+			kustomization: "/root/clusters/dev",
+			hasNamespace:  false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			release, err := tc.layout.ReleasePath("/root", location)
+			require.NoError(t, err, "unexpected release path error")
+			assert.Equal(t, tc.release, release, "release path not as expected")
+
+			kustomization, err := tc.layout.KustomizationPath("/root", location)
+			require.NoError(t, err, "unexpected kustomization path error")
+			assert.Equal(t, tc.kustomization, kustomization, "kustomization path not as expected")
+
+			assert.Equal(t, tc.hasNamespace, tc.layout.HasNamespace(), "has namespace not as expected")
+
+			parsed, ok := tc.layout.ParseReleasePath(release[len("/root/"):])
+			require.True(t, ok, "release path not parsed")
+			expected := location
+			if !tc.hasNamespace {
+				expected.Namespace = ""
+			}
+			assert.Equal(t, expected, parsed, "parsed location not as expected")
+		})
+	}
+}
+
+func TestLayout_ParseReleasePath_mismatch(t *testing.T) {
+	l := Default()
+	for _, p := range []string{"dev/releases/team", "dev/other/team/product", "dev/releases/team/product/extra"} {
+		_, ok := l.ParseReleasePath(p)
+		assert.False(t, ok, "path '%s' parsed", p)
+	}
+}
+
+func TestLayout_discovery(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"dev/releases/dev/product",
+		"dev/releases/team/api",
+		"prod/releases",
+		"clusters/dev/dev",
+		".git/releases/dev/product",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), os.ModePerm))
+	}
+	l := Default()
+
+	environments, err := l.Environments(root)
+	require.NoError(t, err, "unexpected environments error")
+	assert.Equal(t, []string{"dev", "prod"}, environments, "environments not as expected")
+
+	namespaces, err := l.Namespaces(root, "dev")
+	require.NoError(t, err, "unexpected namespaces error")
+	assert.Equal(t, []string{"dev", "team"}, namespaces, "namespaces not as expected")
+
+	namespaces, err = l.Namespaces(root, "prod")
+	require.NoError(t, err, "unexpected namespaces error")
+	assert.Empty(t, namespaces, "namespaces not as expected")
+
+	_, err = l.Namespaces(root, "staging")
+	assert.ErrorIs(t, err, os.ErrNotExist, "unknown environment error not as expected")
+
+	_, err = l.Namespaces(root, "../..")
+	assert.ErrorIs(t, err, os.ErrNotExist, "path traversal error not as expected")
+
+	releases, err := l.Releases(root, Location{Environment: "dev"})
+	require.NoError(t, err, "unexpected releases error")
+	assert.Equal(t, []Location{
+		{Environment: "dev", Namespace: "dev", Service: "product"},
+		{Environment: "dev", Namespace: "team", Service: "api"},
+	}, releases, "releases not as expected")
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "layout.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("release: apps/{service}/{environment}\n"), 0644))
+
+	l, err := Load(path, File{Kustomization: "flux/{environment}"})
+	require.NoError(t, err, "unexpected error")
+	assert.Equal(t, "apps/{service}/{environment}", l.ReleaseTemplate(), "release template not as expected")
+	assert.Equal(t, "flux/{environment}", l.KustomizationTemplate(), "kustomization template not as expected")
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"), File{})
+	assert.ErrorIs(t, err, os.ErrNotExist, "missing file error not as expected")
+}
+
+func mustNew(t *testing.T, release, kustomization string) *Layout {
+	t.Helper()
+	l, err := New(release, kustomization)
+	require.NoError(t, err, "unexpected layout error")
+	return l
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -12,8 +13,20 @@ type Observer struct {
 	releaseCounter      *prometheus.CounterVec
 	flowDuration        *prometheus.HistogramVec
 	releasePushDuration *prometheus.HistogramVec
+	driftResources      *prometheus.GaugeVec
+	driftOldest         *prometheus.GaugeVec
+	retentionArtifacts  *prometheus.CounterVec
+	retentionBytes      *prometheus.CounterVec
+	doraDeployments     *prometheus.GaugeVec
+	doraLeadTime        *prometheus.GaugeVec
+	doraFailureRate     *prometheus.GaugeVec
+	doraTimeToRestore   *prometheus.GaugeVec
 }
 
+// doraLabels are the labels of the DORA metrics. scope is one of "service",
+// "squad" and "environment" and tells which of the other labels are set.
+var doraLabels = []string{"scope", "environment", "service", "squad"}
+
 // NewObserver creates and registers all Prometheus metrics.
 func NewObserver() *Observer {
 	return &Observer{
@@ -37,6 +50,38 @@ func NewObserver() *Observer {
 			Help:    "Wall-clock duration from release intent accepted to release pushed to GitHub, in seconds",
 			Buckets: []float64{.25, .5, 1, 2.5, 5, 10, 20, 30, 60, 120},
 		}, []string{"outcome"}),
+		driftResources: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_drift_resources",
+			Help: "Number of resources where the cluster state differs from the config repository",
+		}, []string{"environment", "type"}),
+		driftOldest: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_drift_oldest_seconds",
+			Help: "Age of the oldest drift between the cluster state and the config repository in seconds",
+		}, []string{"environment"}),
+		retentionArtifacts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "release_manager_artifact_retention_deleted_artifacts_total",
+			Help: "Total number of artifacts deleted by the artifact retention job",
+		}, []string{"dry_run"}),
+		retentionBytes: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "release_manager_artifact_retention_deleted_bytes_total",
+			Help: "Total number of bytes deleted by the artifact retention job",
+		}, []string{"dry_run"}),
+		doraDeployments: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_dora_deployment_frequency",
+			Help: "Average number of deployments per day",
+		}, doraLabels),
+		doraLeadTime: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_dora_lead_time_seconds",
+			Help: "Median duration from an artifact build started to its release in seconds",
+		}, doraLabels),
+		doraFailureRate: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_dora_change_failure_rate",
+			Help: "Ratio of deployments that were rolled back or failed",
+		}, doraLabels),
+		doraTimeToRestore: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "release_manager_dora_time_to_restore_seconds",
+			Help: "Median duration from a failed deployment to the next deployment in seconds",
+		}, doraLabels),
 	}
 }
 
@@ -82,3 +127,71 @@ func (o *Observer) ObserveReleasePushDuration(start time.Time, err error) {
 	}
 	o.releasePushDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
 }
+
+// Drift holds the drift of a single environment.
+type Drift struct {
+	Environment string
+	// Resources is the number of drifting resources by drift type. Types
+	// without drift should be included with a zero count to reset them.
+	Resources map[string]int
+	// Oldest is the time the oldest drift was detected. Zero if nothing is
+	// drifting.
+	Oldest time.Time
+}
+
+// ObserveDrift sets the drift gauges of an environment.
+func (o *Observer) ObserveDrift(drift Drift) {
+	for driftType, count := range drift.Resources {
+		o.driftResources.WithLabelValues(drift.Environment, driftType).Set(float64(count))
+	}
+	var oldest float64
+	if !drift.Oldest.IsZero() {
+		oldest = time.Since(drift.Oldest).Seconds()
+	}
+	o.driftOldest.WithLabelValues(drift.Environment).Set(oldest)
+}
+
+// ArtifactRetention holds the outcome of a single artifact retention run.
+type ArtifactRetention struct {
+	DryRun           bool
+	DeletedArtifacts int
+	DeletedBytes     int64
+}
+
+// ObserveArtifactRetention adds the deleted artifacts and bytes of a retention
+// run to the retention counters. Dry runs are labelled to tell them apart from
+// actual deletions.
+func (o *Observer) ObserveArtifactRetention(retention ArtifactRetention) {
+	dryRun := strconv.FormatBool(retention.DryRun)
+	o.retentionArtifacts.WithLabelValues(dryRun).Add(float64(retention.DeletedArtifacts))
+	o.retentionBytes.WithLabelValues(dryRun).Add(float64(retention.DeletedBytes))
+}
+
+// Dora holds the DORA metrics of a service, squad or environment.
+type Dora struct {
+	// Scope is one of "service", "squad" and "environment".
+	Scope               string
+	Environment         string
+	Service             string
+	Squad               string
+	DeploymentFrequency float64
+	LeadTime            time.Duration
+	ChangeFailureRate   float64
+	TimeToRestore       time.Duration
+}
+
+// ObserveDora replaces the DORA gauges with metrics. Gauges of services,
+// squads and environments not in metrics are removed.
+func (o *Observer) ObserveDora(metrics []Dora) {
+	o.doraDeployments.Reset()
+	o.doraLeadTime.Reset()
+	o.doraFailureRate.Reset()
+	o.doraTimeToRestore.Reset()
+	for _, m := range metrics {
+		labels := []string{m.Scope, m.Environment, m.Service, m.Squad}
+		o.doraDeployments.WithLabelValues(labels...).Set(m.DeploymentFrequency)
+		o.doraLeadTime.WithLabelValues(labels...).Set(m.LeadTime.Seconds())
+		o.doraFailureRate.WithLabelValues(labels...).Set(m.ChangeFailureRate)
+		o.doraTimeToRestore.WithLabelValues(labels...).Set(m.TimeToRestore.Seconds())
+	}
+}
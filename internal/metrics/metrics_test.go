@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // newTestObserver builds an Observer whose flowDuration histogram is registered
@@ -168,3 +169,117 @@ func TestObserveFlowDuration(t *testing.T) {
 		})
 	}
 }
+
+// TestObserveDrift verifies that ObserveDrift sets the drift gauges of an
+// environment and resets the oldest drift age when nothing is drifting.
+func TestObserveDrift(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	resources := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "release_manager_drift_resources",
+	}, []string{"environment", "type"})
+	oldest := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "release_manager_drift_oldest_seconds",
+	}, []string{"environment"})
+	reg.MustRegister(resources, oldest)
+	obs := &Observer{driftResources: resources, driftOldest: oldest}
+
+	obs.ObserveDrift(Drift{
+		Environment: "dev",
+		Resources:   map[string]int{"missing": 2, "unknown": 0},
+		Oldest:      time.Now().Add(-time.Hour),
+	})
+
+	if got := testutil.ToFloat64(resources.WithLabelValues("dev", "missing")); got != 2 {
+		t.Errorf("expected 2 missing resources, got %v", got)
+	}
+	if got := testutil.ToFloat64(resources.WithLabelValues("dev", "unknown")); got != 0 {
+		t.Errorf("expected 0 unknown resources, got %v", got)
+	}
+	if got := testutil.ToFloat64(oldest.WithLabelValues("dev")); got < time.Hour.Seconds() {
+		t.Errorf("expected oldest drift of at least an hour, got %vs", got)
+	}
+
+	obs.ObserveDrift(Drift{
+		Environment: "dev",
+		Resources:   map[string]int{"missing": 0, "unknown": 0},
+	})
+
+	if got := testutil.ToFloat64(oldest.WithLabelValues("dev")); got != 0 {
+		t.Errorf("expected oldest drift to be reset, got %vs", got)
+	}
+}
+
+func TestObserveArtifactRetention(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	artifacts := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "release_manager_artifact_retention_deleted_artifacts_total",
+	}, []string{"dry_run"})
+	bytes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "release_manager_artifact_retention_deleted_bytes_total",
+	}, []string{"dry_run"})
+	reg.MustRegister(artifacts, bytes)
+	obs := &Observer{retentionArtifacts: artifacts, retentionBytes: bytes}
+
+	obs.ObserveArtifactRetention(ArtifactRetention{DeletedArtifacts: 2, DeletedBytes: 100})
+	obs.ObserveArtifactRetention(ArtifactRetention{DeletedArtifacts: 1, DeletedBytes: 50})
+	obs.ObserveArtifactRetention(ArtifactRetention{DryRun: true, DeletedArtifacts: 4, DeletedBytes: 400})
+
+	if got := testutil.ToFloat64(artifacts.WithLabelValues("false")); got != 3 {
+		t.Errorf("expected 3 deleted artifacts, got %v", got)
+	}
+	if got := testutil.ToFloat64(bytes.WithLabelValues("false")); got != 150 {
+		t.Errorf("expected 150 deleted bytes, got %v", got)
+	}
+	if got := testutil.ToFloat64(bytes.WithLabelValues("true")); got != 400 {
+		t.Errorf("expected 400 dry run bytes, got %v", got)
+	}
+}
+
+func TestObserveDora(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+	gauge := func(name string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name}, doraLabels)
+	}
+	deployments := gauge("release_manager_dora_deployment_frequency")
+	leadTime := gauge("release_manager_dora_lead_time_seconds")
+	failureRate := gauge("release_manager_dora_change_failure_rate")
+	timeToRestore := gauge("release_manager_dora_time_to_restore_seconds")
+	reg.MustRegister(deployments, leadTime, failureRate, timeToRestore)
+	obs := &Observer{
+		doraDeployments:   deployments,
+		doraLeadTime:      leadTime,
+		doraFailureRate:   failureRate,
+		doraTimeToRestore: timeToRestore,
+	}
+
+	obs.ObserveDora([]Dora{
+		{Scope: "service", Environment: "prod", Service: "a", Squad: "alpha", DeploymentFrequency: 2},
+		{Scope: "service", Environment: "prod", Service: "b", Squad: "alpha", DeploymentFrequency: 1},
+	})
+	obs.ObserveDora([]Dora{
+		{Scope: "service", Environment: "prod", Service: "a", Squad: "alpha", DeploymentFrequency: 3, LeadTime: time.Hour, ChangeFailureRate: 0.5, TimeToRestore: time.Minute},
+	})
+
+	if got := testutil.ToFloat64(deployments.WithLabelValues("service", "prod", "a", "alpha")); got != 3 {
+		t.Errorf("expected deployment frequency 3, got %v", got)
+	}
+	if got := testutil.ToFloat64(leadTime.WithLabelValues("service", "prod", "a", "alpha")); got != 3600 {
+		t.Errorf("expected lead time 3600, got %v", got)
+	}
+	if got := testutil.ToFloat64(failureRate.WithLabelValues("service", "prod", "a", "alpha")); got != 0.5 {
+		t.Errorf("expected change failure rate 0.5, got %v", got)
+	}
+	if got := testutil.ToFloat64(timeToRestore.WithLabelValues("service", "prod", "a", "alpha")); got != 60 {
+		t.Errorf("expected time to restore 60, got %v", got)
+	}
+	// service b is removed from the second observation
+	if got := testutil.CollectAndCount(deployments); got != 1 {
+		t.Errorf("expected 1 deployment frequency series, got %v", got)
+	}
+}
@@ -49,7 +49,7 @@ func (s *Service) ApplyBranchRestriction(ctx context.Context, actor Actor, svc,
 
 	commitMsg := commitinfo.PolicyUpdateApplyCommitMessage(env, svc, "branch-restriction")
 	var policyID string
-	err = s.updatePolicies(ctx, actor, svc, commitMsg, func(p *Policies) {
+	err = s.updatePolicies(ctx, s.configRepo(env), actor, svc, commitMsg, func(p *Policies) {
 		policyID = p.SetBranchRestriction(branchRegex, env)
 	})
 	if err != nil {
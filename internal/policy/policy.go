@@ -32,10 +32,16 @@ var (
 
 type Service struct {
 	Tracer tracing.Tracer
-	Git    GitService
+	// Git is the default config repository storing policies of all environments
+	// not in Repositories.
+	Git GitService
+	// Repositories are the config repositories storing policies by
+	// environment.
+	Repositories map[string]GitService
 
 	MaxRetries                      int
 	GlobalBranchRestrictionPolicies []BranchRestriction
+	GlobalRequiredStagesPolicies    []RequiredStages
 }
 
 type GitService interface {
@@ -70,6 +76,27 @@ func (s *Service) GetAutoReleases(ctx context.Context, svc, branch string) ([]Au
 	return autoReleases, nil
 }
 
+// EnvironmentAutoReleases returns the IDs of the auto-release policies of
+// service svc releasing to environment env regardless of branch.
+func (s *Service) EnvironmentAutoReleases(ctx context.Context, svc, env string) ([]string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "policy.EnvironmentAutoReleases")
+	defer span.End()
+	policies, err := s.Get(ctx, svc)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, autoRelease := range policies.AutoReleases {
+		if autoRelease.Environment == env {
+			ids = append(ids, autoRelease.ID)
+		}
+	}
+	return ids, nil
+}
+
 // Get gets stored policies for service svc. If no policies are stored
 // ErrNotFound is returned. This method also returns globally configured
 // policies along with the service specific ones.
@@ -81,7 +108,7 @@ func (s *Service) Get(ctx context.Context, svc string) (Policies, error) {
 	if err != nil {
 		// we will only return if an unknown error occoured and no global policies
 		// are defined.
-		if err != ErrNotFound || len(s.GlobalBranchRestrictionPolicies) == 0 {
+		if err != ErrNotFound || (len(s.GlobalBranchRestrictionPolicies) == 0 && len(s.GlobalRequiredStagesPolicies) == 0) {
 			return Policies{}, err
 		}
 		policies = Policies{
@@ -91,7 +118,9 @@ func (s *Service) Get(ctx context.Context, svc string) (Policies, error) {
 
 	// merge global policies with local ones where globals take precedence
 	policies.BranchRestrictions = mergeBranchRestrictions(ctx, svc, s.GlobalBranchRestrictionPolicies, policies.BranchRestrictions)
-	log.WithContext(ctx).WithFields("globalPolicies", s.GlobalBranchRestrictionPolicies, "localPolicies", policies).Infof("Found %d policies", len(policies.BranchRestrictions)+len(policies.AutoReleases))
+	// required stages are additive so globals are included alongside local ones
+	policies.RequiredStages = append(append([]RequiredStages(nil), s.GlobalRequiredStagesPolicies...), policies.RequiredStages...)
+	log.WithContext(ctx).WithFields("globalPolicies", s.GlobalBranchRestrictionPolicies, "globalRequiredStages", s.GlobalRequiredStagesPolicies, "localPolicies", policies).Infof("Found %d policies", len(policies.BranchRestrictions)+len(policies.AutoReleases)+len(policies.RequiredStages))
 
 	// a policy file might exist, but if all policies have been removed from it
 	// we can just act as if it didn't exist
@@ -101,11 +130,37 @@ func (s *Service) Get(ctx context.Context, svc string) (Policies, error) {
 	return policies, nil
 }
 
-// servicePolicies returns policies for a specific service. If no policy file is
-// found ErrNotFound is returned.
+// servicePolicies returns policies for a specific service merged from all
+// config repositories. If no policy file is found ErrNotFound is returned.
 func (s *Service) servicePolicies(svc string) (Policies, error) {
+	var merged Policies
+	found := false
+	for _, repo := range s.repositories() {
+		policies, err := readPolicies(repo.MasterPath(), svc)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return Policies{}, err
+		}
+		found = true
+		policies = s.routedPolicies(repo, policies)
+		merged.Service = policies.Service
+		merged.AutoReleases = append(merged.AutoReleases, policies.AutoReleases...)
+		merged.BranchRestrictions = append(merged.BranchRestrictions, policies.BranchRestrictions...)
+		merged.RequiredStages = append(merged.RequiredStages, policies.RequiredStages...)
+	}
+	if !found {
+		return Policies{}, ErrNotFound
+	}
+	return merged, nil
+}
+
+// readPolicies returns policies for a specific service in the config
+// repository at root. If no policy file is found ErrNotFound is returned.
+func readPolicies(root, svc string) (Policies, error) {
 	// make sure policy directory exists
-	policiesDir := path.Join(s.Git.MasterPath(), "policies")
+	policiesDir := path.Join(root, "policies")
 	policiesPath, err := securejoin.SecureJoin(policiesDir, fmt.Sprintf("%s.json", svc))
 	if err != nil {
 		return Policies{}, errors.WithMessage(err, "join policy path")
@@ -169,7 +224,7 @@ func (s *Service) ApplyAutoRelease(ctx context.Context, actor Actor, svc, branch
 
 	commitMsg := commitinfo.PolicyUpdateApplyCommitMessage(env, svc, "auto-release")
 	var policyID string
-	err = s.updatePolicies(ctx, actor, svc, commitMsg, func(p *Policies) {
+	err = s.updatePolicies(ctx, s.configRepo(env), actor, svc, commitMsg, func(p *Policies) {
 		policyID = p.SetAutoRelease(branch, env)
 	})
 	if err != nil {
@@ -178,22 +233,35 @@ func (s *Service) ApplyAutoRelease(ctx context.Context, actor Actor, svc, branch
 	return policyID, nil
 }
 
-// Delete deletes policies by ID for service svc.
+// Delete deletes policies by ID for service svc in all config repositories.
 func (s *Service) Delete(ctx context.Context, actor Actor, svc string, ids []string) (int, error) {
 	span, ctx := s.Tracer.FromCtx(ctx, "policy.Delete")
 	defer span.End()
 	commitMsg := commitinfo.PolicyUpdateDeleteCommitMessage(svc)
 	var deleted int
-	err := s.updatePolicies(ctx, actor, svc, commitMsg, func(p *Policies) {
-		deleted = p.Delete(ids...)
-	})
-	if err != nil {
-		return 0, err
+	for _, repo := range s.repositories() {
+		// avoid creating policy files in repositories without the policies
+		policies, err := readPolicies(repo.MasterPath(), svc)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return deleted, err
+		}
+		if policies.Delete(ids...) == 0 {
+			continue
+		}
+		err = s.updatePolicies(ctx, repo, actor, svc, commitMsg, func(p *Policies) {
+			deleted += p.Delete(ids...)
+		})
+		if err != nil {
+			return deleted, err
+		}
 	}
 	return deleted, nil
 }
 
-func (s *Service) updatePolicies(ctx context.Context, actor Actor, svc, commitMsg string, f func(p *Policies)) error {
+func (s *Service) updatePolicies(ctx context.Context, repo GitService, actor Actor, svc, commitMsg string, f func(p *Policies)) error {
 	span, ctx := s.Tracer.FromCtx(ctx, "policy.updatePolicies")
 	defer span.End()
 	return try.Do(ctx, s.Tracer, s.MaxRetries, func(ctx context.Context, attempt int) (bool, error) {
@@ -209,7 +277,7 @@ func (s *Service) updatePolicies(ctx context.Context, actor Actor, svc, commitMs
 		// file flags used. This is to avoid opening and closing to file multiple
 		// times during the operation.
 		logger.Debugf("internal/policy: clone config repository")
-		err = s.Git.ShallowClone(ctx, configRepoPath)
+		err = repo.ShallowClone(ctx, configRepoPath)
 		if err != nil {
 			return true, errors.WithMessage(err, fmt.Sprintf("clone to '%s'", configRepoPath))
 		}
@@ -264,7 +332,7 @@ func (s *Service) updatePolicies(ctx context.Context, actor Actor, svc, commitMs
 
 		// commit changes
 		logger.Debugf("internal/policy: commit policies file '%s'", policiesPath)
-		err = s.Git.Commit(ctx, configRepoPath, path.Join(".", "policies"), commitMsg)
+		err = repo.Commit(ctx, configRepoPath, path.Join(".", "policies"), commitMsg)
 		if err != nil {
 			// indicates that the applied policy was already set
 			if errors.Cause(err) == internalgit.ErrNothingToCommit {
@@ -313,6 +381,7 @@ type Policies struct {
 	Service            string              `json:"service,omitempty"`
 	AutoReleases       []AutoReleasePolicy `json:"autoReleases,omitempty"`
 	BranchRestrictions []BranchRestriction `json:"branchRestrictions,omitempty"`
+	RequiredStages     []RequiredStages    `json:"requiredStages,omitempty"`
 }
 
 type AutoReleasePolicy struct {
@@ -323,7 +392,7 @@ type AutoReleasePolicy struct {
 
 // HasPolicies returns whether any policies are applied.
 func (p *Policies) HasPolicies() bool {
-	return len(p.AutoReleases) != 0 || len(p.BranchRestrictions) != 0
+	return len(p.AutoReleases) != 0 || len(p.BranchRestrictions) != 0 || len(p.RequiredStages) != 0
 }
 
 // SetAutoRelease sets an auto-release policy for specified branch and
@@ -377,6 +446,16 @@ func (p *Policies) Delete(ids ...string) int {
 			deleted++
 		}
 		p.BranchRestrictions = filteredBranchRestrictions
+
+		var filteredRequiredStages []RequiredStages
+		for i := range p.RequiredStages {
+			if p.RequiredStages[i].ID != id {
+				filteredRequiredStages = append(filteredRequiredStages, p.RequiredStages[i])
+				continue
+			}
+			deleted++
+		}
+		p.RequiredStages = filteredRequiredStages
 	}
 	return deleted
 }
@@ -544,3 +544,53 @@ func TestMergeBranchRestrictions(t *testing.T) {
 		})
 	}
 }
+
+func TestService_EnvironmentAutoReleases(t *testing.T) {
+	tt := []struct {
+		name    string
+		service string
+		env     string
+		ids     []string
+	}{
+		{
+			name:    "no policies for service",
+			service: "unknown",
+			env:     "dev",
+			ids:     nil,
+		},
+		{
+			name:    "auto-release to environment",
+			service: "autorelease",
+			env:     "dev",
+			ids:     []string{"auto-release-master-dev"},
+		},
+		{
+			name:    "auto-release to other environment",
+			service: "autorelease",
+			env:     "prod",
+			ids:     nil,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			log.Init(&log.Configuration{
+				Level: log.Level{
+					Level: zapcore.DebugLevel,
+				},
+				Development: true,
+			})
+			gitService := MockGitService{}
+			gitService.On("MasterPath").Return("testdata")
+			s := Service{
+				Tracer:     tracing.NewNoop(),
+				Git:        &gitService,
+				MaxRetries: 1,
+			}
+
+			ids, err := s.EnvironmentAutoReleases(context.Background(), tc.service, tc.env)
+
+			assert.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.ids, ids, "policy ids not as expected")
+		})
+	}
+}
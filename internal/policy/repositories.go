@@ -0,0 +1,55 @@
+package policy
+
+// configRepo returns the config repository storing policies of environment
+// env.
+func (s *Service) configRepo(env string) GitService {
+	if repo, ok := s.Repositories[env]; ok {
+		return repo
+	}
+	return s.Git
+}
+
+// repositories returns all config repositories starting with the default one.
+func (s *Service) repositories() []GitService {
+	repos := []GitService{s.Git}
+	for _, repo := range s.Repositories {
+		if !containsRepository(repos, repo) {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func containsRepository(repos []GitService, repo GitService) bool {
+	for _, r := range repos {
+		if r == repo {
+			return true
+		}
+	}
+	return false
+}
+
+// routedPolicies returns the policies of p for environments stored in repo.
+// Policies of environments routed to other repositories are ignored, e.g.
+// those left behind when an environment is moved to another repository.
+func (s *Service) routedPolicies(repo GitService, p Policies) Policies {
+	routed := Policies{
+		Service: p.Service,
+	}
+	for _, policy := range p.AutoReleases {
+		if s.configRepo(policy.Environment) == repo {
+			routed.AutoReleases = append(routed.AutoReleases, policy)
+		}
+	}
+	for _, policy := range p.BranchRestrictions {
+		if s.configRepo(policy.Environment) == repo {
+			routed.BranchRestrictions = append(routed.BranchRestrictions, policy)
+		}
+	}
+	for _, policy := range p.RequiredStages {
+		if s.configRepo(policy.Environment) == repo {
+			routed.RequiredStages = append(routed.RequiredStages, policy)
+		}
+	}
+	return routed
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/lunarway/release-manager/internal/commitinfo"
+	"github.com/pkg/errors"
+)
+
+// RequiredStages is a policy requiring artifacts to contain the specified
+// stages before they can be released to an environment, e.g. provenance in
+// production.
+type RequiredStages struct {
+	ID          string   `json:"id,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Stages      []string `json:"stages,omitempty"`
+}
+
+// ApplyRequiredStages applies a required-stages policy for service svc to
+// environment env requiring artifacts to contain stages.
+func (s *Service) ApplyRequiredStages(ctx context.Context, actor Actor, svc, env string, stages []string) (string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "policy.ApplyRequiredStages")
+	defer span.End()
+
+	if len(stages) == 0 {
+		return "", errors.New("at least one stage is required")
+	}
+
+	commitMsg := commitinfo.PolicyUpdateApplyCommitMessage(env, svc, "required-stages")
+	var policyID string
+	err := s.updatePolicies(ctx, s.configRepo(env), actor, svc, commitMsg, func(p *Policies) {
+		policyID = p.SetRequiredStages(env, stages)
+	})
+	if err != nil {
+		return "", err
+	}
+	return policyID, nil
+}
+
+// RequiredStages returns the stages artifacts of service svc must contain to
+// be released to env. Both global and service policies are included.
+func (s *Service) RequiredStages(ctx context.Context, svc, env string) ([]string, error) {
+	span, ctx := s.Tracer.FromCtx(ctx, "policy.RequiredStages")
+	defer span.End()
+	policies, err := s.Get(ctx, svc)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return requiredStages(policies, env), nil
+}
+
+func requiredStages(policies Policies, env string) []string {
+	set := make(map[string]struct{})
+	for _, policy := range policies.RequiredStages {
+		if policy.Environment != env {
+			continue
+		}
+		for _, stage := range policy.Stages {
+			set[stage] = struct{}{}
+		}
+	}
+	var stages []string
+	for stage := range set {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	return stages
+}
+
+// SetRequiredStages sets a required-stages policy for specified environment.
+//
+// If a policy exists for the same environment it is overwritten.
+func (p *Policies) SetRequiredStages(env string, stages []string) string {
+	id := fmt.Sprintf("required-stages-%s", env)
+	newPolicy := RequiredStages{
+		ID:          id,
+		Environment: env,
+		Stages:      stages,
+	}
+	newPolicies := make([]RequiredStages, len(p.RequiredStages))
+	var replaced bool
+	for i, policy := range p.RequiredStages {
+		if policy.Environment == env {
+			newPolicies[i] = newPolicy
+			replaced = true
+			continue
+		}
+		newPolicies[i] = p.RequiredStages[i]
+	}
+	if !replaced {
+		newPolicies = append(newPolicies, newPolicy)
+	}
+	p.RequiredStages = newPolicies
+	return id
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestService_RequiredStages(t *testing.T) {
+	tt := []struct {
+		name           string
+		service        string
+		env            string
+		globalPolicies []RequiredStages
+		stages         []string
+	}{
+		{
+			name:    "no policies for service",
+			service: "unknown",
+			env:     "prod",
+			stages:  nil,
+		},
+		{
+			name:    "service policy for environment",
+			service: "requiredstages",
+			env:     "prod",
+			stages:  []string{"provenance", "sbom"},
+		},
+		{
+			name:    "service policy for other environment",
+			service: "requiredstages",
+			env:     "dev",
+			stages:  nil,
+		},
+		{
+			name:    "only global policies",
+			service: "unknown",
+			env:     "prod",
+			globalPolicies: []RequiredStages{
+				{
+					Environment: "prod",
+					Stages:      []string{"provenance"},
+				},
+			},
+			stages: []string{"provenance"},
+		},
+		{
+			name:    "global and service policies are combined",
+			service: "requiredstages",
+			env:     "prod",
+			globalPolicies: []RequiredStages{
+				{
+					Environment: "prod",
+					Stages:      []string{"provenance", "snyk-code"},
+				},
+			},
+			stages: []string{"provenance", "sbom", "snyk-code"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			log.Init(&log.Configuration{
+				Level: log.Level{
+					Level: zapcore.DebugLevel,
+				},
+				Development: true,
+			})
+			gitService := MockGitService{}
+			gitService.On("MasterPath").Return("testdata")
+			s := Service{
+				Tracer:                       tracing.NewNoop(),
+				Git:                          &gitService,
+				GlobalRequiredStagesPolicies: tc.globalPolicies,
+				MaxRetries:                   1,
+			}
+
+			stages, err := s.RequiredStages(context.Background(), tc.service, tc.env)
+
+			assert.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.stages, stages, "stages not as expected")
+		})
+	}
+}
+
+func TestPolicies_SetRequiredStages(t *testing.T) {
+	p := Policies{
+		RequiredStages: []RequiredStages{
+			{
+				ID:          "required-stages-dev",
+				Environment: "dev",
+				Stages:      []string{"sbom"},
+			},
+			{
+				ID:          "required-stages-prod",
+				Environment: "prod",
+				Stages:      []string{"sbom"},
+			},
+		},
+	}
+
+	id := p.SetRequiredStages("prod", []string{"provenance"})
+
+	assert.Equal(t, "required-stages-prod", id, "id not as expected")
+	assert.Equal(t, []RequiredStages{
+		{
+			ID:          "required-stages-dev",
+			Environment: "dev",
+			Stages:      []string{"sbom"},
+		},
+		{
+			ID:          "required-stages-prod",
+			Environment: "prod",
+			Stages:      []string{"provenance"},
+		},
+	}, p.RequiredStages, "policies not as expected")
+}
@@ -0,0 +1,240 @@
+package releasestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/jsonfile"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// Store implements flow.ReleaseStatusStorage. Statuses are kept in memory and,
+// if a directory is configured, persisted as one JSON file per release so they
+// survive restarts.
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	mu       sync.RWMutex
+	releases map[string]flow.ReleaseStatus
+	prunedAt time.Time
+}
+
+// pruneInterval is the minimum duration between removing expired statuses of
+// a running Store.
+const pruneInterval = time.Hour
+
+var _ flow.ReleaseStatusStorage = &Store{}
+
+// New allocates a Store persisting statuses in dir. If dir is empty statuses
+// are only kept in memory. Existing statuses in dir are loaded and those not
+// updated within retention are removed. A retention of zero keeps all
+// statuses.
+func New(dir string, retention time.Duration) (*Store, error) {
+	s := &Store{
+		dir:       dir,
+		retention: retention,
+		releases:  make(map[string]flow.ReleaseStatus),
+	}
+	if dir == "" {
+		return s, nil
+	}
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "create directory '%s'", dir)
+	}
+	now := time.Now()
+	err = s.load(now)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load statuses from '%s'", dir)
+	}
+	s.prunedAt = now
+	return s, nil
+}
+
+func (s *Store) load(now time.Time) error {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return errors.WithMessagef(err, "read '%s'", file)
+		}
+		var status flow.ReleaseStatus
+		err = json.Unmarshal(content, &status)
+		if err != nil {
+			log.Errorf("releasestore: skipping unparsable status file '%s': %v", file, err)
+			continue
+		}
+		if s.expired(status, now) {
+			err := os.Remove(file)
+			if err != nil {
+				log.Errorf("releasestore: remove expired status file '%s' failed: %v", file, err)
+			}
+			continue
+		}
+		s.releases[status.ID] = status
+	}
+	log.Infof("releasestore: loaded %d release statuses from '%s'", len(s.releases), s.dir)
+	return nil
+}
+
+func (s *Store) expired(status flow.ReleaseStatus, now time.Time) bool {
+	if s.retention <= 0 {
+		return false
+	}
+	return status.UpdatedAt.Add(s.retention).Before(now)
+}
+
+func (s *Store) Create(ctx context.Context, status flow.ReleaseStatus) error {
+	if status.ID == "" {
+		return errors.New("release id required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.releases[status.ID]; ok {
+		return errors.Errorf("release '%s' already exists", status.ID)
+	}
+	return s.put(status)
+}
+
+func (s *Store) Get(ctx context.Context, id string) (flow.ReleaseStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status, ok := s.releases[id]
+	if !ok {
+		return flow.ReleaseStatus{}, flow.ErrReleaseStatusNotFound
+	}
+	return status, nil
+}
+
+func (s *Store) Update(ctx context.Context, id string, f func(*flow.ReleaseStatus)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.releases[id]
+	if !ok {
+		return flow.ErrReleaseStatusNotFound
+	}
+	// copy transitions to avoid f mutating the stored slice on failed writes
+	status.Transitions = append([]flow.ReleaseTransition(nil), status.Transitions...)
+	f(&status)
+	return s.put(status)
+}
+
+func (s *Store) LatestByArtifact(ctx context.Context, environment, artifactID string) (flow.ReleaseStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest flow.ReleaseStatus
+	var found bool
+	for _, status := range s.releases {
+		if status.Environment != environment || status.ArtifactID != artifactID {
+			continue
+		}
+		if !found || status.CreatedAt.After(latest.CreatedAt) {
+			latest = status
+			found = true
+		}
+	}
+	if !found {
+		return flow.ReleaseStatus{}, flow.ErrReleaseStatusNotFound
+	}
+	return latest, nil
+}
+
+// ClosestByArtifact returns the release of artifactID into environment created
+// closest to at.
+func (s *Store) ClosestByArtifact(ctx context.Context, environment, artifactID string, at time.Time) (flow.ReleaseStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var closest flow.ReleaseStatus
+	var closestDistance time.Duration
+	var found bool
+	for _, status := range s.releases {
+		if status.Environment != environment || status.ArtifactID != artifactID {
+			continue
+		}
+		distance := absDuration(status.CreatedAt.Sub(at))
+		if !found || distance < closestDistance {
+			closest = status
+			closestDistance = distance
+			found = true
+		}
+	}
+	if !found {
+		return flow.ReleaseStatus{}, flow.ErrReleaseStatusNotFound
+	}
+	return closest, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// put stores status in memory and on disk. The caller must hold the write
+// lock.
+func (s *Store) put(status flow.ReleaseStatus) error {
+	s.prune(time.Now())
+	if s.dir != "" {
+		err := s.persist(status)
+		if err != nil {
+			return errors.WithMessagef(err, "persist release '%s'", status.ID)
+		}
+	}
+	s.releases[status.ID] = status
+	return nil
+}
+
+// persist writes status to its file in the directory.
+func (s *Store) persist(status flow.ReleaseStatus) error {
+	path, err := s.path(status.ID)
+	if err != nil {
+		return err
+	}
+	return jsonfile.Write(path, status)
+}
+
+func (s *Store) path(id string) (string, error) {
+	path, err := securejoin.SecureJoin(s.dir, fmt.Sprintf("%s.json", id))
+	if err != nil {
+		return "", errors.WithMessage(err, "join status path")
+	}
+	return path, nil
+}
+
+// prune removes statuses not updated within retention. It runs at most once
+// per pruneInterval to keep writes cheap. The caller must hold the write lock.
+func (s *Store) prune(now time.Time) {
+	if s.retention <= 0 || now.Sub(s.prunedAt) < pruneInterval {
+		return
+	}
+	s.prunedAt = now
+	for id, status := range s.releases {
+		if !s.expired(status, now) {
+			continue
+		}
+		if s.dir != "" {
+			path, err := s.path(id)
+			if err == nil {
+				err = os.Remove(path)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				log.Errorf("releasestore: remove expired status of release '%s' failed: %v", id, err)
+				continue
+			}
+		}
+		delete(s.releases, id)
+	}
+}
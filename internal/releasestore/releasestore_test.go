@@ -0,0 +1,137 @@
+package releasestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestMain(m *testing.M) {
+	log.Init(&log.Configuration{
+		Level:       log.Level{Level: zapcore.ErrorLevel},
+		Development: false,
+	})
+	os.Exit(m.Run())
+}
+
+func newStatus(id, env, artifactID string, createdAt time.Time) flow.ReleaseStatus {
+	status := flow.ReleaseStatus{
+		ID:          id,
+		Service:     "svc",
+		Environment: env,
+		ArtifactID:  artifactID,
+		CreatedAt:   createdAt,
+	}
+	status.Transition(flow.ReleaseStateQueued, "release accepted", createdAt)
+	return status
+}
+
+func TestStore_persistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	now := time.Now()
+
+	store, err := New(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, newStatus("1", "dev", "master-1-2", now)))
+	require.NoError(t, store.Update(ctx, "1", func(r *flow.ReleaseStatus) {
+		r.Transition(flow.ReleaseStatePushed, "pushed", now)
+	}))
+
+	restarted, err := New(dir, 0)
+	require.NoError(t, err)
+	status, err := restarted.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, flow.ReleaseStatePushed, status.State)
+	assert.Len(t, status.Transitions, 2)
+}
+
+func TestStore_retention(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(dir, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, newStatus("old", "dev", "master-1-2", time.Now().Add(-2*time.Hour))))
+	require.NoError(t, store.Create(ctx, newStatus("new", "dev", "master-2-3", time.Now())))
+
+	restarted, err := New(dir, time.Hour)
+	require.NoError(t, err)
+	_, err = restarted.Get(ctx, "old")
+	assert.ErrorIs(t, err, flow.ErrReleaseStatusNotFound)
+	_, err = restarted.Get(ctx, "new")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "old.json"))
+}
+
+func TestStore_retentionWhileRunning(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := New(dir, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, newStatus("old", "dev", "master-1-2", time.Now().Add(-2*time.Hour))))
+	// pretend the last pruning was long ago
+	store.prunedAt = time.Now().Add(-2 * pruneInterval)
+	require.NoError(t, store.Create(ctx, newStatus("new", "dev", "master-2-3", time.Now())))
+
+	_, err = store.Get(ctx, "old")
+	assert.ErrorIs(t, err, flow.ErrReleaseStatusNotFound)
+	_, err = store.Get(ctx, "new")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "old.json"))
+	assert.FileExists(t, filepath.Join(dir, "new.json"))
+}
+
+func TestStore_LatestByArtifact(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store, err := New("", 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, newStatus("1", "dev", "master-1-2", now.Add(-time.Minute))))
+	require.NoError(t, store.Create(ctx, newStatus("2", "dev", "master-1-2", now)))
+	require.NoError(t, store.Create(ctx, newStatus("3", "prod", "master-1-2", now.Add(time.Minute))))
+
+	status, err := store.LatestByArtifact(ctx, "dev", "master-1-2")
+	require.NoError(t, err)
+	assert.Equal(t, "2", status.ID)
+
+	_, err = store.LatestByArtifact(ctx, "staging", "master-1-2")
+	assert.ErrorIs(t, err, flow.ErrReleaseStatusNotFound)
+}
+
+func TestStore_ClosestByArtifact(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store, err := New("", 0)
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, newStatus("1", "dev", "master-1-2", now.Add(-time.Hour))))
+	require.NoError(t, store.Create(ctx, newStatus("2", "dev", "master-1-2", now)))
+	require.NoError(t, store.Create(ctx, newStatus("3", "prod", "master-1-2", now.Add(-time.Hour))))
+
+	status, err := store.ClosestByArtifact(ctx, "dev", "master-1-2", now.Add(-50*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "1", status.ID)
+
+	status, err = store.ClosestByArtifact(ctx, "dev", "master-1-2", now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "2", status.ID)
+
+	_, err = store.ClosestByArtifact(ctx, "staging", "master-1-2", now)
+	assert.ErrorIs(t, err, flow.ErrReleaseStatusNotFound)
+}
+
+func TestStore_Update_unknown(t *testing.T) {
+	store, err := New("", 0)
+	require.NoError(t, err)
+	err = store.Update(context.Background(), "unknown", func(*flow.ReleaseStatus) {})
+	assert.ErrorIs(t, err, flow.ErrReleaseStatusNotFound)
+}
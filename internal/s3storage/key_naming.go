@@ -3,12 +3,13 @@ package s3storage
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
 	"github.com/pkg/errors"
 )
 
@@ -23,27 +24,78 @@ func getServiceAndBranchObjectKeyPrefix(service, branch string) string {
 	return fmt.Sprintf("%s/%s-", service, strings.ReplaceAll(branch, "/", "_"))
 }
 
+func getLatestIndexKeyName(service, branch string) string {
+	return fmt.Sprintf("%s%s/%s", latestIndexPrefix, service, strings.ReplaceAll(branch, "/", "_"))
+}
+
+// getLatestObjectKey returns the key of the latest artifact of service and
+// branch. The latest index is used if it points to an existing artifact and no
+// artifact with an upload URL is uploaded without being indexed. Otherwise all
+// artifacts of the branch are listed and the index is updated.
 func (f *Service) getLatestObjectKey(ctx context.Context, service string, branch string) (string, error) {
 	span, ctx := f.tracer.FromCtx(ctx, "s3storage.getLatestObjectKey")
 	defer span.End()
+	logger := log.WithContext(ctx)
+
+	index, _, err := f.readLatestIndex(ctx, service, branch)
+	if err != nil {
+		logger.Errorf("Failed to read latest index of service '%s' and branch '%s': falling back to listing artifacts: %v", service, branch, err)
+	}
+	if index.ArtifactID != "" {
+		current, err := f.latestIndexIsCurrent(ctx, service, branch, index)
+		if err != nil {
+			return "", err
+		}
+		if current {
+			return getObjectKeyName(service, index.ArtifactID), nil
+		}
+	}
+
 	prefix := getServiceAndBranchObjectKeyPrefix(service, branch)
-	list, err := f.s3client.ListObjectsV2WithContext(ctx, &s3.ListObjectsV2Input{
+	var latest *s3.Object
+	// listed holds the artifacts found of the uploads registered in the index
+	listed := make(map[string]time.Time)
+	err = f.s3client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
 		Bucket:  aws.String(f.bucketName),
-		MaxKeys: aws.Int64(1000), // TODO: Find a solution to handle more than 1000
+		MaxKeys: aws.Int64(1000),
 		Prefix:  aws.String(prefix),
+	}, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range p.Contents {
+			if latest == nil || object.LastModified.After(*latest.LastModified) {
+				latest = object
+			}
+			_, artifactID, _ := parseObjectKey(*object.Key)
+			if requestedAt, ok := index.Uploads[artifactID]; ok {
+				listed[artifactID] = requestedAt
+			}
+		}
+		return true
 	})
-
 	if err != nil {
 		return "", errors.Wrapf(err, "list objects at prefix '%s'", prefix)
 	}
-
-	sort.Slice(list.Contents, func(i, j int) bool {
-		return list.Contents[i].LastModified.After(*list.Contents[j].LastModified)
-	})
-
-	if len(list.Contents) == 0 {
+	if latest == nil {
 		return "", flow.ErrArtifactNotFound
 	}
 
-	return *list.Contents[0].Key, nil
+	_, artifactID, _ := parseObjectKey(*latest.Key)
+	err = f.updateLatestIndex(ctx, service, branch, func(current *latestIndex) bool {
+		// uploads found are covered by the listing and old uploads not found
+		// never happened. Uploads registered again since the index was read
+		// are kept.
+		for id, requestedAt := range current.Uploads {
+			if requestedAt.Equal(listed[id]) || time.Since(requestedAt) > staleUploadAge {
+				delete(current.Uploads, id)
+			}
+		}
+		if current.ArtifactID == "" || !current.LastModified.After(*latest.LastModified) {
+			current.ArtifactID = artifactID
+			current.LastModified = *latest.LastModified
+		}
+		return true
+	})
+	if err != nil {
+		logger.Errorf("Failed to update latest index of service '%s' and branch '%s': %v", service, branch, err)
+	}
+	return *latest.Key, nil
 }
@@ -0,0 +1,220 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/lunarway/release-manager/internal/flow"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// latestIndexPrefix is the key prefix of the latest artifact index objects. It
+// is kept outside the service prefixes to not show up in artifact listings.
+const latestIndexPrefix = ".latest/"
+
+// latestIndexUpdateAttempts is the number of times an update of a latest index
+// is attempted when the index is changed concurrently.
+const latestIndexUpdateAttempts = 10
+
+// staleUploadAge is the age after which an upload registered in a latest index
+// is verified by listing artifacts. It is well beyond the expiry of upload URLs
+// so uploads that never happen are removed from the index.
+const staleUploadAge = time.Hour
+
+// errLatestIndexConflict indicates that a latest index was changed since it was
+// read.
+var errLatestIndexConflict = errors.New("latest index changed concurrently")
+
+// latestIndex points to the latest artifact of a service and branch.
+type latestIndex struct {
+	ArtifactID   string    `json:"artifactId"`
+	LastModified time.Time `json:"lastModified"`
+	// Uploads holds the time upload URLs are created for artifacts of the
+	// branch that are not indexed yet. The index is not trusted while any of
+	// them exists as the artifact may be newer than the indexed one.
+	Uploads map[string]time.Time `json:"uploads,omitempty"`
+}
+
+// IsLatestIndexKey reports whether key is a latest artifact index object and
+// not an artifact.
+func IsLatestIndexKey(key string) bool {
+	return strings.HasPrefix(key, latestIndexPrefix)
+}
+
+// IndexArtifact updates the latest artifact index of the artifact's service
+// and branch if the artifact is newer than the currently indexed one. It
+// should be called when new artifacts are uploaded.
+func (f *Service) IndexArtifact(ctx context.Context, service, artifactID string) error {
+	span, ctx := f.tracer.FromCtx(ctx, "s3storage.IndexArtifact")
+	defer span.End()
+	logger := log.WithContext(ctx)
+
+	key := getObjectKeyName(service, artifactID)
+	// the modification time is read from a listing as it has millisecond
+	// precision where HEAD requests only have seconds. The key is the first
+	// object with itself as prefix.
+	list, err := f.s3client.ListObjectsV2WithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucketName),
+		MaxKeys: aws.Int64(1),
+		Prefix:  aws.String(key),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "list objects at prefix '%s'", key)
+	}
+	if len(list.Contents) == 0 || *list.Contents[0].Key != key {
+		return flow.ErrArtifactNotFound
+	}
+	lastModified := *list.Contents[0].LastModified
+	spec, err := f.getArtifactSpecFromObjectKey(ctx, key)
+	if err != nil {
+		return errors.WithMessage(err, "get artifact spec")
+	}
+	branch := spec.Application.Branch
+	return f.updateLatestIndex(ctx, service, branch, func(index *latestIndex) bool {
+		_, uploaded := index.Uploads[artifactID]
+		delete(index.Uploads, artifactID)
+		if index.ArtifactID != "" && index.LastModified.After(lastModified) {
+			logger.Infof("Latest index of service '%s' and branch '%s' not updated as indexed artifact '%s' is newer than '%s'", service, branch, index.ArtifactID, artifactID)
+			return uploaded
+		}
+		index.ArtifactID = artifactID
+		index.LastModified = lastModified
+		logger.Infof("Latest artifact of service '%s' and branch '%s' indexed as '%s'", service, branch, artifactID)
+		return true
+	})
+}
+
+// registerUpload records in the latest index of service and branch that an
+// upload URL is created for artifactID. Until the artifact is indexed the
+// index is only trusted if the artifact is not uploaded.
+func (f *Service) registerUpload(ctx context.Context, service, branch, artifactID string) error {
+	return f.updateLatestIndex(ctx, service, branch, func(index *latestIndex) bool {
+		if index.Uploads == nil {
+			index.Uploads = make(map[string]time.Time)
+		}
+		index.Uploads[artifactID] = time.Now()
+		return true
+	})
+}
+
+// latestIndexIsCurrent reports whether index points to the latest artifact of
+// service and branch. This is not the case if the indexed artifact is removed
+// or if an artifact with an upload URL is uploaded but not indexed, e.g. as
+// indexing it failed or its notification is not handled yet.
+func (f *Service) latestIndexIsCurrent(ctx context.Context, service, branch string, index latestIndex) (bool, error) {
+	logger := log.WithContext(ctx)
+	for artifactID, requestedAt := range index.Uploads {
+		if time.Since(requestedAt) > staleUploadAge {
+			logger.Infof("Upload of artifact '%s' of service '%s' and branch '%s' is not indexed since %s: falling back to listing artifacts", artifactID, service, branch, requestedAt)
+			return false, nil
+		}
+		exists, err := f.ArtifactExists(ctx, service, artifactID)
+		if err != nil {
+			return false, errors.WithMessage(err, "check uploaded artifact exists")
+		}
+		if exists {
+			logger.Infof("Uploaded artifact '%s' of service '%s' and branch '%s' is not indexed: falling back to listing artifacts", artifactID, service, branch)
+			return false, nil
+		}
+	}
+	exists, err := f.ArtifactExists(ctx, service, index.ArtifactID)
+	if err != nil {
+		return false, errors.WithMessage(err, "check indexed artifact exists")
+	}
+	if !exists {
+		logger.Infof("Indexed latest artifact '%s' of service '%s' and branch '%s' does not exist: falling back to listing artifacts", index.ArtifactID, service, branch)
+		return false, nil
+	}
+	return true, nil
+}
+
+// updateLatestIndex reads the latest index of service and branch, applies
+// update to it and writes it back if update reports a change. The index is
+// written conditionally on it not being changed since it was read and the
+// update is retried if it was.
+//
+// S3 compatible stores without support for conditional writes ignore the
+// conditions so concurrent updates may be lost.
+func (f *Service) updateLatestIndex(ctx context.Context, service, branch string, update func(index *latestIndex) bool) error {
+	for attempt := 1; ; attempt++ {
+		index, etag, err := f.readLatestIndex(ctx, service, branch)
+		if err != nil {
+			return errors.WithMessage(err, "read latest index")
+		}
+		if !update(&index) {
+			return nil
+		}
+		err = f.writeLatestIndex(ctx, service, branch, index, etag)
+		if errors.Is(err, errLatestIndexConflict) && attempt < latestIndexUpdateAttempts {
+			log.WithContext(ctx).Debugf("Latest index of service '%s' and branch '%s' changed concurrently: retrying update", service, branch)
+			continue
+		}
+		if err != nil {
+			return errors.WithMessage(err, "write latest index")
+		}
+		return nil
+	}
+}
+
+// readLatestIndex reads the latest artifact index of service and branch along
+// with its ETag. If no index exists the ETag is empty.
+func (f *Service) readLatestIndex(ctx context.Context, service, branch string) (latestIndex, string, error) {
+	key := getLatestIndexKeyName(service, branch)
+	output, err := f.s3client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(f.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return latestIndex{}, "", nil
+		}
+		return latestIndex{}, "", errors.Wrapf(err, "get object at key '%s'", key)
+	}
+	defer output.Body.Close()
+	var index latestIndex
+	err = json.NewDecoder(output.Body).Decode(&index)
+	if err != nil {
+		return latestIndex{}, "", errors.Wrapf(err, "decode object at key '%s'", key)
+	}
+	return index, aws.StringValue(output.ETag), nil
+}
+
+// writeLatestIndex writes the latest artifact index of service and branch if
+// its ETag is still etag or, if etag is empty, if it does not exist.
+// errLatestIndexConflict is returned if the index is changed.
+func (f *Service) writeLatestIndex(ctx context.Context, service, branch string, index latestIndex, etag string) error {
+	key := getLatestIndexKeyName(service, branch)
+	body, err := json.Marshal(index)
+	if err != nil {
+		return errors.Wrap(err, "marshal latest index")
+	}
+	req, _ := f.s3client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(f.bucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	// the SDK does not support conditional writes so the headers are set
+	// directly
+	if etag == "" {
+		req.HTTPRequest.Header.Set("If-None-Match", "*")
+	} else {
+		req.HTTPRequest.Header.Set("If-Match", etag)
+	}
+	req.SetContext(ctx)
+	err = req.Send()
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "PreconditionFailed" || aerr.Code() == "ConditionalRequestConflict") {
+			return errLatestIndexConflict
+		}
+		return errors.Wrapf(err, "put object at key '%s'", key)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package s3storage_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/s3storage"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestService_LatestArtifactSpecification_pagination(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket)
+	defer s3Close()
+	// store more artifacts than a single list page where the newest artifact is
+	// sorted last by key
+	for i := 0; i < 1000; i++ {
+		_, err := s3Client.PutObject(&s3.PutObjectInput{
+			Body:   strings.NewReader(zipArtifact(t, newArtifact("a", fmt.Sprintf("master-%04d", i), "master"))),
+			Bucket: aws.String(bucket),
+			Key:    aws.String(fmt.Sprintf("a/master-%04d", i)),
+		})
+		require.NoError(t, err, "put artifact")
+	}
+	time.Sleep(2 * time.Millisecond)
+	seedArtifacts(t, s3Client, bucket, []artifact.Spec{newArtifact("a", "master-1000", "master")})
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+
+	spec, err := svc.LatestArtifactSpecification(context.Background(), "a", "master")
+
+	require.NoError(t, err, "get latest artifact")
+	assert.Equal(t, "master-1000", spec.ID, "artifact ID not as expected")
+}
+
+func TestService_IndexArtifact(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	ctx := context.Background()
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket,
+		newArtifact("a", "master-1", "master"),
+		newArtifact("a", "master-2", "master"),
+		newArtifact("a", "feature_x-1", "feature/x"),
+	)
+	defer s3Close()
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+
+	latest := func(branch string) string {
+		t.Helper()
+		spec, err := svc.LatestArtifactSpecification(ctx, "a", branch)
+		require.NoError(t, err, "get latest artifact")
+		return spec.ID
+	}
+	indexExists := func(key string) bool {
+		t.Helper()
+		list, err := s3Client.ListObjectsV2(&s3.ListObjectsV2Input{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(key),
+		})
+		require.NoError(t, err, "list index objects")
+		return len(list.Contents) == 1
+	}
+
+	// an older artifact does not replace the indexed one
+	require.NoError(t, svc.IndexArtifact(ctx, "a", "master-2"), "index master-2")
+	require.NoError(t, svc.IndexArtifact(ctx, "a", "master-1"), "index master-1")
+	assert.Equal(t, "master-2", latest("master"), "latest master artifact not as expected")
+
+	// without an index the artifacts are listed and the index is written
+	assert.False(t, indexExists(".latest/a/feature_x"), "feature index exists before lookup")
+	assert.Equal(t, "feature_x-1", latest("feature/x"), "latest feature artifact not as expected")
+	assert.True(t, indexExists(".latest/a/feature_x"), "feature index not written on lookup")
+
+	// an index to a deleted artifact falls back to listing
+	_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("a/master-2"),
+	})
+	require.NoError(t, err, "delete master-2")
+	assert.Equal(t, "master-1", latest("master"), "latest master artifact after deletion not as expected")
+
+	err = svc.IndexArtifact(ctx, "a", "master-3")
+	assert.EqualError(t, err, "artifact not found")
+}
+
+func TestService_LatestArtifactSpecification_unindexedUpload(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	ctx := context.Background()
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket, newArtifact("a", "master-1", "master"))
+	defer s3Close()
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+	require.NoError(t, svc.IndexArtifact(ctx, "a", "master-1"), "index master-1")
+
+	latest := func() string {
+		t.Helper()
+		spec, err := svc.LatestArtifactSpecification(ctx, "a", "master")
+		require.NoError(t, err, "get latest artifact")
+		return spec.ID
+	}
+
+	// an upload URL without an upload does not invalidate the index
+	_, err = svc.CreateArtifact(newArtifact("a", "master-2", "master"), "")
+	require.NoError(t, err, "create artifact master-2")
+	assert.Equal(t, "master-1", latest(), "latest artifact before upload not as expected")
+
+	// an uploaded artifact is found before it is indexed, e.g. if indexing it
+	// failed
+	seedArtifacts(t, s3Client, bucket, []artifact.Spec{newArtifact("a", "master-2", "master")})
+	assert.Equal(t, "master-2", latest(), "latest artifact after upload not as expected")
+	index := readLatestIndex(t, s3Client, bucket, ".latest/a/master")
+	assert.Equal(t, "master-2", index["artifactId"], "latest index not updated by listing")
+	assert.NotContains(t, index, "uploads", "listed upload not removed from latest index")
+}
+
+func TestService_CreateArtifact_concurrentUploads(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket)
+	defer s3Close()
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.CreateArtifact(newArtifact("a", fmt.Sprintf("master-%d", i), "master"), "")
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err, "create artifact")
+	}
+
+	uploads, ok := readLatestIndex(t, s3Client, bucket, ".latest/a/master")["uploads"].(map[string]interface{})
+	require.True(t, ok, "latest index has no uploads")
+	assert.Len(t, uploads, 5, "concurrently registered uploads lost")
+}
+
+// readLatestIndex reads the latest index object at key.
+func readLatestIndex(t *testing.T, s3Client s3iface.S3API, bucket, key string) map[string]interface{} {
+	t.Helper()
+	output, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	require.NoError(t, err, "get latest index")
+	defer output.Body.Close()
+	var index map[string]interface{}
+	require.NoError(t, json.NewDecoder(output.Body).Decode(&index), "decode latest index")
+	return index
+}
@@ -0,0 +1,254 @@
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// pollWatermarkKey is the key of the object storing the watermark of polling.
+// It is kept outside the service prefixes to not show up in artifact listings.
+const pollWatermarkKey = ".poll/watermark"
+
+// pollWatermark marks the objects handled by polling. All objects modified
+// before LastModified are handled along with the objects in Keys modified at
+// LastModified.
+type pollWatermark struct {
+	LastModified time.Time `json:"lastModified"`
+	Keys         []string  `json:"keys,omitempty"`
+}
+
+// covers reports whether the object with key modified at lastModified is
+// handled according to the watermark.
+func (w pollWatermark) covers(key string, lastModified time.Time) bool {
+	if lastModified.Before(w.LastModified) {
+		return true
+	}
+	if !lastModified.Equal(w.LastModified) {
+		return false
+	}
+	for _, k := range w.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the watermark past the object with key modified at
+// lastModified.
+func (w *pollWatermark) advance(key string, lastModified time.Time) {
+	if lastModified.After(w.LastModified) {
+		w.LastModified = lastModified
+		w.Keys = nil
+	}
+	w.Keys = append(w.Keys, key)
+}
+
+// isMetadataKey reports whether key is an object maintained by the release
+// manager and not an artifact.
+func isMetadataKey(key string) bool {
+	return IsLatestIndexKey(key) || key == pollWatermarkKey
+}
+
+// InitializePolling detects new artifacts by listing the bucket every interval
+// and calls handler for each object not yet handled. It is an alternative to
+// InitializeSQS for S3 compatible stores without SQS notifications, e.g.
+// MinIO.
+//
+// Handled objects are tracked by a watermark stored in the bucket so artifacts
+// uploaded while the release manager is down are handled once polling is
+// initialized again. The first time polling is initialized for a bucket the
+// artifacts already in it are not reported. If handler fails the artifact is
+// reported again on the next poll.
+func (s *Service) InitializePolling(interval time.Duration, handler func(ctx context.Context, service, artifactID string) error) error {
+	if interval <= 0 {
+		return errors.Errorf("poll interval must be positive: got %s", interval)
+	}
+	ctx := context.Background()
+	logger := log.WithFields("type", "s3storage")
+	watermark, ok, err := s.readPollWatermark(ctx)
+	if err != nil {
+		return errors.WithMessage(err, "read poll watermark")
+	}
+	if ok {
+		logger.Infof("Polling s3 bucket %s for new artifacts every %s. Resuming from artifacts modified at %s", s.bucketName, interval, watermark.LastModified)
+	} else {
+		objects, err := s.listObjects(ctx)
+		if err != nil {
+			return errors.WithMessage(err, "list existing artifacts")
+		}
+		for _, object := range objects {
+			watermark.advance(*object.Key, *object.LastModified)
+		}
+		err = s.writePollWatermark(ctx, watermark)
+		if err != nil {
+			return errors.WithMessage(err, "write poll watermark")
+		}
+		logger.Infof("Polling s3 bucket %s for new artifacts every %s. Found %d existing artifacts", s.bucketName, interval, len(objects))
+	}
+
+	s.pollQuitChannel = make(chan struct{})
+	s.pollDoneChannel = make(chan struct{})
+	go func() {
+		defer close(s.pollDoneChannel)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		// handled holds objects handled after the watermark, i.e. after another
+		// object failed, by key to avoid handling them again
+		handled := make(map[string]time.Time)
+		for {
+			select {
+			case <-s.pollQuitChannel:
+				return
+			case <-ticker.C:
+				watermark = s.pollArtifacts(context.Background(), watermark, handled, handler)
+			}
+		}
+	}()
+	return nil
+}
+
+// pollArtifacts calls handler for all objects in the bucket not covered by
+// watermark or in handled and returns the watermark to use on the next poll.
+//
+// Objects are handled in order of modification and the watermark is only
+// advanced until the first failing object. Objects handled after that are
+// recorded in handled until the watermark covers them.
+func (s *Service) pollArtifacts(ctx context.Context, watermark pollWatermark, handled map[string]time.Time, handler func(ctx context.Context, service, artifactID string) error) pollWatermark {
+	span, ctx := s.tracer.FromCtx(ctx, "s3storage.pollArtifacts")
+	defer span.End()
+	logger := log.WithContext(ctx)
+
+	objects, err := s.listObjects(ctx)
+	if err != nil {
+		logger.Errorf("Failed to poll s3 bucket %s for new artifacts: %v", s.bucketName, err)
+		return watermark
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		if !objects[i].LastModified.Equal(*objects[j].LastModified) {
+			return objects[i].LastModified.Before(*objects[j].LastModified)
+		}
+		return *objects[i].Key < *objects[j].Key
+	})
+	next := watermark
+	failed := false
+	for _, object := range objects {
+		key, lastModified := *object.Key, *object.LastModified
+		if next.covers(key, lastModified) {
+			continue
+		}
+		if _, ok := handled[key]; ok {
+			if !failed {
+				next.advance(key, lastModified)
+			}
+			continue
+		}
+		service, artifactID, ok := parseObjectKey(key)
+		if !ok {
+			logger.Infof("Found object %s which can't be parsed to service and artifact id", key)
+			if !failed {
+				next.advance(key, lastModified)
+			}
+			continue
+		}
+		err := handler(ctx, service, artifactID)
+		if err != nil {
+			logger.WithFields("service", service, "artifactId", artifactID).
+				Errorf("Failed handling new artifact %s for service %s: %v", artifactID, service, err)
+			// keep the watermark before the key to retry it on the next poll
+			failed = true
+			continue
+		}
+		logger.WithFields("service", service, "artifactId", artifactID).
+			Infof("Handled new artifact %s for service %s", artifactID, service)
+		if failed {
+			handled[key] = lastModified
+			continue
+		}
+		next.advance(key, lastModified)
+	}
+	for key, lastModified := range handled {
+		if next.covers(key, lastModified) {
+			delete(handled, key)
+		}
+	}
+	if next.LastModified.Equal(watermark.LastModified) && len(next.Keys) == len(watermark.Keys) {
+		return watermark
+	}
+	err = s.writePollWatermark(ctx, next)
+	if err != nil {
+		// the watermark is kept in memory so only a restart before the next
+		// successful write reports the artifacts again
+		logger.Errorf("Failed to write poll watermark of s3 bucket %s: %v", s.bucketName, err)
+	}
+	return next
+}
+
+// listObjects returns all artifact objects in the bucket.
+func (s *Service) listObjects(ctx context.Context) ([]*s3.Object, error) {
+	var objects []*s3.Object
+	err := s.s3client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucketName),
+		MaxKeys: aws.Int64(1000),
+	}, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range p.Contents {
+			if isMetadataKey(*object.Key) {
+				continue
+			}
+			objects = append(objects, object)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list objects")
+	}
+	return objects, nil
+}
+
+// readPollWatermark reads the poll watermark of the bucket. If no watermark
+// exists false is returned.
+func (s *Service) readPollWatermark(ctx context.Context) (pollWatermark, bool, error) {
+	output, err := s.s3client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(pollWatermarkKey),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return pollWatermark{}, false, nil
+		}
+		return pollWatermark{}, false, errors.Wrapf(err, "get object at key '%s'", pollWatermarkKey)
+	}
+	defer output.Body.Close()
+	var watermark pollWatermark
+	err = json.NewDecoder(output.Body).Decode(&watermark)
+	if err != nil {
+		return pollWatermark{}, false, errors.Wrapf(err, "decode object at key '%s'", pollWatermarkKey)
+	}
+	return watermark, true, nil
+}
+
+func (s *Service) writePollWatermark(ctx context.Context, watermark pollWatermark) error {
+	body, err := json.Marshal(watermark)
+	if err != nil {
+		return errors.Wrap(err, "marshal poll watermark")
+	}
+	_, err = s.s3client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(pollWatermarkKey),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "put object at key '%s'", pollWatermarkKey)
+	}
+	return nil
+}
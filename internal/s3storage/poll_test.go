@@ -0,0 +1,126 @@
+package s3storage_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/s3storage"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestService_InitializePolling(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket, newArtifact("a", "master-1", "master"))
+	defer s3Close()
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+
+	var mu sync.Mutex
+	var handled []string
+	failures := 1
+	err = svc.InitializePolling(10*time.Millisecond, func(ctx context.Context, service, artifactID string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// fail b/master-1 once to verify it is retried
+		if service == "b" && failures > 0 {
+			failures--
+			return errors.New("handler failed")
+		}
+		handled = append(handled, service+"/"+artifactID)
+		return nil
+	})
+	require.NoError(t, err, "initialize polling")
+
+	seedArtifacts(t, s3Client, bucket, []artifact.Spec{
+		newArtifact("a", "master-2", "master"),
+		newArtifact("b", "master-1", "master"),
+	})
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 2
+	}, time.Second, 10*time.Millisecond, "new artifacts not handled")
+
+	err = svc.Close()
+	require.NoError(t, err, "close error")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"a/master-2", "b/master-1"}, handled, "handled artifacts not as expected")
+	assert.Equal(t, 0, failures, "failing artifact not retried")
+}
+
+func TestService_InitializePolling_resume(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	bucket := "a-bucket"
+	s3Client, s3Close := setupS3(t, bucket, newArtifact("a", "master-1", "master"))
+	defer s3Close()
+
+	var mu sync.Mutex
+	var handled []string
+	handler := func(ctx context.Context, service, artifactID string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, service+"/"+artifactID)
+		return nil
+	}
+	svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+	err = svc.InitializePolling(10*time.Millisecond, handler)
+	require.NoError(t, err, "initialize polling")
+	err = svc.Close()
+	require.NoError(t, err, "close error")
+
+	// artifacts uploaded while not polling must be handled when polling resumes
+	seedArtifacts(t, s3Client, bucket, []artifact.Spec{
+		newArtifact("b", "master-1", "master"),
+	})
+	svc, err = s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+	err = svc.InitializePolling(10*time.Millisecond, handler)
+	require.NoError(t, err, "initialize polling")
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 1
+	}, time.Second, 10*time.Millisecond, "artifact uploaded while not polling not handled")
+
+	// let a few polls pass to detect duplicates
+	time.Sleep(50 * time.Millisecond)
+	err = svc.Close()
+	require.NoError(t, err, "close error")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"b/master-1"}, handled, "handled artifacts not as expected")
+}
+
+func TestService_InitializePolling_invalidInterval(t *testing.T) {
+	svc, err := s3storage.New("a-bucket", nil, nil, tracing.NewNoop())
+	require.NoError(t, err, "initialization error")
+
+	err = svc.InitializePolling(0, func(context.Context, string, string) error { return nil })
+
+	assert.EqualError(t, err, "poll interval must be positive: got 0s")
+}
@@ -0,0 +1,146 @@
+package s3storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// RetentionPolicy describes which artifacts to keep in the bucket. An artifact
+// is kept if it is among the KeepLast newest artifacts of its service and
+// branch or if it is newer than KeepNewerThan.
+type RetentionPolicy struct {
+	KeepLast      int
+	KeepNewerThan time.Duration
+}
+
+// RetentionArtifact is an artifact stored in the bucket.
+type RetentionArtifact struct {
+	Service      string
+	ArtifactID   string
+	Branch       string
+	Size         int64
+	LastModified time.Time
+}
+
+// RetentionReport is the outcome of applying a RetentionPolicy.
+type RetentionReport struct {
+	DryRun bool
+	// Scanned is the number of artifacts in the bucket.
+	Scanned int
+	// Deleted is the artifacts deleted or, on a dry run, the ones that would
+	// have been deleted.
+	Deleted      []RetentionArtifact
+	DeletedBytes int64
+}
+
+// ApplyRetention deletes artifacts not retained by policy. Artifacts where
+// protected returns true are never deleted. If dryRun is true nothing is
+// deleted and the report contains the artifacts that would have been deleted.
+//
+// Artifacts that cannot be read are kept as their branch cannot be
+// determined.
+func (f *Service) ApplyRetention(ctx context.Context, policy RetentionPolicy, protected func(service, artifactID string) bool, dryRun bool) (RetentionReport, error) {
+	span, ctx := f.tracer.FromCtx(ctx, "s3storage.ApplyRetention")
+	defer span.End()
+	logger := log.WithContext(ctx).WithFields("dryRun", dryRun)
+
+	var objects []*s3.Object
+	err := f.s3client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(f.bucketName),
+		MaxKeys: aws.Int64(1000),
+	}, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range p.Contents {
+			if isMetadataKey(*object.Key) {
+				continue
+			}
+			objects = append(objects, object)
+		}
+		return true
+	})
+	if err != nil {
+		return RetentionReport{}, errors.Wrap(err, "list objects")
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].LastModified.After(*objects[j].LastModified)
+	})
+
+	report := RetentionReport{
+		DryRun:  dryRun,
+		Scanned: len(objects),
+	}
+	newerThan := time.Now().Add(-policy.KeepNewerThan)
+	// kept counts the artifacts kept by service and branch
+	kept := make(map[string]int)
+	for _, object := range objects {
+		service, artifactID, ok := parseObjectKey(*object.Key)
+		if !ok {
+			continue
+		}
+		branch, err := f.artifactBranch(ctx, *object.Key)
+		if err != nil {
+			logger.Errorf("s3storage: retention: keeping artifact at key '%s' as it cannot be read: %v", *object.Key, err)
+			continue
+		}
+		group := service + "/" + branch
+		if kept[group] < policy.KeepLast || object.LastModified.After(newerThan) || protected(service, artifactID) {
+			kept[group]++
+			continue
+		}
+		artifact := RetentionArtifact{
+			Service:      service,
+			ArtifactID:   artifactID,
+			Branch:       branch,
+			Size:         aws.Int64Value(object.Size),
+			LastModified: aws.TimeValue(object.LastModified),
+		}
+		logger.WithFields("service", service, "artifactId", artifactID, "branch", branch, "size", artifact.Size, "lastModified", artifact.LastModified).
+			Infof("s3storage: retention: deleting artifact '%s' of service '%s'", artifactID, service)
+		if !dryRun {
+			_, err := f.s3client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+				Bucket: aws.String(f.bucketName),
+				Key:    object.Key,
+			})
+			if err != nil {
+				return report, errors.Wrapf(err, "delete object at key '%s'", *object.Key)
+			}
+			f.branches.Delete(*object.Key)
+		}
+		report.Deleted = append(report.Deleted, artifact)
+		report.DeletedBytes += artifact.Size
+	}
+	logger.Infof("s3storage: retention: scanned %d artifacts: deleted %d artifacts of %d bytes", report.Scanned, len(report.Deleted), report.DeletedBytes)
+	return report, nil
+}
+
+// artifactBranch returns the branch of the artifact stored at key. As
+// artifacts are never changed after upload the branch is cached to avoid
+// downloading all artifacts on every run.
+func (f *Service) artifactBranch(ctx context.Context, key string) (string, error) {
+	branch, ok := f.branches.Load(key)
+	if ok {
+		return branch.(string), nil
+	}
+	spec, err := f.getArtifactSpecFromObjectKey(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	f.branches.Store(key, spec.Application.Branch)
+	return spec.Application.Branch, nil
+}
+
+// parseObjectKey returns the service and artifact ID of an object key as
+// created by getObjectKeyName.
+func parseObjectKey(key string) (string, string, bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
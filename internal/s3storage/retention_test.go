@@ -0,0 +1,110 @@
+package s3storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/lunarway/release-manager/internal/artifact"
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/s3storage"
+	"github.com/lunarway/release-manager/internal/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestService_ApplyRetention(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	// artifacts are stored from oldest to newest
+	storedArtifacts := []artifact.Spec{
+		newArtifact("a", "master-1", "master"),
+		newArtifact("a", "feature-1", "feature"),
+		newArtifact("a", "master-2", "master"),
+		newArtifact("a", "master-3", "master"),
+		newArtifact("a", "master-4", "master"),
+		newArtifact("b", "master-1", "master"),
+	}
+	protected := func(service, artifactID string) bool {
+		return service == "a" && artifactID == "master-1"
+	}
+
+	tt := []struct {
+		name      string
+		policy    s3storage.RetentionPolicy
+		dryRun    bool
+		deleted   []string
+		remaining []string
+	}{
+		{
+			name: "keep last",
+			policy: s3storage.RetentionPolicy{
+				KeepLast: 2,
+			},
+			deleted:   []string{"a/master-2"},
+			remaining: []string{"a/master-1", "a/feature-1", "a/master-3", "a/master-4", "b/master-1"},
+		},
+		{
+			name: "dry run",
+			policy: s3storage.RetentionPolicy{
+				KeepLast: 2,
+			},
+			dryRun:    true,
+			deleted:   []string{"a/master-2"},
+			remaining: []string{"a/master-1", "a/feature-1", "a/master-2", "a/master-3", "a/master-4", "b/master-1"},
+		},
+		{
+			name: "keep newer than",
+			policy: s3storage.RetentionPolicy{
+				KeepNewerThan: time.Hour,
+			},
+			remaining: []string{"a/master-1", "a/feature-1", "a/master-2", "a/master-3", "a/master-4", "b/master-1"},
+		},
+		{
+			name:      "keep only protected",
+			policy:    s3storage.RetentionPolicy{},
+			deleted:   []string{"b/master-1", "a/master-4", "a/master-3", "a/master-2", "a/feature-1"},
+			remaining: []string{"a/master-1"},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket := "a-bucket"
+			s3Client, s3Close := setupS3(t, bucket, storedArtifacts...)
+			defer s3Close()
+			svc, err := s3storage.New(bucket, s3Client, nil, tracing.NewNoop())
+			require.NoError(t, err, "initialization error")
+
+			report, err := svc.ApplyRetention(context.Background(), tc.policy, protected, tc.dryRun)
+
+			require.NoError(t, err, "unexpected error")
+			assert.Equal(t, tc.dryRun, report.DryRun, "dry run not as expected")
+			assert.Equal(t, len(storedArtifacts), report.Scanned, "scanned count not as expected")
+			var deleted []string
+			var deletedBytes int64
+			for _, a := range report.Deleted {
+				deleted = append(deleted, a.Service+"/"+a.ArtifactID)
+				deletedBytes += a.Size
+			}
+			assert.Equal(t, tc.deleted, deleted, "deleted artifacts not as expected")
+			assert.Equal(t, deletedBytes, report.DeletedBytes, "deleted bytes not as expected")
+
+			list, err := s3Client.ListObjectsV2(&s3.ListObjectsV2Input{
+				Bucket: aws.String(bucket),
+			})
+			require.NoError(t, err, "list remaining objects")
+			var remaining []string
+			for _, object := range list.Contents {
+				remaining = append(remaining, *object.Key)
+			}
+			assert.ElementsMatch(t, tc.remaining, remaining, "remaining artifacts not as expected")
+		})
+	}
+}
@@ -3,10 +3,13 @@ package s3storage_test
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,7 +29,7 @@ func setupS3(t *testing.T, bucket string, artifacts ...artifact.Spec) (s3iface.S
 	t.Helper()
 	backend := s3mem.New()
 	faker := gofakes3.New(backend)
-	ts := httptest.NewServer(faker.Server())
+	ts := httptest.NewServer(conditionalWrites(backend, faker.Server()))
 
 	// configure S3 client
 	s3Config := &aws.Config{
@@ -56,6 +59,35 @@ func setupS3(t *testing.T, bucket string, artifacts ...artifact.Spec) (s3iface.S
 	return s3Client, ts.Close
 }
 
+// conditionalWrites handles the If-Match and If-None-Match conditions of PUT
+// requests as S3 does as they are ignored by gofakes3.
+func conditionalWrites(backend gofakes3.Backend, next http.Handler) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifMatch := r.Header.Get("If-Match")
+		ifNoneMatch := r.Header.Get("If-None-Match")
+		if r.Method != http.MethodPut || (ifMatch == "" && ifNoneMatch == "") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// conditional writes are serialized to check and write atomically
+		mu.Lock()
+		defer mu.Unlock()
+		var etag string
+		path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		object, err := backend.HeadObject(path[0], path[1])
+		if err == nil {
+			etag = `"` + hex.EncodeToString(object.Hash) + `"`
+		}
+		if (ifNoneMatch == "*" && etag != "") || (ifMatch != "" && ifMatch != etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func seedArtifacts(t *testing.T, s3Client s3iface.S3API, bucket string, artifacts []artifact.Spec) {
 	t.Helper()
 	for _, artifact := range artifacts {
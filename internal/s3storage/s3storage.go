@@ -1,7 +1,9 @@
 package s3storage
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -25,6 +27,11 @@ type Service struct {
 	sqsQueueARN            string
 	sqsHandlerQuitChannel  chan struct{}
 	sqsHandlerErrorChannel chan error
+	pollQuitChannel        chan struct{}
+	pollDoneChannel        chan struct{}
+
+	// branches caches the branch of artifacts by object key
+	branches sync.Map
 }
 
 func New(bucketName string, s3client s3iface.S3API, sqsClient sqsiface.SQSAPI, tracer tracing.Tracer) (*Service, error) {
@@ -138,6 +145,13 @@ func (s *Service) InitializeBucket() error {
 func (s *Service) CreateArtifact(artifactSpec artifact.Spec, md5 string) (string, error) {
 	key := getObjectKeyName(artifactSpec.Service, artifactSpec.ID)
 
+	// the upload is registered before the URL is handed out so the latest
+	// index is not trusted until the artifact is indexed
+	err := s.registerUpload(context.Background(), artifactSpec.Service, artifactSpec.Application.Branch, artifactSpec.ID)
+	if err != nil {
+		return "", errors.WithMessagef(err, "register upload of key '%s'", key)
+	}
+
 	req, _ := s.s3client.PutObjectRequest(&s3.PutObjectInput{
 		Bucket: aws.String(s.bucketName),
 		Key:    aws.String(key),
@@ -154,6 +168,10 @@ func (s *Service) CreateArtifact(artifactSpec artifact.Spec, md5 string) (string
 
 // Close closes the S3 storage service. Multiple calls to this method will result in a panic.
 func (s *Service) Close() error {
+	if s.pollQuitChannel != nil {
+		close(s.pollQuitChannel)
+		<-s.pollDoneChannel
+	}
 	if s.sqsHandlerQuitChannel != nil {
 		close(s.sqsHandlerQuitChannel)
 	}
@@ -0,0 +1,259 @@
+// Package signing signs and verifies artifacts with ed25519 keys.
+//
+// An artifact is signed by computing a digest of all its files and storing an
+// ed25519 signature of the digest in SignatureFileName in the root of the
+// artifact. The signature is uploaded with the artifact and verified by the
+// release manager before its resources are released.
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/pkg/errors"
+)
+
+// SignatureFileName is the name of the signature file in the root of a signed
+// artifact.
+const SignatureFileName = "artifact.sig"
+
+// algorithmEd25519 is the only supported signature algorithm.
+const algorithmEd25519 = "ed25519"
+
+var (
+	// ErrSignatureMissing indicates that an artifact is not signed.
+	ErrSignatureMissing = errors.New("artifact signature missing")
+	// ErrUntrustedKey indicates that an artifact is signed by a key that is not
+	// trusted.
+	ErrUntrustedKey = errors.New("artifact signed by untrusted key")
+	// ErrSignatureInvalid indicates that an artifact signature does not match
+	// its contents.
+	ErrSignatureInvalid = errors.New("artifact signature invalid")
+)
+
+// Signature is the content of the signature file of an artifact.
+type Signature struct {
+	KeyID     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	// Digest is the digest of the artifact files in the format
+	// sha256:<hex encoded digest>.
+	Digest string `json:"digest"`
+	// Signature is the base64 encoded signature of Digest.
+	Signature string `json:"signature"`
+}
+
+// Sign signs the artifact in directory root with key and writes the signature
+// to SignatureFileName in root.
+func Sign(root string, key ed25519.PrivateKey) (Signature, error) {
+	digest, err := Digest(root)
+	if err != nil {
+		return Signature{}, errors.WithMessage(err, "digest artifact")
+	}
+	signature := Signature{
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Algorithm: algorithmEd25519,
+		Digest:    digest,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(digest))),
+	}
+	content, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return Signature{}, errors.Wrap(err, "marshal signature")
+	}
+	err = os.WriteFile(filepath.Join(root, SignatureFileName), content, 0644)
+	if err != nil {
+		return Signature{}, errors.Wrap(err, "write signature file")
+	}
+	return signature, nil
+}
+
+// Digest returns the digest of all files in directory root except the
+// signature file. The digest is the SHA256 of a line for each file, sorted by
+// path, with the hex encoded SHA256 of the file content and the slash
+// separated path relative to root.
+func Digest(root string) (string, error) {
+	var lines []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if relativePath == SignatureFileName {
+			return nil
+		}
+		fileDigest, err := fileSHA256(path)
+		if err != nil {
+			return errors.WithMessagef(err, "digest file '%s'", relativePath)
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s\n", fileDigest, relativePath))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// KeyID returns an identifier of key used to find the key a signature is made
+// with.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 ed25519 private key from path as
+// generated by
+//
+//	openssl genpkey -algorithm ed25519
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse private key")
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type %T: only ed25519 keys are supported", key)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX ed25519 public key from path as
+// generated by
+//
+//	openssl pkey -pubout
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse public key")
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported public key type %T: only ed25519 keys are supported", key)
+	}
+	return publicKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read key file")
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in '%s'", path)
+	}
+	return block, nil
+}
+
+// Verifier verifies artifact signatures against a set of trusted keys.
+type Verifier struct {
+	keys map[string]ed25519.PublicKey
+	// enforce controls whether verification errors are returned or only logged.
+	enforce bool
+}
+
+// NewVerifier returns a Verifier trusting signatures made by keys. If enforce
+// is false verification errors are logged and not returned allowing unsigned
+// artifacts to be released while signing is rolled out.
+func NewVerifier(enforce bool, keys ...ed25519.PublicKey) *Verifier {
+	v := Verifier{
+		keys:    make(map[string]ed25519.PublicKey),
+		enforce: enforce,
+	}
+	for _, key := range keys {
+		v.keys[KeyID(key)] = key
+	}
+	return &v
+}
+
+// VerifyArtifact verifies the signature of the artifact in directory root.
+func (v *Verifier) VerifyArtifact(ctx context.Context, root string) error {
+	err := v.verify(root)
+	if err != nil && !v.enforce {
+		log.WithContext(ctx).Errorf("signing: artifact in '%s' failed signature verification but verification is not enforced: %v", root, err)
+		return nil
+	}
+	return err
+}
+
+func (v *Verifier) verify(root string) error {
+	content, err := os.ReadFile(filepath.Join(root, SignatureFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrSignatureMissing
+		}
+		return errors.Wrap(err, "read signature file")
+	}
+	var signature Signature
+	err = json.Unmarshal(content, &signature)
+	if err != nil {
+		return errors.WithMessage(ErrSignatureInvalid, "malformed signature file")
+	}
+	if signature.Algorithm != algorithmEd25519 {
+		return errors.WithMessagef(ErrSignatureInvalid, "unsupported algorithm '%s'", signature.Algorithm)
+	}
+	key, ok := v.keys[signature.KeyID]
+	if !ok {
+		return errors.WithMessagef(ErrUntrustedKey, "key id '%s'", signature.KeyID)
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return errors.WithMessage(ErrSignatureInvalid, "malformed signature")
+	}
+	if !ed25519.Verify(key, []byte(signature.Digest), signatureBytes) {
+		return errors.WithMessage(ErrSignatureInvalid, "signature does not match digest")
+	}
+	digest, err := Digest(root)
+	if err != nil {
+		return errors.WithMessage(err, "digest artifact")
+	}
+	if digest != signature.Digest {
+		return errors.WithMessagef(ErrSignatureInvalid, "artifact digest '%s' does not match signed digest '%s'", digest, signature.Digest)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package signing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lunarway/release-manager/internal/log"
+	"github.com/lunarway/release-manager/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestVerifier_VerifyArtifact(t *testing.T) {
+	log.Init(&log.Configuration{
+		Level: log.Level{
+			Level: zapcore.DebugLevel,
+		},
+		Development: true,
+	})
+	trustedPublic, trustedPrivate := generateKey(t)
+	_, untrustedPrivate := generateKey(t)
+
+	tt := []struct {
+		name    string
+		key     ed25519.PrivateKey
+		enforce bool
+		// modify is called on the artifact root after signing
+		modify func(t *testing.T, root string)
+		err    error
+	}{
+		{
+			name:    "signed by trusted key",
+			key:     trustedPrivate,
+			enforce: true,
+		},
+		{
+			name:    "signed by untrusted key",
+			key:     untrustedPrivate,
+			enforce: true,
+			err:     signing.ErrUntrustedKey,
+		},
+		{
+			name:    "unsigned",
+			enforce: true,
+			err:     signing.ErrSignatureMissing,
+		},
+		{
+			name:    "modified resource",
+			key:     trustedPrivate,
+			enforce: true,
+			modify: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "dev", "deployment.yaml"), "kind: DaemonSet")
+			},
+			err: signing.ErrSignatureInvalid,
+		},
+		{
+			name:    "added resource",
+			key:     trustedPrivate,
+			enforce: true,
+			modify: func(t *testing.T, root string) {
+				writeFile(t, filepath.Join(root, "prod", "deployment.yaml"), "kind: Deployment")
+			},
+			err: signing.ErrSignatureInvalid,
+		},
+		{
+			name:    "unsigned without enforcement",
+			enforce: false,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "artifact.json"), `{"id":"master-1234"}`)
+			writeFile(t, filepath.Join(root, "dev", "deployment.yaml"), "kind: Deployment")
+			if tc.key != nil {
+				_, err := signing.Sign(root, tc.key)
+				require.NoError(t, err, "sign artifact")
+			}
+			if tc.modify != nil {
+				tc.modify(t, root)
+			}
+			verifier := signing.NewVerifier(tc.enforce, trustedPublic)
+
+			err := verifier.VerifyArtifact(context.Background(), root)
+
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	publicKey, privateKey := generateKey(t)
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err, "marshal private key")
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err, "marshal public key")
+	privatePath := filepath.Join(dir, "key.pem")
+	publicPath := filepath.Join(dir, "key.pub")
+	writeFile(t, privatePath, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})))
+	writeFile(t, publicPath, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})))
+
+	loadedPrivate, err := signing.LoadPrivateKey(privatePath)
+	require.NoError(t, err, "load private key")
+	loadedPublic, err := signing.LoadPublicKey(publicPath)
+	require.NoError(t, err, "load public key")
+
+	assert.Equal(t, privateKey, loadedPrivate, "private key not as expected")
+	assert.Equal(t, publicKey, loadedPublic, "public key not as expected")
+}
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, "generate key")
+	return publicKey, privateKey
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	require.NoError(t, err, "create directory")
+	err = os.WriteFile(path, []byte(content), 0644)
+	require.NoError(t, err, "write file")
+}
@@ -0,0 +1,57 @@
+package slack
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatChangelog(t *testing.T) {
+	entries := func(n int) []string {
+		var e []string
+		for i := 0; i < n; i++ {
+			e = append(e, fmt.Sprintf("entry %d", i))
+		}
+		return e
+	}
+	tt := []struct {
+		name   string
+		groups []ChangelogGroup
+		output string
+	}{
+		{
+			name:   "no changelog",
+			groups: nil,
+			output: "",
+		},
+		{
+			name: "groups",
+			groups: []ChangelogGroup{
+				{Title: "Features", Entries: entries(2)},
+				{Title: "Bug Fixes", Entries: entries(1)},
+			},
+			output: "\n*Changelog:*\n*Features*\n• entry 0\n• entry 1\n*Bug Fixes*\n• entry 0",
+		},
+		{
+			name: "truncated",
+			groups: []ChangelogGroup{
+				{Title: "Features", Entries: entries(maxChangelogEntries - 1)},
+				{Title: "Bug Fixes", Entries: entries(2)},
+				{Title: "Chores", Entries: entries(3)},
+			},
+			output: func() string {
+				s := "\n*Changelog:*\n*Features*"
+				for _, e := range entries(maxChangelogEntries - 1) {
+					s += "\n• " + e
+				}
+				return s + "\n*Bug Fixes*\n• entry 0\n_and 4 more_"
+			}(),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.output, formatChangelog(tc.groups))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lunarway/release-manager/internal/http"
 	"github.com/lunarway/release-manager/internal/log"
@@ -152,8 +153,21 @@ type ReleaseOptions struct {
 	Releaser          string
 	Squad             string
 	Environment       string
+	// Changelog lists the changes included in the release. It is left out of
+	// the message if empty.
+	Changelog []ChangelogGroup
 }
 
+// ChangelogGroup is a titled list of changes included in a release.
+type ChangelogGroup struct {
+	Title   string
+	Entries []string
+}
+
+// maxChangelogEntries is the maximum number of changelog entries included in
+// a release message to keep messages readable for large releases.
+const maxChangelogEntries = 20
+
 func (c *Client) NotifyRelease(ctx context.Context, releaseOptions ReleaseOptions) {
 	err := c.notifySlackReleasesChannel(ctx, releaseOptions)
 	if err != nil {
@@ -176,7 +190,64 @@ func (c *Client) notifySlackReleasesChannel(ctx context.Context, options Release
 		Title:      fmt.Sprintf("%s (%s)", options.Service, options.ArtifactID),
 		TitleLink:  options.CommitLink,
 		Color:      MsgColorGreen,
-		Text:       fmt.Sprintf("*Author:* %s, *Releaser:* %s\n*Message:* _%s_", options.CommitAuthor, options.Releaser, options.CommitMessage),
+		Text:       fmt.Sprintf("*Author:* %s, *Releaser:* %s\n*Message:* _%s_%s", options.CommitAuthor, options.Releaser, options.CommitMessage, formatChangelog(options.Changelog)),
+		MarkdownIn: []string{"text", "fields"},
+	})
+	_, _, err := c.client.PostMessageContext(ctx, fmt.Sprintf("#releases-%s", options.Environment), asUser, attachments)
+	c.notifySquadReleaseChannelBestEffort(ctx, options.Squad, options.Environment, asUser, attachments)
+	return err
+}
+
+// formatChangelog returns groups formatted for a release message. At most
+// maxChangelogEntries entries are included.
+func formatChangelog(groups []ChangelogGroup) string {
+	if len(groups) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n*Changelog:*")
+	var total, written int
+	for _, group := range groups {
+		total += len(group.Entries)
+		if written >= maxChangelogEntries {
+			continue
+		}
+		fmt.Fprintf(&b, "\n*%s*", group.Title)
+		for _, entry := range group.Entries {
+			if written >= maxChangelogEntries {
+				break
+			}
+			fmt.Fprintf(&b, "\n• %s", entry)
+			written++
+		}
+	}
+	if total > written {
+		fmt.Fprintf(&b, "\n_and %d more_", total-written)
+	}
+	return b.String()
+}
+
+type UndeployOptions struct {
+	Service     string
+	Environment string
+	Namespace   string
+	ArtifactID  string
+	Undeployer  string
+	Squad       string
+}
+
+// NotifyUndeploy posts a message to the releases channel of the environment
+// when a service is removed from it.
+func (c *Client) NotifyUndeploy(ctx context.Context, options UndeployOptions) error {
+	if c.muteOptions.Releases {
+		return nil
+	}
+
+	asUser := slack.MsgOptionAsUser(true)
+	attachments := slack.MsgOptionAttachments(slack.Attachment{
+		Title:      fmt.Sprintf("%s undeployed", options.Service),
+		Color:      MsgColorYellow,
+		Text:       fmt.Sprintf("*Undeployer:* %s\n*Namespace:* %s\n*Last artifact:* %s", options.Undeployer, options.Namespace, options.ArtifactID),
 		MarkdownIn: []string{"text", "fields"},
 	})
 	_, _, err := c.client.PostMessageContext(ctx, fmt.Sprintf("#releases-%s", options.Environment), asUser, attachments)
@@ -352,6 +423,53 @@ func (c *Client) NotifyReleaseFailedEvent(ctx context.Context, event NotifyRelea
 	return nil
 }
 
+type NotifyDriftOptions struct {
+	Environment string
+	Drifts      []DriftOptions
+}
+
+type DriftOptions struct {
+	Namespace          string
+	Kind               string
+	Name               string
+	Type               string
+	ExpectedArtifactID string
+	ActualArtifactID   string
+	DetectedAt         time.Time
+}
+
+// NotifyDrift posts drifts between cluster state and the config repository to
+// the releases channel of the environment.
+func (c *Client) NotifyDrift(ctx context.Context, options NotifyDriftOptions) error {
+	if c.muteOptions.Kubernetes || len(options.Drifts) == 0 {
+		return nil
+	}
+	var fields []slack.AttachmentField
+	for _, d := range options.Drifts {
+		fields = append(fields, slack.AttachmentField{
+			Title: fmt.Sprintf("%s %s/%s", d.Kind, d.Namespace, d.Name),
+			Value: fmt.Sprintf("%s since %s\nExpected: *%s*\nActual: *%s*", d.Type, d.DetectedAt.UTC().Format(time.RFC3339), valueOrNone(d.ExpectedArtifactID), valueOrNone(d.ActualArtifactID)),
+			Short: false,
+		})
+	}
+	attachments := slack.MsgOptionAttachments(slack.Attachment{
+		Title:      fmt.Sprintf(":kubernetes: k8s (%s) drift detected :warning:", options.Environment),
+		Color:      MsgColorYellow,
+		Text:       fmt.Sprintf("%d resources differ from the config repository", len(options.Drifts)),
+		MarkdownIn: []string{"text", "fields"},
+		Fields:     fields,
+	})
+	_, _, err := c.client.PostMessageContext(ctx, fmt.Sprintf("#releases-%s", options.Environment), slack.MsgOptionAsUser(true), attachments)
+	return err
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
 func (c *Client) NotifyK8SPodErrorEvent(ctx context.Context, event *http.PodErrorEvent) error {
 	if c.muteOptions.Kubernetes {
 		return nil